@@ -1,30 +1,729 @@
 package main
 
-import "os"
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-// Config holds application configuration loaded from environment variables.
+	"github.com/kareempaes/planning/internal/handler"
+	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/service"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// devJWTSecret is the signing key used when none is configured. It is public,
+// so Validate only accepts it in dev mode.
+const devJWTSecret = "dev-secret-do-not-use-in-production"
+
+// Config holds application configuration. LoadConfig builds it in layers, each
+// overriding the one before: built-in defaults, an optional YAML file,
+// environment variables, then command-line flags.
+//
+// Every setting has a YAML key, and its dotted path doubles as the flag name,
+// as in -database.max_open_conns. The env tag names its environment variable;
+// settings marked allowempty treat an empty variable as a value, not as unset.
+// Fields tagged secret are redacted when the configuration is printed.
 type Config struct {
-	DBDriver       string
-	DBDSN          string
-	Port           string
-	JWTSecret      string
-	MigrationsPath string
+	Env string `yaml:"env" env:"APP_ENV"` // "dev" or "production"; dev relaxes validation
+
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Log       LogConfig       `yaml:"log"`
+	Telemetry TelemetryConfig `yaml:"telemetry"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Push      PushConfig      `yaml:"push"`
+	Email     EmailConfig     `yaml:"email"`
+	Outbound  OutboundConfig  `yaml:"outbound"`
+}
+
+// ServerConfig configures the public HTTP listener.
+type ServerConfig struct {
+	Port               string        `yaml:"port" env:"PORT"`
+	ReadTimeout        time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	IdleTimeout        time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`         // how long in-flight requests get to finish
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY"` // how long readiness fails before the listener closes
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header identifies the client.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// DatabaseConfig selects the storage backend and sizes its connection pool.
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" env:"DB_DRIVER"` // "sqlite", "pgx" (or "postgres") or "memory"
+	DSN             string        `yaml:"dsn" env:"DB_DSN" secret:"true"`
+	MigrationsPath  string        `yaml:"migrations_path" env:"MIGRATIONS_PATH"` // overrides the embedded migrations when set
+	AutoMigrate     bool          `yaml:"auto_migrate" env:"AUTO_MIGRATE"`       // disable when a release job runs cmd/migrate
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+}
+
+// AuthConfig configures authentication, sessions and accounts.
+type AuthConfig struct {
+	JWTSecret            string        `yaml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	AccessTokenExpiry    time.Duration `yaml:"access_token_expiry" env:"ACCESS_TOKEN_EXPIRY"`
+	RefreshTokenExpiry   time.Duration `yaml:"refresh_token_expiry" env:"REFRESH_TOKEN_EXPIRY"`
+	AdminToken           string        `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true"` // empty disables /admin routes
+	LockoutStore         string        `yaml:"lockout_store" env:"LOCKOUT_STORE"`           // "shared" (database, works across replicas) or "memory"
+	AccountDeletionGrace time.Duration `yaml:"account_deletion_grace" env:"ACCOUNT_DELETION_GRACE"`
+
+	PasswordMinLength      int  `yaml:"password_min_length" env:"PASSWORD_MIN_LENGTH"`
+	PasswordMinCharClasses int  `yaml:"password_min_char_classes" env:"PASSWORD_MIN_CHAR_CLASSES"`
+	PasswordAllowCommon    bool `yaml:"password_allow_common" env:"PASSWORD_ALLOW_COMMON"`
+	BcryptCost             int  `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+
+	// OIDCProviders come from the file, or from OIDC_PROVIDERS and its
+	// OIDC_<NAME>_* variables, which replace the file's list when set.
+	OIDCProviders []OIDCProviderConfig `yaml:"oidc_providers"`
+}
+
+// OIDCProviderConfig is the file form of service.OIDCProviderConfig.
+type OIDCProviderConfig struct {
+	Name                string   `yaml:"name"`
+	IssuerURL           string   `yaml:"issuer_url"`
+	ClientID            string   `yaml:"client_id"`
+	ClientSecret        string   `yaml:"client_secret" secret:"true"`
+	RedirectURL         string   `yaml:"redirect_url"`
+	Scopes              []string `yaml:"scopes"`
+	AssumeEmailVerified bool     `yaml:"assume_email_verified"`
+}
+
+// LogConfig configures the process logger.
+type LogConfig struct {
+	Format string `yaml:"format" env:"LOG_FORMAT"` // "text" or "json"
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // "debug", "info", "warn" or "error"
+}
+
+// TelemetryConfig configures metrics and tracing.
+type TelemetryConfig struct {
+	MetricsAddr   string `yaml:"metrics_addr" env:"METRICS_ADDR,allowempty"` // admin listener serving /metrics; empty disables it
+	TraceExporter string `yaml:"trace_exporter" env:"TRACE_EXPORTER"`        // "otlp", "stdout" or "none"
+}
+
+// WebSocketConfig tunes WebSocket connections; see infra.HubConfig.
+type WebSocketConfig struct {
+	SendBuffer     int           `yaml:"send_buffer" env:"WS_SEND_BUFFER"`
+	MaxMessageSize int64         `yaml:"max_message_size" env:"WS_MAX_MESSAGE_SIZE"`
+	PongWait       time.Duration `yaml:"pong_wait" env:"WS_PONG_WAIT"`
+	WriteWait      time.Duration `yaml:"write_wait" env:"WS_WRITE_WAIT"`
+}
+
+// RateLimitConfig sets the limit of each route group, written as N/period such
+// as 60/m; "off" disables a group. See handler.RateLimits.
+type RateLimitConfig struct {
+	Store     string            `yaml:"store" env:"RATE_LIMIT_STORE"` // "shared" (database, works across replicas) or "memory"
+	Auth      service.RateLimit `yaml:"auth" env:"RATE_LIMIT_AUTH"`
+	API       service.RateLimit `yaml:"api" env:"RATE_LIMIT_API"`
+	Messages  service.RateLimit `yaml:"messages" env:"RATE_LIMIT_MESSAGES"`
+	Reports   service.RateLimit `yaml:"reports" env:"RATE_LIMIT_REPORTS"`
+	Search    service.RateLimit `yaml:"search" env:"RATE_LIMIT_SEARCH"`
+	WebSocket service.RateLimit `yaml:"websocket" env:"RATE_LIMIT_WS"`
+}
+
+// CORSConfig lists the browser origins allowed to call the API and open
+// WebSocket connections; see handler.CORSConfig. Lists are comma-separated in
+// the environment and flags.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// PushConfig configures push notifications. Each platform is enabled by
+// setting its credentials; with neither, devices can register but nothing is sent.
+type PushConfig struct {
+	CollapseWindow time.Duration `yaml:"collapse_window" env:"PUSH_COLLAPSE_WINDOW"` // see service.PushDispatchConfig
+
+	APNsKeyFile string `yaml:"apns_key_file" env:"APNS_KEY_FILE"` // .p8 token signing key
+	APNsKeyID   string `yaml:"apns_key_id" env:"APNS_KEY_ID"`
+	APNsTeamID  string `yaml:"apns_team_id" env:"APNS_TEAM_ID"`
+	APNsTopic   string `yaml:"apns_topic" env:"APNS_TOPIC"`     // the app's bundle ID
+	APNsSandbox bool   `yaml:"apns_sandbox" env:"APNS_SANDBOX"` // for development builds of the app
+
+	FCMCredentialsFile string `yaml:"fcm_credentials_file" env:"FCM_CREDENTIALS_FILE"` // service account JSON key
+	FCMProjectID       string `yaml:"fcm_project_id" env:"FCM_PROJECT_ID"`             // defaults to the service account's project
+}
+
+// EmailConfig configures outgoing email and the unread message digests sent
+// with it. With mailer "none" no digests are sent.
+type EmailConfig struct {
+	Mailer string `yaml:"mailer" env:"MAILER"` // "smtp", "file" or "none"
+	From   string `yaml:"from" env:"MAIL_FROM"`
+
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+
+	FileDir string `yaml:"file_dir" env:"MAIL_FILE_DIR"` // where the file mailer writes .eml files
+
+	DigestOfflineAfter  time.Duration `yaml:"digest_offline_after" env:"DIGEST_OFFLINE_AFTER"`   // how long a user is away before unread messages are emailed
+	DigestCheckInterval time.Duration `yaml:"digest_check_interval" env:"DIGEST_CHECK_INTERVAL"` // how often the server looks for digests that are due
 }
 
-// LoadConfig reads configuration from environment variables with sensible defaults.
-func LoadConfig() Config {
+// OutboundConfig governs requests to URLs that users supply: outgoing
+// webhooks and slash-command endpoints.
+type OutboundConfig struct {
+	// AllowPrivateNetworks lets them reach loopback, link-local and private
+	// addresses. Leave it off unless every user is trusted with that access.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"OUTBOUND_ALLOW_PRIVATE_NETWORKS"`
+}
+
+// defaultConfig returns the settings used when nothing overrides them.
+func defaultConfig() Config {
 	return Config{
-		DBDriver:       getEnv("DB_DRIVER", "sqlite"),
-		DBDSN:          getEnv("DB_DSN", ":memory:"),
-		Port:           getEnv("PORT", "8080"),
-		JWTSecret:      getEnv("JWT_SECRET", "dev-secret-do-not-use-in-production"),
-		MigrationsPath: getEnv("MIGRATIONS_PATH", "db/migrations"),
+		Env: "production",
+		Server: ServerConfig{
+			Port:            "8080",
+			ReadTimeout:     15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:          "sqlite",
+			DSN:             ":memory:",
+			AutoMigrate:     true,
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: time.Minute,
+		},
+		Auth: AuthConfig{
+			JWTSecret:              devJWTSecret,
+			AccessTokenExpiry:      15 * time.Minute,
+			RefreshTokenExpiry:     7 * 24 * time.Hour,
+			LockoutStore:           "shared",
+			AccountDeletionGrace:   14 * 24 * time.Hour,
+			PasswordMinLength:      8,
+			PasswordMinCharClasses: 1,
+			BcryptCost:             12,
+		},
+		Log:       LogConfig{Format: "text", Level: "info"},
+		Telemetry: TelemetryConfig{MetricsAddr: ":9090", TraceExporter: "none"},
+		WebSocket: WebSocketConfig{
+			SendBuffer:     256,
+			MaxMessageSize: 4096,
+			PongWait:       60 * time.Second,
+			WriteWait:      10 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Store:     "shared",
+			Auth:      service.RateLimit{Requests: 20, Per: time.Minute},
+			API:       service.RateLimit{Requests: 600, Per: time.Minute},
+			Messages:  service.RateLimit{Requests: 60, Per: time.Minute},
+			Reports:   service.RateLimit{Requests: 10, Per: time.Hour},
+			Search:    service.RateLimit{Requests: 30, Per: time.Minute},
+			WebSocket: service.RateLimit{Requests: 120, Per: time.Minute},
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Idempotency-Key"},
+			ExposedHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Idempotent-Replayed"},
+			MaxAge:         10 * time.Minute,
+		},
+		Push: PushConfig{CollapseWindow: 30 * time.Second},
+		Email: EmailConfig{
+			Mailer:              "none",
+			From:                "Chat <noreply@localhost>",
+			SMTPPort:            587,
+			FileDir:             "mail",
+			DigestOfflineAfter:  time.Hour,
+			DigestCheckInterval: 5 * time.Minute,
+		},
+	}
+}
+
+// LoadConfig builds the configuration from args (without the program name)
+// and the environment seen through lookupEnv, then validates it. The file is
+// named by -config or CONFIG_FILE. printOnly reports whether -print-config
+// asked for the effective configuration instead of a server.
+func LoadConfig(args []string, lookupEnv func(string) (string, bool)) (cfg Config, printOnly bool, err error) {
+	cfg = defaultConfig()
+	settings := settingsOf(reflect.ValueOf(&cfg).Elem(), "")
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	file := fs.String("config", "", "YAML configuration file (default $CONFIG_FILE)")
+	fs.BoolVar(&printOnly, "print-config", false, "print the effective configuration with secrets redacted, then exit")
+	flagged := make(map[string]string)
+	for _, s := range settings {
+		usage := "overrides " + s.key
+		if s.env != "" {
+			usage += " and $" + s.env
+		}
+		fs.Func(s.key, usage, func(v string) error {
+			flagged[s.key] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, false, err
+	}
+
+	path := *file
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadConfigFile(&cfg, path); err != nil {
+			return cfg, false, err
+		}
+	}
+
+	for _, s := range settings {
+		v, ok := lookupEnv(s.env)
+		if s.env == "" || !ok || (v == "" && !s.allowEmpty) {
+			continue
+		}
+		if err := s.set(v); err != nil {
+			return cfg, false, fmt.Errorf("$%s: %w", s.env, err)
+		}
+	}
+	if providers := loadOIDCProviders(lookupEnv); providers != nil {
+		cfg.Auth.OIDCProviders = providers
+	}
+
+	for _, s := range settings {
+		if v, ok := flagged[s.key]; ok {
+			if err := s.set(v); err != nil {
+				return cfg, false, fmt.Errorf("-%s: %w", s.key, err)
+			}
+		}
+	}
+
+	return cfg, printOnly, cfg.Validate()
+}
+
+// loadConfigFile overlays the settings present in a YAML file onto cfg.
+// Unknown keys are rejected so that typos do not pass silently.
+func loadConfigFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadOIDCProviders reads providers listed in OIDC_PROVIDERS (comma-separated names),
+// or returns nil if it is unset. Each provider NAME is configured through
+// OIDC_<NAME>_ISSUER_URL, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL, and
+// optionally _SCOPES and _ASSUME_EMAIL_VERIFIED.
+func loadOIDCProviders(lookupEnv func(string) (string, bool)) []OIDCProviderConfig {
+	names, ok := lookupEnv("OIDC_PROVIDERS")
+	if !ok {
+		return nil
+	}
+	getenv := func(key string) string {
+		v, _ := lookupEnv(key)
+		return v
+	}
+	providers := []OIDCProviderConfig{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:                name,
+			IssuerURL:           getenv(prefix + "ISSUER_URL"),
+			ClientID:            getenv(prefix + "CLIENT_ID"),
+			ClientSecret:        getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:         getenv(prefix + "REDIRECT_URL"),
+			Scopes:              strings.Fields(getenv(prefix + "SCOPES")),
+			AssumeEmailVerified: getenv(prefix+"ASSUME_EMAIL_VERIFIED") == "true",
+		})
+	}
+	return providers
+}
+
+// IsDev reports whether the server runs in development mode.
+func (c Config) IsDev() bool {
+	return c.Env == "dev"
+}
+
+// Validate reports every setting the server cannot run with.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key, val string, allowed ...string) {
+		check(slices.Contains(allowed, val), "%s: %q is not one of %s", key, val, strings.Join(allowed, ", "))
+	}
+
+	oneOf("env", c.Env, "dev", "production")
+
+	check(c.Server.Port != "", "server.port: must be set")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout: must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout: must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	check(c.Server.ShutdownDrainDelay >= 0, "server.shutdown_drain_delay: must not be negative")
+	for _, p := range c.Server.TrustedProxies {
+		_, err := parsePrefix(p)
+		check(err == nil, "server.trusted_proxies: %q is not an address or CIDR range such as 10.0.0.0/8", p)
+	}
+
+	oneOf("database.driver", c.Database.Driver, "sqlite", "pgx", "postgres", "memory")
+	check(c.Database.MaxOpenConns > 0, "database.max_open_conns: must be positive")
+	check(c.Database.MaxIdleConns >= 0 && c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns: must be between 0 and max_open_conns")
+	check(c.Database.ConnMaxLifetime > 0, "database.conn_max_lifetime: must be positive")
+	check(c.Database.ConnMaxIdleTime > 0, "database.conn_max_idle_time: must be positive")
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret: must be set")
+	check(c.IsDev() || c.Auth.JWTSecret != devJWTSecret,
+		"auth.jwt_secret: the built-in development secret is only allowed with env=dev; set JWT_SECRET")
+	check(c.Auth.AccessTokenExpiry > 0, "auth.access_token_expiry: must be positive")
+	check(c.Auth.RefreshTokenExpiry > c.Auth.AccessTokenExpiry, "auth.refresh_token_expiry: must exceed access_token_expiry")
+	oneOf("auth.lockout_store", c.Auth.LockoutStore, "shared", "memory")
+	check(c.Auth.AccountDeletionGrace >= 0, "auth.account_deletion_grace: must not be negative")
+	check(c.Auth.PasswordMinLength > 0, "auth.password_min_length: must be positive")
+	check(c.Auth.PasswordMinCharClasses >= 1 && c.Auth.PasswordMinCharClasses <= 4, "auth.password_min_char_classes: must be between 1 and 4")
+	check(c.Auth.BcryptCost >= bcrypt.MinCost && c.Auth.BcryptCost <= bcrypt.MaxCost,
+		"auth.bcrypt_cost: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	for i, p := range c.Auth.OIDCProviders {
+		check(p.Name != "" && p.IssuerURL != "" && p.ClientID != "" && p.RedirectURL != "",
+			"auth.oidc_providers[%d]: name, issuer_url, client_id and redirect_url must be set", i)
+	}
+
+	oneOf("log.format", c.Log.Format, "text", "json")
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: %q is not one of debug, info, warn, error", c.Log.Level)
+	oneOf("telemetry.trace_exporter", c.Telemetry.TraceExporter, "otlp", "stdout", "none")
+
+	check(c.WebSocket.SendBuffer > 0, "websocket.send_buffer: must be positive")
+	check(c.WebSocket.MaxMessageSize > 0, "websocket.max_message_size: must be positive")
+	check(c.WebSocket.PongWait > 0, "websocket.pong_wait: must be positive")
+	check(c.WebSocket.WriteWait > 0, "websocket.write_wait: must be positive")
+
+	oneOf("rate_limit.store", c.RateLimit.Store, "shared", "memory")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(validOrigin(origin), "cors.allowed_origins: %q is not an origin such as https://chat.example.com", origin)
+	}
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"),
+		"cors.allow_credentials: cannot be combined with the * origin")
+	check(c.CORS.MaxAge >= 0, "cors.max_age: must not be negative")
+
+	check(c.Push.CollapseWindow >= 0, "push.collapse_window: must not be negative")
+	apns := []string{c.Push.APNsKeyFile, c.Push.APNsKeyID, c.Push.APNsTeamID, c.Push.APNsTopic}
+	check(!slices.Contains(apns, "") || slices.Equal(apns, make([]string, len(apns))),
+		"push.apns_*: apns_key_file, apns_key_id, apns_team_id and apns_topic must be set together")
+
+	oneOf("email.mailer", c.Email.Mailer, "smtp", "file", "none")
+	if c.Email.Mailer != "none" {
+		_, err := mail.ParseAddress(c.Email.From)
+		check(err == nil, "email.from: %q is not an address such as Chat <noreply@example.com>", c.Email.From)
+	}
+	if c.Email.Mailer == "smtp" {
+		check(c.Email.SMTPHost != "", "email.smtp_host: must be set for the smtp mailer")
+		check(c.Email.SMTPPort > 0 && c.Email.SMTPPort <= 65535, "email.smtp_port: must be between 1 and 65535")
+	}
+	check(c.Email.Mailer != "file" || c.Email.FileDir != "", "email.file_dir: must be set for the file mailer")
+	check(c.Email.DigestOfflineAfter > 0, "email.digest_offline_after: must be positive")
+	check(c.Email.DigestCheckInterval > 0, "email.digest_check_interval: must be positive")
+
+	return errors.Join(errs...)
+}
+
+// validOrigin accepts "*" and scheme://host[:port], where the host may start
+// with "*." to cover its subdomains.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// Redacted returns a copy of c with every non-empty secret replaced, for printing.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+// WriteYAML writes c, with secrets redacted, in the file format LoadConfig reads.
+func (c Config) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// RegistryConfig translates the settings of the service layer.
+func (c Config) RegistryConfig() service.RegistryConfig {
+	return service.RegistryConfig{
+		Auth:     c.ServiceAuthConfig(),
+		Accounts: service.AccountConfig{DeletionGrace: c.Auth.AccountDeletionGrace},
+		Outbound: service.OutboundConfig(c.Outbound),
+	}
+}
+
+// ServiceAuthConfig translates the auth settings for the service layer.
+func (c Config) ServiceAuthConfig() service.AuthConfig {
+	providers := make([]service.OIDCProviderConfig, 0, len(c.Auth.OIDCProviders))
+	for _, p := range c.Auth.OIDCProviders {
+		providers = append(providers, service.OIDCProviderConfig(p))
+	}
+	return service.AuthConfig{
+		JWTSecret:          c.Auth.JWTSecret,
+		AccessTokenExpiry:  c.Auth.AccessTokenExpiry,
+		RefreshTokenExpiry: c.Auth.RefreshTokenExpiry,
+		OIDCProviders:      providers,
+		PasswordPolicy: service.PasswordPolicy{
+			MinLength:      c.Auth.PasswordMinLength,
+			MinCharClasses: c.Auth.PasswordMinCharClasses,
+			AllowCommon:    c.Auth.PasswordAllowCommon,
+		},
+		BcryptCost: c.Auth.BcryptCost,
+	}
+}
+
+// PoolConfig translates the database pool settings for infra.NewDB.
+func (c Config) PoolConfig() infra.PoolConfig {
+	return infra.PoolConfig{
+		MaxOpenConns:    c.Database.MaxOpenConns,
+		MaxIdleConns:    c.Database.MaxIdleConns,
+		ConnMaxLifetime: c.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: c.Database.ConnMaxIdleTime,
+	}
+}
+
+// HubConfig translates the WebSocket settings for infra.NewHub.
+func (c Config) HubConfig() infra.HubConfig {
+	return infra.HubConfig(c.WebSocket)
+}
+
+// CORSPolicy translates the CORS settings for the router.
+func (c Config) CORSPolicy() handler.CORSConfig {
+	return handler.CORSConfig(c.CORS)
+}
+
+// TrustedProxies parses the trusted proxy networks for the router. Validate
+// has checked that each one parses.
+func (c Config) TrustedProxies() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, p := range c.Server.TrustedProxies {
+		if prefix, err := parsePrefix(p); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// parsePrefix parses a CIDR range, or a single address as a range of one.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	return prefix.Masked(), err
+}
+
+// RateLimits translates the rate limit settings for the router.
+func (c Config) RateLimits() handler.RateLimits {
+	return handler.RateLimits{
+		Auth:      c.RateLimit.Auth,
+		API:       c.RateLimit.API,
+		Messages:  c.RateLimit.Messages,
+		Reports:   c.RateLimit.Reports,
+		Search:    c.RateLimit.Search,
+		WebSocket: c.RateLimit.WebSocket,
+	}
+}
+
+// PushProviders builds a provider for each push platform with credentials.
+func (c Config) PushProviders() ([]service.PushProvider, error) {
+	var providers []service.PushProvider
+	if c.Push.APNsKeyFile != "" {
+		key, err := os.ReadFile(c.Push.APNsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("push.apns_key_file: %w", err)
+		}
+		apnsURL := service.APNsProductionURL
+		if c.Push.APNsSandbox {
+			apnsURL = service.APNsSandboxURL
+		}
+		p, err := service.NewAPNsProvider(service.APNsConfig{
+			Key:    key,
+			KeyID:  c.Push.APNsKeyID,
+			TeamID: c.Push.APNsTeamID,
+			Topic:  c.Push.APNsTopic,
+			URL:    apnsURL,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if c.Push.FCMCredentialsFile != "" {
+		creds, err := os.ReadFile(c.Push.FCMCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("push.fcm_credentials_file: %w", err)
+		}
+		p, err := service.NewFCMProvider(service.FCMConfig{Credentials: creds, ProjectID: c.Push.FCMProjectID}, nil)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// Mailer builds the configured mailer, or returns nil for "none".
+func (c Config) Mailer() (service.Mailer, error) {
+	switch c.Email.Mailer {
+	case "smtp":
+		return service.NewSMTPMailer(service.SMTPConfig{
+			Host:     c.Email.SMTPHost,
+			Port:     c.Email.SMTPPort,
+			Username: c.Email.SMTPUsername,
+			Password: c.Email.SMTPPassword,
+			From:     c.Email.From,
+		})
+	case "file":
+		return service.NewFileMailer(c.Email.FileDir, c.Email.From)
+	default:
+		return nil, nil
+	}
+}
+
+// setting is one scalar field of Config that the environment and flags can set.
+type setting struct {
+	key        string // dotted YAML path, also the flag name
+	env        string
+	allowEmpty bool
+	field      reflect.Value
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// settingsOf lists the settable fields of the struct v, descending into nested
+// sections. String lists are comma-separated; lists of sections, such as the
+// OIDC providers, are file-only.
+func settingsOf(v reflect.Value, prefix string) []setting {
+	var settings []setting
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		field := v.Field(i)
+		switch {
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() != reflect.String:
+			continue
+		case f.Type.Kind() == reflect.Struct && !reflect.PointerTo(f.Type).Implements(textUnmarshalerType):
+			settings = append(settings, settingsOf(field, prefix+name+".")...)
+			continue
+		}
+		env, opt, _ := strings.Cut(f.Tag.Get("env"), ",")
+		settings = append(settings, setting{key: prefix + name, env: env, allowEmpty: opt == "allowempty", field: field})
+	}
+	return settings
+}
+
+// set parses val into the setting's field.
+func (s setting) set(val string) error {
+	if u, ok := s.field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+	switch {
+	case s.field.Type() == durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		s.field.SetInt(int64(d))
+	case s.field.Kind() == reflect.String:
+		s.field.SetString(val)
+	case s.field.Kind() == reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.field.Set(reflect.ValueOf(list))
+	case s.field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		s.field.SetBool(b)
+	case s.field.CanInt():
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		s.field.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.field.Type())
 	}
+	return nil
 }
 
-func getEnv(key, fallback string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+// redact blanks every non-empty field tagged secret within v, copying slices
+// first so that the original configuration is left intact.
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Field(i)
+			if v.Type().Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+				field.SetString("[redacted]")
+				continue
+			}
+			redact(field)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		clone := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(clone, v)
+		v.Set(clone)
+		for i := range clone.Len() {
+			redact(clone.Index(i))
+		}
 	}
-	return fallback
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// envMap adapts a map to the lookupEnv signature LoadConfig takes.
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+env: dev
+server:
+  port: "7000"
+  read_timeout: 5s
+database:
+  max_open_conns: 40
+auth:
+  access_token_expiry: 10m
+rate_limit:
+  messages: 5/s
+`)
+	env := map[string]string{
+		"CONFIG_FILE":       path,
+		"PORT":              "7001",
+		"DB_MAX_OPEN_CONNS": "50",
+		"LOG_LEVEL":         "",
+	}
+	cfg, _, err := LoadConfig([]string{"-server.port", "7002"}, envMap(env))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if cfg.Server.Port != "7002" {
+		t.Errorf("expected the flag to win, got port %q", cfg.Server.Port)
+	}
+	if cfg.Database.MaxOpenConns != 50 {
+		t.Errorf("expected the environment to override the file, got %d", cfg.Database.MaxOpenConns)
+	}
+	if cfg.Server.ReadTimeout != 5*time.Second || cfg.Auth.AccessTokenExpiry != 10*time.Minute {
+		t.Errorf("expected file durations, got %v and %v", cfg.Server.ReadTimeout, cfg.Auth.AccessTokenExpiry)
+	}
+	if want := (service.RateLimit{Requests: 5, Per: time.Second}); cfg.RateLimit.Messages != want {
+		t.Errorf("expected the file's rate limit, got %v", cfg.RateLimit.Messages)
+	}
+	if cfg.Server.IdleTimeout != 60*time.Second || cfg.RateLimit.Search != defaultConfig().RateLimit.Search {
+		t.Error("expected settings missing from every layer to keep their defaults")
+	}
+	if cfg.Log.Level != "info" {
+		t.Errorf("expected an empty variable to count as unset, got level %q", cfg.Log.Level)
+	}
+}
+
+func TestLoadConfig_AllowEmpty(t *testing.T) {
+	cfg, _, err := LoadConfig(nil, envMap(map[string]string{"APP_ENV": "dev", "METRICS_ADDR": ""}))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Telemetry.MetricsAddr != "" {
+		t.Errorf("expected METRICS_ADDR= to disable the admin listener, got %q", cfg.Telemetry.MetricsAddr)
+	}
+}
+
+func TestLoadConfig_RejectsDevSecretOutsideDev(t *testing.T) {
+	if _, _, err := LoadConfig(nil, envMap(nil)); err == nil || !strings.Contains(err.Error(), "auth.jwt_secret") {
+		t.Fatalf("expected the default secret to be refused in production, got %v", err)
+	}
+	if _, _, err := LoadConfig(nil, envMap(map[string]string{"JWT_SECRET": "s3cret"})); err != nil {
+		t.Errorf("expected a configured secret to pass, got %v", err)
+	}
+	if _, _, err := LoadConfig([]string{"-env", "dev"}, envMap(nil)); err != nil {
+		t.Errorf("expected the default secret to pass in dev mode, got %v", err)
+	}
+}
+
+func TestLoadConfig_ReportsEveryInvalidSetting(t *testing.T) {
+	env := map[string]string{
+		"APP_ENV":           "dev",
+		"DB_DRIVER":         "mysql",
+		"LOG_FORMAT":        "xml",
+		"WS_SEND_BUFFER":    "0",
+		"DB_MAX_IDLE_CONNS": "100",
+		"APNS_KEY_ID":       "KEY123",
+		"MAILER":            "smtp",
+	}
+	_, _, err := LoadConfig(nil, envMap(env))
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, key := range []string{"database.driver", "log.format", "websocket.send_buffer", "database.max_idle_conns", "push.apns_*", "email.smtp_host"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected %s to be reported, got %v", key, err)
+		}
+	}
+}
+
+func TestLoadConfig_ParseErrors(t *testing.T) {
+	if _, _, err := LoadConfig(nil, envMap(map[string]string{"SERVER_READ_TIMEOUT": "soon"})); err == nil ||
+		!strings.Contains(err.Error(), "SERVER_READ_TIMEOUT") {
+		t.Errorf("expected a bad duration to name its variable, got %v", err)
+	}
+	if _, _, err := LoadConfig([]string{"-rate_limit.api", "lots"}, envMap(nil)); err == nil {
+		t.Error("expected a bad rate limit flag to fail")
+	}
+	path := writeConfigFile(t, "server:\n  prot: \"80\"\n")
+	if _, _, err := LoadConfig([]string{"-config", path}, envMap(nil)); err == nil {
+		t.Error("expected an unknown file key to fail")
+	}
+}
+
+func TestLoadConfig_OIDCProvidersFromEnv(t *testing.T) {
+	env := map[string]string{
+		"APP_ENV":                         "dev",
+		"OIDC_PROVIDERS":                  "corp",
+		"OIDC_CORP_ISSUER_URL":            "https://sso.example.com",
+		"OIDC_CORP_CLIENT_ID":             "chat",
+		"OIDC_CORP_CLIENT_SECRET":         "hunter2",
+		"OIDC_CORP_REDIRECT_URL":          "https://chat.example.com/api/v1/auth/oidc/corp/callback",
+		"OIDC_CORP_SCOPES":                "openid email",
+		"OIDC_CORP_ASSUME_EMAIL_VERIFIED": "true",
+	}
+	cfg, _, err := LoadConfig(nil, envMap(env))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	providers := cfg.ServiceAuthConfig().OIDCProviders
+	if len(providers) != 1 || providers[0].ClientSecret != "hunter2" || !providers[0].AssumeEmailVerified || len(providers[0].Scopes) != 2 {
+		t.Errorf("unexpected providers %+v", providers)
+	}
+}
+
+func TestLoadConfig_CORS(t *testing.T) {
+	cfg, _, err := LoadConfig(nil, envMap(map[string]string{
+		"APP_ENV":              "dev",
+		"CORS_ALLOWED_ORIGINS": "https://a.example.com, https://*.b.example.com",
+		"CORS_ALLOWED_HEADERS": "Authorization",
+	}))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	policy := cfg.CORSPolicy()
+	if len(policy.AllowedOrigins) != 2 || policy.AllowedOrigins[1] != "https://*.b.example.com" {
+		t.Errorf("expected a comma-separated origin list, got %q", policy.AllowedOrigins)
+	}
+	if len(policy.AllowedHeaders) != 1 || len(policy.AllowedMethods) == 0 {
+		t.Errorf("expected the headers to be replaced and the methods defaulted, got %+v", policy)
+	}
+
+	_, _, err = LoadConfig(nil, envMap(map[string]string{
+		"APP_ENV":                "dev",
+		"CORS_ALLOWED_ORIGINS":   "*,chat.example.com",
+		"CORS_ALLOW_CREDENTIALS": "true",
+	}))
+	if err == nil || !strings.Contains(err.Error(), "cors.allow_credentials") || !strings.Contains(err.Error(), "chat.example.com") {
+		t.Errorf("expected the bad origin and the credentialed wildcard to be reported, got %v", err)
+	}
+}
+
+func TestLoadConfig_TrustedProxies(t *testing.T) {
+	cfg, _, err := LoadConfig(nil, envMap(map[string]string{
+		"APP_ENV":         "dev",
+		"TRUSTED_PROXIES": "10.0.0.0/8, 192.168.1.7, ::1",
+	}))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	got := fmt.Sprint(cfg.TrustedProxies())
+	if got != "[10.0.0.0/8 192.168.1.7/32 ::1/128]" {
+		t.Errorf("unexpected trusted proxies %s", got)
+	}
+
+	_, _, err = LoadConfig(nil, envMap(map[string]string{"APP_ENV": "dev", "TRUSTED_PROXIES": "10.0.0.0/33"}))
+	if err == nil || !strings.Contains(err.Error(), "server.trusted_proxies") {
+		t.Errorf("expected the bad range to be reported, got %v", err)
+	}
+}
+
+func TestConfig_WriteYAMLRedactsSecrets(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Auth.JWTSecret = "jwt-s3cret"
+	cfg.Database.DSN = "postgres://chat:dbpass@db/chat"
+	cfg.Auth.OIDCProviders = []OIDCProviderConfig{{
+		Name:         "corp",
+		IssuerURL:    "https://sso.example.com",
+		ClientID:     "chat",
+		ClientSecret: "oidc-s3cret",
+		RedirectURL:  "https://chat.example.com/api/v1/auth/oidc/corp/callback",
+	}}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteYAML(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := buf.String()
+	for _, secret := range []string{"jwt-s3cret", "dbpass", "oidc-s3cret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q to be redacted from:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "messages: 60/m") {
+		t.Errorf("expected rate limits in their written form, got:\n%s", out)
+	}
+	if cfg.Auth.OIDCProviders[0].ClientSecret != "oidc-s3cret" {
+		t.Error("expected redaction to leave the original configuration intact")
+	}
+
+	// The printed form loads back.
+	path := writeConfigFile(t, out)
+	if _, _, err := LoadConfig([]string{"-config", path, "-auth.jwt_secret", "x"}, envMap(nil)); err != nil {
+		t.Errorf("expected printed configuration to load, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// backgroundJobs runs the server's periodic jobs and tracks them, so that
+// shutdown can stop them and wait for the running ones before the store
+// closes.
+type backgroundJobs struct {
+	ctx    context.Context
+	stop   context.CancelFunc
+	logger *slog.Logger
+	wg     sync.WaitGroup
+}
+
+func newBackgroundJobs(ctx context.Context, logger *slog.Logger) *backgroundJobs {
+	ctx, stop := context.WithCancel(ctx)
+	return &backgroundJobs{ctx: ctx, stop: stop, logger: logger}
+}
+
+// runPeriodic calls fn at once and then every interval until shutdown. fn
+// returns how many items it handled; when batch is positive and a call
+// handles a full batch, the next call follows straight away, so a backlog
+// drains without waiting for the ticker.
+func (j *backgroundJobs) runPeriodic(name string, interval time.Duration, batch int, fn func(context.Context) (int, error)) {
+	logger := j.logger.With(slog.String("job", name))
+	j.wg.Go(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for j.ctx.Err() == nil {
+				n, err := fn(j.ctx)
+				if err != nil {
+					logger.Error("background job failed", slog.Any("error", err))
+				} else if n > 0 {
+					logger.Debug("background job ran", slog.Int("count", n))
+				}
+				if err != nil || batch <= 0 || n < batch {
+					break
+				}
+			}
+
+			select {
+			case <-j.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Shutdown stops the jobs and waits for the running ones to return, or until
+// ctx is done.
+func (j *backgroundJobs) Shutdown(ctx context.Context) error {
+	j.stop()
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackgroundJobs_DrainsAndStops(t *testing.T) {
+	jobs := newBackgroundJobs(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// A backlog of 7 items in batches of 3 drains in three calls at once,
+	// without waiting for the hour-long ticker.
+	backlog := int64(7)
+	var calls atomic.Int64
+	drained := make(chan struct{})
+	jobs.runPeriodic("drain", time.Hour, 3, func(context.Context) (int, error) {
+		calls.Add(1)
+		n := min(backlog, 3)
+		backlog -= n
+		if n < 3 {
+			close(drained)
+		}
+		return int(n), nil
+	})
+
+	// A failing job is not retried until the next tick.
+	var failures atomic.Int64
+	failed := make(chan struct{})
+	jobs.runPeriodic("fail", time.Hour, 3, func(context.Context) (int, error) {
+		if failures.Add(1) == 1 {
+			close(failed)
+		}
+		return 3, errors.New("boom")
+	})
+
+	// A running job is waited for on shutdown.
+	started, finished := make(chan struct{}), make(chan struct{})
+	jobs.runPeriodic("slow", time.Hour, 0, func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		close(finished)
+		return 0, ctx.Err()
+	})
+
+	<-drained
+	<-failed
+	<-started
+	if err := jobs.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("expected shutdown to wait for the running job")
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected the backlog to drain in 3 calls, got %d", got)
+	}
+	if got := failures.Load(); got != 1 {
+		t.Errorf("expected a failed call not to be repeated, got %d", got)
+	}
+}
+
+func TestBackgroundJobs_ShutdownTimeout(t *testing.T) {
+	jobs := newBackgroundJobs(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	jobs.runPeriodic("stuck", time.Hour, 0, func(context.Context) (int, error) {
+		close(started)
+		<-release
+		return 0, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := jobs.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the shutdown deadline, got %v", err)
+	}
+}
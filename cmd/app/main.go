@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/db/migrations"
 	"github.com/kareempaes/planning/internal/handler"
 	"github.com/kareempaes/planning/internal/infra"
 	"github.com/kareempaes/planning/internal/repo"
@@ -16,78 +22,265 @@ import (
 )
 
 func main() {
-	cfg := LoadConfig()
+	cfg, printOnly, err := LoadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if printOnly {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			log.Fatalf("failed to print configuration: %v", err)
+		}
+		return
+	}
 	ctx := context.Background()
 
-	// 1. Database
-	driverType := infra.SQLite
-	driverName := "sqlite"
-	if cfg.DBDriver == "pgx" || cfg.DBDriver == "postgres" {
-		driverType = infra.Postgres
-		driverName = "pgx"
+	logger, err := infra.NewLogger(os.Stderr, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	slog.SetDefault(logger)
+	logger.Info("configuration loaded", slog.String("env", cfg.Env))
+	if cfg.Auth.JWTSecret == devJWTSecret {
+		logger.Warn("signing tokens with the built-in development secret; set JWT_SECRET outside local development")
 	}
 
-	db, err := infra.NewDB(ctx, driverType, cfg.DBDSN)
+	shutdownTracing, err := infra.SetupTracing(ctx, infra.TracingConfig{
+		Exporter:    cfg.Telemetry.TraceExporter,
+		ServiceName: "chat",
+		Stdout:      os.Stdout,
+	})
 	if err != nil {
-		log.Fatalf("failed to open database: %v", err)
+		fatal(logger, "failed to set up tracing", err)
 	}
-	defer db.Close()
 
-	// 2. Migrations
-	if err := infra.RunMigrations(db, driverName, cfg.MigrationsPath); err != nil {
-		log.Fatalf("failed to run migrations: %v", err)
+	var metrics *infra.Metrics
+	if cfg.Telemetry.MetricsAddr != "" {
+		metrics = infra.NewMetrics()
 	}
 
-	// 3. Repositories
-	store, err := repo.NewStore(repo.SQLStore, db)
+	// 1. Repositories
+	store, checks, closeStore, err := openStore(ctx, cfg, metrics)
 	if err != nil {
-		log.Fatalf("failed to create store: %v", err)
+		fatal(logger, "failed to open store", err)
 	}
-
-	// 4. Services
-	authCfg := service.AuthConfig{
-		JWTSecret:          cfg.JWTSecret,
-		AccessTokenExpiry:  15 * time.Minute,
-		RefreshTokenExpiry: 7 * 24 * time.Hour,
+	defer closeStore()
+	if cfg.Auth.LockoutStore == "memory" {
+		store.LoginAttempts = repo.NewMemoryLoginAttemptRepo()
 	}
-	registry, err := service.NewRegistry(service.DefaultRegistry, store, authCfg)
+	if cfg.RateLimit.Store == "memory" {
+		store.RateLimits = repo.NewMemoryRateLimitRepo()
+	}
+
+	// 2. Services
+	registry, err := service.NewRegistry(service.DefaultRegistry, store, cfg.RegistryConfig(), metrics)
 	if err != nil {
-		log.Fatalf("failed to create service registry: %v", err)
+		fatal(logger, "failed to create service registry", err)
 	}
 
-	// 5. WebSocket Hub
-	hub := infra.NewHub()
+	// 3. WebSocket Hub
+	hub := infra.NewHub(logger, cfg.HubConfig())
+	metrics.RegisterHub(hub)
+	// Connecting and disconnecting mark a user as seen, which keeps their
+	// email digests to the messages they missed. Disconnects during shutdown
+	// are still recorded, hence the background context.
+	hub.OnPresence(func(userID uuid.UUID, _ bool) {
+		if err := registry.Digests.Seen(context.Background(), userID); err != nil {
+			logger.Warn("failed to record last seen", slog.String("user_id", userID.String()), slog.Any("error", err))
+		}
+	})
 	go hub.Run()
+	checks = append(checks, handler.HealthCheck{Name: "hub", Check: hub.Ping})
 
-	// 6. Router
-	router := handler.NewRouter(registry, hub, cfg.JWTSecret)
+	// Push notifications reach users the hub has no connection for.
+	pushProviders, err := cfg.PushProviders()
+	if err != nil {
+		fatal(logger, "failed to configure push notifications", err)
+	}
+	if len(pushProviders) > 0 {
+		registry.Push.EnableDispatch(service.PushDispatchConfig{
+			Presence:       hub,
+			Providers:      pushProviders,
+			CollapseWindow: cfg.Push.CollapseWindow,
+		})
+	}
+
+	// Users away for longer get their unread messages in an email digest.
+	mailer, err := cfg.Mailer()
+	if err != nil {
+		fatal(logger, "failed to configure email", err)
+	}
+	if mailer != nil {
+		registry.Digests.EnableDelivery(service.DigestDeliveryConfig{
+			Mailer:       mailer,
+			Presence:     hub,
+			OfflineAfter: cfg.Email.DigestOfflineAfter,
+		})
+	}
+	health := handler.NewHealthHandler(checks...)
+
+	// Conversation events reach WebSocket clients and webhooks alike,
+	// including the messages bots post outside of a request.
+	events := handler.NewConversationEvents(registry.Conversations, registry.Webhooks, hub, logger)
+	registry.Messages.SetListener(events)
 
-	// 7. HTTP Server
+	// Background jobs: account deletion, pruning of expired state, and the
+	// outbound queues. Each runs once straight away, so they start only now
+	// that what they post reaches the hub, webhooks and push.
+	jobs := newBackgroundJobs(ctx, logger)
+	defer jobs.stop()
+	jobs.runPeriodic("account deletion", time.Hour, 0, registry.Accounts.PurgeDue)
+	jobs.runPeriodic("rate limit prune", 10*time.Minute, 0, registry.RateLimits.Prune)
+	jobs.runPeriodic("idempotency key prune", time.Hour, 0, registry.Idempotency.Prune)
+	jobs.runPeriodic("webhook delivery", 5*time.Second, service.WebhookBatchSize, registry.Webhooks.DeliverDue)
+	jobs.runPeriodic("reminder delivery", 15*time.Second, service.ReminderBatchSize, registry.Commands.DeliverDueReminders)
+	if mailer != nil {
+		jobs.runPeriodic("email digest", cfg.Email.DigestCheckInterval, service.DigestBatchSize, registry.Digests.SendDue)
+	}
+
+	// 4. Router
+	router := handler.NewRouter(registry, hub, handler.RouterConfig{
+		JWTSecret:  cfg.Auth.JWTSecret,
+		AdminToken: cfg.Auth.AdminToken,
+		Logger:     logger,
+		Metrics:    metrics,
+		Health:     health,
+		RateLimits: cfg.RateLimits(),
+		CORS:       cfg.CORSPolicy(),
+		Events:     events,
+
+		SecureCookies:  !cfg.IsDev(),
+		TrustedProxies: cfg.TrustedProxies(),
+	})
+
+	// 5. HTTP Server
 	srv := &http.Server{
-		Addr:        ":" + cfg.Port,
+		Addr:        ":" + cfg.Server.Port,
 		Handler:     router,
-		ReadTimeout: 15 * time.Second,
-		IdleTimeout: 60 * time.Second,
+		ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadTimeout: cfg.Server.ReadTimeout,
+		IdleTimeout: cfg.Server.IdleTimeout,
 	}
 
 	go func() {
-		log.Printf("server listening on :%s", cfg.Port)
+		logger.Info("server listening", slog.String("addr", srv.Addr))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			fatal(logger, "server error", err)
 		}
 	}()
 
+	// 6. Admin listener, kept off the public port
+	var adminSrv *http.Server
+	if metrics != nil {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		adminSrv = &http.Server{
+			Addr:        cfg.Telemetry.MetricsAddr,
+			Handler:     mux,
+			ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+			ReadTimeout: cfg.Server.ReadTimeout,
+		}
+		go func() {
+			logger.Info("admin listener started", slog.String("addr", adminSrv.Addr))
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal(logger, "admin listener error", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("shutting down server...")
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Fail readiness first and give load balancers time to notice before the
+	// listener closes and in-flight requests drain.
+	logger.Info("shutting down server", slog.Duration("drain_delay", cfg.Server.ShutdownDrainDelay))
+	health.StartDraining()
+	time.Sleep(cfg.Server.ShutdownDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	if adminSrv != nil {
+		adminSrv.Shutdown(shutdownCtx)
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("server forced to shutdown: %v", err)
+		fatal(logger, "server forced to shutdown", err)
+	}
+	// WebSocket connections are hijacked, so srv.Shutdown does not wait for
+	// them; close them once no new upgrades can arrive.
+	if err := hub.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("websocket clients did not close in time", slog.Any("error", err))
+	}
+	// Answer the slash commands already sent.
+	if err := registry.Commands.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("slash commands did not finish in time", slog.Any("error", err))
+	}
+	// Stop the background jobs and let the running ones finish, so that
+	// none is cut off by the store closing.
+	if err := jobs.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("background jobs did not stop in time", slog.Any("error", err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("failed to flush traces", slog.Any("error", err))
+	}
+	logger.Info("server exited")
+}
+
+// openStore opens the configured backend, applying pending migrations to SQL
+// databases unless auto_migrate is off, and returns the store, the readiness
+// checks it needs, and a function that releases it. DB_DRIVER=memory keeps all
+// data in process memory, which is handy for demos.
+func openStore(ctx context.Context, cfg Config, metrics *infra.Metrics) (*repo.Store, []handler.HealthCheck, func(), error) {
+	if cfg.Database.Driver == "memory" {
+		store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+		return store, nil, func() {}, err
+	}
+
+	driverType := infra.SQLite
+	driverName := "sqlite"
+	dialect := repo.DialectSQLite
+	if cfg.Database.Driver == "pgx" || cfg.Database.Driver == "postgres" {
+		driverType = infra.Postgres
+		driverName = "pgx"
+		dialect = repo.DialectPostgres
 	}
-	log.Println("server exited")
+
+	source := migrations.Source(cfg.Database.MigrationsPath)
+	schemaVersion, err := infra.LatestMigration(source, driverName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	db, err := infra.NewDB(ctx, driverType, cfg.Database.DSN, cfg.PoolConfig())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("open database: %w", err)
+	}
+	if cfg.Database.AutoMigrate {
+		if err := infra.RunMigrations(db, driverName, source); err != nil {
+			db.Close()
+			return nil, nil, nil, err
+		}
+	}
+	store, err := repo.NewStore(repo.SQLStore, db, dialect)
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+	metrics.RegisterDB(db, driverName)
+
+	checks := []handler.HealthCheck{
+		{Name: "database", Check: db.PingContext},
+		{Name: "schema", Check: func(ctx context.Context) error { return infra.CheckSchema(ctx, db, schemaVersion) }},
+	}
+	return store, checks, func() { db.Close() }, nil
+}
+
+// fatal logs err and exits, for failures the server cannot run past.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
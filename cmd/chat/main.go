@@ -0,0 +1,50 @@
+// Command chat is an interactive terminal client for the chat server. It uses
+// the REST API through pkg/client and prints messages from the WebSocket
+// stream as they arrive, which makes it a quick way to exercise the realtime
+// path without the web app.
+//
+//	go run ./cmd/chat -server http://localhost:8080 -email alice@example.com
+//
+// The password is read from CHAT_PASSWORD or prompted for, without echo when
+// stdin is a terminal; a personal access token can be given with -token or
+// CHAT_TOKEN instead. Type /help once connected for the commands.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/kareempaes/planning/pkg/client"
+)
+
+func main() {
+	server := flag.String("server", envOr("CHAT_SERVER", "http://localhost:8080"), "server base URL (CHAT_SERVER)")
+	email := flag.String("email", os.Getenv("CHAT_EMAIL"), "log in as this user on start (CHAT_EMAIL)")
+	token := flag.String("token", os.Getenv("CHAT_TOKEN"), "authenticate with a personal access token instead (CHAT_TOKEN)")
+	flag.Parse()
+
+	c, err := client.New(*server, client.Config{Token: *token})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chat:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s := newSession(c, os.Stdin, os.Stdout)
+	if err := s.run(ctx, *email, os.Getenv("CHAT_PASSWORD")); err != nil {
+		fmt.Fprintln(os.Stderr, "chat:", err)
+		os.Exit(1)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/pkg/client"
+	"golang.org/x/term"
+)
+
+// historyPageSize is how many messages /open and /more show at a time.
+const historyPageSize = 20
+
+const help = `commands:
+  /login EMAIL           log in; the password is asked for on the next line
+  /register EMAIL NAME   create an account and log in
+  /list                  list conversations with unread counts
+  /open N                open conversation N from the last /list
+  /more                  show older messages in the open conversation
+  /close                 close the open conversation
+  /whoami                show the logged-in user
+  /help                  show this help
+  /quit                  exit
+Any other line, including other /commands such as /poll or /remind, is sent
+to the open conversation. Start a line with // to send it with one slash,
+e.g. //help runs /help in the conversation.
+`
+
+// session is one interactive run of the client: it reads commands from in
+// and writes to out, while a background goroutine prints live events.
+type session struct {
+	c     *client.Client
+	reads chan bool   // asks the reader for a line; true for a password
+	lines chan string // the reader's answers, closed at the end of input
+	eof   chan struct{}
+	out   io.Writer
+	outMu sync.Mutex
+
+	// readPassword reads a line without echoing it. It is nil unless in is a
+	// terminal, in which case passwords are read like any other line.
+	readPassword func() (string, error)
+
+	mu         sync.Mutex // guards the fields below
+	me         *client.User
+	convos     []client.ConversationSummary // as last listed, numbered from 1
+	open       *openConversation
+	names      map[uuid.UUID]string // display names by user
+	titles     map[uuid.UUID]string // as shown by /list, by conversation
+	stopEvents context.CancelFunc
+}
+
+type openConversation struct {
+	id     uuid.UUID
+	title  string
+	cursor *string // the next older page of history; nil once it is all shown
+}
+
+func newSession(c *client.Client, in io.Reader, out io.Writer) *session {
+	s := &session{c: c, reads: make(chan bool), lines: make(chan string), eof: make(chan struct{}), out: out, names: make(map[uuid.UUID]string), titles: make(map[uuid.UUID]string)}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		s.readPassword = func() (string, error) {
+			b, err := term.ReadPassword(int(f.Fd()))
+			return string(b), err
+		}
+	}
+	go s.read(in)
+	return s
+}
+
+// read answers readLine and readSecret. It reads only when asked, so a
+// password is never taken from in by the scanner before the terminal's echo
+// is turned off.
+func (s *session) read(in io.Reader) {
+	defer close(s.eof)
+	defer close(s.lines)
+	scanner := bufio.NewScanner(in)
+	for secret := range s.reads {
+		var line string
+		if secret && s.readPassword != nil {
+			p, err := s.readPassword()
+			s.printf("\n")
+			if err != nil {
+				return
+			}
+			line = p
+		} else {
+			if !scanner.Scan() {
+				return
+			}
+			line = scanner.Text()
+		}
+		s.lines <- line
+	}
+}
+
+// run handles commands until /quit, the end of input or ctx is done. It logs
+// in as email first if given, or checks a token the client was created with.
+func (s *session) run(ctx context.Context, email, password string) error {
+	defer func() {
+		s.mu.Lock()
+		if s.stopEvents != nil {
+			s.stopEvents()
+		}
+		s.mu.Unlock()
+	}()
+
+	switch {
+	case email != "":
+		s.login(ctx, email, password)
+	case s.c.Tokens().AccessToken != "":
+		s.authenticated(ctx)
+	default:
+		s.printf("not logged in; use /login EMAIL or /register EMAIL NAME (/help for more)\n")
+	}
+
+	for {
+		line, ok := s.readLine(ctx)
+		if !ok {
+			return ctx.Err()
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "/quit" {
+			return nil
+		}
+		s.handle(ctx, line)
+	}
+}
+
+func (s *session) readLine(ctx context.Context) (string, bool) {
+	return s.next(ctx, false)
+}
+
+// readSecret reads a password, without echoing it if in is a terminal.
+func (s *session) readSecret(ctx context.Context) (string, bool) {
+	return s.next(ctx, true)
+}
+
+func (s *session) next(ctx context.Context, secret bool) (string, bool) {
+	select {
+	case s.reads <- secret:
+	case <-s.eof:
+		return "", false
+	case <-ctx.Done():
+		return "", false
+	}
+	select {
+	case line, ok := <-s.lines:
+		return line, ok
+	case <-ctx.Done():
+		return "", false
+	}
+}
+
+func (s *session) handle(ctx context.Context, line string) {
+	if !strings.HasPrefix(line, "/") {
+		s.send(ctx, line)
+		return
+	}
+	if strings.HasPrefix(line, "//") {
+		s.send(ctx, line[1:])
+		return
+	}
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch cmd {
+	case "/help":
+		s.printf("%s", help)
+	case "/login":
+		if arg == "" {
+			s.printf("usage: /login EMAIL\n")
+			return
+		}
+		s.login(ctx, arg, "")
+	case "/register":
+		email, name, _ := strings.Cut(arg, " ")
+		if email == "" || strings.TrimSpace(name) == "" {
+			s.printf("usage: /register EMAIL NAME\n")
+			return
+		}
+		s.register(ctx, email, strings.TrimSpace(name))
+	case "/whoami":
+		s.mu.Lock()
+		me := s.me
+		s.mu.Unlock()
+		if me == nil {
+			s.printf("not logged in\n")
+			return
+		}
+		s.printf("%s <%s>\n", me.DisplayName, me.Email)
+	case "/list":
+		s.list(ctx)
+	case "/open":
+		s.openConversation(ctx, arg)
+	case "/more":
+		s.more(ctx)
+	case "/close":
+		s.mu.Lock()
+		s.open = nil
+		s.mu.Unlock()
+	default:
+		// Slash commands the client does not know are the server's.
+		s.send(ctx, line)
+	}
+}
+
+func (s *session) login(ctx context.Context, email, password string) {
+	if password == "" {
+		s.printf("password: ")
+		var ok bool
+		if password, ok = s.readSecret(ctx); !ok {
+			return
+		}
+	}
+	if _, err := s.c.Login(ctx, email, password); err != nil {
+		s.printf("login failed: %v\n", err)
+		return
+	}
+	s.authenticated(ctx)
+}
+
+func (s *session) register(ctx context.Context, email, name string) {
+	s.printf("choose a password: ")
+	password, ok := s.readSecret(ctx)
+	if !ok {
+		return
+	}
+	req := client.RegisterRequest{Email: email, Password: password, DisplayName: name}
+	if _, err := s.c.Register(ctx, req); err != nil {
+		s.printf("register failed: %v\n", err)
+		return
+	}
+	s.authenticated(ctx)
+}
+
+// authenticated loads the user and (re)starts the live event stream.
+func (s *session) authenticated(ctx context.Context) {
+	me, err := s.c.Me(ctx)
+	if err != nil {
+		s.printf("could not load your profile: %v\n", err)
+		return
+	}
+
+	eventsCtx, stop := context.WithCancel(ctx)
+	s.mu.Lock()
+	if s.stopEvents != nil {
+		s.stopEvents()
+	}
+	s.me, s.stopEvents, s.open, s.convos = me, stop, nil, nil
+	clear(s.names)
+	clear(s.titles)
+	s.mu.Unlock()
+
+	s.printf("logged in as %s <%s>\n", me.DisplayName, me.Email)
+	go s.watch(eventsCtx, s.c.Events(eventsCtx))
+}
+
+// watch prints live events until the stream ends.
+func (s *session) watch(ctx context.Context, events <-chan client.Event) {
+	for e := range events {
+		switch e.Type {
+		case client.EventConnected:
+			s.printf("* live updates connected\n")
+		case client.EventDisconnected:
+			s.printf("* live updates lost (%v); reconnecting\n", e.Err)
+		case client.EventError:
+			s.printf("* server: %v\n", e.Err)
+		case client.EventMessage:
+			s.mu.Lock()
+			open := s.open != nil && s.open.id == e.Message.ConversationID
+			title := s.titles[e.Message.ConversationID]
+			s.mu.Unlock()
+
+			if open {
+				s.printMessage(ctx, *e.Message)
+			} else {
+				if title == "" {
+					title = "a conversation not listed yet"
+				}
+				s.printf("* new message from %s in %s\n", s.name(ctx, e.Message.SenderID), title)
+			}
+		}
+	}
+}
+
+func (s *session) list(ctx context.Context) {
+	page, err := s.c.Conversations(ctx, client.Page{Limit: 100})
+	if err != nil {
+		s.printf("could not list conversations: %v\n", err)
+		return
+	}
+	if len(page.Conversations) == 0 {
+		s.printf("no conversations yet\n")
+	}
+
+	s.mu.Lock()
+	s.convos = page.Conversations
+	s.mu.Unlock()
+
+	for i, convo := range page.Conversations {
+		title := s.title(ctx, convo)
+		s.mu.Lock()
+		s.titles[convo.ID] = title
+		s.mu.Unlock()
+		unread := ""
+		if convo.UnreadCount > 0 {
+			unread = fmt.Sprintf(" [%d unread]", convo.UnreadCount)
+		}
+		last := ""
+		if convo.LastMessage != nil {
+			last = " — " + truncate(convo.LastMessage.Body, 40)
+		}
+		s.printf("%2d. %s%s%s\n", i+1, title, unread, last)
+	}
+}
+
+func (s *session) openConversation(ctx context.Context, arg string) {
+	n, err := strconv.Atoi(arg)
+	s.mu.Lock()
+	if err != nil || n < 1 || n > len(s.convos) {
+		s.mu.Unlock()
+		s.printf("usage: /open N, with N from /list\n")
+		return
+	}
+	convo := s.convos[n-1]
+	s.mu.Unlock()
+
+	s.mu.Lock()
+	open := &openConversation{id: convo.ID, title: s.titles[convo.ID]}
+	s.open = open
+	s.mu.Unlock()
+
+	s.printf("--- %s ---\n", open.title)
+	s.showHistory(ctx, open, "")
+}
+
+func (s *session) more(ctx context.Context) {
+	s.mu.Lock()
+	open := s.open
+	s.mu.Unlock()
+	switch {
+	case open == nil:
+		s.printf("no conversation open\n")
+	case open.cursor == nil:
+		s.printf("no older messages\n")
+	default:
+		s.printf("--- older ---\n")
+		s.showHistory(ctx, open, *open.cursor)
+	}
+}
+
+// showHistory prints a page of history, oldest first, and remembers where
+// the next older page starts.
+func (s *session) showHistory(ctx context.Context, open *openConversation, cursor string) {
+	page, err := s.c.Messages(ctx, open.id, client.Page{Cursor: cursor, Limit: historyPageSize})
+	if err != nil {
+		s.printf("could not load messages: %v\n", err)
+		return
+	}
+	for _, msg := range slices.Backward(page.Messages) {
+		s.printMessage(ctx, msg)
+	}
+
+	s.mu.Lock()
+	open.cursor = nil
+	if page.Pagination.HasMore {
+		open.cursor = page.Pagination.NextCursor
+	}
+	hasMore := open.cursor != nil
+	s.mu.Unlock()
+	if hasMore {
+		s.printf("(/more for older messages)\n")
+	}
+}
+
+func (s *session) send(ctx context.Context, body string) {
+	s.mu.Lock()
+	open := s.open
+	s.mu.Unlock()
+	if open == nil {
+		s.printf("no conversation open; use /list and /open N\n")
+		return
+	}
+
+	// A client message ID makes a retried send return the original message.
+	req := client.SendMessageRequest{Body: body, ClientMessageID: uuid.NewString()}
+	msg, _, err := s.c.SendMessage(ctx, open.id, req)
+	if err != nil {
+		s.printf("not sent: %v\n", err)
+		return
+	}
+	s.printMessage(ctx, *msg)
+}
+
+func (s *session) printMessage(ctx context.Context, msg client.Message) {
+	s.printf("[%s] %s: %s\n", msg.CreatedAt.Local().Format("15:04"), s.name(ctx, msg.SenderID), msg.Body)
+}
+
+// title names a conversation: a group by its name, a direct conversation by
+// the other participants.
+func (s *session) title(ctx context.Context, convo client.ConversationSummary) string {
+	s.mu.Lock()
+	title := s.summaryTitleLocked(convo)
+	s.mu.Unlock()
+	if title != "" {
+		return title
+	}
+
+	// The summary may not carry participants; the full conversation does.
+	full, err := s.c.Conversation(ctx, convo.ID)
+	if err != nil {
+		return "conversation " + convo.ID.String()[:8]
+	}
+	var names []string
+	for _, p := range full.Participants {
+		if s.isMe(p.UserID) {
+			continue
+		}
+		names = append(names, s.name(ctx, p.UserID))
+	}
+	if len(names) == 0 {
+		return "just you"
+	}
+	return strings.Join(names, ", ")
+}
+
+func (s *session) summaryTitleLocked(convo client.ConversationSummary) string {
+	if convo.Name != nil && *convo.Name != "" {
+		return *convo.Name
+	}
+	var names []string
+	for _, p := range convo.Participants {
+		if s.me != nil && p.UserID == s.me.ID {
+			continue
+		}
+		if p.DisplayName != "" {
+			names = append(names, p.DisplayName)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (s *session) isMe(id uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.me != nil && s.me.ID == id
+}
+
+// name returns a user's display name, looking it up once per user.
+func (s *session) name(ctx context.Context, id uuid.UUID) string {
+	s.mu.Lock()
+	if s.me != nil && s.me.ID == id {
+		s.mu.Unlock()
+		return "you"
+	}
+	name, ok := s.names[id]
+	s.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	profile, err := s.c.User(ctx, id)
+	if err != nil {
+		return id.String()[:8]
+	}
+	name = profile.DisplayName
+	s.mu.Lock()
+	s.names[id] = name
+	s.mu.Unlock()
+	return name
+}
+
+func (s *session) printf(format string, args ...any) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format, args...)
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/handler"
+	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/repo"
+	"github.com/kareempaes/planning/internal/service"
+	"github.com/kareempaes/planning/pkg/client"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := service.NewRegistry(service.DefaultRegistry, store, service.RegistryConfig{
+		Auth: service.AuthConfig{
+			JWTSecret:          "test-secret",
+			AccessTokenExpiry:  15 * time.Minute,
+			RefreshTokenExpiry: time.Hour,
+			BcryptCost:         4,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	hub := infra.NewHub(logger, infra.HubConfig{})
+	go hub.Run()
+	events := handler.NewConversationEvents(registry.Conversations, registry.Webhooks, hub, logger)
+	registry.Messages.SetListener(events)
+	srv := httptest.NewServer(handler.NewRouter(registry, hub, handler.RouterConfig{JWTSecret: "test-secret", Logger: logger, Events: events}))
+	t.Cleanup(func() {
+		hub.Shutdown(context.Background())
+		srv.Close()
+	})
+	return srv
+}
+
+// lockedBuffer collects a session's output, which two goroutines write.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSession(t *testing.T) {
+	srv := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alice, err := client.New(srv.URL, client.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Register(ctx, client.RegisterRequest{Email: "alice@example.com", Password: "correct-horse-7", DisplayName: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	bobClient, err := client.New(srv.URL, client.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := bobClient.Register(ctx, client.RegisterRequest{Email: "bob@example.com", Password: "correct-horse-7", DisplayName: "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	convo, _, err := alice.CreateConversation(ctx, client.CreateConversationRequest{Type: "direct", ParticipantIDs: []string{bob.User.ID.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range historyPageSize + 5 {
+		if _, _, err := alice.SendMessage(ctx, convo.ID, client.SendMessageRequest{Body: fmt.Sprintf("old %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Bob runs the terminal client.
+	c, err := client.New(srv.URL, client.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, typed := io.Pipe()
+	var out lockedBuffer
+	done := make(chan error, 1)
+	go func() { done <- newSession(c, in, &out).run(ctx, "bob@example.com", "correct-horse-7") }()
+
+	waitFor := func(want string) {
+		t.Helper()
+		for !strings.Contains(out.String(), want) {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %q in output:\n%s", want, out.String())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	typeLine := func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(typed, line+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor("logged in as Bob")
+	waitFor("live updates connected")
+
+	typeLine("/list")
+	waitFor(" 1. Alice\n")
+
+	typeLine("/open 1")
+	waitFor("Alice: old 24")
+	waitFor("/more for older messages")
+	if strings.Contains(out.String(), "Alice: old 4\n") {
+		t.Errorf("expected only the latest page on open, got:\n%s", out.String())
+	}
+	typeLine("/more")
+	waitFor("Alice: old 0")
+
+	typeLine("hi alice")
+	waitFor("you: hi alice")
+	history, err := alice.Messages(ctx, convo.ID, client.Page{Limit: 1})
+	if err != nil || len(history.Messages) != 1 || history.Messages[0].Body != "hi alice" {
+		t.Errorf("expected alice to see bob's message, got %+v, %v", history, err)
+	}
+
+	// Slash commands the client does not know go to the server's bot, and
+	// a double slash sends a local command's name.
+	typeLine("/nope now")
+	waitFor("you: /nope now")
+	waitFor("Commands: Unknown command /nope")
+	typeLine("//help")
+	waitFor("you: /help")
+	waitFor("/remind <duration> <text>")
+
+	if _, _, err := alice.SendMessage(ctx, convo.ID, client.SendMessageRequest{Body: "live"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("Alice: live")
+
+	typeLine("/close")
+	if _, _, err := alice.SendMessage(ctx, convo.ID, client.SendMessageRequest{Body: "while closed"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("* new message from Alice in Alice")
+
+	typeLine("/quit")
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean exit, got %v", err)
+	}
+}
+
+func TestSession_ReadsPasswordsWithoutEcho(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Piped input has no echo to turn off, so passwords are ordinary lines.
+	s := newSession(nil, strings.NewReader("hunter22\n/list\n"), io.Discard)
+	if p, ok := s.readSecret(ctx); !ok || p != "hunter22" {
+		t.Errorf("expected the password from the next line, got %q, %v", p, ok)
+	}
+
+	// On a terminal the password is read with echo off, and the line after
+	// it is still there for the next command.
+	s = newSession(nil, strings.NewReader("/list\n"), io.Discard)
+	s.readPassword = func() (string, error) { return "hunter22", nil }
+	if p, ok := s.readSecret(ctx); !ok || p != "hunter22" {
+		t.Errorf("expected the password from the terminal, got %q, %v", p, ok)
+	}
+	if line, ok := s.readLine(ctx); !ok || line != "/list" {
+		t.Errorf("expected the next command, got %q, %v", line, ok)
+	}
+	if _, ok := s.readLine(ctx); ok {
+		t.Error("expected the end of input")
+	}
+}
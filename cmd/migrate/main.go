@@ -0,0 +1,167 @@
+// Command migrate manages the database schema. It reads DB_DRIVER, DB_DSN and
+// MIGRATIONS_PATH like the server does, so a release job can run it against the
+// same database before new servers start. DB_DSN has no default: migrating a
+// throwaway in-memory database by accident would report success and change
+// nothing.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kareempaes/planning/db/migrations"
+	"github.com/kareempaes/planning/internal/infra"
+)
+
+const usage = `usage: migrate <command> [arg]
+
+commands:
+  up           apply all pending migrations
+  down N       roll back the last N migrations
+  goto V       migrate up or down to version V
+  status       show the applied version and the available migrations
+  force V      mark version V as applied and clear the dirty flag
+  create NAME  add empty up and down files for every dialect under -dir
+
+flags:
+`
+
+func main() {
+	dir := flag.String("dir", "db/migrations", "migrations source tree written by create")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(flag.Args(), *dir); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, dir string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd, args := args[0], args[1:]
+
+	if cmd == "create" {
+		if len(args) != 1 {
+			return fmt.Errorf("create takes a migration name")
+		}
+		paths, err := infra.CreateMigration(dir, args[0])
+		for _, p := range paths {
+			fmt.Println(p)
+		}
+		return err
+	}
+
+	m, err := openMigrator()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch cmd {
+	case "up":
+		return m.Up()
+	case "down":
+		n, err := intArg(args, "down")
+		if err != nil {
+			return err
+		}
+		return m.Down(n)
+	case "goto":
+		v, err := intArg(args, "goto")
+		if err != nil {
+			return err
+		}
+		if v < 0 {
+			return fmt.Errorf("goto: version must not be negative")
+		}
+		return m.Goto(uint(v))
+	case "force":
+		v, err := intArg(args, "force")
+		if err != nil {
+			return err
+		}
+		return m.Force(v)
+	case "status":
+		return printStatus(m)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func openMigrator() (*infra.Migrator, error) {
+	driverType := infra.SQLite
+	driverName := "sqlite"
+	switch driver := getEnv("DB_DRIVER", "sqlite"); driver {
+	case "sqlite":
+	case "pgx", "postgres":
+		driverType = infra.Postgres
+		driverName = "pgx"
+	default:
+		return nil, fmt.Errorf("DB_DRIVER %q has no schema to migrate", driver)
+	}
+
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" {
+		return nil, fmt.Errorf("DB_DSN must be set")
+	}
+
+	db, err := infra.NewDB(context.Background(), driverType, dsn, infra.PoolConfig{})
+	if err != nil {
+		return nil, err
+	}
+	m, err := infra.NewMigrator(db, driverName, migrations.Source(os.Getenv("MIGRATIONS_PATH")))
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func printStatus(m *infra.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	state := "clean"
+	if status.Dirty {
+		state = "dirty: fix the schema by hand, then run force"
+	}
+	fmt.Printf("version %d (%s)\n\n", status.Version, state)
+	for _, f := range status.Available {
+		mark := "pending"
+		if f.Version <= status.Version {
+			mark = "applied"
+		}
+		fmt.Printf("  %06d  %-8s %s\n", f.Version, mark, f.Name)
+	}
+	return nil
+}
+
+func intArg(args []string, cmd string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%s takes exactly one number", cmd)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", cmd, err)
+	}
+	return n, nil
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
@@ -0,0 +1,23 @@
+// Package migrations embeds the SQL schema migrations so that binaries carry
+// them instead of reading them from disk.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+// FS holds one subdirectory of migrations per SQL dialect: postgres and sqlite.
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
+
+// Source returns the migrations to apply: the embedded set, or the directory at
+// path when one is given, which lets a deployment override what was built in.
+func Source(path string) fs.FS {
+	if path == "" {
+		return FS
+	}
+	return os.DirFS(path)
+}
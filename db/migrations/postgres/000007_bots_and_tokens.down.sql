@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS personal_access_tokens;
+DROP INDEX IF EXISTS idx_users_owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS type;
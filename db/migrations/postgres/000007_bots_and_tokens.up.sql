@@ -0,0 +1,21 @@
+ALTER TABLE users ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'human';
+ALTER TABLE users ADD COLUMN owner_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_users_owner_id ON users (owner_id);
+
+CREATE TABLE personal_access_tokens (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by   UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    token_hash   VARCHAR(255) NOT NULL,
+    scopes       TEXT         NOT NULL,
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    revoked_at   TIMESTAMPTZ,
+
+    CONSTRAINT personal_access_tokens_token_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_pat_created_by ON personal_access_tokens (created_by);
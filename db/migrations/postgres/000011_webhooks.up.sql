@@ -0,0 +1,32 @@
+-- Endpoints that receive a conversation's events, and the queue of deliveries
+-- to them. A pending delivery is due at next_attempt_at; a dispatcher that
+-- claims it pushes next_attempt_at past its own request timeout, so another
+-- instance only picks it up again if the first one died.
+CREATE TABLE webhooks (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID          NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    created_by      UUID          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url             VARCHAR(2048) NOT NULL,
+    secret          VARCHAR(255)  NOT NULL,
+    events          TEXT          NOT NULL,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhooks_conversation_id ON webhooks (conversation_id);
+
+CREATE TABLE webhook_deliveries (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id      UUID         NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type      VARCHAR(64)  NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL,
+    last_attempt_at TIMESTAMPTZ,
+    response_status INTEGER,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at);
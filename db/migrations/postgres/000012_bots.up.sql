@@ -0,0 +1,62 @@
+-- Integrations that post to a conversation as a bot: incoming webhooks, which
+-- external systems call with a token, and slash commands answered by an HTTP
+-- callback. Each has its own bot user, owned by the user who created it.
+CREATE TABLE incoming_webhooks (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID         NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id          UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by      UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            VARCHAR(100) NOT NULL,
+    token_hash      VARCHAR(255) NOT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+
+    CONSTRAINT incoming_webhooks_token_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_incoming_webhooks_conversation_id ON incoming_webhooks (conversation_id);
+
+CREATE TABLE bot_commands (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID          NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id          UUID          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by      UUID          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            VARCHAR(32)   NOT NULL,
+    description     VARCHAR(200)  NOT NULL DEFAULT '',
+    url             VARCHAR(2048) NOT NULL,
+    secret          VARCHAR(255)  NOT NULL,
+    created_at      TIMESTAMPTZ   NOT NULL DEFAULT now(),
+
+    CONSTRAINT bot_commands_conversation_name_unique UNIQUE (conversation_id, name)
+);
+
+-- State of the built-in /remind and /poll commands.
+CREATE TABLE reminders (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID        NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id         UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body            TEXT        NOT NULL,
+    remind_at       TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_reminders_remind_at ON reminders (remind_at);
+
+CREATE TABLE polls (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID         NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    created_by      UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question        VARCHAR(300) NOT NULL,
+    options         TEXT         NOT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_polls_conversation_id ON polls (conversation_id, created_at);
+
+CREATE TABLE poll_votes (
+    poll_id  UUID        NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    user_id  UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    choice   INTEGER     NOT NULL,
+    voted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (poll_id, user_id)
+);
@@ -0,0 +1,26 @@
+-- Devices that receive push notifications. A token belongs to one app install,
+-- so registering it again, even as another user, moves it rather than
+-- duplicating it.
+CREATE TABLE device_tokens (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform   VARCHAR(10)  NOT NULL,
+    token      TEXT         NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+
+    CONSTRAINT device_tokens_platform_token_unique UNIQUE (platform, token)
+);
+
+CREATE INDEX idx_device_tokens_user_id ON device_tokens (user_id);
+
+-- Conversations a user does not want push notifications for, until muted_until
+-- or, when it is NULL, until they unmute.
+CREATE TABLE conversation_mutes (
+    conversation_id UUID        NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id         UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_until     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (conversation_id, user_id)
+);
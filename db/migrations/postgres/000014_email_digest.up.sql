@@ -0,0 +1,20 @@
+-- When the user last had a live connection. Existing accounts start out seen
+-- now, so their first digest covers only what arrives from here on.
+ALTER TABLE users ADD COLUMN last_seen_at TIMESTAMPTZ;
+UPDATE users SET last_seen_at = now();
+
+-- Set once a delivery has been summarized in an email digest, so that it is
+-- never sent twice.
+ALTER TABLE message_deliveries ADD COLUMN digested_at TIMESTAMPTZ;
+
+CREATE INDEX idx_md_user_undigested ON message_deliveries (user_id) WHERE read_at IS NULL AND digested_at IS NULL;
+
+-- Email digest preferences. Users without a row get daily digests.
+CREATE TABLE email_digest_settings (
+    user_id        UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled        BOOLEAN     NOT NULL DEFAULT TRUE,
+    frequency      VARCHAR(10) NOT NULL DEFAULT 'daily',
+    last_sent_at   TIMESTAMPTZ,
+    next_digest_at TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,29 @@
+CREATE TABLE users (
+    id            TEXT PRIMARY KEY,
+    email         VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    display_name  VARCHAR(100) NOT NULL,
+    avatar_url    TEXT,
+    status        VARCHAR(20)  NOT NULL DEFAULT 'offline',
+    created_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT users_email_unique UNIQUE (email)
+);
+
+CREATE INDEX idx_users_email ON users (email);
+CREATE INDEX idx_users_display_name ON users (display_name);
+CREATE INDEX idx_users_display_name_id ON users (display_name, id);
+
+CREATE TABLE sessions (
+    id                 TEXT PRIMARY KEY,
+    user_id            TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(255) NOT NULL,
+    expires_at         TIMESTAMP    NOT NULL,
+    created_at         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at         TIMESTAMP,
+
+    CONSTRAINT sessions_refresh_token_hash_unique UNIQUE (refresh_token_hash)
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,22 @@
+CREATE TABLE conversations (
+    id         TEXT PRIMARY KEY,
+    type       VARCHAR(20)  NOT NULL,
+    name       VARCHAR(100),
+    created_by TEXT         NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE conversation_participants (
+    id              TEXT PRIMARY KEY,
+    conversation_id TEXT        NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id         TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role            VARCHAR(20) NOT NULL DEFAULT 'member',
+    joined_at       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    left_at         TIMESTAMP,
+
+    CONSTRAINT unique_active_participant UNIQUE (conversation_id, user_id)
+);
+
+CREATE INDEX idx_cp_conversation_id ON conversation_participants (conversation_id);
+CREATE INDEX idx_cp_user_id ON conversation_participants (user_id);
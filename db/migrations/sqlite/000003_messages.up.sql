@@ -0,0 +1,25 @@
+CREATE TABLE messages (
+    id              TEXT PRIMARY KEY,
+    conversation_id TEXT        NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_id       TEXT        NOT NULL REFERENCES users(id),
+    body            TEXT        NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'sent',
+    created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_messages_conversation_id ON messages (conversation_id, created_at DESC);
+
+CREATE TABLE message_deliveries (
+    id           TEXT PRIMARY KEY,
+    message_id   TEXT        NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id      TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending',
+    delivered_at TIMESTAMP,
+    read_at      TIMESTAMP,
+
+    CONSTRAINT unique_delivery UNIQUE (message_id, user_id)
+);
+
+CREATE INDEX idx_md_message_id ON message_deliveries (message_id);
+CREATE INDEX idx_md_user_id ON message_deliveries (user_id);
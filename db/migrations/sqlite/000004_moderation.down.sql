@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS blocked_users;
@@ -0,0 +1,25 @@
+CREATE TABLE blocked_users (
+    id         TEXT PRIMARY KEY,
+    blocker_id TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_block UNIQUE (blocker_id, blocked_id),
+    CONSTRAINT no_self_block CHECK (blocker_id != blocked_id)
+);
+
+CREATE INDEX idx_blocked_blocker ON blocked_users (blocker_id);
+
+CREATE TABLE reports (
+    id          TEXT PRIMARY KEY,
+    reporter_id TEXT        NOT NULL REFERENCES users(id),
+    target_type VARCHAR(20) NOT NULL,
+    target_id   TEXT        NOT NULL,
+    reason      TEXT        NOT NULL,
+    status      VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX idx_reports_reporter ON reports (reporter_id);
+CREATE INDEX idx_reports_target ON reports (target_type, target_id);
@@ -0,0 +1,21 @@
+ALTER TABLE users ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'human';
+ALTER TABLE users ADD COLUMN owner_id TEXT REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_users_owner_id ON users (owner_id);
+
+CREATE TABLE personal_access_tokens (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by   TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    token_hash   VARCHAR(255) NOT NULL,
+    scopes       TEXT         NOT NULL,
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at   TIMESTAMP,
+
+    CONSTRAINT personal_access_tokens_token_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_pat_created_by ON personal_access_tokens (created_by);
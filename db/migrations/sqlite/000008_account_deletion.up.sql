@@ -0,0 +1,8 @@
+-- Deleted accounts are anonymized in place rather than removed: messages.sender_id
+-- and conversations.created_by keep pointing at the scrubbed row, so other
+-- participants' history stays intact.
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Token buckets stored as their theoretical arrival time (GCRA), in Unix
+-- microseconds. A bucket whose time has passed is full and may be pruned.
+CREATE TABLE rate_limits (
+    bucket_key VARCHAR(320) PRIMARY KEY,
+    tat_micros BIGINT       NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_tat ON rate_limits (tat_micros);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS idempotency_keys;
+DROP INDEX IF EXISTS idx_messages_client_message_id;
+ALTER TABLE messages DROP COLUMN client_message_id;
@@ -0,0 +1,25 @@
+-- A client-chosen ID makes a message send safe to retry: a repeated send from
+-- the same sender in the same conversation finds the original message. NULLs
+-- are distinct, so messages sent without an ID are unaffected.
+ALTER TABLE messages ADD COLUMN client_message_id VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_messages_client_message_id
+    ON messages (conversation_id, sender_id, client_message_id);
+
+-- Responses to POST requests carrying an Idempotency-Key header, kept so that
+-- a retry replays the original response. status_code is 0 while the first
+-- request is still being handled.
+CREATE TABLE idempotency_keys (
+    user_id         TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope           VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash    VARCHAR(64)  NOT NULL,
+    status_code     INTEGER      NOT NULL DEFAULT 0,
+    content_type    VARCHAR(255) NOT NULL DEFAULT '',
+    response_body   TEXT         NOT NULL DEFAULT '',
+    created_at      TIMESTAMP    NOT NULL,
+
+    PRIMARY KEY (user_id, scope, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
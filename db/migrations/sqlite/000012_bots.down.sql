@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS polls;
+DROP TABLE IF EXISTS reminders;
+DROP TABLE IF EXISTS bot_commands;
+DROP TABLE IF EXISTS incoming_webhooks;
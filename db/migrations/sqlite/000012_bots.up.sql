@@ -0,0 +1,62 @@
+-- Integrations that post to a conversation as a bot: incoming webhooks, which
+-- external systems call with a token, and slash commands answered by an HTTP
+-- callback. Each has its own bot user, owned by the user who created it.
+CREATE TABLE incoming_webhooks (
+    id              TEXT PRIMARY KEY,
+    conversation_id TEXT         NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id          TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by      TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            VARCHAR(100) NOT NULL,
+    token_hash      VARCHAR(255) NOT NULL,
+    created_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT incoming_webhooks_token_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_incoming_webhooks_conversation_id ON incoming_webhooks (conversation_id);
+
+CREATE TABLE bot_commands (
+    id              TEXT PRIMARY KEY,
+    conversation_id TEXT          NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    bot_id          TEXT          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by      TEXT          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            VARCHAR(32)   NOT NULL,
+    description     VARCHAR(200)  NOT NULL DEFAULT '',
+    url             VARCHAR(2048) NOT NULL,
+    secret          VARCHAR(255)  NOT NULL,
+    created_at      TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT bot_commands_conversation_name_unique UNIQUE (conversation_id, name)
+);
+
+-- State of the built-in /remind and /poll commands.
+CREATE TABLE reminders (
+    id              TEXT PRIMARY KEY,
+    conversation_id TEXT        NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id         TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body            TEXT        NOT NULL,
+    remind_at       TIMESTAMP   NOT NULL,
+    created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reminders_remind_at ON reminders (remind_at);
+
+CREATE TABLE polls (
+    id              TEXT PRIMARY KEY,
+    conversation_id TEXT         NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    created_by      TEXT         NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question        VARCHAR(300) NOT NULL,
+    options         TEXT         NOT NULL,
+    created_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_polls_conversation_id ON polls (conversation_id, created_at);
+
+CREATE TABLE poll_votes (
+    poll_id  TEXT        NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    user_id  TEXT        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    choice   INTEGER     NOT NULL,
+    voted_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (poll_id, user_id)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_digest_settings;
+DROP INDEX IF EXISTS idx_md_user_undigested;
+ALTER TABLE message_deliveries DROP COLUMN digested_at;
+ALTER TABLE users DROP COLUMN last_seen_at;
@@ -29,6 +29,12 @@ type LogoutRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// ChangePasswordRequest is the body for POST /users/me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
 // TokenResponse represents the issued token pair.
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -49,5 +55,8 @@ type UserResponse struct {
 	DisplayName string    `json:"display_name"`
 	AvatarURL   *string   `json:"avatar_url"`
 	Status      string    `json:"status"`
+	Type        string    `json:"type"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 }
@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateIncomingWebhookRequest is the body for POST /conversations/:id/incoming-webhooks.
+type CreateIncomingWebhookRequest struct {
+	Name string `json:"name"` // display name of the bot the webhook posts as
+}
+
+// IncomingWebhookResponse describes an incoming webhook without its token.
+type IncomingWebhookResponse struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	BotID          uuid.UUID `json:"bot_id"`
+	Name           string    `json:"name"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateIncomingWebhookResponse is returned once when an incoming webhook is
+// created; the token is not retrievable later.
+type CreateIncomingWebhookResponse struct {
+	IncomingWebhookResponse
+	Token string `json:"token"`
+}
+
+// IncomingWebhookListResponse is the response for GET /conversations/:id/incoming-webhooks.
+type IncomingWebhookListResponse struct {
+	IncomingWebhooks []IncomingWebhookResponse `json:"incoming_webhooks"`
+}
+
+// PostHookMessageRequest is the body for POST /hooks/:id.
+type PostHookMessageRequest struct {
+	Body string `json:"body"`
+}
+
+// CreateCommandRequest is the body for POST /conversations/:id/commands.
+type CreateCommandRequest struct {
+	Name        string `json:"name"` // with or without the leading slash
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+}
+
+// CommandResponse describes an HTTP slash command without its signing secret.
+type CommandResponse struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	BotID          uuid.UUID `json:"bot_id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	URL            string    `json:"url"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateCommandResponse is returned once when a command is created; the secret is not retrievable later.
+type CreateCommandResponse struct {
+	CommandResponse
+	Secret string `json:"secret"`
+}
+
+// CommandListResponse is the response for GET /conversations/:id/commands.
+type CommandListResponse struct {
+	Commands []CommandResponse `json:"commands"`
+}
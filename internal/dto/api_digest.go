@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+// DigestSettingsRequest is the body for PUT /users/me/digest. Omitted fields
+// keep their current value.
+type DigestSettingsRequest struct {
+	Enabled   *bool   `json:"enabled,omitempty"`
+	Frequency *string `json:"frequency,omitempty"` // "hourly", "daily" or "weekly"
+}
+
+// DigestSettingsResponse describes the caller's email digest settings.
+type DigestSettingsResponse struct {
+	Enabled      bool       `json:"enabled"`
+	Frequency    string     `json:"frequency"`
+	LastSentAt   *time.Time `json:"last_sent_at"`
+	NextDigestAt *time.Time `json:"next_digest_at"`
+}
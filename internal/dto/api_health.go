@@ -0,0 +1,7 @@
+package dto
+
+// HealthResponse is the body of GET /healthz and GET /readyz.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // readiness check results by name
+}
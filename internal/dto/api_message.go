@@ -7,18 +7,22 @@ import (
 )
 
 // SendMessageRequest is the body for POST /conversations/:id/messages.
+// ClientMessageID makes the send safe to retry: repeating it returns the
+// original message. The Idempotency-Key header is used when it is empty.
 type SendMessageRequest struct {
-	Body string `json:"body"`
+	Body            string `json:"body"`
+	ClientMessageID string `json:"client_message_id,omitempty"`
 }
 
 // MessageResponse is a single message in an API response.
 type MessageResponse struct {
-	ID             uuid.UUID `json:"id"`
-	ConversationID uuid.UUID `json:"conversation_id"`
-	SenderID       uuid.UUID `json:"sender_id"`
-	Body           string    `json:"body"`
-	Status         string    `json:"status"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID              uuid.UUID `json:"id"`
+	ConversationID  uuid.UUID `json:"conversation_id"`
+	SenderID        uuid.UUID `json:"sender_id"`
+	Body            string    `json:"body"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+	ClientMessageID *string   `json:"client_message_id,omitempty"`
 }
 
 // MessageListResponse is the response for GET /conversations/:id/messages.
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegisterDeviceRequest is the body for POST /users/me/devices.
+type RegisterDeviceRequest struct {
+	Platform string `json:"platform"` // "apns" or "fcm"
+	Token    string `json:"token"`
+}
+
+// DeviceResponse describes a registered device without its token.
+type DeviceResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Platform  string    `json:"platform"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeviceListResponse is the response for GET /users/me/devices.
+type DeviceListResponse struct {
+	Devices []DeviceResponse `json:"devices"`
+}
+
+// MuteRequest is the body for PUT /conversations/:id/mute.
+type MuteRequest struct {
+	Until *time.Time `json:"until,omitempty"` // omitted or null mutes until unmuted
+}
+
+// MuteResponse describes whether the caller gets push notifications for a conversation.
+type MuteResponse struct {
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until"`
+}
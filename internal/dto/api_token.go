@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateBotRequest is the body for POST /bots.
+type CreateBotRequest struct {
+	DisplayName string `json:"display_name"`
+}
+
+// BotResponse is a bot account owned by the authenticated user.
+type BotResponse struct {
+	ID          uuid.UUID `json:"id"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   *string   `json:"avatar_url"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BotListResponse is the response for GET /bots.
+type BotListResponse struct {
+	Bots []BotResponse `json:"bots"`
+}
+
+// CreateTokenRequest is the body for POST /tokens.
+type CreateTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"` // omit for a non-expiring token
+	BotID         *string  `json:"bot_id"`          // issue the token for one of the caller's bots
+}
+
+// PersonalAccessTokenResponse describes a token without its secret.
+type PersonalAccessTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateTokenResponse is returned once when a token is created; the secret is not retrievable later.
+type CreateTokenResponse struct {
+	PersonalAccessTokenResponse
+	Token string `json:"token"`
+}
+
+// TokenListResponse is the response for GET /tokens.
+type TokenListResponse struct {
+	Tokens []PersonalAccessTokenResponse `json:"tokens"`
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // UpdateProfileRequest is the body for PATCH /users/me.
 type UpdateProfileRequest struct {
@@ -14,6 +18,7 @@ type PublicProfileResponse struct {
 	DisplayName string    `json:"display_name"`
 	AvatarURL   *string   `json:"avatar_url"`
 	Status      string    `json:"status"`
+	Type        string    `json:"type,omitempty"`
 }
 
 // SearchResponse is the response for GET /users?q=.
@@ -27,3 +32,8 @@ type PaginationResponse struct {
 	NextCursor *string `json:"next_cursor"`
 	HasMore    bool    `json:"has_more"`
 }
+
+// AccountDeletionResponse is returned by DELETE /users/me.
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateWebhookRequest is the body for POST /conversations/:id/webhooks.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // omit to receive every event
+}
+
+// WebhookResponse describes a webhook without its signing secret.
+type WebhookResponse struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	URL            string    `json:"url"`
+	Events         []string  `json:"events"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CreateWebhookResponse is returned once when a webhook is created; the secret is not retrievable later.
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// WebhookListResponse is the response for GET /conversations/:id/webhooks.
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+// WebhookDeliveryResponse is one entry of a webhook's delivery log.
+type WebhookDeliveryResponse struct {
+	ID             uuid.UUID       `json:"id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"` // the body as sent
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"` // set while pending
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookDeliveryListResponse is the response for GET /conversations/:id/webhooks/:webhookId/deliveries.
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Pagination PaginationResponse        `json:"pagination"`
+}
+
+// ParticipantsAddedEvent is the data of participant.added webhooks and
+// participants_added WebSocket frames.
+type ParticipantsAddedEvent struct {
+	ConversationID uuid.UUID             `json:"conversation_id"`
+	AddedBy        uuid.UUID             `json:"added_by"`
+	Participants   []ParticipantResponse `json:"participants"`
+}
+
+// ConversationRenamedEvent is the data of conversation.renamed webhooks and
+// conversation_renamed WebSocket frames.
+type ConversationRenamedEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Name           string    `json:"name"`
+	RenamedBy      uuid.UUID `json:"renamed_by"`
+}
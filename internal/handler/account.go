@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// AccountHandler handles personal data export and account deletion endpoints.
+type AccountHandler struct {
+	accounts *service.AccountService
+}
+
+// NewAccountHandler creates a new AccountHandler.
+func NewAccountHandler(accounts *service.AccountService) *AccountHandler {
+	return &AccountHandler{accounts: accounts}
+}
+
+// Export handles GET /users/me/export.
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	// Build the archive in memory so a failure can still be reported as JSON.
+	var buf bytes.Buffer
+	if err := h.accounts.Export(r.Context(), userID, &buf); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="export-`+userID.String()+`.zip"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// Delete handles DELETE /users/me.
+func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	at, err := h.accounts.RequestDeletion(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, dto.AccountDeletionResponse{DeletionScheduledAt: at})
+}
+
+// CancelDeletion handles POST /users/me/deletion/cancel.
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	if err := h.accounts.CancelDeletion(r.Context(), userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// AdminHandler handles operator-only endpoints.
+type AdminHandler struct {
+	auth *service.AuthService
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(auth *service.AuthService) *AdminHandler {
+	return &AdminHandler{auth: auth}
+}
+
+// ClearLockout handles DELETE /admin/lockouts?email=&ip=.
+func (h *AdminHandler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if err := h.auth.Unlock(r.Context(), q.Get("email"), q.Get("ip")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
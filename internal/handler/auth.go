@@ -31,7 +31,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.auth.Register(r.Context(), req.Email, req.Password, req.DisplayName)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -51,7 +51,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.auth.Login(r.Context(), req.Email, req.Password)
+	result, err := h.auth.Login(r.Context(), req.Email, req.Password, clientIP(r))
 	if err != nil {
 		if errors.Is(err, model.ErrNotFound) {
 			writeJSON(w, http.StatusUnauthorized, ErrorBody{
@@ -59,7 +59,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -81,7 +81,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 	tokens, err := h.auth.RefreshToken(r.Context(), req.RefreshToken)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -99,13 +99,34 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.auth.Logout(r.Context(), req.RefreshToken); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	writeNoContent(w)
 }
 
+// ChangePassword handles POST /users/me/password.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	var req dto.ChangePasswordRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	tokens, err := h.auth.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword, clientIP(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toTokenResponse(tokens))
+}
+
 func toUserResponse(u *model.User) dto.UserResponse {
 	return dto.UserResponse{
 		ID:          u.ID,
@@ -113,7 +134,10 @@ func toUserResponse(u *model.User) dto.UserResponse {
 		DisplayName: u.DisplayName,
 		AvatarURL:   u.AvatarURL,
 		Status:      u.Status,
+		Type:        u.Type,
 		CreatedAt:   u.CreatedAt,
+
+		DeletionScheduledAt: u.DeletionScheduledAt,
 	}
 }
 
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/kareempaes/planning/internal/dto"
 	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
 	"github.com/kareempaes/planning/internal/service"
 )
 
@@ -78,6 +79,22 @@ func (m *mockUserRepo) Search(_ context.Context, _ string, _ string, _ int) (*mo
 	return &model.Page[model.UserSearchResult]{Items: nil, HasMore: false}, nil
 }
 
+func (m *mockUserRepo) UpdatePasswordHash(_ context.Context, id uuid.UUID, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.ID == id {
+			u.PasswordHash = hash
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+func (m *mockUserRepo) ListByOwner(_ context.Context, _ uuid.UUID) ([]model.User, error) {
+	return nil, nil
+}
+
 // ---------------------------------------------------------------------------
 // Mock SessionRepository
 // ---------------------------------------------------------------------------
@@ -138,7 +155,7 @@ func newTestAuthHandler() *AuthHandler {
 	mockUsers := &mockUserRepo{users: make(map[string]*model.User)}
 	mockSessions := &mockSessionRepo{}
 
-	authSvc := service.NewAuthService(mockUsers, mockSessions, service.AuthConfig{
+	authSvc := service.NewAuthService(mockUsers, mockSessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: mockUsers, Sessions: mockSessions}, service.AuthConfig{
 		JWTSecret:          "test-secret",
 		AccessTokenExpiry:  15 * time.Minute,
 		RefreshTokenExpiry: 7 * 24 * time.Hour,
@@ -262,3 +279,39 @@ func TestLoginHandler_WrongPassword(t *testing.T) {
 		t.Fatalf("expected status 401, got %d; body: %s", loginRec.Code, loginRec.Body.String())
 	}
 }
+
+func TestLoginHandler_TooManyAttempts(t *testing.T) {
+	h := newTestAuthHandler()
+
+	regBody := `{"email":"dan@example.com","password":"correctpass","display_name":"Dan"}`
+	regReq := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(regBody))
+	regRec := httptest.NewRecorder()
+	h.Register(regRec, regReq)
+	if regRec.Code != http.StatusCreated {
+		t.Fatalf("register failed with status %d: %s", regRec.Code, regRec.Body.String())
+	}
+
+	// The default policy locks the account after five failures.
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login",
+			strings.NewReader(`{"email":"dan@example.com","password":"wrongpass1"}`))
+		rec = httptest.NewRecorder()
+		h.Login(rec, req)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	var body ErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Error.Code != "too_many_attempts" {
+		t.Errorf("expected code too_many_attempts, got %q", body.Error.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// IncomingWebhookHandler handles incoming webhook endpoints: managing them
+// under a conversation, and posting through them.
+type IncomingWebhookHandler struct {
+	hooks *service.IncomingWebhookService
+}
+
+// NewIncomingWebhookHandler creates a new IncomingWebhookHandler.
+func NewIncomingWebhookHandler(hooks *service.IncomingWebhookService) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{hooks: hooks}
+}
+
+// Create handles POST /conversations/{id}/incoming-webhooks.
+func (h *IncomingWebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CreateIncomingWebhookRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	hook, token, err := h.hooks.Create(r.Context(), userID, convoID, req.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dto.CreateIncomingWebhookResponse{
+		IncomingWebhookResponse: toIncomingWebhookResponse(hook),
+		Token:                   token,
+	})
+}
+
+// List handles GET /conversations/{id}/incoming-webhooks.
+func (h *IncomingWebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	hooks, err := h.hooks.List(r.Context(), userID, convoID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := dto.IncomingWebhookListResponse{IncomingWebhooks: make([]dto.IncomingWebhookResponse, 0, len(hooks))}
+	for i := range hooks {
+		resp.IncomingWebhooks = append(resp.IncomingWebhooks, toIncomingWebhookResponse(&hooks[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Delete handles DELETE /conversations/{id}/incoming-webhooks/{hookId}.
+func (h *IncomingWebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+	hookID, ok := parsePathID(w, r, "hookId", "invalid incoming webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.hooks.Delete(r.Context(), userID, convoID, hookID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
+
+// Post handles POST /hooks/{id}. The webhook's token is a bearer token rather
+// than part of the URL, so that it stays out of request logs and traces.
+func (h *IncomingWebhookHandler) Post(w http.ResponseWriter, r *http.Request) {
+	hookID, ok := parsePathID(w, r, "id", "invalid incoming webhook ID")
+	if !ok {
+		return
+	}
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		writeJSON(w, http.StatusUnauthorized, ErrorBody{
+			Error: ErrorDetail{Code: "unauthorized", Message: "missing or invalid authorization header"},
+		})
+		return
+	}
+
+	var req dto.PostHookMessageRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	msg, err := h.hooks.Post(r.Context(), hookID, strings.TrimPrefix(authHeader, "Bearer "), req.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toMessageResponse(msg))
+}
+
+// CommandHandler handles the HTTP slash command endpoints of a conversation.
+type CommandHandler struct {
+	commands *service.CommandService
+}
+
+// NewCommandHandler creates a new CommandHandler.
+func NewCommandHandler(commands *service.CommandService) *CommandHandler {
+	return &CommandHandler{commands: commands}
+}
+
+// Create handles POST /conversations/{id}/commands.
+func (h *CommandHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CreateCommandRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	cmd, err := h.commands.CreateCommand(r.Context(), userID, convoID, service.CreateCommandParams{
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dto.CreateCommandResponse{
+		CommandResponse: toCommandResponse(cmd),
+		Secret:          cmd.Secret,
+	})
+}
+
+// List handles GET /conversations/{id}/commands.
+func (h *CommandHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	cmds, err := h.commands.ListCommands(r.Context(), userID, convoID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := dto.CommandListResponse{Commands: make([]dto.CommandResponse, 0, len(cmds))}
+	for i := range cmds {
+		resp.Commands = append(resp.Commands, toCommandResponse(&cmds[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Delete handles DELETE /conversations/{id}/commands/{commandId}.
+func (h *CommandHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+	commandID, ok := parsePathID(w, r, "commandId", "invalid command ID")
+	if !ok {
+		return
+	}
+
+	if err := h.commands.DeleteCommand(r.Context(), userID, convoID, commandID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
+
+func toIncomingWebhookResponse(hook *model.IncomingWebhook) dto.IncomingWebhookResponse {
+	return dto.IncomingWebhookResponse{
+		ID:             hook.ID,
+		ConversationID: hook.ConversationID,
+		BotID:          hook.BotID,
+		Name:           hook.Name,
+		CreatedBy:      hook.CreatedBy,
+		CreatedAt:      hook.CreatedAt,
+	}
+}
+
+func toCommandResponse(cmd *model.BotCommand) dto.CommandResponse {
+	return dto.CommandResponse{
+		ID:             cmd.ID,
+		ConversationID: cmd.ConversationID,
+		BotID:          cmd.BotID,
+		Name:           cmd.Name,
+		Description:    cmd.Description,
+		URL:            cmd.URL,
+		CreatedBy:      cmd.CreatedBy,
+		CreatedAt:      cmd.CreatedAt,
+	}
+}
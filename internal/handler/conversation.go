@@ -14,11 +14,12 @@ import (
 // ConversationHandler handles conversation endpoints.
 type ConversationHandler struct {
 	convos *service.ConversationService
+	events *ConversationEvents
 }
 
 // NewConversationHandler creates a new ConversationHandler.
-func NewConversationHandler(convos *service.ConversationService) *ConversationHandler {
-	return &ConversationHandler{convos: convos}
+func NewConversationHandler(convos *service.ConversationService, events *ConversationEvents) *ConversationHandler {
+	return &ConversationHandler{convos: convos, events: events}
 }
 
 // Create handles POST /conversations.
@@ -43,7 +44,7 @@ func (h *ConversationHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.convos.Create(r.Context(), userID, req.Type, req.Name, participantIDs)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -68,17 +69,18 @@ func (h *ConversationHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	page, err := h.convos.List(r.Context(), userID, cursor, limit)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	summaries := make([]dto.ConversationSummaryResponse, len(page.Items))
 	for i, s := range page.Items {
 		summary := dto.ConversationSummaryResponse{
-			ID:          s.ID,
-			Type:        s.Type,
-			Name:        s.Name,
-			UnreadCount: s.UnreadCount,
+			ID:           s.ID,
+			Type:         s.Type,
+			Name:         s.Name,
+			UnreadCount:  s.UnreadCount,
+			Participants: make([]dto.ParticipantMinDTO, 0, len(s.Participants)),
 		}
 		if s.LastMessage != nil {
 			summary.LastMessage = &dto.MessagePreviewDTO{
@@ -119,7 +121,7 @@ func (h *ConversationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	convo, participants, err := h.convos.GetByID(r.Context(), userID, convoID)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -145,18 +147,28 @@ func (h *ConversationHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	convo, err := h.convos.Update(r.Context(), userID, convoID, req.Name)
+	renamed, err := h.convos.Update(r.Context(), userID, convoID, req.Name)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
-
-	writeJSON(w, http.StatusOK, dto.ConversationResponse{
-		ID:        convo.ID,
-		Type:      convo.Type,
-		Name:      convo.Name,
-		CreatedAt: convo.CreatedAt,
+	h.events.publish(r, userID, convoID, conversationEvent{
+		frame:   frameConversationRenamed,
+		webhook: model.WebhookEventConversationRenamed,
+		data: dto.ConversationRenamedEvent{
+			ConversationID: convoID,
+			Name:           *renamed.Name,
+			RenamedBy:      userID,
+		},
 	})
+
+	convo, participants, err := h.convos.GetByID(r.Context(), userID, convoID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toConversationResponse(convo, participants))
 }
 
 // AddParticipants handles POST /conversations/{id}/participants.
@@ -186,21 +198,25 @@ func (h *ConversationHandler) AddParticipants(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	participants, err := h.convos.AddParticipants(r.Context(), userID, convoID, newUserIDs)
+	result, err := h.convos.AddParticipants(r.Context(), userID, convoID, newUserIDs)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
-	resp := make([]dto.ParticipantResponse, len(participants))
-	for i, p := range participants {
-		resp[i] = dto.ParticipantResponse{
-			UserID: p.UserID,
-			Role:   p.Role,
-		}
+	if len(result.Added) > 0 {
+		h.events.publish(r, userID, convoID, conversationEvent{
+			frame:   frameParticipantsAdded,
+			webhook: model.WebhookEventParticipantAdded,
+			data: dto.ParticipantsAddedEvent{
+				ConversationID: convoID,
+				AddedBy:        userID,
+				Participants:   toParticipantResponses(result.Added),
+			},
+		})
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	writeJSON(w, http.StatusOK, toParticipantResponses(result.Participants))
 }
 
 // RemoveParticipant handles DELETE /conversations/{id}/participants/{userId}.
@@ -223,7 +239,7 @@ func (h *ConversationHandler) RemoveParticipant(w http.ResponseWriter, r *http.R
 	}
 
 	if err := h.convos.RemoveParticipant(r.Context(), userID, convoID, targetUserID); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -232,13 +248,19 @@ func (h *ConversationHandler) RemoveParticipant(w http.ResponseWriter, r *http.R
 
 func toConversationResponse(c *model.Conversation, participants []model.ConversationParticipant) dto.ConversationResponse {
 	resp := dto.ConversationResponse{
-		ID:        c.ID,
-		Type:      c.Type,
-		Name:      c.Name,
-		CreatedAt: c.CreatedAt,
+		ID:           c.ID,
+		Type:         c.Type,
+		Name:         c.Name,
+		CreatedAt:    c.CreatedAt,
+		Participants: toParticipantResponses(participants),
 	}
+	return resp
+}
+
+func toParticipantResponses(participants []model.ConversationParticipant) []dto.ParticipantResponse {
+	resp := make([]dto.ParticipantResponse, 0, len(participants))
 	for _, p := range participants {
-		resp.Participants = append(resp.Participants, dto.ParticipantResponse{
+		resp = append(resp, dto.ParticipantResponse{
 			UserID: p.UserID,
 			Role:   p.Role,
 		})
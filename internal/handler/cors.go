@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig controls which browser origins may call the API and open
+// WebSocket connections. With no allowed origins, cross-origin requests get no
+// CORS headers and browsers refuse them.
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://chat.example.com". A host
+	// may start with "*." to match its subdomains, and "*" alone allows every
+	// origin, which cannot be combined with AllowCredentials.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string // response headers scripts may read beyond the CORS-safelisted ones
+	AllowCredentials bool
+	MaxAge           time.Duration // how long browsers may cache a preflight; zero leaves it to the browser
+}
+
+// AllowsOrigin reports whether origin is on the allowlist.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || matchesWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWildcardOrigin matches a pattern like "https://*.example.com" against
+// an origin with the same scheme and a host below example.com.
+func matchesWildcardOrigin(pattern, origin string) bool {
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Scheme, scheme) {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Host), "."+strings.ToLower(host))
+}
+
+// CORS returns middleware that applies cfg to cross-origin requests and
+// answers preflight requests itself. Requests without an Origin header, such
+// as those from other servers, pass through untouched.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	wildcard := slices.Contains(cfg.AllowedOrigins, "*") && !cfg.AllowCredentials
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+			if !cfg.AllowsOrigin(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var requested []string
+			if preflight {
+				requested = requestedHeaders(r)
+				if !containsFold(cfg.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) ||
+					!allHeadersAllowed(cfg.AllowedHeaders, requested) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+			}
+
+			if wildcard {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Methods", methods)
+			if len(requested) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// requestedHeaders parses the preflight's Access-Control-Request-Headers list.
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				headers = append(headers, name)
+			}
+		}
+	}
+	return headers
+}
+
+func allHeadersAllowed(allowed, requested []string) bool {
+	if slices.Contains(allowed, "*") {
+		return true
+	}
+	for _, name := range requested {
+		if !containsFold(allowed, name) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
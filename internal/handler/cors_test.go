@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins:   []string{"https://chat.example.com", "https://*.preview.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PATCH"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
+func TestCORSConfig_AllowsOrigin(t *testing.T) {
+	cfg := testCORSConfig()
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://chat.example.com", true},
+		{"HTTPS://CHAT.EXAMPLE.COM", true},
+		{"https://pr-12.preview.example.com", true},
+		{"https://preview.example.com", false},
+		{"http://pr-12.preview.example.com", false},
+		{"https://evil.com", false},
+		{"https://chat.example.com.evil.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := cfg.AllowsOrigin(tt.origin); got != tt.want {
+			t.Errorf("AllowsOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+	if !(CORSConfig{AllowedOrigins: []string{"*"}}).AllowsOrigin("https://anything.test") {
+		t.Error("expected * to allow any origin")
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	called := false
+	h := CORS(testCORSConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/conversations", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight("https://chat.example.com", "POST", "authorization, content-type")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://chat.example.com",
+		"Access-Control-Allow-Methods":     "GET, POST, PATCH",
+		"Access-Control-Allow-Headers":     "authorization, content-type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("expected %s %q, got %q", k, v, got)
+		}
+	}
+	if called {
+		t.Error("expected the preflight to be answered without reaching the handler")
+	}
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"origin":  preflight("https://evil.com", "POST", ""),
+		"method":  preflight("https://chat.example.com", "DELETE", ""),
+		"headers": preflight("https://chat.example.com", "POST", "X-Secret"),
+	} {
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("expected a disallowed %s to be refused, got %d %v", name, rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	h := CORS(testCORSConfig())(okHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set("Origin", "https://chat.example.com")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://chat.example.com" ||
+		rec.Header().Get("Access-Control-Expose-Headers") != "RateLimit-Remaining" {
+		t.Errorf("expected CORS headers for an allowed origin, got %v", rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Origin" {
+		t.Errorf("expected Vary: Origin, got %q", rec.Header().Get("Vary"))
+	}
+
+	req.Header.Set("Origin", "https://evil.com")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected a disallowed origin to get no CORS headers, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCORS_WildcardWithoutCredentials(t *testing.T) {
+	h := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(okHandler())
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://anything.test")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected *, got %q", got)
+	}
+}
+
+func TestCheckWSOrigin(t *testing.T) {
+	cfg := testCORSConfig()
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},                         // non-browser client
+		{"https://api.example.com", true},  // same host as the request
+		{"https://chat.example.com", true}, // allowlisted
+		{"https://evil.com", false},        // cross-site hijacking attempt
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/api/v1/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := checkWSOrigin(req, cfg); got != tt.want {
+			t.Errorf("checkWSOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
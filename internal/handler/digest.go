@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// DigestHandler handles the caller's email digest settings.
+type DigestHandler struct {
+	digests *service.DigestService
+}
+
+// NewDigestHandler creates a new DigestHandler.
+func NewDigestHandler(digests *service.DigestService) *DigestHandler {
+	return &DigestHandler{digests: digests}
+}
+
+// GetSettings handles GET /users/me/digest.
+func (h *DigestHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	settings, err := h.digests.GetSettings(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toDigestSettingsResponse(settings))
+}
+
+// UpdateSettings handles PUT /users/me/digest.
+func (h *DigestHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	var req dto.DigestSettingsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	settings, err := h.digests.UpdateSettings(r.Context(), userID, req.Enabled, req.Frequency)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toDigestSettingsResponse(settings))
+}
+
+func toDigestSettingsResponse(s *model.DigestSettings) dto.DigestSettingsResponse {
+	return dto.DigestSettingsResponse{
+		Enabled:      s.Enabled,
+		Frequency:    s.Frequency,
+		LastSentAt:   s.LastSentAt,
+		NextDigestAt: s.NextDigestAt,
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// WebSocket frame types pushed for conversation events.
+const (
+	frameMessage             = "message"
+	frameParticipantsAdded   = "participants_added"
+	frameConversationRenamed = "conversation_renamed"
+)
+
+// broadcastTimeout bounds the participant lookup a broadcast makes after its
+// request has finished.
+const broadcastTimeout = 10 * time.Second
+
+// conversationEvent is something that happened in a conversation, as told to
+// WebSocket clients and to webhooks.
+type conversationEvent struct {
+	frame   string // WebSocket frame type
+	webhook string // webhook event type
+	data    any    // payload of both
+}
+
+// ConversationEvents publishes conversation events to the other participants'
+// WebSocket connections and to the conversation's webhooks, so that both see
+// the same events with the same payloads. It is also the messages' listener,
+// publishing the messages that bots post outside of any request.
+type ConversationEvents struct {
+	convos   *service.ConversationService
+	webhooks *service.WebhookService
+	hub      *infra.Hub
+	logger   *slog.Logger // for events published outside of a request
+}
+
+// NewConversationEvents creates a new ConversationEvents. logger may be nil
+// to use slog.Default.
+func NewConversationEvents(convos *service.ConversationService, webhooks *service.WebhookService, hub *infra.Hub, logger *slog.Logger) *ConversationEvents {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &ConversationEvents{convos: convos, webhooks: webhooks, hub: hub, logger: logger}
+}
+
+// publish sends an event caused by actorID. Webhook deliveries are queued
+// before the request finishes, so that they survive a restart; failing to
+// queue them is logged rather than failing a request whose change is already
+// made. The WebSocket broadcast happens in the background.
+func (e *ConversationEvents) publish(r *http.Request, actorID, convoID uuid.UUID, event conversationEvent) {
+	if err := e.webhooks.Publish(r.Context(), convoID, event.webhook, event.data); err != nil {
+		requestLogger(r).Error("failed to queue webhook deliveries",
+			slog.String("event", event.webhook), slog.Any("error", err))
+	}
+
+	// The broadcast outlives the request, so it keeps the request's trace
+	// but not its cancellation.
+	go e.broadcast(context.WithoutCancel(r.Context()), actorID, convoID, event)
+}
+
+// broadcast pushes an event to every participant except the actor.
+func (e *ConversationEvents) broadcast(ctx context.Context, actorID, convoID uuid.UUID, event conversationEvent) {
+	ctx, cancel := context.WithTimeout(ctx, broadcastTimeout)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "ConversationEvents.broadcast")
+	defer span.End()
+
+	_, participants, err := e.convos.GetByID(ctx, actorID, convoID)
+	if err != nil {
+		return
+	}
+
+	recipientIDs := make([]uuid.UUID, 0, len(participants))
+	for _, p := range participants {
+		if p.UserID != actorID {
+			recipientIDs = append(recipientIDs, p.UserID)
+		}
+	}
+	e.send(recipientIDs, event)
+}
+
+// MessagePosted publishes a message a bot posted. Bots are not participants,
+// so every participant is told.
+func (e *ConversationEvents) MessagePosted(ctx context.Context, msg *model.Message, recipientIDs []uuid.UUID) {
+	event := conversationEvent{
+		frame:   frameMessage,
+		webhook: model.WebhookEventMessageCreated,
+		data:    toMessageResponse(msg),
+	}
+	if err := e.webhooks.Publish(ctx, msg.ConversationID, event.webhook, event.data); err != nil {
+		e.logger.ErrorContext(ctx, "failed to queue webhook deliveries",
+			slog.String("event", event.webhook), slog.Any("error", err))
+	}
+	e.send(recipientIDs, event)
+}
+
+// send pushes an event's frame to the given users.
+func (e *ConversationEvents) send(recipientIDs []uuid.UUID, event conversationEvent) {
+	if len(recipientIDs) == 0 {
+		return
+	}
+
+	data, _ := json.Marshal(event.data)
+	e.hub.SendToUsers(recipientIDs, infra.Event{
+		Type: event.frame,
+		Data: data,
+	})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kareempaes/planning/internal/dto"
+)
+
+// healthCheckTimeout bounds each readiness check.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck is one named dependency readiness requires.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthHandler serves liveness and readiness probes.
+type HealthHandler struct {
+	checks   []HealthCheck
+	draining atomic.Bool
+}
+
+// NewHealthHandler creates a HealthHandler that is ready while every check passes.
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// StartDraining makes readiness fail from now on, so that load balancers stop
+// routing new requests here while in-flight ones finish.
+func (h *HealthHandler) StartDraining() {
+	h.draining.Store(true)
+}
+
+// Live handles GET /healthz. It succeeds whenever the process can serve HTTP.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, dto.HealthResponse{Status: "ok"})
+}
+
+// Ready handles GET /readyz. It fails while draining or while any check fails;
+// failure details are logged rather than returned, since the probe is public.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, dto.HealthResponse{Status: "draining"})
+		return
+	}
+
+	status, results := http.StatusOK, make(map[string]string, len(h.checks))
+	for _, c := range h.checks {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		err := c.Check(ctx)
+		cancel()
+		if err != nil {
+			requestLogger(r).Warn("readiness check failed", slog.String("check", c.Name), slog.Any("error", err))
+			status, results[c.Name] = http.StatusServiceUnavailable, "failing"
+			continue
+		}
+		results[c.Name] = "ok"
+	}
+
+	body := dto.HealthResponse{Status: "ready", Checks: results}
+	if status != http.StatusOK {
+		body.Status = "not_ready"
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthHandler_Ready(t *testing.T) {
+	dbErr := error(nil)
+	h := NewHealthHandler(
+		HealthCheck{Name: "database", Check: func(context.Context) error { return dbErr }},
+		HealthCheck{Name: "hub", Check: func(context.Context) error { return nil }},
+	)
+	probe := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec
+	}
+
+	if rec := probe(); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 while checks pass, got %d", rec.Code)
+	}
+
+	dbErr = errors.New("connection refused to 10.0.0.5")
+	rec := probe()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with a failing check, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"database":"failing"`) || strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("expected the failing check named without details, got %s", rec.Body.String())
+	}
+
+	dbErr = nil
+	h.StartDraining()
+	if rec := probe(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while draining, got %d", rec.Code)
+	}
+
+	live := httptest.NewRecorder()
+	h.Live(live, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if live.Code != http.StatusOK {
+		t.Errorf("expected liveness to pass while draining, got %d", live.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// IdempotencyKeyHeader carries a client-chosen key that makes a POST safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotentBodySize bounds the request body buffered to fingerprint a request.
+const maxIdempotentBodySize = 1 << 20
+
+// IdempotencyStore remembers responses by idempotency key. It is satisfied by
+// *service.IdempotencyKeys.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, rec *model.IdempotencyRecord) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, rec *model.IdempotencyRecord) error
+	Release(ctx context.Context, rec *model.IdempotencyRecord) error
+}
+
+// Idempotency returns middleware that makes POST requests carrying an
+// Idempotency-Key header safe to retry. The first request with a key runs as
+// usual and a successful response is stored; a retry with the same key and
+// body gets that response again, marked with Idempotent-Replayed, without
+// reaching the handler. A retry while the first request is still running gets
+// 409, and reusing a key for a different body gets 422. Failed requests are
+// not stored, so retrying them runs them again.
+//
+// Keys are private to the authenticated user and scoped to the method and
+// path, so the middleware must run after AuthMiddleware.
+func Idempotency(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, ErrorBody{
+					Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+				})
+				return
+			}
+			if len(body) > maxIdempotentBodySize {
+				writeJSON(w, http.StatusRequestEntityTooLarge, ErrorBody{
+					Error: ErrorDetail{Code: "payload_too_large", Message: "request body too large"},
+				})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.Sum256(body)
+			rec := &model.IdempotencyRecord{
+				UserID:      UserIDFromContext(r.Context()),
+				Scope:       r.Method + " " + r.URL.Path,
+				Key:         key,
+				RequestHash: hex.EncodeToString(sum[:]),
+			}
+			existing, err := store.Begin(r.Context(), rec)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if existing != nil {
+				replayIdempotent(w, rec, existing)
+				return
+			}
+
+			// The outcome is recorded even if the client has gone away, since
+			// a lost response is exactly what the retry will be for.
+			ctx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.Release(ctx, rec); err != nil {
+					requestLogger(r).Warn("failed to release idempotency key", slog.Any("error", err))
+				}
+			}()
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status < 200 || status >= 300 {
+				return
+			}
+			rec.StatusCode = status
+			rec.ContentType = w.Header().Get("Content-Type")
+			rec.Body = buf.Bytes()
+			if err := store.Complete(ctx, rec); err != nil {
+				requestLogger(r).Warn("failed to store idempotent response", slog.Any("error", err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replayIdempotent answers a request whose key was used before.
+func replayIdempotent(w http.ResponseWriter, rec, existing *model.IdempotencyRecord) {
+	switch {
+	case existing.RequestHash != rec.RequestHash:
+		writeJSON(w, http.StatusUnprocessableEntity, ErrorBody{
+			Error: ErrorDetail{Code: "idempotency_key_reused", Message: "idempotency key was already used for a different request"},
+		})
+	case !existing.Completed():
+		w.Header().Set("Retry-After", "1")
+		writeJSON(w, http.StatusConflict, ErrorBody{
+			Error: ErrorDetail{Code: "idempotency_key_in_use", Message: "a request with this idempotency key is still in progress"},
+		})
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.Body)
+	}
+}
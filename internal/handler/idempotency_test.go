@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/repo"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+func newTestIdempotencyKeys(t *testing.T) *service.IdempotencyKeys {
+	t.Helper()
+	store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service.NewIdempotencyKeys(store.Idempotency)
+}
+
+func TestIdempotency_ReplaysSuccessfulResponse(t *testing.T) {
+	calls := 0
+	h := Idempotency(newTestIdempotencyKeys(t))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, http.StatusCreated, map[string]int{"call": calls})
+	}))
+	userID := uuid.New()
+
+	send := func(user uuid.UUID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/reports", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, user))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send(userID, "k1", `{"reason":"spam"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected the first request to run, got %d %v", first.Code, first.Header())
+	}
+
+	retry := send(userID, "k1", `{"reason":"spam"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the original response, got %d %s", retry.Code, retry.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected replay headers, got %v", retry.Header())
+	}
+
+	if rec := send(userID, "k1", `{"reason":"abuse"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a reused key with another body to get 422, got %d", rec.Code)
+	}
+	if rec := send(uuid.New(), "k1", `{"reason":"spam"}`); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected another user's key to be independent, got %d", rec.Code)
+	}
+	if calls != 2 {
+		t.Errorf("expected the handler to run twice, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_DoesNotStoreFailures(t *testing.T) {
+	status := http.StatusInternalServerError
+	calls := 0
+	h := Idempotency(newTestIdempotencyKeys(t))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	ctx := context.WithValue(context.Background(), userIDKey, uuid.New())
+
+	for _, want := range []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/conversations", nil).WithContext(ctx)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("expected %d, got %d", want, rec.Code)
+		}
+		status = http.StatusOK
+	}
+	if calls != 2 {
+		t.Errorf("expected the failed request to be retried once and the success replayed, got %d calls", calls)
+	}
+}
+
+func TestIdempotency_RejectsConcurrentRetry(t *testing.T) {
+	keys := newTestIdempotencyKeys(t)
+	ctx := context.WithValue(context.Background(), userIDKey, uuid.New())
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/conversations", nil).WithContext(ctx)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		return req
+	}
+
+	var inner *httptest.ResponseRecorder
+	var h http.Handler
+	h = Idempotency(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A retry arriving while the first request is still being handled.
+		inner = httptest.NewRecorder()
+		h.ServeHTTP(inner, newRequest())
+		w.WriteHeader(http.StatusCreated)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	if inner == nil || inner.Code != http.StatusConflict || inner.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected the concurrent retry to get 409 with Retry-After, got %+v", inner)
+	}
+}
+
+func TestIdempotency_IgnoresRequestsWithoutKey(t *testing.T) {
+	calls := 0
+	h := Idempotency(newTestIdempotencyKeys(t))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/reports", nil))
+	}
+	if calls != 2 {
+		t.Errorf("expected both requests to run, got %d", calls)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestLogKey contextKey = "requestLog"
+
+// requestLog is shared by the middleware chain of one request so that inner
+// middleware (AuthMiddleware) can enrich the logger the access line uses.
+type requestLog struct {
+	logger *slog.Logger
+}
+
+// RequestLogger returns middleware that writes one structured line per request
+// with its request ID, authenticated user, route pattern, status and latency,
+// and makes a logger carrying the same request attributes available to inner
+// handlers. It also recovers panics, logging them with their stack. It must
+// run after chi's RequestID middleware, and after RequestTracing for log lines
+// to carry the trace ID.
+func RequestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rl := &requestLog{logger: logger}
+			if id := middleware.GetReqID(r.Context()); id != "" {
+				rl.logger = rl.logger.With(slog.String("request_id", id))
+			}
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				rl.logger = rl.logger.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+			r = r.WithContext(context.WithValue(r.Context(), requestLogKey, rl))
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+					requestLogger(r).Error("panic serving request",
+						slog.Any("panic", rec), slog.String("stack", string(debug.Stack())))
+					if ww.Status() == 0 {
+						ww.WriteHeader(http.StatusInternalServerError)
+					}
+				}
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				if status >= http.StatusInternalServerError {
+					level = slog.LevelError
+				}
+				requestLogger(r).LogAttrs(r.Context(), level, "request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("latency", time.Since(start)),
+				)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// requestLogger returns the logger for r, carrying its request ID, the
+// authenticated user once known, and the matched route pattern.
+func requestLogger(r *http.Request) *slog.Logger {
+	logger := slog.Default()
+	if rl, ok := r.Context().Value(requestLogKey).(*requestLog); ok {
+		logger = rl.logger
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			logger = logger.With(slog.String("route", pattern))
+		}
+	}
+	return logger
+}
+
+// logUser adds the authenticated user to the request's log lines.
+func logUser(ctx context.Context, userID uuid.UUID) {
+	if rl, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		rl.logger = rl.logger.With(slog.String("user_id", userID.String()))
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// newLoggedRouter returns a router wired like NewRouter's logging chain, with
+// every log line captured as JSON in buf.
+func newLoggedRouter(buf *bytes.Buffer) *chi.Mux {
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(RequestLogger(logger))
+	return r
+}
+
+// logLines decodes each JSON line written to buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestRequestLogger_AccessLineCarriesRequestAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLoggedRouter(&buf)
+	r.With(AuthMiddleware(testSecret, nil)).Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	userID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("Authorization", "Bearer "+makeToken(userID, time.Now().Add(time.Minute)))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := logLines(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 log line, got %d", len(lines))
+	}
+	line := lines[0]
+	if line["msg"] != "request" || line["request_id"] == nil || line["latency"] == nil {
+		t.Errorf("unexpected access line: %v", line)
+	}
+	if line["user_id"] != userID.String() {
+		t.Errorf("expected user_id %s, got %v", userID, line["user_id"])
+	}
+	if line["route"] != "/items/{id}" {
+		t.Errorf("expected route pattern, got %v", line["route"])
+	}
+	if line["status"] != float64(http.StatusAccepted) {
+		t.Errorf("expected status 202, got %v", line["status"])
+	}
+}
+
+func TestWriteError_LogsInternalErrors(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLoggedRouter(&buf)
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, errors.New("db exploded"))
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "db exploded") {
+		t.Error("expected the underlying error to stay out of the response")
+	}
+
+	lines := logLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("expected error and access lines, got %d", len(lines))
+	}
+	if lines[0]["error"] != "db exploded" || lines[0]["request_id"] == nil || lines[0]["request_id"] != lines[1]["request_id"] {
+		t.Errorf("expected the error logged with the request ID, got %v", lines[0])
+	}
+}
+
+func TestRequestLogger_RecoversPanics(t *testing.T) {
+	var buf bytes.Buffer
+	r := newLoggedRouter(&buf)
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	lines := logLines(t, &buf)
+	if len(lines) != 2 || lines[0]["panic"] != "boom" || lines[1]["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("expected panic and access lines, got %v", lines)
+	}
+}
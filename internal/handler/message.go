@@ -1,15 +1,12 @@
 package handler
 
 import (
-	"context"
-	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/kareempaes/planning/internal/dto"
-	"github.com/kareempaes/planning/internal/infra"
 	"github.com/kareempaes/planning/internal/model"
 	"github.com/kareempaes/planning/internal/service"
 )
@@ -17,13 +14,12 @@ import (
 // MessageHandler handles message endpoints.
 type MessageHandler struct {
 	messages *service.MessageService
-	convos   *service.ConversationService
-	hub      *infra.Hub
+	events   *ConversationEvents
 }
 
 // NewMessageHandler creates a new MessageHandler.
-func NewMessageHandler(messages *service.MessageService, convos *service.ConversationService, hub *infra.Hub) *MessageHandler {
-	return &MessageHandler{messages: messages, convos: convos, hub: hub}
+func NewMessageHandler(messages *service.MessageService, events *ConversationEvents) *MessageHandler {
+	return &MessageHandler{messages: messages, events: events}
 }
 
 // Send handles POST /conversations/{id}/messages.
@@ -45,16 +41,30 @@ func (h *MessageHandler) Send(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	msg, err := h.messages.Send(r.Context(), userID, convoID, req.Body)
+	clientMessageID := req.ClientMessageID
+	if clientMessageID == "" {
+		clientMessageID = r.Header.Get(IdempotencyKeyHeader)
+	}
+
+	msg, created, err := h.messages.Send(r.Context(), userID, convoID, req.Body, clientMessageID)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
+		return
+	}
+	if !created {
+		// A retried send; the participants were told the first time.
+		writeJSON(w, http.StatusOK, toMessageResponse(msg))
 		return
 	}
 
-	// Push real-time event to other participants.
-	go h.broadcastMessage(r.Context(), userID, convoID, msg)
+	resp := toMessageResponse(msg)
+	h.events.publish(r, userID, convoID, conversationEvent{
+		frame:   frameMessage,
+		webhook: model.WebhookEventMessageCreated,
+		data:    resp,
+	})
 
-	writeJSON(w, http.StatusCreated, toMessageResponse(msg))
+	writeJSON(w, http.StatusCreated, resp)
 }
 
 // GetHistory handles GET /conversations/{id}/messages.
@@ -78,7 +88,7 @@ func (h *MessageHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 
 	page, err := h.messages.GetHistory(r.Context(), userID, convoID, cursor, limit)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -117,44 +127,21 @@ func (h *MessageHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	msg, err := h.messages.GetByID(r.Context(), userID, convoID, msgID)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, toMessageResponse(msg))
 }
 
-func (h *MessageHandler) broadcastMessage(ctx context.Context, senderID uuid.UUID, convoID uuid.UUID, msg *model.Message) {
-	_, participants, err := h.convos.GetByID(ctx, senderID, convoID)
-	if err != nil {
-		return
-	}
-
-	recipientIDs := make([]uuid.UUID, 0, len(participants))
-	for _, p := range participants {
-		if p.UserID != senderID {
-			recipientIDs = append(recipientIDs, p.UserID)
-		}
-	}
-
-	if len(recipientIDs) == 0 {
-		return
-	}
-
-	data, _ := json.Marshal(toMessageResponse(msg))
-	h.hub.SendToUsers(recipientIDs, infra.Event{
-		Type: "message",
-		Data: data,
-	})
-}
-
 func toMessageResponse(m *model.Message) dto.MessageResponse {
 	return dto.MessageResponse{
-		ID:             m.ID,
-		ConversationID: m.ConversationID,
-		SenderID:       m.SenderID,
-		Body:           m.Body,
-		Status:         m.Status,
-		CreatedAt:      m.CreatedAt,
+		ID:              m.ID,
+		ConversationID:  m.ConversationID,
+		SenderID:        m.SenderID,
+		Body:            m.Body,
+		Status:          m.Status,
+		CreatedAt:       m.CreatedAt,
+		ClientMessageID: m.ClientMessageID,
 	}
 }
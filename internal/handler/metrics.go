@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kareempaes/planning/internal/infra"
+)
+
+// RequestMetrics returns middleware that records each request's count and
+// latency by chi route pattern and status code. Requests that match no route
+// share the "unmatched" label so that arbitrary paths cannot grow the series.
+func RequestMetrics(m *infra.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.ObserveRequest(r.Method, route, status, time.Since(start))
+		})
+	}
+}
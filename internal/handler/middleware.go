@@ -2,19 +2,37 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey   contextKey = "userID"
+	scopesKey   contextKey = "scopes"
+	clientIPKey contextKey = "clientIP"
+)
+
+// TokenAuthenticator resolves personal access tokens. It is satisfied by *service.TokenService.
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, raw string) (*model.PersonalAccessToken, error)
+}
 
-// AuthMiddleware returns chi-compatible middleware that validates JWT Bearer tokens.
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// AuthMiddleware returns chi-compatible middleware that validates Bearer credentials.
+// JWT access tokens grant full access; personal access tokens (resolved through pats,
+// which may be nil to disable them) additionally put their scopes into the context.
+func AuthMiddleware(jwtSecret string, pats TokenAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -26,6 +44,25 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 			}
 			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
+			if pats != nil && strings.HasPrefix(tokenStr, service.TokenPrefix) {
+				pat, err := pats.Authenticate(r.Context(), tokenStr)
+				if errors.Is(err, model.ErrUnauthenticated) {
+					writeJSON(w, http.StatusUnauthorized, ErrorBody{
+						Error: ErrorDetail{Code: "unauthorized", Message: "invalid, revoked or expired token"},
+					})
+					return
+				}
+				if err != nil {
+					writeError(w, r, err)
+					return
+				}
+				logUser(r.Context(), pat.UserID)
+				ctx := context.WithValue(r.Context(), userIDKey, pat.UserID)
+				ctx = context.WithValue(ctx, scopesKey, pat.Scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
 				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, jwt.ErrSignatureInvalid
@@ -63,6 +100,7 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 				return
 			}
 
+			logUser(r.Context(), userID)
 			ctx := context.WithValue(r.Context(), userIDKey, userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -77,3 +115,121 @@ func UserIDFromContext(ctx context.Context) uuid.UUID {
 	}
 	return id
 }
+
+// ScopesFromContext returns the scopes granted to the request's personal access token.
+// ok is false for session (JWT) credentials, which are not scope-restricted.
+func ScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+// RequireScope returns middleware that rejects personal access tokens lacking the given scope.
+// Session credentials always pass.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := ScopesFromContext(r.Context()); ok && !slices.Contains(scopes, scope) {
+				writeJSON(w, http.StatusForbidden, ErrorBody{
+					Error: ErrorDetail{Code: "insufficient_scope", Message: "token lacks the " + scope + " scope"},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession returns middleware that rejects personal access tokens, so that a
+// leaked token cannot be used to mint further tokens or bots.
+func RequireSession() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := ScopesFromContext(r.Context()); ok {
+				writeJSON(w, http.StatusForbidden, ErrorBody{
+					Error: ErrorDetail{Code: "forbidden", Message: "this endpoint requires a user session"},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminMiddleware returns middleware that only admits requests bearing the static admin token.
+// An empty token rejects every request.
+func AdminMiddleware(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if adminToken == "" || subtle.ConstantTimeCompare([]byte(tokenStr), []byte(adminToken)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, ErrorBody{
+					Error: ErrorDetail{Code: "unauthorized", Message: "invalid admin token"},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RealIP returns middleware that resolves the client IP behind trusted reverse
+// proxies. When the peer is one of the trusted networks, X-Forwarded-For is
+// read from the right, skipping trusted hops, and the first address that is
+// not trusted is the client. Entries left of it were written by the client and
+// are ignored. Without trusted networks the peer address is always used.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trusted) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := forwardedFor(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey, ip)))
+		})
+	}
+}
+
+// forwardedFor walks the X-Forwarded-For chain back from the peer while each
+// hop is trusted, and returns the last address reached.
+func forwardedFor(r *http.Request, trusted []netip.Prefix) string {
+	ip := remoteHost(r)
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && isTrusted(addr, trusted); i-- {
+		next, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = next
+	}
+	return addr.Unmap().String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	return slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// clientIP returns the client IP resolved by RealIP, or else the host part of
+// the request's remote address.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// remoteHost returns the host part of the request's remote address.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,14 +1,17 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
 )
 
 const testSecret = "test-secret"
@@ -34,7 +37,7 @@ func contextHandler() http.Handler {
 }
 
 func TestAuthMiddleware_MissingHeader(t *testing.T) {
-	middleware := AuthMiddleware(testSecret)
+	middleware := AuthMiddleware(testSecret, nil)
 	handler := middleware(contextHandler())
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -48,7 +51,7 @@ func TestAuthMiddleware_MissingHeader(t *testing.T) {
 }
 
 func TestAuthMiddleware_InvalidToken(t *testing.T) {
-	middleware := AuthMiddleware(testSecret)
+	middleware := AuthMiddleware(testSecret, nil)
 	handler := middleware(contextHandler())
 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
@@ -63,7 +66,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 }
 
 func TestAuthMiddleware_ExpiredToken(t *testing.T) {
-	middleware := AuthMiddleware(testSecret)
+	middleware := AuthMiddleware(testSecret, nil)
 	handler := middleware(contextHandler())
 
 	userID := uuid.New()
@@ -81,7 +84,7 @@ func TestAuthMiddleware_ExpiredToken(t *testing.T) {
 }
 
 func TestAuthMiddleware_ValidToken(t *testing.T) {
-	middleware := AuthMiddleware(testSecret)
+	middleware := AuthMiddleware(testSecret, nil)
 	handler := middleware(contextHandler())
 
 	userID := uuid.New()
@@ -102,3 +105,133 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 		t.Errorf("expected body %q, got %q", userID.String(), body)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Mock: TokenAuthenticator
+// ---------------------------------------------------------------------------
+
+type mockTokenAuthenticator struct {
+	tokens map[string]*model.PersonalAccessToken
+}
+
+func (m *mockTokenAuthenticator) Authenticate(_ context.Context, raw string) (*model.PersonalAccessToken, error) {
+	t, ok := m.tokens[raw]
+	if !ok {
+		return nil, model.ErrUnauthenticated
+	}
+	return t, nil
+}
+
+func TestAuthMiddleware_PersonalAccessToken(t *testing.T) {
+	userID := uuid.New()
+	pats := &mockTokenAuthenticator{tokens: map[string]*model.PersonalAccessToken{
+		"pat_valid": {UserID: userID, Scopes: []string{model.ScopeMessagesWrite}},
+	}}
+	handler := AuthMiddleware(testSecret, pats)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes, ok := ScopesFromContext(r.Context())
+		if !ok || len(scopes) != 1 || scopes[0] != model.ScopeMessagesWrite {
+			t.Errorf("expected scopes in context, got %v (ok=%v)", scopes, ok)
+		}
+		fmt.Fprint(w, UserIDFromContext(r.Context()).String())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer pat_valid")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Body.String() != userID.String() {
+		t.Errorf("expected body %q, got %q", userID.String(), rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer pat_revoked")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 for unknown token, got %d", rec.Code)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	userID := uuid.New()
+	pats := &mockTokenAuthenticator{tokens: map[string]*model.PersonalAccessToken{
+		"pat_reader": {UserID: userID, Scopes: []string{model.ScopeConversationsRead}},
+	}}
+	handler := AuthMiddleware(testSecret, pats)(RequireScope(model.ScopeMessagesWrite)(contextHandler()))
+
+	cases := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "token without scope", token: "pat_reader", want: http.StatusForbidden},
+		{name: "session token", token: makeToken(userID, time.Now().Add(time.Minute)), want: http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != c.want {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.want, rec.Code)
+		}
+	}
+}
+
+func TestRequireSession_RejectsPersonalAccessTokens(t *testing.T) {
+	pats := &mockTokenAuthenticator{tokens: map[string]*model.PersonalAccessToken{
+		"pat_all": {UserID: uuid.New(), Scopes: model.AllScopes},
+	}}
+	handler := AuthMiddleware(testSecret, pats)(RequireSession()(contextHandler()))
+
+	req := httptest.NewRequest(http.MethodPost, "/tokens", nil)
+	req.Header.Set("Authorization", "Bearer pat_all")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	cases := []struct {
+		name, remote string
+		forwarded    []string
+		trusted      []netip.Prefix
+		want         string
+	}{
+		{name: "no trusted proxies", remote: "10.0.0.1:1234", forwarded: []string{"203.0.113.7"}, want: "10.0.0.1"},
+		{name: "untrusted peer", remote: "198.51.100.1:1234", forwarded: []string{"203.0.113.7"}, trusted: trusted, want: "198.51.100.1"},
+		{name: "trusted peer", remote: "10.0.0.1:1234", forwarded: []string{"203.0.113.7"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "spoofed entries ignored", remote: "10.0.0.1:1234", forwarded: []string{"1.2.3.4, 203.0.113.7"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "proxy chain", remote: "[::1]:1234", forwarded: []string{"1.2.3.4", "203.0.113.7, 10.1.1.1"}, trusted: trusted, want: "203.0.113.7"},
+		{name: "all hops trusted", remote: "10.0.0.1:1234", forwarded: []string{"10.2.2.2"}, trusted: trusted, want: "10.2.2.2"},
+		{name: "garbage stops the walk", remote: "10.0.0.1:1234", forwarded: []string{"203.0.113.7, nonsense"}, trusted: trusted, want: "10.0.0.1"},
+		{name: "no header", remote: "10.0.0.1:1234", trusted: trusted, want: "10.0.0.1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			h := RealIP(c.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = c.remote
+			for _, f := range c.forwarded {
+				req.Header.Add("X-Forwarded-For", f)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != c.want {
+				t.Errorf("expected client IP %s, got %s", c.want, got)
+			}
+		})
+	}
+}
@@ -32,7 +32,7 @@ func (h *ModerationHandler) Block(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.mod.Block(r.Context(), userID, blockedID); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -51,7 +51,7 @@ func (h *ModerationHandler) Unblock(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.mod.Unblock(r.Context(), userID, blockedID); err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -64,7 +64,7 @@ func (h *ModerationHandler) ListBlocked(w http.ResponseWriter, r *http.Request)
 
 	blocked, err := h.mod.ListBlocked(r.Context(), userID)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -106,7 +106,7 @@ func (h *ModerationHandler) Report(w http.ResponseWriter, r *http.Request) {
 
 	report, err := h.mod.Report(r.Context(), userID, req.TargetType, targetID, req.Reason)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+const (
+	oidcCookieName = "oidc_login"
+	oidcCookiePath = "/api/v1/auth/oidc"
+	oidcCookieTTL  = 600 // seconds the user has to finish signing in at the provider
+)
+
+// OIDCHandler handles OpenID Connect sign-in endpoints.
+type OIDCHandler struct {
+	oidc   *service.OIDCService
+	secure bool
+}
+
+// NewOIDCHandler creates a new OIDCHandler. secure marks its cookie Secure;
+// the request alone cannot tell, since TLS usually ends at a proxy.
+func NewOIDCHandler(oidc *service.OIDCService, secure bool) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc, secure: secure}
+}
+
+// Login handles GET /auth/oidc/{provider}/login — redirects to the provider.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	req, err := h.oidc.BeginLogin(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The PKCE verifier and nonce stay with the browser in an HttpOnly cookie;
+	// only the state and code challenge travel through the provider.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    strings.Join([]string{req.State, req.Nonce, req.Verifier}, "."),
+		Path:     oidcCookiePath,
+		MaxAge:   oidcCookieTTL,
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, req.URL, http.StatusFound)
+}
+
+// Callback handles GET /auth/oidc/{provider}/callback.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if providerErr := q.Get("error"); providerErr != "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorBody{
+			Error: ErrorDetail{Code: "unauthorized", Message: "identity provider returned " + providerErr},
+		})
+		return
+	}
+
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "missing login state"},
+		})
+		return
+	}
+	// The cookie is single-use regardless of the outcome.
+	http.SetCookie(w, &http.Cookie{Name: oidcCookieName, Path: oidcCookiePath, MaxAge: -1, HttpOnly: true, Secure: h.secure})
+
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(q.Get("state"))) != 1 {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid login state"},
+		})
+		return
+	}
+	nonce, verifier := parts[1], parts[2]
+
+	result, err := h.oidc.CompleteLogin(r.Context(), chi.URLParam(r, "provider"), q.Get("code"), verifier, nonce)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.AuthResponse{
+		User:   toUserResponse(result.User),
+		Tokens: toTokenResponse(&result.Tokens),
+	})
+}
@@ -0,0 +1,389 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/openapi"
+)
+
+// OpenAPIPath is where NewRouter serves the OpenAPI document.
+const OpenAPIPath = "/api/v1/openapi.json"
+
+// Security schemes an operation may require.
+const (
+	securityBearer = "bearerAuth" // a JWT access token or personal access token
+	securityAdmin  = "adminToken"
+	securityHook   = "hookToken" // an incoming webhook's token
+)
+
+// apiRoute documents one route mounted by NewRouter.
+type apiRoute struct {
+	method, path string
+	tag          string
+	summary      string
+	security     string   // required scheme; empty for public routes
+	scopes       []string // personal access token scopes the route requires
+	params       []openapi.Parameter
+	request      any // request body type; nil if the route takes none
+	responses    []apiResponse
+}
+
+// apiResponse documents one success response of a route. Error responses are
+// documented once for every route, as ErrorBody.
+type apiResponse struct {
+	status      int
+	description string
+	body        any    // JSON body type; nil for responses without a body
+	contentType string // set for a non-JSON body, which is described as binary
+}
+
+var (
+	cursorParam = openapi.Parameter{Name: "cursor", In: "query", Description: "Opaque cursor from a previous page's next_cursor.",
+		Schema: &openapi.Schema{Type: openapi.Types{"string"}}}
+	idempotencyKeyParam = openapi.Parameter{Name: IdempotencyKeyHeader, In: "header",
+		Description: "Client-chosen key that makes the request safe to retry; see Idempotency in docs/API.md.",
+		Schema:      &openapi.Schema{Type: openapi.Types{"string"}}}
+)
+
+func pathID(name string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: openapi.Types{"string"}, Format: "uuid"}}
+}
+
+func limitParam(description string) openapi.Parameter {
+	return openapi.Parameter{Name: "limit", In: "query", Description: description, Schema: &openapi.Schema{Type: openapi.Types{"integer"}}}
+}
+
+func queryString(name, description string, required bool) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Required: required,
+		Schema: &openapi.Schema{Type: openapi.Types{"string"}}}
+}
+
+// apiRoutes lists every route NewRouter mounts. It is the source of the
+// OpenAPI document, and a test keeps it in step with the router.
+func apiRoutes() []apiRoute {
+	conversation := apiResponse{http.StatusOK, "The conversation.", dto.ConversationResponse{}, ""}
+	message := apiResponse{http.StatusOK, "The message.", dto.MessageResponse{}, ""}
+	noContent := apiResponse{http.StatusNoContent, "Done.", nil, ""}
+	user := apiResponse{http.StatusOK, "The authenticated user.", dto.UserResponse{}, ""}
+	tokens := apiResponse{http.StatusOK, "A new token pair.", dto.TokenResponse{}, ""}
+	provider := openapi.Parameter{Name: "provider", In: "path", Required: true, Schema: &openapi.Schema{Type: openapi.Types{"string"}}}
+
+	return []apiRoute{
+		{method: "GET", path: "/healthz", tag: "health", summary: "Liveness probe",
+			responses: []apiResponse{{http.StatusOK, "The process is serving.", dto.HealthResponse{}, ""}}},
+		{method: "GET", path: "/readyz", tag: "health", summary: "Readiness probe",
+			responses: []apiResponse{
+				{http.StatusOK, "Ready for traffic.", dto.HealthResponse{}, ""},
+				{http.StatusServiceUnavailable, "Draining or a dependency is failing.", dto.HealthResponse{}, ""},
+			}},
+		{method: "GET", path: OpenAPIPath, tag: "meta", summary: "This OpenAPI document",
+			responses: []apiResponse{{http.StatusOK, "The OpenAPI 3.1 document.", map[string]any{}, ""}}},
+
+		{method: "POST", path: "/api/v1/auth/register", tag: "auth", summary: "Register with email and password",
+			request:   dto.RegisterRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The new user and their tokens.", dto.AuthResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/auth/login", tag: "auth", summary: "Log in with email and password",
+			request:   dto.LoginRequest{},
+			responses: []apiResponse{{http.StatusOK, "The user and their tokens.", dto.AuthResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/auth/refresh", tag: "auth", summary: "Exchange a refresh token for a new token pair",
+			request: dto.RefreshRequest{}, responses: []apiResponse{tokens}},
+		{method: "GET", path: "/api/v1/auth/oidc/{provider}/login", tag: "auth", summary: "Start single sign-on",
+			params:    []openapi.Parameter{provider},
+			responses: []apiResponse{{http.StatusFound, "Redirect to the identity provider.", nil, ""}}},
+		{method: "GET", path: "/api/v1/auth/oidc/{provider}/callback", tag: "auth", summary: "Finish single sign-on",
+			params: []openapi.Parameter{provider, queryString("code", "Authorization code.", false),
+				queryString("state", "State from the login redirect.", false), queryString("error", "Error reported by the provider.", false)},
+			responses: []apiResponse{{http.StatusOK, "The user and their tokens.", dto.AuthResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/auth/logout", tag: "auth", summary: "Revoke a refresh token", security: securityBearer,
+			request: dto.LogoutRequest{}, responses: []apiResponse{noContent}},
+
+		{method: "GET", path: "/api/v1/users/me", tag: "users", summary: "Get the authenticated user", security: securityBearer,
+			scopes: []string{model.ScopeUsersRead}, responses: []apiResponse{user}},
+		{method: "PATCH", path: "/api/v1/users/me", tag: "users", summary: "Update the authenticated user's profile", security: securityBearer,
+			scopes: []string{model.ScopeUsersWrite}, request: dto.UpdateProfileRequest{}, responses: []apiResponse{user}},
+		{method: "GET", path: "/api/v1/users/me/blocked", tag: "moderation", summary: "List blocked users", security: securityBearer,
+			scopes:    []string{model.ScopeUsersRead},
+			responses: []apiResponse{{http.StatusOK, "Blocked users.", dto.BlockedListResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/users/{id}", tag: "users", summary: "Get a user's public profile", security: securityBearer,
+			scopes: []string{model.ScopeUsersRead}, params: []openapi.Parameter{pathID("id")},
+			responses: []apiResponse{{http.StatusOK, "The profile.", dto.PublicProfileResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/users", tag: "users", summary: "Search users by display name", security: securityBearer,
+			scopes:    []string{model.ScopeUsersRead},
+			params:    []openapi.Parameter{queryString("q", "Search term.", true), cursorParam, limitParam("Page size, at most 100.")},
+			responses: []apiResponse{{http.StatusOK, "Matching users.", dto.SearchResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/users/{id}/block", tag: "moderation", summary: "Block a user", security: securityBearer,
+			scopes: []string{model.ScopeUsersWrite}, params: []openapi.Parameter{pathID("id")}, responses: []apiResponse{noContent}},
+		{method: "DELETE", path: "/api/v1/users/{id}/block", tag: "moderation", summary: "Unblock a user", security: securityBearer,
+			scopes: []string{model.ScopeUsersWrite}, params: []openapi.Parameter{pathID("id")}, responses: []apiResponse{noContent}},
+
+		{method: "POST", path: "/api/v1/users/me/devices", tag: "push", summary: "Register a device for push notifications", security: securityBearer,
+			scopes: []string{model.ScopeUsersWrite}, request: dto.RegisterDeviceRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The device. A token registered before, by any user, is moved to the caller.", dto.DeviceResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/users/me/devices", tag: "push", summary: "List your registered devices", security: securityBearer,
+			scopes:    []string{model.ScopeUsersRead},
+			responses: []apiResponse{{http.StatusOK, "The devices, without tokens.", dto.DeviceListResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/users/me/devices/{id}", tag: "push", summary: "Unregister a device", security: securityBearer,
+			scopes: []string{model.ScopeUsersWrite}, params: []openapi.Parameter{pathID("id")}, responses: []apiResponse{noContent}},
+		{method: "GET", path: "/api/v1/users/me/digest", tag: "notifications", summary: "Get your email digest settings", security: securityBearer,
+			scopes:    []string{model.ScopeUsersRead},
+			responses: []apiResponse{{http.StatusOK, "The settings, or the defaults if you never changed them.", dto.DigestSettingsResponse{}, ""}}},
+		{method: "PUT", path: "/api/v1/users/me/digest", tag: "notifications", summary: "Change your email digest settings", security: securityBearer,
+			scopes: []string{model.ScopeUsersWrite}, request: dto.DigestSettingsRequest{},
+			responses: []apiResponse{{http.StatusOK, "The updated settings.", dto.DigestSettingsResponse{}, ""}}},
+
+		{method: "POST", path: "/api/v1/conversations", tag: "conversations", summary: "Create a conversation", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{idempotencyKeyParam},
+			request: dto.CreateConversationRequest{},
+			responses: []apiResponse{
+				{http.StatusCreated, "The new conversation.", dto.ConversationResponse{}, ""},
+				{http.StatusOK, "The existing direct conversation between the two users.", dto.ConversationResponse{}, ""},
+			}},
+		{method: "GET", path: "/api/v1/conversations", tag: "conversations", summary: "List the caller's conversations", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{cursorParam, limitParam("Page size, at most 100.")},
+			responses: []apiResponse{{http.StatusOK, "A page of conversations.", dto.ConversationListResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/conversations/{id}", tag: "conversations", summary: "Get a conversation", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{pathID("id")}, responses: []apiResponse{conversation}},
+		{method: "PATCH", path: "/api/v1/conversations/{id}", tag: "conversations", summary: "Rename a group conversation", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			request: dto.UpdateConversationRequest{}, responses: []apiResponse{conversation}},
+		{method: "POST", path: "/api/v1/conversations/{id}/participants", tag: "conversations", summary: "Add participants to a group", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			request:   dto.AddParticipantsRequest{},
+			responses: []apiResponse{{http.StatusOK, "The participants added.", []dto.ParticipantResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/conversations/{id}/participants/{userId}", tag: "conversations", summary: "Remove a participant or leave", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id"), pathID("userId")},
+			responses: []apiResponse{noContent}},
+		{method: "PUT", path: "/api/v1/conversations/{id}/mute", tag: "push", summary: "Mute a conversation's push notifications", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			request:   dto.MuteRequest{},
+			responses: []apiResponse{{http.StatusOK, "The mute.", dto.MuteResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/conversations/{id}/mute", tag: "push", summary: "Get whether a conversation is muted", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{pathID("id")},
+			responses: []apiResponse{{http.StatusOK, "The mute, if any.", dto.MuteResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/conversations/{id}/mute", tag: "push", summary: "Unmute a conversation", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			responses: []apiResponse{noContent}},
+
+		{method: "POST", path: "/api/v1/conversations/{id}/webhooks", tag: "webhooks", summary: "Register a webhook for a conversation you own", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			request:   dto.CreateWebhookRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The webhook, including its signing secret, which is not shown again.", dto.CreateWebhookResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/conversations/{id}/webhooks", tag: "webhooks", summary: "List a conversation's webhooks", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{pathID("id")},
+			responses: []apiResponse{{http.StatusOK, "The webhooks, without secrets.", dto.WebhookListResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/conversations/{id}/webhooks/{webhookId}", tag: "webhooks", summary: "Delete a webhook and its delivery log", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id"), pathID("webhookId")},
+			responses: []apiResponse{noContent}},
+		{method: "GET", path: "/api/v1/conversations/{id}/webhooks/{webhookId}/deliveries", tag: "webhooks", summary: "Get a webhook's delivery log, newest first", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{pathID("id"), pathID("webhookId"), cursorParam, limitParam("Page size, at most 100.")},
+			responses: []apiResponse{{http.StatusOK, "A page of deliveries.", dto.WebhookDeliveryListResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/conversations/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", tag: "webhooks", summary: "Send a delivery again", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id"), pathID("webhookId"), pathID("deliveryId")},
+			responses: []apiResponse{{http.StatusAccepted, "The delivery, queued to be sent straight away.", dto.WebhookDeliveryResponse{}, ""}}},
+
+		{method: "POST", path: "/api/v1/conversations/{id}/incoming-webhooks", tag: "bots", summary: "Create an incoming webhook for a conversation you own", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			request:   dto.CreateIncomingWebhookRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The incoming webhook, including its token, which is not shown again.", dto.CreateIncomingWebhookResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/conversations/{id}/incoming-webhooks", tag: "bots", summary: "List a conversation's incoming webhooks", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{pathID("id")},
+			responses: []apiResponse{{http.StatusOK, "The incoming webhooks, without tokens.", dto.IncomingWebhookListResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/conversations/{id}/incoming-webhooks/{hookId}", tag: "bots", summary: "Delete an incoming webhook", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id"), pathID("hookId")},
+			responses: []apiResponse{noContent}},
+		{method: "POST", path: "/api/v1/hooks/{id}", tag: "bots", summary: "Post a message through an incoming webhook", security: securityHook,
+			params:    []openapi.Parameter{pathID("id")},
+			request:   dto.PostHookMessageRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The message, sent by the webhook's bot.", dto.MessageResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/conversations/{id}/commands", tag: "bots", summary: "Register an HTTP slash command for a conversation you own", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id")},
+			request:   dto.CreateCommandRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The command, including its signing secret, which is not shown again.", dto.CreateCommandResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/conversations/{id}/commands", tag: "bots", summary: "List a conversation's HTTP slash commands", security: securityBearer,
+			scopes: []string{model.ScopeConversationsRead}, params: []openapi.Parameter{pathID("id")},
+			responses: []apiResponse{{http.StatusOK, "The commands, without secrets.", dto.CommandListResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/conversations/{id}/commands/{commandId}", tag: "bots", summary: "Delete an HTTP slash command", security: securityBearer,
+			scopes: []string{model.ScopeConversationsWrite}, params: []openapi.Parameter{pathID("id"), pathID("commandId")},
+			responses: []apiResponse{noContent}},
+
+		{method: "POST", path: "/api/v1/conversations/{id}/messages", tag: "messages", summary: "Send a message", security: securityBearer,
+			scopes: []string{model.ScopeMessagesWrite}, params: []openapi.Parameter{pathID("id"), idempotencyKeyParam},
+			request: dto.SendMessageRequest{},
+			responses: []apiResponse{
+				{http.StatusCreated, "The new message.", dto.MessageResponse{}, ""},
+				{http.StatusOK, "The original message of a retried send.", dto.MessageResponse{}, ""},
+			}},
+		{method: "GET", path: "/api/v1/conversations/{id}/messages", tag: "messages", summary: "Get message history, newest first", security: securityBearer,
+			scopes: []string{model.ScopeMessagesRead}, params: []openapi.Parameter{pathID("id"), cursorParam, limitParam("Page size, at most 100.")},
+			responses: []apiResponse{{http.StatusOK, "A page of messages.", dto.MessageListResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/conversations/{id}/messages/{messageId}", tag: "messages", summary: "Get a message", security: securityBearer,
+			scopes: []string{model.ScopeMessagesRead}, params: []openapi.Parameter{pathID("id"), pathID("messageId")},
+			responses: []apiResponse{message}},
+
+		{method: "POST", path: "/api/v1/reports", tag: "moderation", summary: "Report a user, message or conversation", security: securityBearer,
+			scopes: []string{model.ScopeReportsWrite}, params: []openapi.Parameter{idempotencyKeyParam},
+			request:   dto.ReportRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The report.", dto.ReportResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/ws", tag: "realtime", summary: "Open the WebSocket event stream", security: securityBearer,
+			scopes:    []string{model.ScopeMessagesRead},
+			params:    []openapi.Parameter{queryString("token", "Access token, for clients that cannot set headers.", false)},
+			responses: []apiResponse{{http.StatusSwitchingProtocols, "Upgraded to a WebSocket.", nil, ""}}},
+
+		{method: "GET", path: "/api/v1/users/me/export", tag: "account", summary: "Download a copy of the account's data", security: securityBearer,
+			responses: []apiResponse{{http.StatusOK, "A zip archive of JSON files.", nil, "application/zip"}}},
+		{method: "DELETE", path: "/api/v1/users/me", tag: "account", summary: "Schedule the account for deletion", security: securityBearer,
+			responses: []apiResponse{{http.StatusAccepted, "Deletion is scheduled.", dto.AccountDeletionResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/users/me/deletion/cancel", tag: "account", summary: "Cancel a scheduled deletion", security: securityBearer,
+			responses: []apiResponse{noContent}},
+		{method: "POST", path: "/api/v1/users/me/password", tag: "account", summary: "Change the password", security: securityBearer,
+			request: dto.ChangePasswordRequest{}, responses: []apiResponse{tokens}},
+		{method: "POST", path: "/api/v1/bots", tag: "tokens", summary: "Create a bot", security: securityBearer,
+			request:   dto.CreateBotRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The new bot.", dto.BotResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/bots", tag: "tokens", summary: "List the caller's bots", security: securityBearer,
+			responses: []apiResponse{{http.StatusOK, "The bots.", dto.BotListResponse{}, ""}}},
+		{method: "POST", path: "/api/v1/tokens", tag: "tokens", summary: "Create a personal access token", security: securityBearer,
+			request:   dto.CreateTokenRequest{},
+			responses: []apiResponse{{http.StatusCreated, "The token, including its secret, which is not shown again.", dto.CreateTokenResponse{}, ""}}},
+		{method: "GET", path: "/api/v1/tokens", tag: "tokens", summary: "List personal access tokens", security: securityBearer,
+			responses: []apiResponse{{http.StatusOK, "The tokens, without secrets.", dto.TokenListResponse{}, ""}}},
+		{method: "DELETE", path: "/api/v1/tokens/{id}", tag: "tokens", summary: "Revoke a personal access token", security: securityBearer,
+			params: []openapi.Parameter{pathID("id")}, responses: []apiResponse{noContent}},
+
+		{method: "DELETE", path: "/api/v1/admin/lockouts", tag: "admin", summary: "Clear login lockouts", security: securityAdmin,
+			params:    []openapi.Parameter{queryString("email", "Account to unlock.", false), queryString("ip", "Client IP to unlock.", false)},
+			responses: []apiResponse{noContent}},
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// OpenAPISpec builds the OpenAPI document describing the routes NewRouter
+// mounts. Schemas are derived from the dto types the handlers encode and decode.
+func OpenAPISpec() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Chat API",
+		Version:     "1.0.0",
+		Description: "Generated from the server's routes and DTO types. See docs/API.md for behaviour.",
+	})
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		securityBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT or pat_…",
+			Description: "A JWT access token, or a personal access token restricted to the listed scopes. Routes without scopes require a session (JWT)."},
+		securityAdmin: {Type: "http", Scheme: "bearer", Description: "The server's ADMIN_TOKEN."},
+		securityHook:  {Type: "http", Scheme: "bearer", BearerFormat: "hook_…", Description: "The token returned when the incoming webhook was created."},
+	}
+	errorBody := doc.SchemaOf(ErrorBody{})
+
+	for _, route := range apiRoutes() {
+		op := &openapi.Operation{
+			OperationID: operationID(route.method, route.path),
+			Summary:     route.summary,
+			Tags:        []string{route.tag},
+			Parameters:  route.params,
+			Responses: map[string]*openapi.Response{
+				"default": {Description: "An error.", Content: openapi.JSONContent(errorBody)},
+			},
+		}
+		if route.security != "" {
+			scopes := route.scopes
+			if scopes == nil {
+				scopes = []string{}
+			}
+			op.Security = []map[string][]string{{route.security: scopes}}
+		}
+		if route.request != nil {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSONContent(doc.Request(route.request))}
+		}
+		for _, resp := range route.responses {
+			r := &openapi.Response{Description: resp.description}
+			switch {
+			case resp.contentType != "":
+				r.Content = map[string]*openapi.MediaType{
+					resp.contentType: {Schema: &openapi.Schema{Type: openapi.Types{"string"}, Format: "binary"}},
+				}
+			case resp.body != nil:
+				r.Content = openapi.JSONContent(doc.SchemaOf(resp.body))
+			}
+			op.Responses[strconv.Itoa(resp.status)] = r
+		}
+		doc.Add(route.method, route.path, op)
+	}
+	return doc
+}
+
+// operationID derives an identifier such as "postConversationsIdMessages".
+func operationID(method, path string) string {
+	path = strings.TrimPrefix(path, "/api/v1")
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(pathParamPattern.ReplaceAllString(path, "$1"), func(r rune) bool {
+		return r == '/' || r == '.' || r == '-'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// serveOpenAPI returns a handler serving doc as JSON.
+func serveOpenAPI(doc *openapi.Document) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		panic("handler: encode OpenAPI document: " + err.Error())
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// ValidateOpenAPI returns middleware that checks every routed request and its
+// response against doc, passing each mismatch to report. It buffers bodies
+// and is meant for tests, where report can fail the test; it must be the
+// router's outermost middleware so that it sees the matched route pattern.
+func ValidateOpenAPI(doc *openapi.Document, report func(r *http.Request, err error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqBody, err := io.ReadAll(r.Body)
+			if err != nil {
+				report(r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+			var respBody bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&respBody)
+			next.ServeHTTP(ww, r)
+
+			rctx := chi.RouteContext(r.Context())
+			pattern := ""
+			if rctx != nil {
+				pattern = rctx.RoutePattern()
+			}
+			if pattern == "" {
+				return // not routed, such as a 404 for an unknown path
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if err := doc.ValidateRequest(r.Method, pattern, r, rctx.URLParam, reqBody); err != nil {
+				report(r, err)
+			}
+			if err := doc.ValidateResponse(r.Method, pattern, status, w.Header(), respBody.Bytes()); err != nil {
+				report(r, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/openapi"
+	"github.com/kareempaes/planning/internal/repo"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// newTestAPI mounts NewRouter over an in-memory store, with every request
+// and response checked against the OpenAPI document.
+func newTestAPI(t *testing.T) http.Handler {
+	t.Helper()
+	store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := service.NewRegistry(service.DefaultRegistry, store, service.RegistryConfig{
+		Auth: service.AuthConfig{
+			JWTSecret:          "test-secret",
+			AccessTokenExpiry:  15 * time.Minute,
+			RefreshTokenExpiry: time.Hour,
+			BcryptCost:         4,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := infra.NewHub(nil, infra.HubConfig{})
+	go hub.Run()
+	t.Cleanup(func() { hub.Shutdown(context.Background()) })
+
+	return NewRouter(registry, hub, RouterConfig{
+		JWTSecret:  "test-secret",
+		AdminToken: "admin-secret",
+		ValidateOpenAPI: func(r *http.Request, err error) {
+			t.Errorf("%s %s does not match the OpenAPI document: %v", r.Method, r.URL.Path, err)
+		},
+	})
+}
+
+func TestOpenAPISpec_DocumentsEveryRoute(t *testing.T) {
+	router := newTestAPI(t).(*chi.Mux)
+	spec := OpenAPISpec()
+
+	mounted := map[string]bool{}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(strings.ReplaceAll(route, "/*/", "/"), "/")
+		mounted[method+" "+route] = true
+		if spec.Operation(method, route) == nil {
+			t.Errorf("%s %s is mounted but not documented", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, item := range spec.Paths {
+		for method := range *item {
+			if key := strings.ToUpper(method) + " " + path; !mounted[key] {
+				t.Errorf("%s is documented but not mounted", key)
+			}
+		}
+	}
+}
+
+func TestOpenAPISpec_Served(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestAPI(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON document, got %d %v", rec.Code, rec.Header())
+	}
+
+	var doc struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != openapi.Version || doc.Paths["/api/v1/conversations/{id}/messages"] == nil {
+		t.Errorf("unexpected document: %s", rec.Body.String()[:min(rec.Body.Len(), 200)])
+	}
+}
+
+// TestOpenAPISpec_MatchesResponses drives the main API flows through the
+// router with validation on, so that any handler answering with something
+// other than what the document promises fails the test.
+func TestOpenAPISpec_MatchesResponses(t *testing.T) {
+	api := newTestAPI(t)
+
+	call := func(method, path, token string, body any, want int) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			if err := json.NewEncoder(&buf).Encode(body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("%s %s: expected %d, got %d: %s", method, path, want, rec.Code, rec.Body)
+		}
+		return rec.Body.Bytes()
+	}
+	register := func(email, name string) dto.AuthResponse {
+		t.Helper()
+		var auth dto.AuthResponse
+		body := call("POST", "/api/v1/auth/register", "", dto.RegisterRequest{Email: email, Password: "correct-horse-7", DisplayName: name}, http.StatusCreated)
+		if err := json.Unmarshal(body, &auth); err != nil {
+			t.Fatal(err)
+		}
+		return auth
+	}
+
+	alice := register("alice@example.com", "Alice")
+	bob := register("bob@example.com", "Bob")
+	carol := register("carol@example.com", "Carol")
+	token := alice.Tokens.AccessToken
+
+	call("GET", "/healthz", "", nil, http.StatusOK)
+	call("GET", "/readyz", "", nil, http.StatusOK)
+	call("POST", "/api/v1/auth/login", "", dto.LoginRequest{Email: "alice@example.com", Password: "correct-horse-7"}, http.StatusOK)
+	call("POST", "/api/v1/auth/login", "", dto.LoginRequest{Email: "alice@example.com", Password: "wrong"}, http.StatusUnauthorized)
+	call("GET", "/api/v1/users/me", token, nil, http.StatusOK)
+	call("PATCH", "/api/v1/users/me", token, map[string]string{"display_name": "Alice A."}, http.StatusOK)
+	call("GET", "/api/v1/users/"+bob.User.ID.String(), token, nil, http.StatusOK)
+	call("GET", "/api/v1/users?q=bo", token, nil, http.StatusOK)
+	call("GET", "/api/v1/users/me", "", nil, http.StatusUnauthorized)
+
+	var convo dto.ConversationResponse
+	body := call("POST", "/api/v1/conversations", token, dto.CreateConversationRequest{
+		Type: "group", Name: new(string), ParticipantIDs: []string{bob.User.ID.String()},
+	}, http.StatusCreated)
+	if err := json.Unmarshal(body, &convo); err != nil {
+		t.Fatal(err)
+	}
+	convoPath := "/api/v1/conversations/" + convo.ID.String()
+	call("POST", "/api/v1/conversations", token, dto.CreateConversationRequest{
+		Type: "direct", ParticipantIDs: []string{carol.User.ID.String()},
+	}, http.StatusCreated)
+	call("GET", "/api/v1/conversations", token, nil, http.StatusOK)
+	call("GET", convoPath, token, nil, http.StatusOK)
+	call("PATCH", convoPath, token, dto.UpdateConversationRequest{Name: "Planning"}, http.StatusOK)
+	call("POST", convoPath+"/participants", token, dto.AddParticipantsRequest{UserIDs: []string{carol.User.ID.String()}}, http.StatusOK)
+	call("DELETE", convoPath+"/participants/"+carol.User.ID.String(), token, nil, http.StatusNoContent)
+
+	var msg dto.MessageResponse
+	body = call("POST", convoPath+"/messages", token, dto.SendMessageRequest{Body: "hello", ClientMessageID: "c1"}, http.StatusCreated)
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	call("POST", convoPath+"/messages", token, dto.SendMessageRequest{Body: "hello", ClientMessageID: "c1"}, http.StatusOK)
+	call("GET", convoPath+"/messages", token, nil, http.StatusOK)
+	call("GET", convoPath+"/messages/"+msg.ID.String(), token, nil, http.StatusOK)
+	call("GET", "/api/v1/conversations", token, nil, http.StatusOK)
+
+	var hook dto.CreateIncomingWebhookResponse
+	body = call("POST", convoPath+"/incoming-webhooks", token, dto.CreateIncomingWebhookRequest{Name: "CI"}, http.StatusCreated)
+	if err := json.Unmarshal(body, &hook); err != nil {
+		t.Fatal(err)
+	}
+	call("GET", convoPath+"/incoming-webhooks", token, nil, http.StatusOK)
+	call("POST", "/api/v1/hooks/"+hook.ID.String(), hook.Token, dto.PostHookMessageRequest{Body: "build passed"}, http.StatusCreated)
+	call("POST", "/api/v1/hooks/"+hook.ID.String(), token, dto.PostHookMessageRequest{Body: "build passed"}, http.StatusUnauthorized)
+	call("DELETE", convoPath+"/incoming-webhooks/"+hook.ID.String(), token, nil, http.StatusNoContent)
+
+	var cmd dto.CreateCommandResponse
+	body = call("POST", convoPath+"/commands", token, dto.CreateCommandRequest{Name: "deploy", URL: "https://example.com/deploy"}, http.StatusCreated)
+	if err := json.Unmarshal(body, &cmd); err != nil {
+		t.Fatal(err)
+	}
+	call("GET", convoPath+"/commands", token, nil, http.StatusOK)
+	call("DELETE", convoPath+"/commands/"+cmd.ID.String(), token, nil, http.StatusNoContent)
+
+	var device dto.DeviceResponse
+	body = call("POST", "/api/v1/users/me/devices", token, dto.RegisterDeviceRequest{Platform: "fcm", Token: "device-token"}, http.StatusCreated)
+	if err := json.Unmarshal(body, &device); err != nil {
+		t.Fatal(err)
+	}
+	call("GET", "/api/v1/users/me/devices", token, nil, http.StatusOK)
+	call("DELETE", "/api/v1/users/me/devices/"+device.ID.String(), token, nil, http.StatusNoContent)
+	weekly := "weekly"
+	call("PUT", "/api/v1/users/me/digest", token, dto.DigestSettingsRequest{Frequency: &weekly}, http.StatusOK)
+	call("GET", "/api/v1/users/me/digest", token, nil, http.StatusOK)
+	until := time.Now().Add(time.Hour)
+	call("PUT", convoPath+"/mute", token, dto.MuteRequest{Until: &until}, http.StatusOK)
+	call("GET", convoPath+"/mute", token, nil, http.StatusOK)
+	call("DELETE", convoPath+"/mute", token, nil, http.StatusNoContent)
+
+	call("POST", "/api/v1/users/"+carol.User.ID.String()+"/block", token, nil, http.StatusNoContent)
+	call("GET", "/api/v1/users/me/blocked", token, nil, http.StatusOK)
+	call("DELETE", "/api/v1/users/"+carol.User.ID.String()+"/block", token, nil, http.StatusNoContent)
+	call("POST", "/api/v1/reports", token, map[string]string{"target_type": "message", "target_id": msg.ID.String(), "reason": "spam"}, http.StatusCreated)
+
+	call("POST", "/api/v1/bots", token, map[string]string{"display_name": "Helper"}, http.StatusCreated)
+	call("GET", "/api/v1/bots", token, nil, http.StatusOK)
+	var pat dto.CreateTokenResponse
+	body = call("POST", "/api/v1/tokens", token, map[string]any{"name": "ci", "scopes": []string{"messages:read"}}, http.StatusCreated)
+	if err := json.Unmarshal(body, &pat); err != nil {
+		t.Fatal(err)
+	}
+	call("GET", "/api/v1/tokens", token, nil, http.StatusOK)
+	call("GET", "/api/v1/users/me", pat.Token, nil, http.StatusForbidden)
+	call("DELETE", "/api/v1/tokens/"+pat.ID.String(), token, nil, http.StatusNoContent)
+
+	call("POST", "/api/v1/auth/refresh", "", dto.RefreshRequest{RefreshToken: alice.Tokens.RefreshToken}, http.StatusOK)
+	call("DELETE", "/api/v1/users/me", bob.Tokens.AccessToken, nil, http.StatusAccepted)
+	call("POST", "/api/v1/users/me/deletion/cancel", bob.Tokens.AccessToken, nil, http.StatusNoContent)
+	call("DELETE", "/api/v1/admin/lockouts?email=alice@example.com", "admin-secret", nil, http.StatusNoContent)
+}
+
+// webTypes maps the interfaces in web/src/lib/types.ts to the schemas they mirror.
+var webTypes = map[string]string{
+	"AuthResponse":        "AuthResponse",
+	"Tokens":              "TokenResponse",
+	"User":                "UserResponse",
+	"PublicProfile":       "PublicProfileResponse",
+	"Conversation":        "ConversationResponse",
+	"ConversationSummary": "ConversationSummaryResponse",
+	"Participant":         "ParticipantResponse",
+	"ParticipantMin":      "ParticipantMinDTO",
+	"MessagePreview":      "MessagePreviewDTO",
+	"Message":             "MessageResponse",
+	"Pagination":          "PaginationResponse",
+}
+
+var (
+	tsInterface = regexp.MustCompile(`(?s)export interface (\w+) \{(.*?)\n\}`)
+	tsProperty  = regexp.MustCompile(`(?m)^\s*(\w+)(\??):\s*([^;]+);`)
+)
+
+// TestOpenAPISpec_MatchesWebTypes checks that the web client's hand-written
+// types have the same properties as the schemas the server documents, and
+// that the optional ones agree.
+func TestOpenAPISpec_MatchesWebTypes(t *testing.T) {
+	src, err := os.ReadFile("../../web/src/lib/types.ts")
+	if err != nil {
+		t.Skipf("web client not present: %v", err)
+	}
+	spec := OpenAPISpec()
+
+	seen := map[string]bool{}
+	for _, m := range tsInterface.FindAllStringSubmatch(string(src), -1) {
+		iface, body := m[1], m[2]
+		name, ok := webTypes[iface]
+		if !ok {
+			continue
+		}
+		seen[iface] = true
+		schema := spec.Components.Schemas[name]
+		if schema == nil {
+			t.Errorf("%s: schema %s is not in the document", iface, name)
+			continue
+		}
+
+		props := map[string]bool{}
+		for _, p := range tsProperty.FindAllStringSubmatch(body, -1) {
+			prop, optional := p[1], p[2] == "?"
+			props[prop] = true
+			if schema.Properties[prop] == nil {
+				t.Errorf("%s.%s is not a property of %s", iface, prop, name)
+				continue
+			}
+			if required := slices.Contains(schema.Required, prop); required == optional {
+				t.Errorf("%s.%s: optional is %v in TypeScript but required is %v in %s", iface, prop, optional, required, name)
+			}
+			if nullable := strings.Contains(p[3], "null"); nullable != acceptsNull(spec, schema.Properties[prop]) {
+				t.Errorf("%s.%s: nullable is %v in TypeScript but %v in %s", iface, prop, nullable, !nullable, name)
+			}
+		}
+		for prop := range schema.Properties {
+			if !props[prop] {
+				t.Errorf("%s has no property %s, which %s documents", iface, prop, name)
+			}
+		}
+	}
+	for iface := range webTypes {
+		if !seen[iface] {
+			t.Errorf("interface %s not found in types.ts", iface)
+		}
+	}
+}
+
+func acceptsNull(doc *openapi.Document, s *openapi.Schema) bool {
+	return doc.Validate(s, []byte("null")) == nil
+}
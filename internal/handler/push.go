@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// PushHandler handles the endpoints that control push notifications: the
+// caller's devices and their conversation mutes.
+type PushHandler struct {
+	push *service.PushService
+}
+
+// NewPushHandler creates a new PushHandler.
+func NewPushHandler(push *service.PushService) *PushHandler {
+	return &PushHandler{push: push}
+}
+
+// RegisterDevice handles POST /users/me/devices.
+func (h *PushHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	var req dto.RegisterDeviceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	device, err := h.push.RegisterDevice(r.Context(), userID, req.Platform, req.Token)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toDeviceResponse(device))
+}
+
+// ListDevices handles GET /users/me/devices.
+func (h *PushHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	devices, err := h.push.ListDevices(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := dto.DeviceListResponse{Devices: make([]dto.DeviceResponse, 0, len(devices))}
+	for i := range devices {
+		resp.Devices = append(resp.Devices, toDeviceResponse(&devices[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteDevice handles DELETE /users/me/devices/{id}.
+func (h *PushHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	deviceID, ok := parsePathID(w, r, "id", "invalid device ID")
+	if !ok {
+		return
+	}
+
+	if err := h.push.DeleteDevice(r.Context(), userID, deviceID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
+
+// Mute handles PUT /conversations/{id}/mute.
+func (h *PushHandler) Mute(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	var req dto.MuteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	mute, err := h.push.Mute(r.Context(), userID, convoID, req.Until)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMuteResponse(mute))
+}
+
+// GetMute handles GET /conversations/{id}/mute.
+func (h *PushHandler) GetMute(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	mute, err := h.push.GetMute(r.Context(), userID, convoID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toMuteResponse(mute))
+}
+
+// Unmute handles DELETE /conversations/{id}/mute.
+func (h *PushHandler) Unmute(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	if err := h.push.Unmute(r.Context(), userID, convoID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
+
+func toDeviceResponse(d *model.DeviceToken) dto.DeviceResponse {
+	return dto.DeviceResponse{
+		ID:        d.ID,
+		Platform:  d.Platform,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// toMuteResponse describes mute, which is nil when the conversation is not muted.
+func toMuteResponse(mute *model.ConversationMute) dto.MuteResponse {
+	if mute == nil {
+		return dto.MuteResponse{}
+	}
+	return dto.MuteResponse{Muted: true, MutedUntil: mute.MutedUntil}
+}
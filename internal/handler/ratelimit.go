@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// RateLimiter decides whether a request may proceed. It is satisfied by
+// *service.RateLimiter.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit service.RateLimit) (service.RateLimitDecision, error)
+}
+
+// RateLimits configures the token bucket of each route group. A zero
+// service.RateLimit leaves its group unlimited.
+type RateLimits struct {
+	Auth      service.RateLimit // unauthenticated /auth/* routes, per client IP
+	API       service.RateLimit // every authenticated route, per user
+	Messages  service.RateLimit // sending messages, per user
+	Reports   service.RateLimit // filing reports, per user
+	Search    service.RateLimit // searching users, per user
+	WebSocket service.RateLimit // inbound WebSocket frames, per user
+}
+
+// RateLimit returns middleware that spends a token from the caller's bucket in
+// group before serving the request. Every limited response carries
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and a
+// rejected one gets 429 with Retry-After. keyOf picks the bucket, such as
+// byUser or byIP. If the limiter fails the request is let through, so that an
+// outage of the shared store does not take the API down with it.
+func RateLimit(limiter RateLimiter, group string, limit service.RateLimit, keyOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil || !limit.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, err := limiter.Allow(r.Context(), group+":"+keyOf(r), limit)
+			if err != nil {
+				requestLogger(r).Warn("rate limiter unavailable, allowing request",
+					slog.String("group", group), slog.Any("error", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("RateLimit-Reset", headerSeconds(d.Reset))
+			if !d.Allowed {
+				h.Set("Retry-After", headerSeconds(d.RetryAfter))
+				writeJSON(w, http.StatusTooManyRequests, ErrorBody{
+					Error: ErrorDetail{Code: "rate_limited", Message: "too many requests, try again later"},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// byUser keys rate limits by the authenticated user. It must run after AuthMiddleware.
+func byUser(r *http.Request) string {
+	return "user:" + UserIDFromContext(r.Context()).String()
+}
+
+// byIP keys rate limits by the client IP.
+func byIP(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// headerSeconds formats d as whole seconds, rounded up so that clients never
+// retry early.
+func headerSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/repo"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(context.Context, string, service.RateLimit) (service.RateLimitDecision, error) {
+	return service.RateLimitDecision{}, errors.New("database is down")
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+}
+
+func TestRateLimit_ByIP(t *testing.T) {
+	limiter := service.NewRateLimiter(repo.NewMemoryRateLimitRepo())
+	limit := service.RateLimit{Requests: 2, Per: time.Minute}
+	h := RateLimit(limiter, "auth", limit, byIP)(okHandler())
+
+	send := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := send("10.0.0.1:1234")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("expected RateLimit-Limit 2, got %q", got)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("request %d: expected RateLimit-Remaining %s, got %q", i+1, remaining, got)
+		}
+	}
+
+	// Another port on the same host shares the bucket.
+	rec := send("10.0.0.1:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Reset"); got != "60" {
+		t.Errorf("expected RateLimit-Reset 60, got %q", got)
+	}
+
+	if rec := send("10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("expected a different IP to be admitted, got %d", rec.Code)
+	}
+}
+
+func TestRateLimit_ByUserAfterAuth(t *testing.T) {
+	limiter := service.NewRateLimiter(repo.NewMemoryRateLimitRepo())
+	limit := service.RateLimit{Requests: 1, Per: time.Hour}
+	h := AuthMiddleware(testSecret, nil)(RateLimit(limiter, "reports", limit, byUser)(okHandler()))
+
+	send := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/reports", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	alice := makeToken(uuid.New(), time.Now().Add(time.Hour))
+	bob := makeToken(uuid.New(), time.Now().Add(time.Hour))
+	if code := send(alice); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := send(alice); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 on the second report, got %d", code)
+	}
+	if code := send(bob); code != http.StatusOK {
+		t.Errorf("expected another user to be admitted, got %d", code)
+	}
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	h := RateLimit(failingRateLimiter{}, "api", service.RateLimit{Requests: 1, Per: time.Second}, byIP)(okHandler())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected the request through when the limiter fails, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "" {
+		t.Error("expected no rate limit headers without a decision")
+	}
+}
+
+func TestRateLimit_DisabledIsPassThrough(t *testing.T) {
+	next := okHandler()
+	h := RateLimit(failingRateLimiter{}, "api", service.RateLimit{}, byIP)(next)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("expected a disabled limit to pass requests untouched, got %d %v", rec.Code, rec.Header())
+	}
+}
@@ -3,9 +3,13 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/kareempaes/planning/internal/model"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrorBody is the standard error response envelope.
@@ -25,23 +29,41 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	json.NewEncoder(w).Encode(v)
 }
 
-func writeError(w http.ResponseWriter, err error) {
+// writeError maps err to its HTTP status and error envelope. Errors without a
+// mapping become a generic 500; the underlying error is logged with the request.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
 	var ve *model.ValidationError
+	var tme *model.TooManyAttemptsError
 
 	switch {
 	case errors.As(err, &ve):
 		writeJSON(w, http.StatusUnprocessableEntity, ErrorBody{
 			Error: ErrorDetail{Code: "validation_error", Message: ve.Error()},
 		})
+	case errors.As(err, &tme):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(tme.RetryAfter.Seconds()))))
+		writeJSON(w, http.StatusTooManyRequests, ErrorBody{
+			Error: ErrorDetail{Code: "too_many_attempts", Message: "too many failed attempts, try again later"},
+		})
 	case errors.Is(err, model.ErrNotFound):
 		writeJSON(w, http.StatusNotFound, ErrorBody{
 			Error: ErrorDetail{Code: "not_found", Message: "resource not found"},
 		})
+	case errors.Is(err, model.ErrUnauthenticated):
+		writeJSON(w, http.StatusUnauthorized, ErrorBody{
+			Error: ErrorDetail{Code: "unauthorized", Message: "authentication failed"},
+		})
+	case errors.Is(err, model.ErrForbidden):
+		writeJSON(w, http.StatusForbidden, ErrorBody{
+			Error: ErrorDetail{Code: "forbidden", Message: "not allowed"},
+		})
 	case errors.Is(err, model.ErrConflict):
 		writeJSON(w, http.StatusConflict, ErrorBody{
 			Error: ErrorDetail{Code: "conflict", Message: "resource already exists"},
 		})
 	default:
+		requestLogger(r).Error("internal error", slog.Any("error", err))
+		trace.SpanFromContext(r.Context()).RecordError(err)
 		writeJSON(w, http.StatusInternalServerError, ErrorBody{
 			Error: ErrorDetail{Code: "internal_error", Message: "internal server error"},
 		})
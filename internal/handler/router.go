@@ -1,60 +1,201 @@
 package handler
 
 import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/model"
 	"github.com/kareempaes/planning/internal/service"
 )
 
+// RouterConfig holds settings the HTTP layer needs beyond the service registry.
+type RouterConfig struct {
+	JWTSecret  string
+	AdminToken string         // bearer token for /admin routes; empty disables them
+	Logger     *slog.Logger   // request logs; nil uses slog.Default
+	Metrics    *infra.Metrics // request metrics; nil records none
+	Health     *HealthHandler // serves /healthz and /readyz; nil reports ready with no checks
+	RateLimits RateLimits     // per route group; zero limits leave groups unlimited
+	CORS       CORSConfig     // browser origins allowed to call the API and open WebSockets
+
+	// SecureCookies marks cookies the API sets as HTTPS-only. Set it
+	// wherever clients reach the server over TLS, including through a
+	// proxy that terminates it.
+	SecureCookies bool
+
+	// Events publishes conversation events. The caller creates it, so that it
+	// can also make it the messages' listener; nil publishes through a new
+	// one that bots' messages do not reach.
+	Events *ConversationEvents
+
+	// TrustedProxies are the networks of reverse proxies whose
+	// X-Forwarded-For header is believed; see RealIP. Empty uses the peer
+	// address as the client IP.
+	TrustedProxies []netip.Prefix
+
+	// ValidateOpenAPI, if set, checks every request and response against
+	// OpenAPISpec and reports mismatches to it. Tests use it to keep the
+	// document honest; it buffers bodies and is not meant for production.
+	ValidateOpenAPI func(r *http.Request, err error)
+}
+
 // NewRouter creates the chi router with all API routes.
-func NewRouter(registry *service.Registry, hub *infra.Hub, jwtSecret string) *chi.Mux {
+func NewRouter(registry *service.Registry, hub *infra.Hub, cfg RouterConfig) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	spec := OpenAPISpec()
+	if cfg.ValidateOpenAPI != nil {
+		r.Use(ValidateOpenAPI(spec, cfg.ValidateOpenAPI))
+	}
 	r.Use(middleware.RequestID)
+	r.Use(RealIP(cfg.TrustedProxies))
+	r.Use(RequestTracing())
+	r.Use(RequestLogger(cfg.Logger))
+	if cfg.Metrics != nil {
+		r.Use(RequestMetrics(cfg.Metrics))
+	}
+	r.Use(CORS(cfg.CORS))
+
+	health := cfg.Health
+	if health == nil {
+		health = NewHealthHandler()
+	}
+	r.Get("/healthz", health.Live)
+	r.Get("/readyz", health.Ready)
+	r.Get(OpenAPIPath, serveOpenAPI(spec))
+
+	limits := cfg.RateLimits
+	perUser := func(group string, limit service.RateLimit) func(http.Handler) http.Handler {
+		return RateLimit(registry.RateLimits, group, limit, byUser)
+	}
+	idempotent := Idempotency(registry.Idempotency)
+	events := cfg.Events
+	if events == nil {
+		events = NewConversationEvents(registry.Conversations, registry.Webhooks, hub, cfg.Logger)
+	}
 
 	r.Route("/api/v1", func(r chi.Router) {
 		auth := NewAuthHandler(registry.Auth)
-		r.Post("/auth/register", auth.Register)
-		r.Post("/auth/login", auth.Login)
-		r.Post("/auth/refresh", auth.Refresh)
+
+		// Unauthenticated auth endpoints are limited per client IP.
+		r.Group(func(r chi.Router) {
+			r.Use(RateLimit(registry.RateLimits, "auth", limits.Auth, byIP))
+
+			r.Post("/auth/register", auth.Register)
+			r.Post("/auth/login", auth.Login)
+			r.Post("/auth/refresh", auth.Refresh)
+
+			oidc := NewOIDCHandler(registry.OIDC, cfg.SecureCookies)
+			r.Get("/auth/oidc/{provider}/login", oidc.Login)
+			r.Get("/auth/oidc/{provider}/callback", oidc.Callback)
+		})
+
+		// Incoming webhooks authenticate with their own token, not a user.
+		hooks := NewIncomingWebhookHandler(registry.IncomingWebhooks)
+		r.With(RateLimit(registry.RateLimits, "hooks", limits.Messages, byIP)).Post("/hooks/{id}", hooks.Post)
 
 		r.Group(func(r chi.Router) {
-			r.Use(AuthMiddleware(jwtSecret))
+			r.Use(AuthMiddleware(cfg.JWTSecret, registry.Tokens))
+			r.Use(perUser("api", limits.API))
 
 			r.Post("/auth/logout", auth.Logout)
 
+			// Personal access tokens are scope-restricted; session (JWT) callers pass every check.
+			usersRead := r.With(RequireScope(model.ScopeUsersRead))
+			usersWrite := r.With(RequireScope(model.ScopeUsersWrite))
+			convosRead := r.With(RequireScope(model.ScopeConversationsRead))
+			convosWrite := r.With(RequireScope(model.ScopeConversationsWrite))
+			msgsRead := r.With(RequireScope(model.ScopeMessagesRead))
+			msgsWrite := r.With(RequireScope(model.ScopeMessagesWrite))
+
 			users := NewUserHandler(registry.Users)
 			mod := NewModerationHandler(registry.Moderation, registry.Users)
 
-			r.Get("/users/me", users.GetMe)
-			r.Patch("/users/me", users.UpdateMe)
-			r.Get("/users/me/blocked", mod.ListBlocked)
-			r.Get("/users/{id}", users.GetPublicProfile)
-			r.Get("/users", users.Search)
-			r.Post("/users/{id}/block", mod.Block)
-			r.Delete("/users/{id}/block", mod.Unblock)
-
-			convos := NewConversationHandler(registry.Conversations)
-			r.Post("/conversations", convos.Create)
-			r.Get("/conversations", convos.List)
-			r.Get("/conversations/{id}", convos.GetByID)
-			r.Patch("/conversations/{id}", convos.Update)
-			r.Post("/conversations/{id}/participants", convos.AddParticipants)
-			r.Delete("/conversations/{id}/participants/{userId}", convos.RemoveParticipant)
-
-			msgs := NewMessageHandler(registry.Messages, registry.Conversations, hub)
-			r.Post("/conversations/{id}/messages", msgs.Send)
-			r.Get("/conversations/{id}/messages", msgs.GetHistory)
-			r.Get("/conversations/{id}/messages/{messageId}", msgs.GetByID)
-
-			r.Post("/reports", mod.Report)
-
-			ws := NewWSHandler(hub, jwtSecret)
-			r.Get("/ws", ws.Upgrade)
+			usersRead.Get("/users/me", users.GetMe)
+			usersWrite.Patch("/users/me", users.UpdateMe)
+			usersRead.Get("/users/me/blocked", mod.ListBlocked)
+			usersRead.Get("/users/{id}", users.GetPublicProfile)
+			usersRead.With(perUser("search", limits.Search)).Get("/users", users.Search)
+			usersWrite.Post("/users/{id}/block", mod.Block)
+			usersWrite.Delete("/users/{id}/block", mod.Unblock)
+
+			push := NewPushHandler(registry.Push)
+			usersWrite.Post("/users/me/devices", push.RegisterDevice)
+			usersRead.Get("/users/me/devices", push.ListDevices)
+			usersWrite.Delete("/users/me/devices/{id}", push.DeleteDevice)
+
+			digest := NewDigestHandler(registry.Digests)
+			usersRead.Get("/users/me/digest", digest.GetSettings)
+			usersWrite.Put("/users/me/digest", digest.UpdateSettings)
+
+			convos := NewConversationHandler(registry.Conversations, events)
+			convosWrite.With(idempotent).Post("/conversations", convos.Create)
+			convosRead.Get("/conversations", convos.List)
+			convosRead.Get("/conversations/{id}", convos.GetByID)
+			convosWrite.Patch("/conversations/{id}", convos.Update)
+			convosWrite.Post("/conversations/{id}/participants", convos.AddParticipants)
+			convosWrite.Delete("/conversations/{id}/participants/{userId}", convos.RemoveParticipant)
+			convosWrite.Put("/conversations/{id}/mute", push.Mute)
+			convosRead.Get("/conversations/{id}/mute", push.GetMute)
+			convosWrite.Delete("/conversations/{id}/mute", push.Unmute)
+
+			webhooks := NewWebhookHandler(registry.Webhooks)
+			convosWrite.Post("/conversations/{id}/webhooks", webhooks.Create)
+			convosRead.Get("/conversations/{id}/webhooks", webhooks.List)
+			convosWrite.Delete("/conversations/{id}/webhooks/{webhookId}", webhooks.Delete)
+			convosRead.Get("/conversations/{id}/webhooks/{webhookId}/deliveries", webhooks.ListDeliveries)
+			convosWrite.Post("/conversations/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", webhooks.Redeliver)
+
+			convosWrite.Post("/conversations/{id}/incoming-webhooks", hooks.Create)
+			convosRead.Get("/conversations/{id}/incoming-webhooks", hooks.List)
+			convosWrite.Delete("/conversations/{id}/incoming-webhooks/{hookId}", hooks.Delete)
+
+			commands := NewCommandHandler(registry.Commands)
+			convosWrite.Post("/conversations/{id}/commands", commands.Create)
+			convosRead.Get("/conversations/{id}/commands", commands.List)
+			convosWrite.Delete("/conversations/{id}/commands/{commandId}", commands.Delete)
+
+			msgs := NewMessageHandler(registry.Messages, events)
+			msgsWrite.With(perUser("messages", limits.Messages), idempotent).Post("/conversations/{id}/messages", msgs.Send)
+			msgsRead.Get("/conversations/{id}/messages", msgs.GetHistory)
+			msgsRead.Get("/conversations/{id}/messages/{messageId}", msgs.GetByID)
+
+			r.With(RequireScope(model.ScopeReportsWrite), perUser("reports", limits.Reports), idempotent).Post("/reports", mod.Report)
+
+			ws := NewWSHandler(hub, cfg.JWTSecret, registry.RateLimits, limits.WebSocket, cfg.CORS)
+			msgsRead.Get("/ws", ws.Upgrade)
+
+			// Bots, tokens and the account itself can only be managed from a user session.
+			r.Group(func(r chi.Router) {
+				r.Use(RequireSession())
+
+				accounts := NewAccountHandler(registry.Accounts)
+				r.Get("/users/me/export", accounts.Export)
+				r.Delete("/users/me", accounts.Delete)
+				r.Post("/users/me/deletion/cancel", accounts.CancelDeletion)
+				r.Post("/users/me/password", auth.ChangePassword)
+
+				tokens := NewTokenHandler(registry.Tokens)
+				r.Post("/bots", tokens.CreateBot)
+				r.Get("/bots", tokens.ListBots)
+				r.Post("/tokens", tokens.Create)
+				r.Get("/tokens", tokens.List)
+				r.Delete("/tokens/{id}", tokens.Revoke)
+			})
 		})
+
+		if cfg.AdminToken != "" {
+			r.Group(func(r chi.Router) {
+				r.Use(AdminMiddleware(cfg.AdminToken))
+
+				admin := NewAdminHandler(registry.Auth)
+				r.Delete("/admin/lockouts", admin.ClearLockout)
+			})
+		}
 	})
 
 	return r
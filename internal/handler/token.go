@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// TokenHandler handles bot account and personal access token endpoints.
+type TokenHandler struct {
+	tokens *service.TokenService
+}
+
+// NewTokenHandler creates a new TokenHandler.
+func NewTokenHandler(tokens *service.TokenService) *TokenHandler {
+	return &TokenHandler{tokens: tokens}
+}
+
+// CreateBot handles POST /bots.
+func (h *TokenHandler) CreateBot(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	var req dto.CreateBotRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	bot, err := h.tokens.CreateBot(r.Context(), userID, req.DisplayName)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toBotResponse(bot))
+}
+
+// ListBots handles GET /bots.
+func (h *TokenHandler) ListBots(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	bots, err := h.tokens.ListBots(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := dto.BotListResponse{Bots: make([]dto.BotResponse, 0, len(bots))}
+	for i := range bots {
+		resp.Bots = append(resp.Bots, toBotResponse(&bots[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Create handles POST /tokens.
+func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	var req dto.CreateTokenRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	params := service.CreateTokenParams{Name: req.Name, Scopes: req.Scopes}
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays <= 0 {
+			writeError(w, r, &model.ValidationError{Field: "expires_in_days", Message: "must be positive"})
+			return
+		}
+		params.ExpiresIn = time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+	}
+	if req.BotID != nil {
+		botID, err := uuid.Parse(*req.BotID)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorBody{
+				Error: ErrorDetail{Code: "bad_request", Message: "invalid bot ID"},
+			})
+			return
+		}
+		params.BotID = &botID
+	}
+
+	token, raw, err := h.tokens.CreateToken(r.Context(), userID, params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dto.CreateTokenResponse{
+		PersonalAccessTokenResponse: toTokenInfoResponse(token),
+		Token:                       raw,
+	})
+}
+
+// List handles GET /tokens.
+func (h *TokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+
+	tokens, err := h.tokens.ListTokens(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := dto.TokenListResponse{Tokens: make([]dto.PersonalAccessTokenResponse, 0, len(tokens))}
+	for i := range tokens {
+		resp.Tokens = append(resp.Tokens, toTokenInfoResponse(&tokens[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Revoke handles DELETE /tokens/{id}.
+func (h *TokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	tokenID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid token ID"},
+		})
+		return
+	}
+
+	if err := h.tokens.RevokeToken(r.Context(), userID, tokenID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
+
+func toBotResponse(u *model.User) dto.BotResponse {
+	resp := dto.BotResponse{
+		ID:          u.ID,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.AvatarURL,
+		CreatedAt:   u.CreatedAt,
+	}
+	if u.OwnerID != nil {
+		resp.OwnerID = *u.OwnerID
+	}
+	return resp
+}
+
+func toTokenInfoResponse(t *model.PersonalAccessToken) dto.PersonalAccessTokenResponse {
+	return dto.PersonalAccessTokenResponse{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
@@ -27,7 +27,7 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.users.GetProfile(r.Context(), userID)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -51,7 +51,7 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		AvatarURL:   req.AvatarURL,
 	})
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -70,7 +70,7 @@ func (h *UserHandler) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
 
 	profile, err := h.users.GetPublicProfile(r.Context(), id)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -79,6 +79,7 @@ func (h *UserHandler) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
 		DisplayName: profile.DisplayName,
 		AvatarURL:   profile.AvatarURL,
 		Status:      profile.Status,
+		Type:        profile.Type,
 	})
 }
 
@@ -95,7 +96,7 @@ func (h *UserHandler) Search(w http.ResponseWriter, r *http.Request) {
 
 	page, err := h.users.SearchUsers(r.Context(), q, cursor, limit)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/dto"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// WebhookHandler handles conversation webhook endpoints.
+type WebhookHandler struct {
+	webhooks *service.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler.
+func NewWebhookHandler(webhooks *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// Create handles POST /conversations/{id}/webhooks.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: "invalid request body"},
+		})
+		return
+	}
+
+	hook, err := h.webhooks.Create(r.Context(), userID, convoID, service.CreateWebhookParams{URL: req.URL, Events: req.Events})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dto.CreateWebhookResponse{
+		WebhookResponse: toWebhookResponse(hook),
+		Secret:          hook.Secret,
+	})
+}
+
+// List handles GET /conversations/{id}/webhooks.
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+
+	hooks, err := h.webhooks.List(r.Context(), userID, convoID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := dto.WebhookListResponse{Webhooks: make([]dto.WebhookResponse, 0, len(hooks))}
+	for i := range hooks {
+		resp.Webhooks = append(resp.Webhooks, toWebhookResponse(&hooks[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Delete handles DELETE /conversations/{id}/webhooks/{webhookId}.
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parsePathID(w, r, "webhookId", "invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.Delete(r.Context(), userID, convoID, webhookID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeNoContent(w)
+}
+
+// ListDeliveries handles GET /conversations/{id}/webhooks/{webhookId}/deliveries.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parsePathID(w, r, "webhookId", "invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	page, err := h.webhooks.ListDeliveries(r.Context(), userID, convoID, webhookID, cursor, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deliveries := make([]dto.WebhookDeliveryResponse, len(page.Items))
+	for i := range page.Items {
+		deliveries[i] = toWebhookDeliveryResponse(&page.Items[i])
+	}
+
+	writeJSON(w, http.StatusOK, dto.WebhookDeliveryListResponse{
+		Deliveries: deliveries,
+		Pagination: dto.PaginationResponse{
+			NextCursor: page.NextCursor,
+			HasMore:    page.HasMore,
+		},
+	})
+}
+
+// Redeliver handles POST /conversations/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	userID := UserIDFromContext(r.Context())
+	convoID, ok := parsePathID(w, r, "id", "invalid conversation ID")
+	if !ok {
+		return
+	}
+	webhookID, ok := parsePathID(w, r, "webhookId", "invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parsePathID(w, r, "deliveryId", "invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	d, err := h.webhooks.Redeliver(r.Context(), userID, convoID, webhookID, deliveryID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, toWebhookDeliveryResponse(d))
+}
+
+// parsePathID parses a UUID URL parameter, answering 400 with msg if it is malformed.
+func parsePathID(w http.ResponseWriter, r *http.Request, param, msg string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, param))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorBody{
+			Error: ErrorDetail{Code: "bad_request", Message: msg},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func toWebhookResponse(hook *model.Webhook) dto.WebhookResponse {
+	return dto.WebhookResponse{
+		ID:             hook.ID,
+		ConversationID: hook.ConversationID,
+		URL:            hook.URL,
+		Events:         hook.Events,
+		CreatedBy:      hook.CreatedBy,
+		CreatedAt:      hook.CreatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(d *model.WebhookDelivery) dto.WebhookDeliveryResponse {
+	resp := dto.WebhookDeliveryResponse{
+		ID:             d.ID,
+		EventType:      d.EventType,
+		Payload:        json.RawMessage(d.Payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == model.WebhookDeliveryPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
@@ -1,29 +1,45 @@
 package handler
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/service"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin:     func(r *http.Request) bool { return true }, // TODO: restrict in production
-}
-
 // WSHandler handles WebSocket upgrade requests.
 type WSHandler struct {
-	hub       *infra.Hub
-	jwtSecret string
+	hub        *infra.Hub
+	jwtSecret  string
+	limiter    RateLimiter
+	frameLimit service.RateLimit
+	upgrader   websocket.Upgrader
 }
 
-// NewWSHandler creates a new WSHandler.
-func NewWSHandler(hub *infra.Hub, jwtSecret string) *WSHandler {
-	return &WSHandler{hub: hub, jwtSecret: jwtSecret}
+// NewWSHandler creates a new WSHandler. Inbound frames are limited per user by
+// frameLimit; a nil limiter or zero frameLimit leaves them unlimited. Browsers
+// may only connect from the page's own origin or one cors allows, so that other
+// sites cannot open sockets with a visitor's credentials.
+func NewWSHandler(hub *infra.Hub, jwtSecret string, limiter RateLimiter, frameLimit service.RateLimit, cors CORSConfig) *WSHandler {
+	return &WSHandler{
+		hub:        hub,
+		jwtSecret:  jwtSecret,
+		limiter:    limiter,
+		frameLimit: frameLimit,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     func(r *http.Request) bool { return checkWSOrigin(r, cors) },
+		},
+	}
 }
 
 // Upgrade handles GET /ws — upgrades to a WebSocket connection.
@@ -85,20 +101,53 @@ func (h *WSHandler) Upgrade(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *WSHandler) upgradeConnection(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		// The upgrader has already written an error response.
+		requestLogger(r).Warn("ws upgrade failed", slog.Any("error", err))
 		return
 	}
 
-	client := &infra.Client{
-		Hub:    h.hub,
-		Conn:   conn,
-		UserID: userID,
-		Send:   make(chan []byte, 256),
+	client := h.hub.NewClient(conn, userID)
+	if h.limiter != nil && h.frameLimit.Enabled() {
+		client.Allow = h.allowFrame(r, userID)
 	}
 
-	h.hub.Register(client)
+	if err := h.hub.Register(client); err != nil {
+		// The hub has already told the client to reconnect elsewhere.
+		return
+	}
 
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// allowFrame spends a token from the user's WebSocket bucket for each inbound
+// frame. Like the HTTP middleware it lets frames through if the limiter fails.
+func (h *WSHandler) allowFrame(r *http.Request, userID uuid.UUID) func() (time.Duration, bool) {
+	// The connection outlives the request, so keep its values but not its cancellation.
+	ctx := context.WithoutCancel(r.Context())
+	key := "ws:user:" + userID.String()
+	logger := requestLogger(r)
+	return func() (time.Duration, bool) {
+		d, err := h.limiter.Allow(ctx, key, h.frameLimit)
+		if err != nil {
+			logger.Warn("rate limiter unavailable, allowing frame", slog.Any("error", err))
+			return 0, true
+		}
+		return d.RetryAfter, d.Allowed
+	}
+}
+
+// checkWSOrigin admits upgrades without an Origin header (non-browser clients),
+// from the same host as the request, or from an origin cors allows.
+func checkWSOrigin(r *http.Request, cors CORSConfig) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return cors.AllowsOrigin(origin)
+}
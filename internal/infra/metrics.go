@@ -0,0 +1,140 @@
+package infra
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "chat"
+
+// Metrics collects the Prometheus metrics the server exposes on /metrics.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	messagesSent  prometheus.Counter
+	registrations *prometheus.CounterVec
+	logins        *prometheus.CounterVec
+}
+
+// NewMetrics creates a metrics registry with the HTTP and business metrics,
+// along with the Go runtime and process collectors.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, chi route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, chi route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		messagesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "messages_sent_total",
+			Help:      "Messages accepted for delivery.",
+		}),
+		registrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "registrations_total",
+			Help:      "Accounts created, by method (password or oidc).",
+		}, []string{"method"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "logins_total",
+			Help:      "Successful logins, by method (password or oidc).",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.messagesSent, m.registrations, m.logins,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterHub exposes the hub's connection gauges and frame counters.
+func (m *Metrics) RegisterHub(h *Hub) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "ws", Name: "connected_users",
+			Help: "Users with at least one open WebSocket connection.",
+		}, func() float64 { return float64(h.Stats().Users) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Subsystem: "ws", Name: "connected_clients",
+			Help: "Open WebSocket connections.",
+		}, func() float64 { return float64(h.Stats().Clients) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "ws", Name: "frames_sent_total",
+			Help: "Frames queued for delivery to WebSocket clients.",
+		}, func() float64 { return float64(h.framesSent.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Subsystem: "ws", Name: "frames_dropped_total",
+			Help: "Frames dropped because a client's send buffer was full.",
+		}, func() float64 { return float64(h.framesDropped.Load()) }),
+	)
+}
+
+// ObserveRequest records one served HTTP request. route is the chi route
+// pattern, which keeps the label set bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// MessageSent counts a message accepted for delivery.
+func (m *Metrics) MessageSent() {
+	if m == nil {
+		return
+	}
+	m.messagesSent.Inc()
+}
+
+// UserRegistered counts a new account created through method.
+func (m *Metrics) UserRegistered(method string) {
+	if m == nil {
+		return
+	}
+	m.registrations.WithLabelValues(method).Inc()
+}
+
+// LoginSucceeded counts a successful login through method.
+func (m *Metrics) LoginSucceeded(method string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(method).Inc()
+}
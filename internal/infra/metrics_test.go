@@ -0,0 +1,65 @@
+package infra
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMetrics_Exposition(t *testing.T) {
+	db, err := NewDB(context.Background(), SQLite, ":memory:", PoolConfig{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	hub := NewHub(nil, HubConfig{})
+	c := &Client{Hub: hub, UserID: uuid.New(), Send: make(chan []byte, 1)}
+	hub.clients[c.UserID] = map[*Client]struct{}{c: {}}
+	hub.SendToUsers([]uuid.UUID{c.UserID}, Event{Type: "a"})
+	hub.SendToUsers([]uuid.UUID{c.UserID}, Event{Type: "b"}) // buffer full
+
+	m := NewMetrics()
+	m.RegisterDB(db, "sqlite")
+	m.RegisterHub(hub)
+	m.ObserveRequest(http.MethodGet, "/api/v1/users/{id}", http.StatusOK, 20*time.Millisecond)
+	m.MessageSent()
+	m.UserRegistered("password")
+	m.LoginSucceeded("oidc")
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`chat_http_requests_total{method="GET",route="/api/v1/users/{id}",status="200"} 1`,
+		`chat_http_request_duration_seconds_count{method="GET",route="/api/v1/users/{id}",status="200"} 1`,
+		`chat_messages_sent_total 1`,
+		`chat_registrations_total{method="password"} 1`,
+		`chat_logins_total{method="oidc"} 1`,
+		`chat_ws_connected_users 1`,
+		`chat_ws_connected_clients 1`,
+		`chat_ws_frames_sent_total 1`,
+		`chat_ws_frames_dropped_total 1`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected exposition to contain %q", want)
+		}
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.ObserveRequest(http.MethodGet, "/", http.StatusOK, time.Millisecond)
+	m.MessageSent()
+	m.UserRegistered("password")
+	m.LoginSucceeded("password")
+	m.RegisterHub(NewHub(nil, HubConfig{}))
+}
@@ -1,47 +1,236 @@
 package infra
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-// RunMigrations applies all pending up-migrations from the given directory.
-func RunMigrations(db *sql.DB, driver string, migrationsPath string) error {
+// migrationDialects lists the subdirectories a migrations tree holds, one per
+// supported SQL dialect.
+var migrationDialects = []string{"postgres", "sqlite"}
+
+// Migrator applies and inspects schema migrations for one database.
+type Migrator struct {
+	m          *migrate.Migrate
+	migrations fs.FS
+}
+
+// MigrationStatus describes the schema version of a database.
+type MigrationStatus struct {
+	Version   uint // 0 when no migration has been applied
+	Dirty     bool // a migration failed part-way and needs Force
+	Available []MigrationFile
+}
+
+// MigrationFile is one migration available to apply.
+type MigrationFile struct {
+	Version uint
+	Name    string
+}
+
+// NewMigrator prepares the migrations in the driver's dialect subdirectory of
+// migrations ("postgres" or "sqlite") for db. Closing the Migrator closes db.
+func NewMigrator(db *sql.DB, driver string, migrations fs.FS) (*Migrator, error) {
 	var (
 		dbDriver database.Driver
+		dialect  string
 		err      error
 	)
 
 	switch driver {
 	case "pgx":
+		dialect = "postgres"
 		dbDriver, err = postgres.WithInstance(db, &postgres.Config{})
 	case "sqlite":
+		dialect = "sqlite"
 		dbDriver, err = sqlite.WithInstance(db, &sqlite.Config{})
 	default:
-		return fmt.Errorf("unsupported migration driver: %s", driver)
+		return nil, fmt.Errorf("unsupported migration driver: %s", driver)
 	}
 	if err != nil {
-		return fmt.Errorf("create migrate driver: %w", err)
+		return nil, fmt.Errorf("create migrate driver: %w", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationsPath,
-		driver,
-		dbDriver,
-	)
+	sub, err := fs.Sub(migrations, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("open %s migrations: %w", dialect, err)
+	}
+	src, err := iofs.New(sub, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read %s migrations: %w", dialect, err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, driver, dbDriver)
+	if err != nil {
+		return nil, fmt.Errorf("create migrator: %w", err)
+	}
+	return &Migrator{m: m, migrations: sub}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down rolls back the last n applied migrations.
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("down: step count must be positive, got %d", n)
+	}
+	return ignoreNoChange(m.m.Steps(-n))
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force records version as applied and clears the dirty flag without running
+// any migration. Use it to recover after fixing a failed migration by hand.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Status reports the applied version and the migrations available to apply.
+func (m *Migrator) Status() (MigrationStatus, error) {
+	var status MigrationStatus
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, fmt.Errorf("read version: %w", err)
+	}
+	status.Version, status.Dirty = version, dirty
+
+	entries, err := fs.ReadDir(m.migrations, ".")
 	if err != nil {
-		return fmt.Errorf("create migrator: %w", err)
+		return status, fmt.Errorf("list migrations: %w", err)
+	}
+	for _, e := range entries {
+		mig, err := source.Parse(e.Name())
+		if err != nil || mig.Direction != source.Up {
+			continue
+		}
+		status.Available = append(status.Available, MigrationFile{Version: mig.Version, Name: mig.Identifier})
 	}
+	return status, nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+// Close releases the migrator along with the database it was created for.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// RunMigrations applies all pending up-migrations for the driver's dialect.
+// migrations holds one subdirectory per dialect: "postgres" and "sqlite".
+func RunMigrations(db *sql.DB, driver string, migrations fs.FS) error {
+	// The migrator is not closed: that would close db, which the caller owns.
+	m, err := NewMigrator(db, driver, migrations)
+	if err != nil {
+		return err
+	}
+	if err := m.Up(); err != nil {
 		return fmt.Errorf("run migrations: %w", err)
 	}
+	return nil
+}
 
+// LatestMigration returns the highest version among the driver's migrations.
+func LatestMigration(migrations fs.FS, driver string) (uint, error) {
+	dialect, ok := map[string]string{"pgx": "postgres", "sqlite": "sqlite"}[driver]
+	if !ok {
+		return 0, fmt.Errorf("unsupported migration driver: %s", driver)
+	}
+	entries, err := fs.ReadDir(migrations, dialect)
+	if err != nil {
+		return 0, fmt.Errorf("list %s migrations: %w", dialect, err)
+	}
+	var latest uint
+	for _, e := range entries {
+		if mig, err := source.Parse(e.Name()); err == nil && mig.Version > latest {
+			latest = mig.Version
+		}
+	}
+	return latest, nil
+}
+
+// CheckSchema returns an error unless db's schema has reached version want and
+// is not dirty. A newer schema passes, so that servers of the previous release
+// stay ready while a release job migrates ahead of them. It reads
+// golang-migrate's bookkeeping table directly, so unlike a Migrator it holds no
+// connection and is cheap enough for a readiness probe.
+func CheckSchema(ctx context.Context, db *sql.DB, want uint) error {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty", version)
+	}
+	if version < want {
+		return fmt.Errorf("schema at version %d, want %d", version, want)
+	}
 	return nil
 }
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+var migrationNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// CreateMigration writes empty up and down files named after the next version
+// into every dialect subdirectory of dir, and returns their paths.
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(name)))
+	if !migrationNameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: use letters, digits and underscores", name)
+	}
+
+	var next uint = 1
+	for _, dialect := range migrationDialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if err != nil {
+			return nil, fmt.Errorf("list %s migrations: %w", dialect, err)
+		}
+		for _, e := range entries {
+			if mig, err := source.Parse(e.Name()); err == nil && mig.Version >= next {
+				next = mig.Version + 1
+			}
+		}
+	}
+
+	var paths []string
+	for _, dialect := range migrationDialects {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, dialect, fmt.Sprintf("%06d_%s.%s.sql", next, name, direction))
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return paths, fmt.Errorf("create migration: %w", err)
+			}
+			f.Close()
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
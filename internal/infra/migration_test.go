@@ -0,0 +1,138 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kareempaes/planning/db/migrations"
+)
+
+func TestMigrator_UpDownGotoForce(t *testing.T) {
+	db, err := NewDB(context.Background(), SQLite, ":memory:", PoolConfig{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	m, err := NewMigrator(db, "sqlite", migrations.FS)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	defer m.Close()
+
+	status, err := m.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.Version != 0 || len(status.Available) == 0 {
+		t.Fatalf("expected an empty database with migrations available, got %+v", status)
+	}
+	latest := status.Available[len(status.Available)-1].Version
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("expected a second up to be a no-op, got %v", err)
+	}
+	assertVersion(t, m, latest)
+
+	if err := m.Down(2); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	assertVersion(t, m, latest-2)
+
+	if err := m.Goto(1); err != nil {
+		t.Fatalf("goto: %v", err)
+	}
+	assertVersion(t, m, 1)
+
+	if err := m.Force(3); err != nil {
+		t.Fatalf("force: %v", err)
+	}
+	assertVersion(t, m, 3)
+
+	if err := m.Down(0); err == nil {
+		t.Error("expected an error for a zero step count")
+	}
+}
+
+func assertVersion(t *testing.T, m *Migrator, want uint) {
+	t.Helper()
+	status, err := m.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.Version != want || status.Dirty {
+		t.Errorf("expected clean version %d, got %d (dirty=%v)", want, status.Version, status.Dirty)
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, dialect := range migrationDialects {
+		if err := os.Mkdir(filepath.Join(dir, dialect), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The dialects may have drifted; the next version follows the highest.
+	if err := os.WriteFile(filepath.Join(dir, "sqlite", "000004_old.up.sql"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := CreateMigration(dir, "Add reactions")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "postgres", "000005_add_reactions.up.sql"),
+		filepath.Join(dir, "postgres", "000005_add_reactions.down.sql"),
+		filepath.Join(dir, "sqlite", "000005_add_reactions.up.sql"),
+		filepath.Join(dir, "sqlite", "000005_add_reactions.down.sql"),
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("expected %d files, got %v", len(want), paths)
+	}
+	for i, p := range want {
+		if paths[i] != p {
+			t.Errorf("expected %s, got %s", p, paths[i])
+		}
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s to exist: %v", p, err)
+		}
+	}
+
+	if _, err := CreateMigration(dir, "drop;table"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(ctx, SQLite, ":memory:", PoolConfig{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	latest, err := LatestMigration(migrations.FS, "sqlite")
+	if err != nil {
+		t.Fatalf("latest migration: %v", err)
+	}
+	if err := CheckSchema(ctx, db, latest); err == nil {
+		t.Error("expected an unmigrated database to fail")
+	}
+
+	if err := RunMigrations(db, "sqlite", migrations.FS); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	if err := CheckSchema(ctx, db, latest); err != nil {
+		t.Errorf("expected a migrated database to pass, got %v", err)
+	}
+	if err := CheckSchema(ctx, db, latest+1); err == nil {
+		t.Error("expected a schema behind the expected version to fail")
+	}
+	if err := CheckSchema(ctx, db, latest-1); err != nil {
+		t.Errorf("expected a schema ahead of the expected version to pass, got %v", err)
+	}
+}
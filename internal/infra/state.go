@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -18,22 +19,48 @@ const (
 	SQLite
 )
 
-// NewDB creates a database connection based on the driver type.
-func NewDB(ctx context.Context, driver DriverType, dsn string) (*sql.DB, error) {
+// PoolConfig sizes a PostgreSQL connection pool. Zero fields take defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (p PoolConfig) withDefaults() PoolConfig {
+	if p.MaxOpenConns == 0 {
+		p.MaxOpenConns = 25
+	}
+	if p.MaxIdleConns == 0 {
+		p.MaxIdleConns = 10
+	}
+	if p.ConnMaxLifetime == 0 {
+		p.ConnMaxLifetime = 5 * time.Minute
+	}
+	if p.ConnMaxIdleTime == 0 {
+		p.ConnMaxIdleTime = 1 * time.Minute
+	}
+	return p
+}
+
+// NewDB creates a database connection based on the driver type. pool applies
+// to PostgreSQL only: SQLite always uses a single connection.
+func NewDB(ctx context.Context, driver DriverType, dsn string, pool PoolConfig) (*sql.DB, error) {
 	switch driver {
 	case Postgres:
 		db, err := OpenDB(ctx, DBConfig{Driver: "pgx", DSN: dsn})
 		if err != nil {
 			return nil, err
 		}
-		db.SetMaxOpenConns(25)
-		db.SetMaxIdleConns(10)
-		db.SetConnMaxLifetime(5 * time.Minute)
-		db.SetConnMaxIdleTime(1 * time.Minute)
+		pool = pool.withDefaults()
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 		return db, nil
 
 	case SQLite:
-		db, err := OpenDB(ctx, DBConfig{Driver: "sqlite", DSN: dsn})
+		db, err := OpenDB(ctx, DBConfig{Driver: "sqlite", DSN: sqliteDSN(dsn)})
 		if err != nil {
 			return nil, err
 		}
@@ -44,3 +71,24 @@ func NewDB(ctx context.Context, driver DriverType, dsn string) (*sql.DB, error)
 		return nil, fmt.Errorf("unknown driver type: %d", driver)
 	}
 }
+
+// sqliteDSN adds the connection parameters the repositories rely on, unless the
+// DSN already sets them: foreign key enforcement, which SQLite leaves off by
+// default, and storing times in the layout SQLite's date functions understand.
+func sqliteDSN(dsn string) string {
+	params := []struct{ key, param string }{
+		{"_pragma=foreign_keys", "_pragma=foreign_keys(1)"},
+		{"_time_format=", "_time_format=sqlite"},
+	}
+	for _, p := range params {
+		if strings.Contains(dsn, p.key) {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + p.param
+	}
+	return dsn
+}
@@ -1,21 +1,56 @@
 package infra
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
-const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 4096
-)
+// HubConfig tunes WebSocket connections. Zero fields take defaults.
+type HubConfig struct {
+	SendBuffer     int           // outbound frames queued per client before new ones are dropped
+	MaxMessageSize int64         // largest inbound frame in bytes; larger ones close the connection
+	PongWait       time.Duration // how long a client may stay silent before it is dropped
+	WriteWait      time.Duration // deadline for writing one frame
+}
+
+func (c HubConfig) withDefaults() HubConfig {
+	if c.SendBuffer == 0 {
+		c.SendBuffer = 256
+	}
+	if c.MaxMessageSize == 0 {
+		c.MaxMessageSize = 4096
+	}
+	if c.PongWait == 0 {
+		c.PongWait = 60 * time.Second
+	}
+	if c.WriteWait == 0 {
+		c.WriteWait = 10 * time.Second
+	}
+	return c
+}
+
+// pingPeriod is how often clients are pinged: often enough that a pong
+// arrives before PongWait runs out.
+func (c HubConfig) pingPeriod() time.Duration {
+	return c.PongWait * 9 / 10
+}
+
+// restartReason accompanies the close frame clients receive when the server
+// shuts down, telling them to reconnect rather than treat it as an error.
+const restartReason = "server restarting, reconnect"
+
+// ErrHubClosed is returned by Register once the hub has been shut down.
+var ErrHubClosed = errors.New("ws: hub is shut down")
 
 // Event is a WebSocket frame envelope.
 type Event struct {
@@ -28,7 +63,27 @@ type Hub struct {
 	clients    map[uuid.UUID]map[*Client]struct{}
 	register   chan *Client
 	unregister chan *Client
+	ping       chan chan struct{}
 	mu         sync.RWMutex
+	logger     *slog.Logger
+	config     HubConfig
+	presence   func(userID uuid.UUID, online bool)
+
+	quit     chan struct{} // closed by Shutdown to stop the run loop
+	quitOnce sync.Once
+	done     chan struct{}  // closed when the run loop has exited
+	pumps    sync.WaitGroup // write pumps of registered clients
+
+	framesSent    atomic.Uint64
+	framesDropped atomic.Uint64
+}
+
+// HubStats is a point-in-time view of the hub's connections and traffic.
+type HubStats struct {
+	Users         int    // users with at least one connection
+	Clients       int    // open connections
+	FramesSent    uint64 // frames queued for delivery by SendToUsers
+	FramesDropped uint64 // frames SendToUsers dropped because a client's buffer was full
 }
 
 // Client is a single WebSocket connection bound to a user.
@@ -37,38 +92,95 @@ type Client struct {
 	Conn   *websocket.Conn
 	UserID uuid.UUID
 	Send   chan []byte
+
+	// done is closed by the hub when it lets go of the client, which tells
+	// the write pump to flush Send and close the connection. Send itself is
+	// never closed, since ReadPump may still be queueing replies on it.
+	done chan struct{}
+
+	// Allow, when set, is consulted for every inbound frame. Frames it rejects
+	// are dropped and answered with a rate_limited error event.
+	Allow func() (retryAfter time.Duration, ok bool)
 }
 
-// NewHub creates and returns a new Hub.
-func NewHub() *Hub {
+// NewHub creates and returns a new Hub that reports connection lifecycle
+// events to logger, or to slog.Default when logger is nil.
+func NewHub(logger *slog.Logger, config HubConfig) *Hub {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &Hub{
+		config:     config.withDefaults(),
 		clients:    make(map[uuid.UUID]map[*Client]struct{}),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		ping:       make(chan chan struct{}),
+		logger:     logger,
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
 	}
 }
 
-// Run starts the hub event loop. Must be called in a goroutine.
+// OnPresence sets fn to be told, on a goroutine of its own, when a user's
+// first connection opens and when their last one closes, including when the
+// hub shuts down. It must be called before Run.
+func (h *Hub) OnPresence(fn func(userID uuid.UUID, online bool)) {
+	h.presence = fn
+}
+
+// notifyPresence reports a user coming online or going offline to the
+// OnPresence function, if there is one.
+func (h *Hub) notifyPresence(userID uuid.UUID, online bool) {
+	if h.presence != nil {
+		go h.presence(userID, online)
+	}
+}
+
+// NewClient creates a Client for conn with a send buffer sized by the hub's config.
+func (h *Hub) NewClient(conn *websocket.Conn, userID uuid.UUID) *Client {
+	return &Client{Hub: h, Conn: conn, UserID: userID, Send: make(chan []byte, h.config.SendBuffer)}
+}
+
+// Run starts the hub event loop. Must be called in a goroutine. It returns once
+// Shutdown has closed every client.
 func (h *Hub) Run() {
+	defer close(h.done)
 	for {
 		select {
+		case <-h.quit:
+			h.closeAll()
+			return
+
+		case reply := <-h.ping:
+			close(reply)
+
 		case client := <-h.register:
+			h.pumps.Add(1)
 			h.mu.Lock()
 			if h.clients[client.UserID] == nil {
 				h.clients[client.UserID] = make(map[*Client]struct{})
 			}
 			h.clients[client.UserID][client] = struct{}{}
+			conns := len(h.clients[client.UserID])
 			h.mu.Unlock()
+			h.logger.Info("ws client connected",
+				slog.String("user_id", client.UserID.String()), slog.Int("user_connections", conns))
+			if conns == 1 {
+				h.notifyPresence(client.UserID, true)
+			}
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if conns, ok := h.clients[client.UserID]; ok {
 				if _, exists := conns[client]; exists {
 					delete(conns, client)
-					close(client.Send)
+					close(client.done)
 					if len(conns) == 0 {
 						delete(h.clients, client.UserID)
+						h.notifyPresence(client.UserID, false)
 					}
+					h.logger.Info("ws client disconnected",
+						slog.String("user_id", client.UserID.String()), slog.Int("user_connections", len(conns)))
 				}
 			}
 			h.mu.Unlock()
@@ -76,21 +188,93 @@ func (h *Hub) Run() {
 	}
 }
 
-// Register adds a client to the hub.
-func (h *Hub) Register(c *Client) {
-	h.register <- c
+// closeAll lets go of every client, which makes its write pump send the
+// restart close frame and exit.
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for userID, conns := range h.clients {
+		for client := range conns {
+			close(client.done)
+			n++
+		}
+		h.notifyPresence(userID, false)
+	}
+	clear(h.clients)
+	h.logger.Info("ws hub closing connections", slog.Int("clients", n))
+}
+
+// Shutdown stops the hub: every client receives a close frame with code 1012
+// (service restart) asking it to reconnect, and Shutdown waits for the write
+// pumps to flush those frames and for Run to return, or for ctx to be done.
+// Clients that connect afterwards are turned away with the same close frame.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.quitOnce.Do(func() { close(h.quit) })
+
+	flushed := make(chan struct{})
+	go func() {
+		<-h.done
+		h.pumps.Wait()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("ws: hub shutdown: %w", ctx.Err())
+	}
+}
+
+// Ping reports whether the event loop started by Run is responsive, waiting at
+// most until ctx is done.
+func (h *Hub) Ping(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case h.ping <- reply:
+	case <-ctx.Done():
+		return fmt.Errorf("ws: hub loop not running: %w", ctx.Err())
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("ws: hub loop not responding: %w", ctx.Err())
+	}
+}
+
+// Register adds a client to the hub. The caller must start the client's pumps
+// only if it succeeds; once the hub is shut down, Register sends the client the
+// restart close frame, closes its connection and returns ErrHubClosed.
+func (h *Hub) Register(c *Client) error {
+	c.done = make(chan struct{})
+	select {
+	case h.register <- c:
+		return nil
+	case <-h.quit:
+		c.Conn.WriteControl(websocket.CloseMessage, restartCloseMessage(), time.Now().Add(h.config.WriteWait))
+		c.Conn.Close()
+		return ErrHubClosed
+	}
 }
 
-// Unregister removes a client from the hub.
+// Unregister removes a client from the hub. It is a no-op after shutdown.
 func (h *Hub) Unregister(c *Client) {
-	h.unregister <- c
+	select {
+	case h.unregister <- c:
+	case <-h.done:
+	}
+}
+
+func restartCloseMessage() []byte {
+	return websocket.FormatCloseMessage(websocket.CloseServiceRestart, restartReason)
 }
 
 // SendToUsers marshals an event and sends it to all connected clients for the given user IDs.
 func (h *Hub) SendToUsers(userIDs []uuid.UUID, event Event) {
 	data, err := json.Marshal(event)
 	if err != nil {
-		log.Printf("ws: marshal event: %v", err)
+		h.logger.Error("ws marshal event", slog.String("type", event.Type), slog.Any("error", err))
 		return
 	}
 
@@ -102,75 +286,165 @@ func (h *Hub) SendToUsers(userIDs []uuid.UUID, event Event) {
 			for client := range conns {
 				select {
 				case client.Send <- data:
+					h.framesSent.Add(1)
 				default:
+					h.framesDropped.Add(1)
 					// Client send buffer full, skip.
+					h.logger.Warn("ws send buffer full, dropping event",
+						slog.String("user_id", uid.String()), slog.String("type", event.Type))
 				}
 			}
 		}
 	}
 }
 
-// ReadPump reads messages from the WebSocket connection.
+// Stats reports the hub's current connections and frame counters.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stats := HubStats{
+		Users:         len(h.clients),
+		FramesSent:    h.framesSent.Load(),
+		FramesDropped: h.framesDropped.Load(),
+	}
+	for _, conns := range h.clients {
+		stats.Clients += len(conns)
+	}
+	return stats
+}
+
+// Online reports whether the user has at least one open connection.
+func (h *Hub) Online(userID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
+
+// ReadPump reads messages from the WebSocket connection. It leaves closing
+// the connection to the write pump, which the hub stops once ReadPump
+// unregisters the client.
 func (c *Client) ReadPump() {
-	defer func() {
-		c.Hub.Unregister(c)
-		c.Conn.Close()
-	}()
+	defer c.Hub.Unregister(c)
 
-	c.Conn.SetReadLimit(maxMessageSize)
-	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	cfg := c.Hub.config
+	c.Conn.SetReadLimit(cfg.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
 	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
 		return nil
 	})
 
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				c.Hub.logger.Warn("ws read failed", slog.String("user_id", c.UserID.String()), slog.Any("error", err))
+			}
 			break
 		}
 
+		if c.Allow != nil {
+			if retryAfter, ok := c.Allow(); !ok {
+				c.Hub.logger.Debug("ws dropping rate limited frame", slog.String("user_id", c.UserID.String()))
+				c.rateLimited(retryAfter)
+				continue
+			}
+		}
+
 		var event Event
 		if err := json.Unmarshal(message, &event); err != nil {
+			c.Hub.logger.Debug("ws ignoring malformed frame", slog.String("user_id", c.UserID.String()), slog.Any("error", err))
 			continue
 		}
 
 		switch event.Type {
 		case "ping":
 			resp, _ := json.Marshal(Event{Type: "pong"})
-			select {
-			case c.Send <- resp:
-			default:
-			}
+			c.reply(resp)
 		}
 	}
 }
 
+// rateLimited tells the client a frame was dropped and when to send the next.
+func (c *Client) rateLimited(retryAfter time.Duration) {
+	data, _ := json.Marshal(map[string]any{
+		"code":        "rate_limited",
+		"retry_after": int(math.Ceil(retryAfter.Seconds())),
+	})
+	resp, _ := json.Marshal(Event{Type: "error", Data: data})
+	c.reply(resp)
+}
+
+// reply queues a frame for the client unless the hub has let go of it or
+// its buffer is full.
+func (c *Client) reply(frame []byte) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	select {
+	case c.Send <- frame:
+	default:
+	}
+}
+
 // WritePump writes messages from the send channel to the WebSocket connection.
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	cfg := c.Hub.config
+	ticker := time.NewTicker(cfg.pingPeriod())
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
+		c.Hub.pumps.Done()
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+		case message := <-c.Send:
+			if !c.write(message) {
 				return
 			}
-			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				return
+
+		case <-c.done:
+			// The hub let go of the client: flush what it had queued, then
+			// tell the client why.
+			for flushed := false; !flushed; {
+				select {
+				case message := <-c.Send:
+					if !c.write(message) {
+						return
+					}
+				default:
+					flushed = true
+				}
 			}
+			closeMsg := []byte{}
+			select {
+			case <-c.Hub.quit:
+				closeMsg = restartCloseMessage()
+			default:
+			}
+			c.Conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			c.Conn.WriteMessage(websocket.CloseMessage, closeMsg)
+			return
 
 		case <-ticker.C:
-			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.Conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Hub.logger.Debug("ws ping failed", slog.String("user_id", c.UserID.String()), slog.Any("error", err))
 				return
 			}
 		}
 	}
 }
+
+// write sends one text frame, reporting whether the connection is still usable.
+func (c *Client) write(message []byte) bool {
+	c.Conn.SetWriteDeadline(time.Now().Add(c.Hub.config.WriteWait))
+	if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		c.Hub.logger.Warn("ws write failed", slog.String("user_id", c.UserID.String()), slog.Any("error", err))
+		return false
+	}
+	return true
+}
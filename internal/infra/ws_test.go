@@ -0,0 +1,233 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func TestHub_Ping(t *testing.T) {
+	hub := NewHub(nil, HubConfig{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := hub.Ping(ctx); err == nil {
+		t.Error("expected ping to fail before Run")
+	}
+
+	go hub.Run()
+	if err := hub.Ping(context.Background()); err != nil {
+		t.Errorf("expected ping to succeed once running, got %v", err)
+	}
+}
+
+// dialTestClient serves one WebSocket endpoint that registers its connections
+// with hub, and returns the client side of a new connection to it. setup, if
+// not nil, adjusts the server-side Client before it is registered.
+func dialTestClient(t *testing.T, hub *Hub, setup func(*Client)) (*websocket.Conn, <-chan error) {
+	t.Helper()
+	registered := make(chan error, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			registered <- err
+			return
+		}
+		c := &Client{Hub: hub, Conn: conn, UserID: uuid.New(), Send: make(chan []byte, 1)}
+		if setup != nil {
+			setup(c)
+		}
+		if err := hub.Register(c); err != nil {
+			registered <- err
+			return
+		}
+		registered <- nil
+		go c.WritePump()
+		go c.ReadPump()
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, registered
+}
+
+func TestHub_ShutdownSendsRestartCloseFrame(t *testing.T) {
+	hub := NewHub(nil, HubConfig{})
+	presence := make(chan bool, 2)
+	hub.OnPresence(func(_ uuid.UUID, online bool) { presence <- online })
+	runDone := make(chan struct{})
+	go func() {
+		hub.Run()
+		close(runDone)
+	}()
+
+	var userID uuid.UUID
+	conn, registered := dialTestClient(t, hub, func(c *Client) { userID = c.UserID })
+	if err := <-registered; err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// The run loop handles the ping after the registration before it.
+	if err := hub.Ping(ctx); err != nil || !hub.Online(userID) {
+		t.Fatalf("expected the user to be online, got %v", err)
+	}
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if hub.Online(userID) {
+		t.Error("expected no one to be online after shutdown")
+	}
+	// The user came online and went offline again, reported in either order.
+	seen := map[bool]bool{}
+	for range 2 {
+		select {
+		case online := <-presence:
+			seen[online] = true
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for presence changes")
+		}
+	}
+	if !seen[true] || !seen[false] {
+		t.Errorf("expected online and offline reports, got %v", seen)
+	}
+	select {
+	case <-runDone:
+	default:
+		t.Error("expected Run to have returned")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Fatalf("expected close code 1012, got %v", err)
+	}
+
+	// Late arrivals are turned away the same way.
+	late, registered := dialTestClient(t, hub, nil)
+	if err := <-registered; !errors.Is(err, ErrHubClosed) {
+		t.Fatalf("expected ErrHubClosed, got %v", err)
+	}
+	late.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := late.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Errorf("expected close code 1012 for a late client, got %v", err)
+	}
+}
+
+func TestClient_ReadPumpDropsRateLimitedFrames(t *testing.T) {
+	hub := NewHub(nil, HubConfig{})
+	go hub.Run()
+	t.Cleanup(func() { hub.Shutdown(context.Background()) })
+
+	allowed := 1
+	conn, registered := dialTestClient(t, hub, func(c *Client) {
+		c.Allow = func() (time.Duration, bool) {
+			if allowed == 0 {
+				return 1500 * time.Millisecond, false
+			}
+			allowed--
+			return 0, true
+		}
+	})
+	if err := <-registered; err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	read := func() string {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return string(msg)
+	}
+
+	ping := []byte(`{"type":"ping"}`)
+	if err := conn.WriteMessage(websocket.TextMessage, ping); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := read(); got != `{"type":"pong"}` {
+		t.Fatalf("expected pong for an allowed frame, got %s", got)
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, ping); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, want := read(), `{"type":"error","data":{"code":"rate_limited","retry_after":2}}`; got != want {
+		t.Errorf("expected %s for a limited frame, got %s", want, got)
+	}
+}
+
+func TestHub_ShutdownWhileClientSendsFrames(t *testing.T) {
+	hub := NewHub(nil, HubConfig{})
+	go hub.Run()
+
+	limited := 0
+	conn, registered := dialTestClient(t, hub, func(c *Client) {
+		// Every other frame is rate limited, so both of ReadPump's replies
+		// race with the shutdown.
+		c.Allow = func() (time.Duration, bool) {
+			limited++
+			return time.Second, limited%2 == 0
+		}
+	})
+	if err := <-registered; err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	stop := make(chan struct{})
+	writing := make(chan struct{})
+	go func() {
+		defer close(writing)
+		ping := []byte(`{"type":"ping"}`)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if conn.WriteMessage(websocket.TextMessage, ping) != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-writing
+	}()
+
+	// Let some frames through before shutting down mid-stream.
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+			t.Fatalf("expected close code 1012, got %v", err)
+		}
+		break
+	}
+}
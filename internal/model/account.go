@@ -0,0 +1,17 @@
+package model
+
+// DeletedUserDisplayName replaces the display name of anonymized accounts.
+const DeletedUserDisplayName = "Deleted user"
+
+// UserStatusDeleted marks an anonymized account.
+const UserStatusDeleted = "deleted"
+
+// AccountExport is the personal data held about a user, as returned by GET /users/me/export.
+type AccountExport struct {
+	Profile       User           `json:"profile"`
+	Sessions      []Session      `json:"sessions"`
+	Conversations []Conversation `json:"conversations"`
+	Messages      []Message      `json:"messages"`
+	Blocks        []BlockedUser  `json:"blocks"`
+	Reports       []Report       `json:"reports"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How often a user offline for long enough is sent an email digest.
+const (
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+
+	DefaultDigestFrequency = DigestDaily
+)
+
+// DigestInterval returns the time between two digests sent at frequency, and
+// false if frequency is not one of the known values.
+func DigestInterval(frequency string) (time.Duration, bool) {
+	switch frequency {
+	case DigestHourly:
+		return time.Hour, true
+	case DigestDaily:
+		return 24 * time.Hour, true
+	case DigestWeekly:
+		return 7 * 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
+
+// DigestSettings are a user's email digest preferences. A user who never
+// changed them has the defaults: enabled, daily.
+type DigestSettings struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Enabled      bool       `json:"enabled"`
+	Frequency    string     `json:"frequency"`
+	LastSentAt   *time.Time `json:"last_sent_at"`
+	NextDigestAt *time.Time `json:"next_digest_at"` // nil when a digest may go out any time
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// DigestRecipient is a user who is due an email digest.
+type DigestRecipient struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Frequency   string    `json:"frequency"`
+}
+
+// DigestMessage is an unread message waiting to be summarized in a digest,
+// along with the names needed to present it.
+type DigestMessage struct {
+	DeliveryID       uuid.UUID `json:"delivery_id"`
+	MessageID        uuid.UUID `json:"message_id"`
+	ConversationID   uuid.UUID `json:"conversation_id"`
+	ConversationName *string   `json:"conversation_name"` // nil for direct conversations
+	SenderName       string    `json:"sender_name"`
+	Body             string    `json:"body"`
+	CreatedAt        time.Time `json:"created_at"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrNotFound indicates the requested resource does not exist.
@@ -11,6 +14,15 @@ var (
 
 	// ErrConflict indicates a uniqueness constraint violation.
 	ErrConflict = errors.New("conflict")
+
+	// ErrUnauthenticated indicates the caller's identity could not be established.
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrForbidden indicates the caller is authenticated but not allowed to perform the action.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrTooManyAttempts indicates the caller is temporarily locked out.
+	ErrTooManyAttempts = errors.New("too many attempts")
 )
 
 // ValidationError carries a field-level validation message.
@@ -26,3 +38,16 @@ func (e *ValidationError) Error() string {
 func (e *ValidationError) Unwrap() error {
 	return ErrValidation
 }
+
+// TooManyAttemptsError carries how long the caller must wait before retrying.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return "too many attempts: retry after " + e.RetryAfter.String()
+}
+
+func (e *TooManyAttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
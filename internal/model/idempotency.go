@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyRecord remembers the response to a request made with an
+// Idempotency-Key, so that a retry can be answered without repeating it.
+// Records are private to the user who made the request and are scoped to a
+// method and path.
+type IdempotencyRecord struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Scope       string    `json:"scope"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"` // fingerprint of the request body
+	StatusCode  int       `json:"status_code"`  // zero while the request is in progress
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Completed reports whether the response has been recorded.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IncomingWebhook lets an external system post messages to a conversation as
+// a bot, authenticating with a token instead of a user session.
+type IncomingWebhook struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	BotID          uuid.UUID `json:"bot_id"` // the bot the messages are posted as
+	CreatedBy      uuid.UUID `json:"created_by"`
+	Name           string    `json:"name"`
+	TokenHash      string    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// BotCommand is a slash command of one conversation, answered by an HTTP
+// callback. Its replies are posted as the command's bot.
+type BotCommand struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	BotID          uuid.UUID `json:"bot_id"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	Name           string    `json:"name"` // without the leading slash
+	Description    string    `json:"description"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"-"` // signs callback requests
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Reminder is a message the built-in bot posts to a conversation later, on
+// behalf of the user who asked for it.
+type Reminder struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Body           string    `json:"body"`
+	RemindAt       time.Time `json:"remind_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Poll is a question put to a conversation with /poll. Participants vote for
+// one option each and may change their vote.
+type Poll struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	Question       string    `json:"question"`
+	Options        []string  `json:"options"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginAttempt tracks recent failed logins for a throttle key (an account or a client IP).
+type LoginAttempt struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
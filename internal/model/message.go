@@ -15,6 +15,9 @@ type Message struct {
 	Status         string    `json:"status"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	// ClientMessageID is the sender's own ID for the message, if it gave one.
+	// It is unique per sender and conversation, which makes sends retryable.
+	ClientMessageID *string `json:"client_message_id,omitempty"`
 }
 
 // MessageDelivery tracks per-user delivery status of a message.
@@ -25,4 +28,5 @@ type MessageDelivery struct {
 	Status      string     `json:"status"`
 	DeliveredAt *time.Time `json:"delivered_at"`
 	ReadAt      *time.Time `json:"read_at"`
+	DigestedAt  *time.Time `json:"digested_at"` // when it was summarized in an email digest
 }
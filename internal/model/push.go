@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Push notification platforms a device can register for.
+const (
+	PlatformAPNs = "apns" // Apple Push Notification service
+	PlatformFCM  = "fcm"  // Firebase Cloud Messaging
+)
+
+// DeviceToken is an app install that receives push notifications for a user.
+type DeviceToken struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"` // last registered
+}
+
+// ConversationMute silences push notifications from a conversation for one
+// user. Messages still arrive; only notifications are withheld.
+type ConversationMute struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	MutedUntil     *time.Time `json:"muted_until"` // nil mutes until unmuted
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ActiveAt reports whether the mute still applies at t.
+func (m *ConversationMute) ActiveAt(t time.Time) bool {
+	return m.MutedUntil == nil || m.MutedUntil.After(t)
+}
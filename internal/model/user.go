@@ -6,16 +6,26 @@ import (
 	"github.com/google/uuid"
 )
 
+// User types.
+const (
+	UserTypeHuman = "human"
+	UserTypeBot   = "bot"
+)
+
 // User is the full domain representation of a user row.
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	DisplayName  string    `json:"display_name"`
-	AvatarURL    *string   `json:"avatar_url"`
-	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID  `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"`
+	DisplayName  string     `json:"display_name"`
+	AvatarURL    *string    `json:"avatar_url"`
+	Status       string     `json:"status"`
+	Type         string     `json:"type"`
+	OwnerID      *uuid.UUID `json:"owner_id"` // set for bots: the human who manages them
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"` // when a requested deletion takes effect
 }
 
 // PublicProfile is the subset of user data visible to other users.
@@ -24,6 +34,7 @@ type PublicProfile struct {
 	DisplayName string    `json:"display_name"`
 	AvatarURL   *string   `json:"avatar_url"`
 	Status      string    `json:"status"`
+	Type        string    `json:"type"`
 }
 
 // UpdateProfileParams holds the mutable fields for PATCH /users/me.
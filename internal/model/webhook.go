@@ -0,0 +1,60 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types.
+const (
+	WebhookEventMessageCreated      = "message.created"
+	WebhookEventParticipantAdded    = "participant.added"
+	WebhookEventConversationRenamed = "conversation.renamed"
+)
+
+// AllWebhookEvents lists every event a webhook may subscribe to.
+var AllWebhookEvents = []string{
+	WebhookEventMessageCreated,
+	WebhookEventParticipantAdded,
+	WebhookEventConversationRenamed,
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead" // gave up after the last retry
+)
+
+// Webhook is an endpoint that receives a conversation's events as signed HTTP POSTs.
+type Webhook struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"-"` // kept in the clear: it signs every delivery
+	Events         []string  `json:"events"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook receives events of the given type.
+func (w *Webhook) Subscribes(eventType string) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// WebhookDelivery is one event queued for, or sent to, one webhook.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	WebhookID      uuid.UUID  `json:"webhook_id"`
+	EventType      string     `json:"event_type"`
+	Payload        []byte     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus *int       `json:"response_status"` // of the last attempt, if the endpoint answered
+	LastError      *string    `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
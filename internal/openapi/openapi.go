@@ -0,0 +1,280 @@
+// Package openapi builds OpenAPI 3.1 documents from Go types and validates
+// JSON values against them. Schemas are derived by reflection from the
+// encoding/json form of a type, so a document stays in step with the structs
+// the API actually encodes and decodes.
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version is the OpenAPI version documents declare.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document. Only the parts this package generates are modelled.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	schemaNames map[reflect.Type]string
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the schemas and security schemes operations refer to.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is an HTTP authentication scheme.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on one path, keyed by lower-case method.
+type PathItem map[string]*Operation
+
+// Operation is a single API operation.
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request body.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes one response of an operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body in one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema (2020-12) as used by OpenAPI 3.1, restricted to the
+// keywords this package generates and validates.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+
+	// closed forbids properties beyond Properties; it is written as
+	// "additionalProperties": false.
+	closed bool
+}
+
+// MarshalJSON writes closed object schemas with "additionalProperties": false.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	if !s.closed {
+		return json.Marshal((*plain)(s))
+	}
+	return json.Marshal(struct {
+		*plain
+		AdditionalProperties bool `json:"additionalProperties"`
+	}{plain: (*plain)(s)})
+}
+
+// Types is the value of a schema's "type" keyword. A single type is written
+// as a string and several, such as a nullable string, as an array.
+type Types []string
+
+// MarshalJSON implements json.Marshaler.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// New creates an empty document.
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI:     Version,
+		Info:        info,
+		Paths:       make(map[string]*PathItem),
+		Components:  Components{Schemas: make(map[string]*Schema)},
+		schemaNames: make(map[reflect.Type]string),
+	}
+}
+
+// Add registers op under method and path, where path uses {name} templates.
+func (d *Document) Add(method, path string, op *Operation) {
+	item := d.Paths[path]
+	if item == nil {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// Operation returns the operation registered for method and path, or nil.
+func (d *Document) Operation(method, path string) *Operation {
+	item := d.Paths[path]
+	if item == nil {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// JSONContent describes a JSON body with schema s.
+func JSONContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+// SchemaOf returns the schema of the JSON encoding of v, which is usually a
+// zero value such as dto.UserResponse{}. Named struct types become components
+// referred to by name. Fields are required unless tagged omitempty, and
+// pointer fields are nullable unless omitempty leaves them out instead.
+func (d *Document) SchemaOf(v any) *Schema {
+	return d.schemaFor(reflect.TypeOf(v), false)
+}
+
+// Request is like SchemaOf for request bodies, in which pointer fields are
+// optional as well as nullable: the server decodes a missing field as nil.
+func (d *Document) Request(v any) *Schema {
+	return d.schemaFor(reflect.TypeOf(v), true)
+}
+
+var (
+	timeType    = reflect.TypeFor[time.Time]()
+	uuidType    = reflect.TypeFor[uuid.UUID]()
+	rawJSONType = reflect.TypeFor[json.RawMessage]()
+)
+
+func (d *Document) schemaFor(t reflect.Type, request bool) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: Types{"string"}, Format: "uuid"}
+	case rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(d.schemaFor(t.Elem(), request))
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, Format: "byte"}
+		}
+		return &Schema{Type: Types{"array"}, Items: d.schemaFor(t.Elem(), request)}
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: d.schemaFor(t.Elem(), request)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t, request)
+		}
+		name, ok := d.schemaNames[t]
+		if !ok {
+			name = t.Name()
+			d.schemaNames[t] = name
+			d.Components.Schemas[name] = d.structSchema(t, request)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) structSchema(t reflect.Type, request bool) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema), closed: true}
+	d.addFields(s, t, request)
+	return s
+}
+
+// addFields adds the JSON-encoded fields of struct type t to s, flattening
+// embedded structs the way encoding/json does.
+func (d *Document) addFields(s *Schema, t reflect.Type, request bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			d.addFields(s, f.Type, request)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(opts, "omitempty")
+		ft := f.Type
+		if omitempty && !request && ft.Kind() == reflect.Pointer {
+			ft = ft.Elem() // encoding/json leaves out a nil pointer rather than writing null
+		}
+		s.Properties[name] = d.schemaFor(ft, request)
+		optional := omitempty || (request && f.Type.Kind() == reflect.Pointer)
+		if !optional {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// nullable extends s to also accept null.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{OneOf: []*Schema{s, {Type: Types{"null"}}}}
+	}
+	if len(s.Type) == 0 {
+		return s // already accepts anything
+	}
+	n := *s
+	n.Type = append(append(Types{}, s.Type...), "null")
+	return &n
+}
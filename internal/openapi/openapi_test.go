@@ -0,0 +1,156 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type testEmbedded struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testItem struct {
+	ID   uuid.UUID `json:"id"`
+	Name *string   `json:"name"`
+}
+
+type testBody struct {
+	testEmbedded
+	Title   string            `json:"title"`
+	Count   int               `json:"count,omitempty"`
+	Items   []testItem        `json:"items"`
+	Parent  *testItem         `json:"parent"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Skipped string            `json:"-"`
+	hidden  string
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	ref := doc.SchemaOf(testBody{})
+	if ref.Ref != "#/components/schemas/testBody" {
+		t.Fatalf("expected a component reference, got %+v", ref)
+	}
+
+	body := doc.Components.Schemas["testBody"]
+	if body == nil || doc.Components.Schemas["testItem"] == nil {
+		t.Fatalf("expected both structs as components, got %v", doc.Components.Schemas)
+	}
+	for _, name := range []string{"created_at", "title", "count", "items", "parent", "labels"} {
+		if body.Properties[name] == nil {
+			t.Errorf("expected property %q", name)
+		}
+	}
+	if len(body.Properties) != 6 {
+		t.Errorf("expected skipped and unexported fields to be left out, got %d properties", len(body.Properties))
+	}
+	if got := strings.Join(body.Required, ","); got != "created_at,title,items,parent" {
+		t.Errorf("expected omitempty fields to be optional, got required %s", got)
+	}
+	if p := body.Properties["parent"]; len(p.OneOf) != 2 {
+		t.Errorf("expected a nullable reference, got %+v", p)
+	}
+	if name := doc.Components.Schemas["testItem"].Properties["name"]; strings.Join(name.Type, ",") != "string,null" {
+		t.Errorf("expected a nullable string, got %v", name.Type)
+	}
+
+	out, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"additionalProperties":false`) {
+		t.Errorf("expected a closed object, got %s", out)
+	}
+}
+
+func TestRequest_PointerFieldsAreOptional(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Request(testItem{})
+	if got := doc.Components.Schemas["testItem"].Required; len(got) != 1 || got[0] != "id" {
+		t.Errorf("expected only id to be required, got %v", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	s := doc.SchemaOf(testBody{})
+
+	valid := `{"created_at":"2026-01-02T03:04:05Z","title":"t","items":[{"id":"` + uuid.NewString() + `","name":null}],"parent":null}`
+	if err := doc.Validate(s, []byte(valid)); err != nil {
+		t.Errorf("expected a valid document, got %v", err)
+	}
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{"missing field", `{"created_at":"2026-01-02T03:04:05Z","items":[],"parent":null}`, `missing required property "title"`},
+		{"null slice", `{"created_at":"2026-01-02T03:04:05Z","title":"t","items":null,"parent":null}`, "$.items: expected array, got null"},
+		{"extra field", `{"created_at":"2026-01-02T03:04:05Z","title":"t","items":[],"parent":null,"extra":1}`, "$.extra: is not a documented property"},
+		{"bad format", `{"created_at":"yesterday","title":"t","items":[],"parent":null}`, `"yesterday" is not a valid date-time`},
+		{"nested", `{"created_at":"2026-01-02T03:04:05Z","title":"t","items":[{"id":"x","name":null}],"parent":null}`, `$.items[0].id: "x" is not a valid uuid`},
+		{"wrong type", `{"created_at":"2026-01-02T03:04:05Z","title":"t","count":1.5,"items":[],"parent":null}`, "$.count: expected integer, got number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.Validate(s, []byte(tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateRequestAndResponse(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("POST", "/items/{id}", &Operation{
+		Parameters: []Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &Schema{Type: Types{"string"}, Format: "uuid"}},
+			{Name: "limit", In: "query", Schema: &Schema{Type: Types{"integer"}}},
+		},
+		RequestBody: &RequestBody{Required: true, Content: JSONContent(doc.Request(testItem{}))},
+		Responses: map[string]*Response{
+			"201":     {Description: "created", Content: JSONContent(doc.SchemaOf(testItem{}))},
+			"204":     {Description: "nothing"},
+			"default": {Description: "error", Content: JSONContent(&Schema{Type: Types{"object"}})},
+		},
+	})
+	id := uuid.NewString()
+	params := func(name string) string {
+		if name == "id" {
+			return id
+		}
+		return ""
+	}
+
+	r := httptest.NewRequest("POST", "/items/"+id+"?limit=10", nil)
+	r.Header.Set("Content-Type", "application/json")
+	if err := doc.ValidateRequest("POST", "/items/{id}", r, params, []byte(`{"id":"`+id+`"}`)); err != nil {
+		t.Errorf("expected a valid request, got %v", err)
+	}
+
+	r = httptest.NewRequest("POST", "/items/"+id+"?limit=ten", nil)
+	err := doc.ValidateRequest("POST", "/items/{id}", r, params, []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "query parameter limit") || !strings.Contains(err.Error(), `missing required property "id"`) {
+		t.Errorf("expected parameter and body problems, got %v", err)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if err := doc.ValidateResponse("POST", "/items/{id}", 201, header, []byte(`{"id":"`+id+`","name":"n"}`)); err != nil {
+		t.Errorf("expected a valid response, got %v", err)
+	}
+	if err := doc.ValidateResponse("POST", "/items/{id}", 500, header, []byte(`{}`)); err != nil {
+		t.Errorf("expected the default response to apply, got %v", err)
+	}
+	if err := doc.ValidateResponse("POST", "/items/{id}", 204, header, []byte(`{}`)); err == nil {
+		t.Error("expected a body on a bodiless response to be reported")
+	}
+	if err := doc.ValidateResponse("GET", "/items/{id}", 200, header, nil); err == nil {
+		t.Error("expected an undocumented operation to be reported")
+	}
+}
@@ -0,0 +1,292 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ValidationError lists every way a request or response departs from the document.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// ValidateRequest checks a request to the operation at method and path, as
+// registered with Add, against its parameters and request body schema.
+// pathParam looks up the value of a path parameter, and body is the request
+// body already read from r.
+func (d *Document) ValidateRequest(method, path string, r *http.Request, pathParam func(string) string, body []byte) error {
+	op := d.Operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+
+	v := &validator{doc: d}
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var value string
+		var present bool
+		switch p.In {
+		case "path":
+			value = pathParam(p.Name)
+			present = value != ""
+		case "query":
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		}
+		where := p.In + " parameter " + p.Name
+		if !present {
+			if p.Required {
+				v.fail(where, "is required")
+			}
+			continue
+		}
+		v.validate(p.Schema, parameterValue(p.Schema, value), where)
+	}
+
+	if op.RequestBody != nil && (len(body) > 0 || op.RequestBody.Required) {
+		v.validateBody(op.RequestBody.Content, r.Header.Get("Content-Type"), body, "request body")
+	}
+	return v.err()
+}
+
+// ValidateResponse checks a response of the operation at method and path
+// against the schema documented for its status code, falling back to the
+// "default" response.
+func (d *Document) ValidateResponse(method, path string, status int, header http.Header, body []byte) error {
+	op := d.Operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+
+	v := &validator{doc: d}
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			v.fail("response body", "is not documented for status %d", status)
+		}
+		return v.err()
+	}
+	v.validateBody(resp.Content, header.Get("Content-Type"), body, fmt.Sprintf("response %d body", status))
+	return v.err()
+}
+
+// Validate checks the JSON document data against s.
+func (d *Document) Validate(s *Schema, data []byte) error {
+	v := &validator{doc: d}
+	v.validateJSON(s, data, "$")
+	return v.err()
+}
+
+// parameterValue converts a raw parameter to the JSON type its schema expects.
+func parameterValue(s *Schema, raw string) any {
+	switch {
+	case slices.Contains(s.Type, "integer"), slices.Contains(s.Type, "number"):
+		return json.Number(raw)
+	case slices.Contains(s.Type, "boolean"):
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+type validator struct {
+	doc      *Document
+	problems []string
+}
+
+func (v *validator) fail(where, format string, args ...any) {
+	v.problems = append(v.problems, where+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+func (v *validator) validateBody(content map[string]*MediaType, contentType string, body []byte, where string) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	mt := content[mediaType]
+	if mt == nil {
+		if len(content) == 1 && content["application/json"] != nil && mediaType == "" {
+			mt = content["application/json"] // clients often omit the header
+		} else {
+			v.fail(where, "content type %q is not documented", contentType)
+			return
+		}
+	}
+	if mediaType != "" && mediaType != "application/json" {
+		return // only JSON bodies are checked
+	}
+	v.validateJSON(mt.Schema, body, where)
+}
+
+func (v *validator) validateJSON(s *Schema, data []byte, where string) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		v.fail(where, "invalid JSON: %v", err)
+		return
+	}
+	v.validate(s, value, where)
+}
+
+// validate checks value, decoded with json.Decoder.UseNumber, against s.
+func (v *validator) validate(s *Schema, value any, where string) {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		target := v.doc.Components.Schemas[name]
+		if target == nil {
+			v.fail(where, "unresolved reference %s", s.Ref)
+			return
+		}
+		v.validate(target, value, where)
+		return
+	}
+
+	if len(s.OneOf) > 0 {
+		matches := 0
+		var first []string
+		for _, alt := range s.OneOf {
+			sub := &validator{doc: v.doc}
+			sub.validate(alt, value, where)
+			if len(sub.problems) == 0 {
+				matches++
+			} else if first == nil {
+				first = sub.problems
+			}
+		}
+		if matches == 0 {
+			v.problems = append(v.problems, first...)
+		} else if matches > 1 {
+			v.fail(where, "matches more than one alternative")
+		}
+		return
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(value, t) }) {
+		v.fail(where, "expected %s, got %s", strings.Join(s.Type, " or "), typeName(value))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		v.fail(where, "%v is not one of %v", value, s.Enum)
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateFormat(s.Format, value, where)
+	case []any:
+		if s.Items != nil {
+			for i, item := range value {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", where, i))
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				v.fail(where, "missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			child := where + "." + k
+			switch {
+			case s.Properties[k] != nil:
+				v.validate(s.Properties[k], value[k], child)
+			case s.AdditionalProperties != nil:
+				v.validate(s.AdditionalProperties, value[k], child)
+			case s.closed:
+				v.fail(child, "is not a documented property")
+			}
+		}
+	}
+}
+
+func (v *validator) validateFormat(format, value, where string) {
+	var err error
+	switch format {
+	case "uuid":
+		_, err = uuid.Parse(value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, value)
+	case "byte":
+		_, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil {
+		v.fail(where, "%q is not a valid %s", value, format)
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch value := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "number" {
+			_, err := value.Float64()
+			return err == nil
+		}
+		if t == "integer" {
+			_, err := value.Int64()
+			return err == nil
+		}
+		return false
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,399 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// AccountRepository defines the data access contract for account-wide operations
+// that span several tables: personal data export and deletion.
+type AccountRepository interface {
+	Export(ctx context.Context, userID uuid.UUID) (*model.AccountExport, error)
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) error
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+	ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	Anonymize(ctx context.Context, userID uuid.UUID, now time.Time) error
+}
+
+type accountRepo struct {
+	db DBTX
+}
+
+// NewAccountRepo creates a new AccountRepository backed by the given database.
+func NewAccountRepo(db DBTX) AccountRepository {
+	return &accountRepo{db: db}
+}
+
+// Export collects the rows that belong to a user. The profile is left for the caller to fill.
+func (r *accountRepo) Export(ctx context.Context, userID uuid.UUID) (*model.AccountExport, error) {
+	ctx = statement(ctx, "accountRepo.Export")
+	exp := &model.AccountExport{
+		Sessions:      []model.Session{},
+		Conversations: []model.Conversation{},
+		Messages:      []model.Message{},
+		Blocks:        []model.BlockedUser{},
+		Reports:       []model.Report{},
+	}
+
+	err := r.collect(ctx, `
+		SELECT id, user_id, refresh_token_hash, expires_at, created_at, revoked_at
+		FROM sessions WHERE user_id = $1 ORDER BY created_at
+	`, userID, func(rows *sql.Rows) error {
+		var s model.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.RefreshTokenHash, &s.ExpiresAt, &s.CreatedAt, &s.RevokedAt); err != nil {
+			return err
+		}
+		exp.Sessions = append(exp.Sessions, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repo: export sessions: %w", err)
+	}
+
+	err = r.collect(ctx, `
+		SELECT c.id, c.type, c.name, c.created_by, c.created_at, c.updated_at
+		FROM conversations c
+		JOIN conversation_participants cp ON cp.conversation_id = c.id
+		WHERE cp.user_id = $1 ORDER BY c.created_at
+	`, userID, func(rows *sql.Rows) error {
+		var c model.Conversation
+		if err := rows.Scan(&c.ID, &c.Type, &c.Name, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return err
+		}
+		exp.Conversations = append(exp.Conversations, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repo: export conversations: %w", err)
+	}
+
+	err = r.collect(ctx, `
+		SELECT id, conversation_id, sender_id, body, status, created_at, updated_at, client_message_id
+		FROM messages WHERE sender_id = $1 ORDER BY created_at
+	`, userID, func(rows *sql.Rows) error {
+		var m model.Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Body, &m.Status, &m.CreatedAt, &m.UpdatedAt, &m.ClientMessageID); err != nil {
+			return err
+		}
+		exp.Messages = append(exp.Messages, m)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repo: export messages: %w", err)
+	}
+
+	err = r.collect(ctx, `
+		SELECT id, blocker_id, blocked_id, created_at
+		FROM blocked_users WHERE blocker_id = $1 ORDER BY created_at
+	`, userID, func(rows *sql.Rows) error {
+		var b model.BlockedUser
+		if err := rows.Scan(&b.ID, &b.BlockerID, &b.BlockedID, &b.CreatedAt); err != nil {
+			return err
+		}
+		exp.Blocks = append(exp.Blocks, b)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repo: export blocks: %w", err)
+	}
+
+	err = r.collect(ctx, `
+		SELECT id, reporter_id, target_type, target_id, reason, status, created_at, resolved_at
+		FROM reports WHERE reporter_id = $1 ORDER BY created_at
+	`, userID, func(rows *sql.Rows) error {
+		var rp model.Report
+		if err := rows.Scan(&rp.ID, &rp.ReporterID, &rp.TargetType, &rp.TargetID, &rp.Reason, &rp.Status, &rp.CreatedAt, &rp.ResolvedAt); err != nil {
+			return err
+		}
+		exp.Reports = append(exp.Reports, rp)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repo: export reports: %w", err)
+	}
+
+	return exp, nil
+}
+
+func (r *accountRepo) collect(ctx context.Context, query string, userID uuid.UUID, scan func(*sql.Rows) error) error {
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *accountRepo) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	ctx = statement(ctx, "accountRepo.ScheduleDeletion")
+	query := `
+		UPDATE users SET deletion_scheduled_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, at, userID)
+	if err != nil {
+		return fmt.Errorf("repo: schedule deletion: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *accountRepo) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	ctx = statement(ctx, "accountRepo.CancelDeletion")
+	query := `
+		UPDATE users SET deletion_scheduled_at = NULL
+		WHERE id = $1 AND deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("repo: cancel deletion: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *accountRepo) ListDueForDeletion(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	ctx = statement(ctx, "accountRepo.ListDueForDeletion")
+	query := `
+		SELECT id FROM users
+		WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= $1 AND deleted_at IS NULL
+		ORDER BY deletion_scheduled_at
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list due deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("repo: scan due deletion: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list due deletions rows error: %w", err)
+	}
+	return ids, nil
+}
+
+// Anonymize scrubs a user's personal data across several tables; run it inside
+// Store.WithTx so a partial failure leaves nothing half-scrubbed. The users row
+// survives as a placeholder so that messages and conversations that reference it
+// remain readable for the other participants.
+func (r *accountRepo) Anonymize(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	ctx = statement(ctx, "accountRepo.Anonymize")
+	var email string
+	err := r.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL`, userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		return model.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("repo: anonymize: get user: %w", err)
+	}
+
+	steps := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{"scrub profile", `
+			UPDATE users SET
+				email = $1, password_hash = '', display_name = $2, avatar_url = NULL,
+				status = $3, deletion_scheduled_at = NULL, deleted_at = $4, updated_at = $4
+			WHERE id = $5
+		`, []any{"deleted-" + userID.String() + "@deleted.invalid", model.DeletedUserDisplayName, model.UserStatusDeleted, now, userID}},
+		{"delete sessions", `DELETE FROM sessions WHERE user_id = $1`, []any{userID}},
+		{"delete identities", `DELETE FROM user_identities WHERE user_id = $1`, []any{userID}},
+		{"delete tokens", `DELETE FROM personal_access_tokens WHERE user_id = $1 OR created_by = $1`, []any{userID}},
+		{"delete blocks", `DELETE FROM blocked_users WHERE blocker_id = $1 OR blocked_id = $1`, []any{userID}},
+		{"delete deliveries", `DELETE FROM message_deliveries WHERE user_id = $1`, []any{userID}},
+		{"delete idempotency keys", `DELETE FROM idempotency_keys WHERE user_id = $1`, []any{userID}},
+		{"delete webhook deliveries", `
+			DELETE FROM webhook_deliveries
+			WHERE webhook_id IN (SELECT id FROM webhooks WHERE created_by = $1)
+		`, []any{userID}},
+		{"delete webhooks", `DELETE FROM webhooks WHERE created_by = $1`, []any{userID}},
+		{"delete incoming webhooks", `DELETE FROM incoming_webhooks WHERE created_by = $1`, []any{userID}},
+		{"delete bot commands", `DELETE FROM bot_commands WHERE created_by = $1`, []any{userID}},
+		{"delete reminders", `DELETE FROM reminders WHERE user_id = $1`, []any{userID}},
+		{"delete devices", `DELETE FROM device_tokens WHERE user_id = $1`, []any{userID}},
+		{"delete mutes", `DELETE FROM conversation_mutes WHERE user_id = $1`, []any{userID}},
+		{"delete digest settings", `DELETE FROM email_digest_settings WHERE user_id = $1`, []any{userID}},
+		{"leave conversations", `
+			UPDATE conversation_participants SET left_at = $1
+			WHERE user_id = $2 AND left_at IS NULL
+		`, []any{now, userID}},
+		{"delete login attempts", `DELETE FROM login_attempts WHERE throttle_key = $1`, []any{"account:" + email}},
+	}
+	for _, step := range steps {
+		if _, err := r.db.ExecContext(ctx, step.query, step.args...); err != nil {
+			return fmt.Errorf("repo: anonymize: %s: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+type memoryAccountRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryAccountRepo) Export(_ context.Context, userID uuid.UUID) (*model.AccountExport, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	exp := &model.AccountExport{
+		Sessions:      []model.Session{},
+		Conversations: []model.Conversation{},
+		Messages:      []model.Message{},
+		Blocks:        []model.BlockedUser{},
+		Reports:       []model.Report{},
+	}
+	for _, s := range t.sessions {
+		if s.UserID == userID {
+			exp.Sessions = append(exp.Sessions, s)
+		}
+	}
+	for _, p := range t.participants {
+		if c, ok := t.conversations[p.ConversationID]; ok && p.UserID == userID {
+			exp.Conversations = append(exp.Conversations, c)
+		}
+	}
+	for _, m := range t.messages {
+		if m.SenderID == userID {
+			exp.Messages = append(exp.Messages, m)
+		}
+	}
+	for _, b := range t.blocks {
+		if b.BlockerID == userID {
+			exp.Blocks = append(exp.Blocks, b)
+		}
+	}
+	for _, rp := range t.reports {
+		if rp.ReporterID == userID {
+			exp.Reports = append(exp.Reports, rp)
+		}
+	}
+
+	slices.SortFunc(exp.Sessions, func(a, b model.Session) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortFunc(exp.Conversations, func(a, b model.Conversation) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortFunc(exp.Messages, func(a, b model.Message) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortFunc(exp.Blocks, func(a, b model.BlockedUser) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	slices.SortFunc(exp.Reports, func(a, b model.Report) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return exp, nil
+}
+
+func (r *memoryAccountRepo) ScheduleDeletion(_ context.Context, userID uuid.UUID, at time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	u, ok := t.users[userID]
+	if !ok || u.Status == model.UserStatusDeleted {
+		return model.ErrNotFound
+	}
+	u.DeletionScheduledAt = &at
+	t.users[userID] = u
+	return nil
+}
+
+func (r *memoryAccountRepo) CancelDeletion(_ context.Context, userID uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	u, ok := t.users[userID]
+	if !ok || u.DeletionScheduledAt == nil || u.Status == model.UserStatusDeleted {
+		return model.ErrNotFound
+	}
+	u.DeletionScheduledAt = nil
+	t.users[userID] = u
+	return nil
+}
+
+func (r *memoryAccountRepo) ListDueForDeletion(_ context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	var due []model.User
+	for _, u := range t.users {
+		if u.DeletionScheduledAt != nil && !u.DeletionScheduledAt.After(now) && u.Status != model.UserStatusDeleted {
+			due = append(due, u)
+		}
+	}
+	slices.SortFunc(due, func(a, b model.User) int { return a.DeletionScheduledAt.Compare(*b.DeletionScheduledAt) })
+
+	var ids []uuid.UUID
+	for _, u := range due[:min(limit, len(due))] {
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+// Anonymize mirrors the SQL implementation: the user row is scrubbed in place
+// and everything else tied to the account is removed.
+func (r *memoryAccountRepo) Anonymize(_ context.Context, userID uuid.UUID, now time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	u, ok := t.users[userID]
+	if !ok || u.Status == model.UserStatusDeleted {
+		return model.ErrNotFound
+	}
+	email := u.Email
+
+	u.Email = "deleted-" + userID.String() + "@deleted.invalid"
+	u.PasswordHash = ""
+	u.DisplayName = model.DeletedUserDisplayName
+	u.AvatarURL = nil
+	u.Status = model.UserStatusDeleted
+	u.DeletionScheduledAt = nil
+	u.UpdatedAt = now
+	t.users[userID] = u
+
+	maps.DeleteFunc(t.sessions, func(_ uuid.UUID, s model.Session) bool { return s.UserID == userID })
+	maps.DeleteFunc(t.identities, func(_ uuid.UUID, i model.UserIdentity) bool { return i.UserID == userID })
+	maps.DeleteFunc(t.tokens, func(_ uuid.UUID, tok model.PersonalAccessToken) bool {
+		return tok.UserID == userID || tok.CreatedBy == userID
+	})
+	maps.DeleteFunc(t.blocks, func(_ uuid.UUID, b model.BlockedUser) bool {
+		return b.BlockerID == userID || b.BlockedID == userID
+	})
+	maps.DeleteFunc(t.deliveries, func(_ uuid.UUID, d model.MessageDelivery) bool { return d.UserID == userID })
+	maps.DeleteFunc(t.idempotencyKeys, func(k idempotencyKey, _ model.IdempotencyRecord) bool { return k.userID == userID })
+	for id, hook := range t.webhooks {
+		if hook.CreatedBy == userID {
+			delete(t.webhooks, id)
+			maps.DeleteFunc(t.webhookDeliveries, func(_ uuid.UUID, d model.WebhookDelivery) bool { return d.WebhookID == id })
+		}
+	}
+	maps.DeleteFunc(t.incomingWebhooks, func(_ uuid.UUID, h model.IncomingWebhook) bool { return h.CreatedBy == userID })
+	maps.DeleteFunc(t.botCommands, func(_ uuid.UUID, c model.BotCommand) bool { return c.CreatedBy == userID })
+	maps.DeleteFunc(t.reminders, func(_ uuid.UUID, r model.Reminder) bool { return r.UserID == userID })
+	maps.DeleteFunc(t.deviceTokens, func(_ uuid.UUID, d model.DeviceToken) bool { return d.UserID == userID })
+	maps.DeleteFunc(t.mutes, func(k muteKey, _ model.ConversationMute) bool { return k.userID == userID })
+	delete(t.digestSettings, userID)
+	for id, p := range t.participants {
+		if p.UserID == userID && p.LeftAt == nil {
+			p.LeftAt = &now
+			t.participants[id] = p
+		}
+	}
+	delete(t.loginAttempts, "account:"+email)
+	return nil
+}
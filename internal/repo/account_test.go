@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestAccountRepo_ScheduleAndList(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		now := time.Now().UTC()
+
+		if err := store.Accounts.ScheduleDeletion(ctx, alice.ID, now.Add(-time.Minute)); err != nil {
+			t.Fatalf("schedule alice: %v", err)
+		}
+		if err := store.Accounts.ScheduleDeletion(ctx, bob.ID, now.Add(time.Hour)); err != nil {
+			t.Fatalf("schedule bob: %v", err)
+		}
+
+		due, err := store.Accounts.ListDueForDeletion(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("list due: %v", err)
+		}
+		if len(due) != 1 || due[0] != alice.ID {
+			t.Fatalf("expected only alice to be due, got %v", due)
+		}
+
+		if err := store.Accounts.CancelDeletion(ctx, bob.ID); err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+		if err := store.Accounts.CancelDeletion(ctx, bob.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound with nothing pending, got %v", err)
+		}
+	})
+}
+
+func TestAccountRepo_ExportAndAnonymize(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		convo := createTestConversation(t, store, alice.ID, bob.ID)
+		sess := createTestSession(t, store, alice.ID, time.Now().UTC().Add(time.Hour))
+		msg := newTestMessage(convo.ID, alice.ID, "hi", time.Now().UTC())
+		if err := store.Messages.Create(ctx, msg); err != nil {
+			t.Fatalf("create message: %v", err)
+		}
+		if err := store.Moderation.Block(ctx, bob.ID, alice.ID); err != nil {
+			t.Fatalf("block: %v", err)
+		}
+		hook := createTestWebhook(t, store, convo.ID, alice.ID, model.WebhookEventMessageCreated)
+		incoming := createTestIncomingWebhook(t, store, convo.ID, bob.ID, alice.ID, "hash-alice")
+		device := newTestDevice(alice.ID, model.PlatformFCM, "fcm-alice")
+		if err := store.Push.UpsertDevice(ctx, device); err != nil {
+			t.Fatalf("upsert device: %v", err)
+		}
+		if err := store.Push.SetMute(ctx, &model.ConversationMute{ConversationID: convo.ID, UserID: alice.ID, CreatedAt: time.Now().UTC()}); err != nil {
+			t.Fatalf("set mute: %v", err)
+		}
+		if err := store.Digests.UpsertSettings(ctx, &model.DigestSettings{UserID: alice.ID, Frequency: model.DigestWeekly, UpdatedAt: time.Now().UTC()}); err != nil {
+			t.Fatalf("upsert digest settings: %v", err)
+		}
+
+		exp, err := store.Accounts.Export(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("export: %v", err)
+		}
+		if len(exp.Sessions) != 1 || len(exp.Conversations) != 1 || len(exp.Messages) != 1 {
+			t.Errorf("unexpected export: %+v", exp)
+		}
+
+		err = store.WithTx(ctx, func(tx *Store) error {
+			return tx.Accounts.Anonymize(ctx, alice.ID, time.Now().UTC())
+		})
+		if err != nil {
+			t.Fatalf("anonymize: %v", err)
+		}
+
+		got, err := store.Users.GetByID(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		if got.DisplayName != model.DeletedUserDisplayName || got.Status != model.UserStatusDeleted {
+			t.Errorf("expected scrubbed profile, got %+v", got)
+		}
+		if _, err := store.Sessions.GetByToken(ctx, sess.RefreshTokenHash); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected sessions to be deleted, got %v", err)
+		}
+		if ok, _ := store.Moderation.IsBlocked(ctx, bob.ID, alice.ID); ok {
+			t.Error("expected blocks to be deleted")
+		}
+		if _, err := store.Webhooks.GetByID(ctx, hook.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected webhooks to be deleted, got %v", err)
+		}
+		if _, err := store.Integrations.GetIncomingWebhook(ctx, incoming.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected incoming webhooks to be deleted, got %v", err)
+		}
+		if devices, _ := store.Push.ListDevices(ctx, alice.ID); len(devices) != 0 {
+			t.Errorf("expected devices to be deleted, got %+v", devices)
+		}
+		if _, err := store.Push.GetMute(ctx, convo.ID, alice.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected mutes to be deleted, got %v", err)
+		}
+		if _, err := store.Digests.GetSettings(ctx, alice.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected digest settings to be deleted, got %v", err)
+		}
+		if _, err := store.Messages.GetByID(ctx, msg.ID); err != nil {
+			t.Errorf("expected messages to be kept, got %v", err)
+		}
+		if ok, _ := store.Conversations.IsParticipant(ctx, convo.ID, alice.ID); ok {
+			t.Error("expected the user to have left their conversations")
+		}
+
+		if err := store.Accounts.Anonymize(ctx, alice.ID, time.Now().UTC()); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for an anonymized account, got %v", err)
+		}
+	})
+}
@@ -1,10 +1,12 @@
 package repo
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,15 +28,16 @@ type ConversationRepository interface {
 }
 
 type conversationRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewConversationRepo creates a new ConversationRepository backed by the given database.
-func NewConversationRepo(db *sql.DB) ConversationRepository {
+func NewConversationRepo(db DBTX) ConversationRepository {
 	return &conversationRepo{db: db}
 }
 
 func (r *conversationRepo) Create(ctx context.Context, convo *model.Conversation) error {
+	ctx = statement(ctx, "conversationRepo.Create")
 	query := `
 		INSERT INTO conversations (id, type, name, created_by, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
@@ -54,6 +57,7 @@ func (r *conversationRepo) Create(ctx context.Context, convo *model.Conversation
 }
 
 func (r *conversationRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Conversation, error) {
+	ctx = statement(ctx, "conversationRepo.GetByID")
 	query := `
 		SELECT id, type, name, created_by, created_at, updated_at
 		FROM conversations
@@ -78,6 +82,7 @@ func (r *conversationRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Co
 }
 
 func (r *conversationRepo) ListByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*model.Page[model.ConversationSummary], error) {
+	ctx = statement(ctx, "conversationRepo.ListByUser")
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
@@ -150,6 +155,7 @@ func (r *conversationRepo) ListByUser(ctx context.Context, userID uuid.UUID, cur
 }
 
 func (r *conversationRepo) Update(ctx context.Context, id uuid.UUID, name string) (*model.Conversation, error) {
+	ctx = statement(ctx, "conversationRepo.Update")
 	query := `
 		UPDATE conversations
 		SET name = $1, updated_at = $2
@@ -175,6 +181,7 @@ func (r *conversationRepo) Update(ctx context.Context, id uuid.UUID, name string
 }
 
 func (r *conversationRepo) FindDirectBetween(ctx context.Context, userA uuid.UUID, userB uuid.UUID) (*model.Conversation, error) {
+	ctx = statement(ctx, "conversationRepo.FindDirectBetween")
 	query := `
 		SELECT c.id, c.type, c.name, c.created_by, c.created_at, c.updated_at
 		FROM conversations c
@@ -208,11 +215,15 @@ func (r *conversationRepo) FindDirectBetween(ctx context.Context, userA uuid.UUI
 }
 
 func (r *conversationRepo) AddParticipant(ctx context.Context, participant *model.ConversationParticipant) error {
+	ctx = statement(ctx, "conversationRepo.AddParticipant")
+	// DO NOTHING instead of a unique violation: on Postgres a failed statement
+	// aborts the surrounding transaction, and callers treat duplicates as skippable.
 	query := `
 		INSERT INTO conversation_participants (id, conversation_id, user_id, role, joined_at)
 		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (conversation_id, user_id) DO NOTHING
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	res, err := r.db.ExecContext(ctx, query,
 		participant.ID,
 		participant.ConversationID,
 		participant.UserID,
@@ -220,15 +231,16 @@ func (r *conversationRepo) AddParticipant(ctx context.Context, participant *mode
 		participant.JoinedAt,
 	)
 	if err != nil {
-		if strings.Contains(err.Error(), "unique") || strings.Contains(err.Error(), "duplicate") {
-			return model.ErrConflict
-		}
 		return fmt.Errorf("repo: add participant: %w", err)
 	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return model.ErrConflict
+	}
 	return nil
 }
 
 func (r *conversationRepo) RemoveParticipant(ctx context.Context, conversationID uuid.UUID, userID uuid.UUID) error {
+	ctx = statement(ctx, "conversationRepo.RemoveParticipant")
 	query := `
 		UPDATE conversation_participants
 		SET left_at = $1
@@ -246,6 +258,7 @@ func (r *conversationRepo) RemoveParticipant(ctx context.Context, conversationID
 }
 
 func (r *conversationRepo) GetParticipants(ctx context.Context, conversationID uuid.UUID) ([]model.ConversationParticipant, error) {
+	ctx = statement(ctx, "conversationRepo.GetParticipants")
 	query := `
 		SELECT id, conversation_id, user_id, role, joined_at, left_at
 		FROM conversation_participants
@@ -269,6 +282,7 @@ func (r *conversationRepo) GetParticipants(ctx context.Context, conversationID u
 }
 
 func (r *conversationRepo) IsParticipant(ctx context.Context, conversationID uuid.UUID, userID uuid.UUID) (bool, error) {
+	ctx = statement(ctx, "conversationRepo.IsParticipant")
 	query := `
 		SELECT EXISTS(
 			SELECT 1 FROM conversation_participants
@@ -307,3 +321,164 @@ func encodeTimeCursor(t time.Time, id uuid.UUID) string {
 	raw := t.Format(time.RFC3339Nano) + "|" + id.String()
 	return base64.URLEncoding.EncodeToString([]byte(raw))
 }
+
+type memoryConversationRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryConversationRepo) Create(_ context.Context, convo *model.Conversation) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.conversations[convo.ID]; ok {
+		return fmt.Errorf("repo: create conversation: %w", model.ErrConflict)
+	}
+	t.conversations[convo.ID] = *convo
+	return nil
+}
+
+func (r *memoryConversationRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Conversation, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	c, ok := t.conversations[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *memoryConversationRepo) ListByUser(_ context.Context, userID uuid.UUID, cursor string, limit int) (*model.Page[model.ConversationSummary], error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var (
+		cursorTime time.Time
+		cursorID   uuid.UUID
+	)
+	if cursor != "" {
+		var err error
+		cursorTime, cursorID, err = decodeTimeCursor(cursor)
+		if err != nil {
+			return nil, &model.ValidationError{Field: "cursor", Message: "invalid cursor"}
+		}
+	}
+
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	var convos []model.Conversation
+	for _, p := range t.participants {
+		if p.UserID != userID || p.LeftAt != nil {
+			continue
+		}
+		c, ok := t.conversations[p.ConversationID]
+		if !ok {
+			continue
+		}
+		if cursor != "" && compareTimeID(c.UpdatedAt, c.ID, cursorTime, cursorID) >= 0 {
+			continue
+		}
+		convos = append(convos, c)
+	}
+	slices.SortFunc(convos, func(a, b model.Conversation) int {
+		return compareTimeID(b.UpdatedAt, b.ID, a.UpdatedAt, a.ID)
+	})
+
+	page := memoryPage(convos, limit, func(c model.Conversation) string {
+		return encodeTimeCursor(c.UpdatedAt, c.ID)
+	})
+	summaries := make([]model.ConversationSummary, 0, len(page.Items))
+	for _, c := range page.Items {
+		summaries = append(summaries, model.ConversationSummary{ID: c.ID, Type: c.Type, Name: c.Name})
+	}
+	return &model.Page[model.ConversationSummary]{
+		Items:      summaries,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}, nil
+}
+
+func (r *memoryConversationRepo) Update(_ context.Context, id uuid.UUID, name string) (*model.Conversation, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	c, ok := t.conversations[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	c.Name = &name
+	c.UpdatedAt = time.Now().UTC()
+	t.conversations[id] = c
+	return &c, nil
+}
+
+func (r *memoryConversationRepo) FindDirectBetween(_ context.Context, userA uuid.UUID, userB uuid.UUID) (*model.Conversation, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, c := range t.conversations {
+		if c.Type == "direct" && isActiveParticipant(t, c.ID, userA) && isActiveParticipant(t, c.ID, userB) {
+			return &c, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *memoryConversationRepo) AddParticipant(_ context.Context, participant *model.ConversationParticipant) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, p := range t.participants {
+		if p.ConversationID == participant.ConversationID && p.UserID == participant.UserID {
+			return model.ErrConflict
+		}
+	}
+	t.participants[participant.ID] = *participant
+	return nil
+}
+
+func (r *memoryConversationRepo) RemoveParticipant(_ context.Context, conversationID uuid.UUID, userID uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for id, p := range t.participants {
+		if p.ConversationID == conversationID && p.UserID == userID && p.LeftAt == nil {
+			now := time.Now().UTC()
+			p.LeftAt = &now
+			t.participants[id] = p
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
+
+func (r *memoryConversationRepo) GetParticipants(_ context.Context, conversationID uuid.UUID) ([]model.ConversationParticipant, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	var participants []model.ConversationParticipant
+	for _, p := range t.participants {
+		if p.ConversationID == conversationID && p.LeftAt == nil {
+			participants = append(participants, p)
+		}
+	}
+	slices.SortFunc(participants, func(a, b model.ConversationParticipant) int {
+		return compareTimeID(a.JoinedAt, a.ID, b.JoinedAt, b.ID)
+	})
+	return participants, nil
+}
+
+func (r *memoryConversationRepo) IsParticipant(_ context.Context, conversationID uuid.UUID, userID uuid.UUID) (bool, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	return isActiveParticipant(t, conversationID, userID), nil
+}
+
+func isActiveParticipant(t *memoryTables, conversationID uuid.UUID, userID uuid.UUID) bool {
+	for _, p := range t.participants {
+		if p.ConversationID == conversationID && p.UserID == userID && p.LeftAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compareTimeID orders rows by (time, id), the key the time cursors paginate on.
+func compareTimeID(at time.Time, aID uuid.UUID, bt time.Time, bID uuid.UUID) int {
+	return cmp.Or(at.Compare(bt), strings.Compare(aID.String(), bID.String()))
+}
@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestConversationRepo_ListByUserPaginates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+
+		base := time.Now().UTC().Truncate(time.Second)
+		for i := range 3 {
+			c := newTestConversation(alice.ID)
+			c.UpdatedAt = base.Add(time.Duration(i) * 500 * time.Millisecond)
+			if err := store.Conversations.Create(ctx, c); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if err := store.Conversations.AddParticipant(ctx, newTestParticipant(c.ID, alice.ID)); err != nil {
+				t.Fatalf("add participant: %v", err)
+			}
+		}
+
+		first, err := store.Conversations.ListByUser(ctx, alice.ID, "", 2)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(first.Items) != 2 || !first.HasMore {
+			t.Fatalf("unexpected first page: %+v", first)
+		}
+
+		second, err := store.Conversations.ListByUser(ctx, alice.ID, *first.NextCursor, 2)
+		if err != nil {
+			t.Fatalf("list page 2: %v", err)
+		}
+		if len(second.Items) != 1 || second.HasMore {
+			t.Fatalf("unexpected second page: %+v", second)
+		}
+	})
+}
+
+func TestConversationRepo_FindDirectBetween(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		carol := createTestUser(t, store, "Carol")
+
+		direct := newTestConversation(alice.ID)
+		direct.Type = "direct"
+		if err := store.Conversations.Create(ctx, direct); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		for _, uid := range []*model.User{alice, bob} {
+			if err := store.Conversations.AddParticipant(ctx, newTestParticipant(direct.ID, uid.ID)); err != nil {
+				t.Fatalf("add participant: %v", err)
+			}
+		}
+
+		got, err := store.Conversations.FindDirectBetween(ctx, bob.ID, alice.ID)
+		if err != nil {
+			t.Fatalf("find direct: %v", err)
+		}
+		if got.ID != direct.ID {
+			t.Errorf("expected %s, got %s", direct.ID, got.ID)
+		}
+
+		if _, err := store.Conversations.FindDirectBetween(ctx, alice.ID, carol.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestConversationRepo_Participants(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		convo := createTestConversation(t, store, alice.ID, bob.ID)
+
+		ok, err := store.Conversations.IsParticipant(ctx, convo.ID, bob.ID)
+		if err != nil || !ok {
+			t.Fatalf("expected bob to be a participant, got %v, %v", ok, err)
+		}
+
+		if err := store.Conversations.RemoveParticipant(ctx, convo.ID, bob.ID); err != nil {
+			t.Fatalf("remove participant: %v", err)
+		}
+		if err := store.Conversations.RemoveParticipant(ctx, convo.ID, bob.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound on second removal, got %v", err)
+		}
+
+		participants, err := store.Conversations.GetParticipants(ctx, convo.ID)
+		if err != nil {
+			t.Fatalf("get participants: %v", err)
+		}
+		if len(participants) != 1 || participants[0].UserID != alice.ID {
+			t.Errorf("expected only alice, got %+v", participants)
+		}
+	})
+}
+
+func TestConversationRepo_Update(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+
+		updated, err := store.Conversations.Update(context.Background(), convo.ID, "Team")
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if updated.Name == nil || *updated.Name != "Team" {
+			t.Errorf("expected name Team, got %v", updated.Name)
+		}
+	})
+}
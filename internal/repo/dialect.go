@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect identifies the SQL flavour spoken by the database behind a Store.
+// Queries are written in the common subset of both dialects; the few
+// constructs that differ are produced by the methods below.
+type Dialect int
+
+const (
+	DialectPostgres Dialect = iota
+	DialectSQLite
+)
+
+// String returns the dialect name, which is also the name of its migrations directory.
+func (d Dialect) String() string {
+	switch d {
+	case DialectPostgres:
+		return "postgres"
+	case DialectSQLite:
+		return "sqlite"
+	default:
+		return fmt.Sprintf("Dialect(%d)", int(d))
+	}
+}
+
+// NewUUID returns an SQL expression that generates a random (version 4) UUID.
+func (d Dialect) NewUUID() string {
+	if d == DialectSQLite {
+		return `lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+			substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+			substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))`
+	}
+	return "gen_random_uuid()"
+}
+
+// ILike returns a case-insensitive LIKE predicate matching column against the
+// given placeholder. SQLite's LIKE already ignores case for ASCII letters.
+func (d Dialect) ILike(column, placeholder string) string {
+	if d == DialectSQLite {
+		return column + " LIKE " + placeholder
+	}
+	return column + " ILIKE " + placeholder
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation. Postgres
+// says "duplicate key value violates unique constraint", SQLite "UNIQUE constraint failed".
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique") || strings.Contains(msg, "duplicate")
+}
@@ -0,0 +1,463 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// DigestRepository defines the data access contract for email digests: the
+// per-user settings, when each user was last seen, and which unread messages
+// have already been summarized.
+type DigestRepository interface {
+	// GetSettings returns model.ErrNotFound for a user who never changed
+	// the defaults.
+	GetSettings(ctx context.Context, userID uuid.UUID) (*model.DigestSettings, error)
+	// UpsertSettings stores a user's preferences and next digest time. The
+	// time the last digest was sent is kept.
+	UpsertSettings(ctx context.Context, settings *model.DigestSettings) error
+	// Reschedule sets when a user's next digest is due, creating default
+	// settings if they have none. A non-nil sentAt also records when the
+	// last one went out.
+	Reschedule(ctx context.Context, userID uuid.UUID, next time.Time, sentAt *time.Time) error
+
+	// SetLastSeen records that the user had a live connection at the given time.
+	SetLastSeen(ctx context.Context, userID uuid.UUID, at time.Time) error
+
+	// ListDue returns up to limit human users who are due a digest at now:
+	// digests are enabled for them, they were last seen no later than
+	// seenBefore, and messages they have not read arrived after that and
+	// were not yet summarized.
+	ListDue(ctx context.Context, now, seenBefore time.Time, limit int) ([]model.DigestRecipient, error)
+	// ListUndigested returns up to limit of those messages for one user,
+	// oldest first, from conversations they still belong to.
+	ListUndigested(ctx context.Context, userID uuid.UUID, limit int) ([]model.DigestMessage, error)
+	// MarkDigested stamps the given deliveries that are not yet digested and
+	// returns their IDs. Concurrent senders therefore never both claim the
+	// same message.
+	MarkDigested(ctx context.Context, deliveryIDs []uuid.UUID, at time.Time) ([]uuid.UUID, error)
+	// UnmarkDigested returns deliveries to the pool, for a digest that could
+	// not be sent.
+	UnmarkDigested(ctx context.Context, deliveryIDs []uuid.UUID) error
+}
+
+type digestRepo struct {
+	db DBTX
+}
+
+// NewDigestRepo creates a new DigestRepository backed by the given database.
+func NewDigestRepo(db DBTX) DigestRepository {
+	return &digestRepo{db: db}
+}
+
+func (r *digestRepo) GetSettings(ctx context.Context, userID uuid.UUID) (*model.DigestSettings, error) {
+	ctx = statement(ctx, "digestRepo.GetSettings")
+	query := `
+		SELECT user_id, enabled, frequency, last_sent_at, next_digest_at, updated_at
+		FROM email_digest_settings
+		WHERE user_id = $1
+	`
+	s := &model.DigestSettings{}
+	var lastSent, next sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&s.UserID, &s.Enabled, &s.Frequency, &lastSent, &next, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get digest settings: %w", err)
+	}
+	if lastSent.Valid {
+		s.LastSentAt = &lastSent.Time
+	}
+	if next.Valid {
+		s.NextDigestAt = &next.Time
+	}
+	return s, nil
+}
+
+func (r *digestRepo) UpsertSettings(ctx context.Context, settings *model.DigestSettings) error {
+	ctx = statement(ctx, "digestRepo.UpsertSettings")
+	query := `
+		INSERT INTO email_digest_settings (user_id, enabled, frequency, next_digest_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			enabled = excluded.enabled,
+			frequency = excluded.frequency,
+			next_digest_at = excluded.next_digest_at,
+			updated_at = excluded.updated_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		settings.UserID,
+		settings.Enabled,
+		settings.Frequency,
+		settings.NextDigestAt,
+		settings.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("repo: upsert digest settings: %w", err)
+	}
+	return nil
+}
+
+func (r *digestRepo) Reschedule(ctx context.Context, userID uuid.UUID, next time.Time, sentAt *time.Time) error {
+	ctx = statement(ctx, "digestRepo.Reschedule")
+	query := `
+		INSERT INTO email_digest_settings (user_id, last_sent_at, next_digest_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			last_sent_at = COALESCE(excluded.last_sent_at, email_digest_settings.last_sent_at),
+			next_digest_at = excluded.next_digest_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, sentAt, next, time.Now().UTC()); err != nil {
+		return fmt.Errorf("repo: reschedule digest: %w", err)
+	}
+	return nil
+}
+
+func (r *digestRepo) SetLastSeen(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	ctx = statement(ctx, "digestRepo.SetLastSeen")
+	if _, err := r.db.ExecContext(ctx, `UPDATE users SET last_seen_at = $1 WHERE id = $2`, at, userID); err != nil {
+		return fmt.Errorf("repo: set last seen: %w", err)
+	}
+	return nil
+}
+
+// undigestedDeliveries is the condition shared by ListDue and ListUndigested:
+// d is an unread, undigested delivery to u of message m, which arrived after
+// u was last seen in a conversation u has not left, from a sender u has not
+// blocked.
+const undigestedDeliveries = `
+	d.read_at IS NULL AND d.digested_at IS NULL
+	AND (u.last_seen_at IS NULL OR m.created_at > u.last_seen_at)
+	AND EXISTS (
+		SELECT 1 FROM conversation_participants cp
+		WHERE cp.conversation_id = m.conversation_id AND cp.user_id = d.user_id AND cp.left_at IS NULL
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM blocked_users b
+		WHERE b.blocker_id = d.user_id AND b.blocked_id = m.sender_id
+	)
+`
+
+func (r *digestRepo) ListDue(ctx context.Context, now, seenBefore time.Time, limit int) ([]model.DigestRecipient, error) {
+	ctx = statement(ctx, "digestRepo.ListDue")
+	query := `
+		SELECT u.id, u.email, u.display_name, COALESCE(s.frequency, $1)
+		FROM users u
+		LEFT JOIN email_digest_settings s ON s.user_id = u.id
+		WHERE u.type = $2 AND u.deleted_at IS NULL
+			AND (s.user_id IS NULL OR (s.enabled AND (s.next_digest_at IS NULL OR s.next_digest_at <= $3)))
+			AND (u.last_seen_at IS NULL OR u.last_seen_at <= $4)
+			AND EXISTS (
+				SELECT 1 FROM message_deliveries d
+				JOIN messages m ON m.id = d.message_id
+				WHERE d.user_id = u.id AND ` + undigestedDeliveries + `
+			)
+		ORDER BY u.id
+		LIMIT $5
+	`
+	rows, err := r.db.QueryContext(ctx, query, model.DefaultDigestFrequency, model.UserTypeHuman, now, seenBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list due digests: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []model.DigestRecipient{}
+	for rows.Next() {
+		var rc model.DigestRecipient
+		if err := rows.Scan(&rc.UserID, &rc.Email, &rc.DisplayName, &rc.Frequency); err != nil {
+			return nil, fmt.Errorf("repo: scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list due digests rows error: %w", err)
+	}
+	return recipients, nil
+}
+
+func (r *digestRepo) ListUndigested(ctx context.Context, userID uuid.UUID, limit int) ([]model.DigestMessage, error) {
+	ctx = statement(ctx, "digestRepo.ListUndigested")
+	query := `
+		SELECT d.id, m.id, m.conversation_id, c.name, sender.display_name, m.body, m.created_at
+		FROM message_deliveries d
+		JOIN users u ON u.id = d.user_id
+		JOIN messages m ON m.id = d.message_id
+		JOIN conversations c ON c.id = m.conversation_id
+		JOIN users sender ON sender.id = m.sender_id
+		WHERE d.user_id = $1 AND ` + undigestedDeliveries + `
+		ORDER BY m.created_at, m.id
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list undigested messages: %w", err)
+	}
+	defer rows.Close()
+
+	msgs := []model.DigestMessage{}
+	for rows.Next() {
+		var dm model.DigestMessage
+		var name sql.NullString
+		if err := rows.Scan(&dm.DeliveryID, &dm.MessageID, &dm.ConversationID, &name, &dm.SenderName, &dm.Body, &dm.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repo: scan undigested message: %w", err)
+		}
+		if name.Valid {
+			dm.ConversationName = &name.String
+		}
+		msgs = append(msgs, dm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list undigested messages rows error: %w", err)
+	}
+	return msgs, nil
+}
+
+func (r *digestRepo) MarkDigested(ctx context.Context, deliveryIDs []uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	ctx = statement(ctx, "digestRepo.MarkDigested")
+	claimed := []uuid.UUID{}
+	if len(deliveryIDs) == 0 {
+		return claimed, nil
+	}
+	placeholders, args := idList(deliveryIDs, 2)
+	query := fmt.Sprintf(`
+		UPDATE message_deliveries SET digested_at = $1
+		WHERE id IN (%s) AND digested_at IS NULL
+		RETURNING id
+	`, placeholders)
+	rows, err := r.db.QueryContext(ctx, query, append([]any{at}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: mark digested: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("repo: scan digested delivery: %w", err)
+		}
+		claimed = append(claimed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: mark digested rows error: %w", err)
+	}
+	return claimed, nil
+}
+
+func (r *digestRepo) UnmarkDigested(ctx context.Context, deliveryIDs []uuid.UUID) error {
+	ctx = statement(ctx, "digestRepo.UnmarkDigested")
+	if len(deliveryIDs) == 0 {
+		return nil
+	}
+	placeholders, args := idList(deliveryIDs, 1)
+	query := fmt.Sprintf(`UPDATE message_deliveries SET digested_at = NULL WHERE id IN (%s)`, placeholders)
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("repo: unmark digested: %w", err)
+	}
+	return nil
+}
+
+// idList returns the placeholders for an IN list of ids, numbered from
+// first, and the matching arguments.
+func idList(ids []uuid.UUID, first int) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", first+i)
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+type memoryDigestRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryDigestRepo) GetSettings(_ context.Context, userID uuid.UUID) (*model.DigestSettings, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	s, ok := t.digestSettings[userID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return copyDigestSettings(s), nil
+}
+
+func (r *memoryDigestRepo) UpsertSettings(_ context.Context, settings *model.DigestSettings) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	stored := *copyDigestSettings(*settings)
+	stored.LastSentAt = nil
+	if existing, ok := t.digestSettings[settings.UserID]; ok {
+		stored.LastSentAt = existing.LastSentAt
+	}
+	t.digestSettings[settings.UserID] = stored
+	return nil
+}
+
+func (r *memoryDigestRepo) Reschedule(_ context.Context, userID uuid.UUID, next time.Time, sentAt *time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	s, ok := t.digestSettings[userID]
+	if !ok {
+		s = model.DigestSettings{UserID: userID, Enabled: true, Frequency: model.DefaultDigestFrequency, UpdatedAt: time.Now().UTC()}
+	}
+	if sentAt != nil {
+		sent := *sentAt
+		s.LastSentAt = &sent
+	}
+	s.NextDigestAt = &next
+	t.digestSettings[userID] = s
+	return nil
+}
+
+func (r *memoryDigestRepo) SetLastSeen(_ context.Context, userID uuid.UUID, at time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.users[userID]; ok {
+		t.lastSeen[userID] = at
+	}
+	return nil
+}
+
+// undigested reports whether d is a delivery ListDue and ListUndigested
+// consider, mirroring undigestedDeliveries.
+func (t *memoryTables) undigested(d model.MessageDelivery) (model.Message, bool) {
+	m, ok := t.messages[d.MessageID]
+	if !ok || d.ReadAt != nil || d.DigestedAt != nil {
+		return m, false
+	}
+	if seen, ok := t.lastSeen[d.UserID]; ok && !m.CreatedAt.After(seen) {
+		return m, false
+	}
+	for _, b := range t.blocks {
+		if b.BlockerID == d.UserID && b.BlockedID == m.SenderID {
+			return m, false
+		}
+	}
+	for _, p := range t.participants {
+		if p.ConversationID == m.ConversationID && p.UserID == d.UserID && p.LeftAt == nil {
+			return m, true
+		}
+	}
+	return m, false
+}
+
+func (r *memoryDigestRepo) ListDue(_ context.Context, now, seenBefore time.Time, limit int) ([]model.DigestRecipient, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	pending := make(map[uuid.UUID]bool)
+	for _, d := range t.deliveries {
+		if _, ok := t.undigested(d); ok {
+			pending[d.UserID] = true
+		}
+	}
+
+	recipients := []model.DigestRecipient{}
+	for id := range pending {
+		u, ok := t.users[id]
+		if !ok || u.Type != model.UserTypeHuman || u.Status == model.UserStatusDeleted {
+			continue
+		}
+		frequency := model.DefaultDigestFrequency
+		if s, ok := t.digestSettings[id]; ok {
+			if !s.Enabled || (s.NextDigestAt != nil && s.NextDigestAt.After(now)) {
+				continue
+			}
+			frequency = s.Frequency
+		}
+		if seen, ok := t.lastSeen[id]; ok && seen.After(seenBefore) {
+			continue
+		}
+		recipients = append(recipients, model.DigestRecipient{
+			UserID: id, Email: u.Email, DisplayName: u.DisplayName, Frequency: frequency,
+		})
+	}
+	slices.SortFunc(recipients, func(a, b model.DigestRecipient) int {
+		return strings.Compare(a.UserID.String(), b.UserID.String())
+	})
+	return recipients[:min(limit, len(recipients))], nil
+}
+
+func (r *memoryDigestRepo) ListUndigested(_ context.Context, userID uuid.UUID, limit int) ([]model.DigestMessage, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	msgs := []model.DigestMessage{}
+	for _, d := range t.deliveries {
+		if d.UserID != userID {
+			continue
+		}
+		m, ok := t.undigested(d)
+		if !ok {
+			continue
+		}
+		dm := model.DigestMessage{
+			DeliveryID:       d.ID,
+			MessageID:        m.ID,
+			ConversationID:   m.ConversationID,
+			ConversationName: t.conversations[m.ConversationID].Name,
+			SenderName:       t.users[m.SenderID].DisplayName,
+			Body:             m.Body,
+			CreatedAt:        m.CreatedAt,
+		}
+		if dm.ConversationName != nil {
+			name := *dm.ConversationName
+			dm.ConversationName = &name
+		}
+		msgs = append(msgs, dm)
+	}
+	slices.SortFunc(msgs, func(a, b model.DigestMessage) int {
+		return compareTimeID(a.CreatedAt, a.MessageID, b.CreatedAt, b.MessageID)
+	})
+	return msgs[:min(limit, len(msgs))], nil
+}
+
+func (r *memoryDigestRepo) MarkDigested(_ context.Context, deliveryIDs []uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	claimed := []uuid.UUID{}
+	for _, id := range deliveryIDs {
+		d, ok := t.deliveries[id]
+		if !ok || d.DigestedAt != nil {
+			continue
+		}
+		stamp := at
+		d.DigestedAt = &stamp
+		t.deliveries[id] = d
+		claimed = append(claimed, id)
+	}
+	return claimed, nil
+}
+
+func (r *memoryDigestRepo) UnmarkDigested(_ context.Context, deliveryIDs []uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, id := range deliveryIDs {
+		if d, ok := t.deliveries[id]; ok {
+			d.DigestedAt = nil
+			t.deliveries[id] = d
+		}
+	}
+	return nil
+}
+
+// copyDigestSettings returns a copy of s that shares no pointers with it.
+func copyDigestSettings(s model.DigestSettings) *model.DigestSettings {
+	if s.LastSentAt != nil {
+		at := *s.LastSentAt
+		s.LastSentAt = &at
+	}
+	if s.NextDigestAt != nil {
+		at := *s.NextDigestAt
+		s.NextDigestAt = &at
+	}
+	return &s
+}
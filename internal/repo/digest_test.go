@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestDigestRepo_Settings(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		if _, err := store.Digests.GetSettings(ctx, alice.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound without settings, got %v", err)
+		}
+
+		// Rescheduling a user without settings stores the defaults.
+		next := now.Add(24 * time.Hour)
+		if err := store.Digests.Reschedule(ctx, alice.ID, next, &now); err != nil {
+			t.Fatalf("reschedule: %v", err)
+		}
+		s, err := store.Digests.GetSettings(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !s.Enabled || s.Frequency != model.DefaultDigestFrequency || s.LastSentAt == nil || !s.LastSentAt.Equal(now) || !s.NextDigestAt.Equal(next) {
+			t.Errorf("unexpected settings after reschedule: %+v", s)
+		}
+
+		// Changing the preferences keeps when the last digest was sent.
+		weekly := now.Add(7 * 24 * time.Hour)
+		if err := store.Digests.UpsertSettings(ctx, &model.DigestSettings{
+			UserID: alice.ID, Enabled: false, Frequency: model.DigestWeekly, NextDigestAt: &weekly, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		s, err = store.Digests.GetSettings(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if s.Enabled || s.Frequency != model.DigestWeekly || s.LastSentAt == nil || !s.NextDigestAt.Equal(weekly) {
+			t.Errorf("unexpected settings after upsert: %+v", s)
+		}
+
+		// Rescheduling after a failed send leaves the last sent time alone.
+		if err := store.Digests.Reschedule(ctx, alice.ID, now.Add(time.Hour), nil); err != nil {
+			t.Fatalf("reschedule: %v", err)
+		}
+		if s, _ := store.Digests.GetSettings(ctx, alice.ID); s.LastSentAt == nil || !s.LastSentAt.Equal(now) {
+			t.Errorf("expected last sent time to be kept, got %+v", s)
+		}
+	})
+}
+
+func TestDigestRepo_DueAndUndigested(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		carol := createTestUser(t, store, "Carol")
+		convo := createTestConversation(t, store, alice.ID, bob.ID, carol.ID)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		// Bob was last seen before both messages, Carol after the first.
+		for _, seen := range []struct {
+			id uuid.UUID
+			at time.Time
+		}{{bob.ID, now.Add(-3 * time.Hour)}, {carol.ID, now.Add(-90 * time.Minute)}} {
+			if err := store.Digests.SetLastSeen(ctx, seen.id, seen.at); err != nil {
+				t.Fatalf("set last seen: %v", err)
+			}
+		}
+		first := newTestMessage(convo.ID, alice.ID, "first", now.Add(-2*time.Hour))
+		second := newTestMessage(convo.ID, alice.ID, "second", now.Add(-time.Hour))
+		for _, m := range []*model.Message{first, second} {
+			if err := store.Messages.Create(ctx, m); err != nil {
+				t.Fatalf("create message: %v", err)
+			}
+			if err := store.Messages.CreateDeliveries(ctx, m.ID, []uuid.UUID{bob.ID, carol.ID}); err != nil {
+				t.Fatalf("create deliveries: %v", err)
+			}
+		}
+
+		due, err := store.Digests.ListDue(ctx, now, now.Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatalf("list due: %v", err)
+		}
+		if len(due) != 2 {
+			t.Fatalf("expected bob and carol to be due, got %+v", due)
+		}
+		// Carol was seen too recently for a shorter offline period.
+		due, err = store.Digests.ListDue(ctx, now, now.Add(-2*time.Hour), 10)
+		if err != nil || len(due) != 1 || due[0].UserID != bob.ID || due[0].Frequency != model.DefaultDigestFrequency {
+			t.Fatalf("expected only bob to be due, got %+v, %v", due, err)
+		}
+
+		msgs, err := store.Digests.ListUndigested(ctx, bob.ID, 10)
+		if err != nil {
+			t.Fatalf("list undigested: %v", err)
+		}
+		if len(msgs) != 2 || msgs[0].MessageID != first.ID || msgs[1].MessageID != second.ID || msgs[0].SenderName != "Alice" {
+			t.Fatalf("expected both messages oldest first, got %+v", msgs)
+		}
+		if msgs, _ := store.Digests.ListUndigested(ctx, carol.ID, 10); len(msgs) != 1 || msgs[0].MessageID != second.ID {
+			t.Errorf("expected carol to get only the message since she was seen, got %+v", msgs)
+		}
+
+		// Messages from a sender the recipient blocked are left out.
+		if err := store.Moderation.Block(ctx, carol.ID, alice.ID); err != nil {
+			t.Fatalf("block: %v", err)
+		}
+		if msgs, err := store.Digests.ListUndigested(ctx, carol.ID, 10); err != nil || len(msgs) != 0 {
+			t.Errorf("expected nothing from a blocked sender, got %+v, %v", msgs, err)
+		}
+		if due, err := store.Digests.ListDue(ctx, now, now.Add(-time.Hour), 10); err != nil || len(due) != 1 || due[0].UserID != bob.ID {
+			t.Errorf("expected carol not to be due for blocked messages only, got %+v, %v", due, err)
+		}
+		if err := store.Moderation.Unblock(ctx, carol.ID, alice.ID); err != nil {
+			t.Fatalf("unblock: %v", err)
+		}
+
+		// A delivery is claimed once.
+		ids := []uuid.UUID{msgs[0].DeliveryID, msgs[1].DeliveryID}
+		claimed, err := store.Digests.MarkDigested(ctx, ids, now)
+		if err != nil || len(claimed) != 2 {
+			t.Fatalf("expected both deliveries claimed, got %v, %v", claimed, err)
+		}
+		if claimed, err := store.Digests.MarkDigested(ctx, ids, now); err != nil || len(claimed) != 0 {
+			t.Errorf("expected nothing left to claim, got %v, %v", claimed, err)
+		}
+		if msgs, _ := store.Digests.ListUndigested(ctx, bob.ID, 10); len(msgs) != 0 {
+			t.Errorf("expected no undigested messages, got %+v", msgs)
+		}
+
+		// Released deliveries are offered again.
+		if err := store.Digests.UnmarkDigested(ctx, ids[:1]); err != nil {
+			t.Fatalf("unmark: %v", err)
+		}
+		if msgs, _ := store.Digests.ListUndigested(ctx, bob.ID, 10); len(msgs) != 1 || msgs[0].MessageID != first.ID {
+			t.Errorf("expected the released message, got %+v", msgs)
+		}
+
+		// Disabled digests and digests not yet due are skipped.
+		later := now.Add(time.Hour)
+		if err := store.Digests.Reschedule(ctx, bob.ID, later, &now); err != nil {
+			t.Fatalf("reschedule: %v", err)
+		}
+		if err := store.Digests.UpsertSettings(ctx, &model.DigestSettings{
+			UserID: carol.ID, Enabled: false, Frequency: model.DigestDaily, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		if due, err := store.Digests.ListDue(ctx, now, now, 10); err != nil || len(due) != 0 {
+			t.Errorf("expected nobody due, got %+v, %v", due, err)
+		}
+		if due, err := store.Digests.ListDue(ctx, later, later, 10); err != nil || len(due) != 1 || due[0].UserID != bob.ID {
+			t.Errorf("expected bob due again, got %+v, %v", due, err)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// IdempotencyRepository defines the data access contract for idempotency keys.
+type IdempotencyRepository interface {
+	// Reserve claims rec's key for a new request, recording it as in progress.
+	// It returns nil if the caller now holds the key and the existing record
+	// otherwise. An in-progress record created before staleBefore is taken
+	// over, so that a request which died mid-flight does not block its
+	// retries until the record is pruned.
+	Reserve(ctx context.Context, rec *model.IdempotencyRecord, staleBefore time.Time) (*model.IdempotencyRecord, error)
+	// Complete stores the response of a reserved request. It returns
+	// ErrNotFound if the reservation has since been taken over.
+	Complete(ctx context.Context, rec *model.IdempotencyRecord) error
+	// Release drops a reservation without a response, letting the next
+	// request with the key run afresh.
+	Release(ctx context.Context, rec *model.IdempotencyRecord) error
+	// Prune removes records created before the given time, returning how many.
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+type idempotencyRepo struct {
+	db DBTX
+}
+
+// NewIdempotencyRepo creates a new IdempotencyRepository backed by the given database.
+func NewIdempotencyRepo(db DBTX) IdempotencyRepository {
+	return &idempotencyRepo{db: db}
+}
+
+func (r *idempotencyRepo) Reserve(ctx context.Context, rec *model.IdempotencyRecord, staleBefore time.Time) (*model.IdempotencyRecord, error) {
+	ctx = statement(ctx, "idempotencyRepo.Reserve")
+	// The conflict update only applies to an abandoned reservation; otherwise
+	// no row is returned and the existing record is read back.
+	query := `
+		INSERT INTO idempotency_keys (user_id, scope, idempotency_key, request_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, scope, idempotency_key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			created_at = excluded.created_at
+		WHERE idempotency_keys.status_code = 0 AND idempotency_keys.created_at < $6
+		RETURNING status_code
+	`
+	var status int
+	err := r.db.QueryRowContext(ctx, query, rec.UserID, rec.Scope, rec.Key, rec.RequestHash, rec.CreatedAt, staleBefore).Scan(&status)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: reserve idempotency key: %w", err)
+	}
+
+	existing := &model.IdempotencyRecord{}
+	var body string
+	err = r.db.QueryRowContext(ctx, `
+		SELECT user_id, scope, idempotency_key, request_hash, status_code, content_type, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND scope = $2 AND idempotency_key = $3
+	`, rec.UserID, rec.Scope, rec.Key).Scan(
+		&existing.UserID,
+		&existing.Scope,
+		&existing.Key,
+		&existing.RequestHash,
+		&existing.StatusCode,
+		&existing.ContentType,
+		&body,
+		&existing.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		// Pruned since the insert conflicted; the caller may simply retry.
+		return nil, fmt.Errorf("repo: reserve idempotency key: %w", model.ErrConflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get idempotency key: %w", err)
+	}
+	existing.Body = []byte(body)
+	return existing, nil
+}
+
+func (r *idempotencyRepo) Complete(ctx context.Context, rec *model.IdempotencyRecord) error {
+	ctx = statement(ctx, "idempotencyRepo.Complete")
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2, response_body = $3
+		WHERE user_id = $4 AND scope = $5 AND idempotency_key = $6 AND request_hash = $7 AND status_code = 0
+	`
+	res, err := r.db.ExecContext(ctx, query, rec.StatusCode, rec.ContentType, string(rec.Body),
+		rec.UserID, rec.Scope, rec.Key, rec.RequestHash)
+	if err != nil {
+		return fmt.Errorf("repo: complete idempotency key: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) Release(ctx context.Context, rec *model.IdempotencyRecord) error {
+	ctx = statement(ctx, "idempotencyRepo.Release")
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND scope = $2 AND idempotency_key = $3 AND request_hash = $4 AND status_code = 0
+	`
+	if _, err := r.db.ExecContext(ctx, query, rec.UserID, rec.Scope, rec.Key, rec.RequestHash); err != nil {
+		return fmt.Errorf("repo: release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) Prune(ctx context.Context, before time.Time) (int, error) {
+	ctx = statement(ctx, "idempotencyRepo.Prune")
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("repo: prune idempotency keys: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: prune idempotency keys: %w", err)
+	}
+	return int(n), nil
+}
+
+// idempotencyKey identifies a record in memoryTables.
+type idempotencyKey struct {
+	userID uuid.UUID
+	scope  string
+	key    string
+}
+
+type memoryIdempotencyRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryIdempotencyRepo) Reserve(_ context.Context, rec *model.IdempotencyRecord, staleBefore time.Time) (*model.IdempotencyRecord, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	k := idempotencyKey{rec.UserID, rec.Scope, rec.Key}
+	if existing, ok := t.idempotencyKeys[k]; ok &&
+		(existing.Completed() || !existing.CreatedAt.Before(staleBefore)) {
+		return &existing, nil
+	}
+	t.idempotencyKeys[k] = model.IdempotencyRecord{
+		UserID:      rec.UserID,
+		Scope:       rec.Scope,
+		Key:         rec.Key,
+		RequestHash: rec.RequestHash,
+		CreatedAt:   rec.CreatedAt,
+	}
+	return nil, nil
+}
+
+func (r *memoryIdempotencyRepo) Complete(_ context.Context, rec *model.IdempotencyRecord) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	k := idempotencyKey{rec.UserID, rec.Scope, rec.Key}
+	existing, ok := t.idempotencyKeys[k]
+	if !ok || existing.Completed() || existing.RequestHash != rec.RequestHash {
+		return model.ErrNotFound
+	}
+	existing.StatusCode = rec.StatusCode
+	existing.ContentType = rec.ContentType
+	existing.Body = rec.Body
+	t.idempotencyKeys[k] = existing
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) Release(_ context.Context, rec *model.IdempotencyRecord) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	k := idempotencyKey{rec.UserID, rec.Scope, rec.Key}
+	if existing, ok := t.idempotencyKeys[k]; ok && !existing.Completed() && existing.RequestHash == rec.RequestHash {
+		delete(t.idempotencyKeys, k)
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) Prune(_ context.Context, before time.Time) (int, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	n := 0
+	for k, rec := range t.idempotencyKeys {
+		if rec.CreatedAt.Before(before) {
+			delete(t.idempotencyKeys, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestIdempotencyRepo_ReserveAndComplete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.Idempotency
+		alice := createTestUser(t, store, "Alice")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		rec := &model.IdempotencyRecord{UserID: alice.ID, Scope: "POST /reports", Key: "k1", RequestHash: "h1", CreatedAt: now}
+		existing, err := repo.Reserve(ctx, rec, now.Add(-time.Minute))
+		if err != nil || existing != nil {
+			t.Fatalf("expected a fresh key to be reserved, got %+v, %v", existing, err)
+		}
+
+		// A concurrent retry sees the request in progress.
+		existing, err = repo.Reserve(ctx, rec, now.Add(-time.Minute))
+		if err != nil {
+			t.Fatalf("reserve: %v", err)
+		}
+		if existing == nil || existing.Completed() || existing.RequestHash != "h1" {
+			t.Fatalf("expected an in-progress record, got %+v", existing)
+		}
+
+		rec.StatusCode = 201
+		rec.ContentType = "application/json"
+		rec.Body = []byte(`{"id":"1"}`)
+		if err := repo.Complete(ctx, rec); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		if err := repo.Complete(ctx, rec); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected a second completion to be refused, got %v", err)
+		}
+
+		existing, err = repo.Reserve(ctx, rec, now.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("reserve: %v", err)
+		}
+		if existing == nil || existing.StatusCode != 201 || existing.ContentType != "application/json" ||
+			string(existing.Body) != `{"id":"1"}` {
+			t.Errorf("expected the stored response, even past the lock timeout, got %+v", existing)
+		}
+
+		// Keys are scoped to the user and the route.
+		other := *rec
+		other.Scope = "POST /conversations"
+		if existing, err := repo.Reserve(ctx, &other, now.Add(-time.Minute)); err != nil || existing != nil {
+			t.Errorf("expected the key to be free on another route, got %+v, %v", existing, err)
+		}
+	})
+}
+
+func TestIdempotencyRepo_ReleaseAndTakeover(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.Idempotency
+		alice := createTestUser(t, store, "Alice")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		rec := &model.IdempotencyRecord{UserID: alice.ID, Scope: "POST /reports", Key: "k1", RequestHash: "h1", CreatedAt: now}
+		if _, err := repo.Reserve(ctx, rec, now.Add(-time.Minute)); err != nil {
+			t.Fatalf("reserve: %v", err)
+		}
+		if err := repo.Release(ctx, rec); err != nil {
+			t.Fatalf("release: %v", err)
+		}
+		if existing, err := repo.Reserve(ctx, rec, now.Add(-time.Minute)); err != nil || existing != nil {
+			t.Fatalf("expected a released key to be free, got %+v, %v", existing, err)
+		}
+
+		// An abandoned reservation is taken over by a later request, and the
+		// original holder can no longer complete it.
+		later := &model.IdempotencyRecord{UserID: alice.ID, Scope: "POST /reports", Key: "k1", RequestHash: "h2", CreatedAt: now.Add(2 * time.Minute)}
+		if existing, err := repo.Reserve(ctx, later, now.Add(time.Minute)); err != nil || existing != nil {
+			t.Fatalf("expected the stale reservation to be taken over, got %+v, %v", existing, err)
+		}
+		rec.StatusCode = 201
+		if err := repo.Complete(ctx, rec); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected the superseded request's completion to be refused, got %v", err)
+		}
+	})
+}
+
+func TestIdempotencyRepo_Prune(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.Idempotency
+		alice := createTestUser(t, store, "Alice")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		for i, key := range []string{"old", "new"} {
+			rec := &model.IdempotencyRecord{UserID: alice.ID, Scope: "POST /reports", Key: key, RequestHash: "h",
+				CreatedAt: now.Add(time.Duration(i) * time.Hour)}
+			if _, err := repo.Reserve(ctx, rec, now.Add(-time.Minute)); err != nil {
+				t.Fatalf("reserve: %v", err)
+			}
+		}
+		n, err := repo.Prune(ctx, now.Add(30*time.Minute))
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 pruned record, got %d", n)
+		}
+	})
+}
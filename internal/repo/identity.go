@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// IdentityRepository defines the data access contract for external identity links.
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *model.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]model.UserIdentity, error)
+}
+
+type identityRepo struct {
+	db DBTX
+}
+
+// NewIdentityRepo creates a new IdentityRepository backed by the given database.
+func NewIdentityRepo(db DBTX) IdentityRepository {
+	return &identityRepo{db: db}
+}
+
+func (r *identityRepo) Create(ctx context.Context, identity *model.UserIdentity) error {
+	ctx = statement(ctx, "identityRepo.Create")
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		identity.ID,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create identity: %w", err)
+	}
+	return nil
+}
+
+func (r *identityRepo) GetByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	ctx = statement(ctx, "identityRepo.GetByProviderSubject")
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+	i := &model.UserIdentity{}
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get identity by provider subject: %w", err)
+	}
+	return i, nil
+}
+
+func (r *identityRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]model.UserIdentity, error) {
+	ctx = statement(ctx, "identityRepo.ListByUser")
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []model.UserIdentity
+	for rows.Next() {
+		var i model.UserIdentity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repo: scan identity: %w", err)
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+type memoryIdentityRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryIdentityRepo) Create(_ context.Context, identity *model.UserIdentity) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, i := range t.identities {
+		if i.ID == identity.ID || (i.Provider == identity.Provider && i.Subject == identity.Subject) {
+			return model.ErrConflict
+		}
+	}
+	t.identities[identity.ID] = *identity
+	return nil
+}
+
+func (r *memoryIdentityRepo) GetByProviderSubject(_ context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, i := range t.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *memoryIdentityRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]model.UserIdentity, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	var identities []model.UserIdentity
+	for _, i := range t.identities {
+		if i.UserID == userID {
+			identities = append(identities, i)
+		}
+	}
+	slices.SortFunc(identities, func(a, b model.UserIdentity) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return identities, nil
+}
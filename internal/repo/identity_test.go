@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestIdentityRepo_CreateAndLookup(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+
+		ident := &model.UserIdentity{
+			ID: uuid.New(), UserID: alice.ID, Provider: "google", Subject: "sub-1",
+			Email: alice.Email, CreatedAt: time.Now().UTC(),
+		}
+		if err := store.Identities.Create(ctx, ident); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		got, err := store.Identities.GetByProviderSubject(ctx, "google", "sub-1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if got.UserID != alice.ID {
+			t.Errorf("expected user %s, got %s", alice.ID, got.UserID)
+		}
+
+		dup := *ident
+		dup.ID = uuid.New()
+		if err := store.Identities.Create(ctx, &dup); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+
+		list, err := store.Identities.ListByUser(ctx, alice.ID)
+		if err != nil || len(list) != 1 {
+			t.Errorf("expected 1 identity, got %d, %v", len(list), err)
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// IntegrationRepository defines the data access contract for the bots that
+// integrations post as: incoming webhooks and HTTP slash commands. Deletes are
+// scoped to a conversation and report ErrNotFound for rows of another one.
+type IntegrationRepository interface {
+	CreateIncomingWebhook(ctx context.Context, hook *model.IncomingWebhook) error
+	GetIncomingWebhook(ctx context.Context, id uuid.UUID) (*model.IncomingWebhook, error)
+	ListIncomingWebhooks(ctx context.Context, conversationID uuid.UUID) ([]model.IncomingWebhook, error)
+	DeleteIncomingWebhook(ctx context.Context, conversationID, id uuid.UUID) error
+
+	CreateCommand(ctx context.Context, cmd *model.BotCommand) error
+	GetCommand(ctx context.Context, conversationID uuid.UUID, name string) (*model.BotCommand, error)
+	ListCommands(ctx context.Context, conversationID uuid.UUID) ([]model.BotCommand, error)
+	DeleteCommand(ctx context.Context, conversationID, id uuid.UUID) error
+}
+
+type integrationRepo struct {
+	db DBTX
+}
+
+// NewIntegrationRepo creates a new IntegrationRepository backed by the given database.
+func NewIntegrationRepo(db DBTX) IntegrationRepository {
+	return &integrationRepo{db: db}
+}
+
+func (r *integrationRepo) CreateIncomingWebhook(ctx context.Context, hook *model.IncomingWebhook) error {
+	ctx = statement(ctx, "integrationRepo.CreateIncomingWebhook")
+	query := `
+		INSERT INTO incoming_webhooks (id, conversation_id, bot_id, created_by, name, token_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		hook.ID,
+		hook.ConversationID,
+		hook.BotID,
+		hook.CreatedBy,
+		hook.Name,
+		hook.TokenHash,
+		hook.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create incoming webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *integrationRepo) GetIncomingWebhook(ctx context.Context, id uuid.UUID) (*model.IncomingWebhook, error) {
+	ctx = statement(ctx, "integrationRepo.GetIncomingWebhook")
+	query := `
+		SELECT id, conversation_id, bot_id, created_by, name, token_hash, created_at
+		FROM incoming_webhooks
+		WHERE id = $1
+	`
+	hook, err := scanIncomingWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get incoming webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (r *integrationRepo) ListIncomingWebhooks(ctx context.Context, conversationID uuid.UUID) ([]model.IncomingWebhook, error) {
+	ctx = statement(ctx, "integrationRepo.ListIncomingWebhooks")
+	query := `
+		SELECT id, conversation_id, bot_id, created_by, name, token_hash, created_at
+		FROM incoming_webhooks
+		WHERE conversation_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list incoming webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []model.IncomingWebhook{}
+	for rows.Next() {
+		hook, err := scanIncomingWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo: scan incoming webhook: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list incoming webhooks rows error: %w", err)
+	}
+	return hooks, nil
+}
+
+func (r *integrationRepo) DeleteIncomingWebhook(ctx context.Context, conversationID, id uuid.UUID) error {
+	ctx = statement(ctx, "integrationRepo.DeleteIncomingWebhook")
+	res, err := r.db.ExecContext(ctx, `DELETE FROM incoming_webhooks WHERE id = $1 AND conversation_id = $2`, id, conversationID)
+	if err != nil {
+		return fmt.Errorf("repo: delete incoming webhook: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *integrationRepo) CreateCommand(ctx context.Context, cmd *model.BotCommand) error {
+	ctx = statement(ctx, "integrationRepo.CreateCommand")
+	query := `
+		INSERT INTO bot_commands (id, conversation_id, bot_id, created_by, name, description, url, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		cmd.ID,
+		cmd.ConversationID,
+		cmd.BotID,
+		cmd.CreatedBy,
+		cmd.Name,
+		cmd.Description,
+		cmd.URL,
+		cmd.Secret,
+		cmd.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create bot command: %w", err)
+	}
+	return nil
+}
+
+func (r *integrationRepo) GetCommand(ctx context.Context, conversationID uuid.UUID, name string) (*model.BotCommand, error) {
+	ctx = statement(ctx, "integrationRepo.GetCommand")
+	query := `
+		SELECT id, conversation_id, bot_id, created_by, name, description, url, secret, created_at
+		FROM bot_commands
+		WHERE conversation_id = $1 AND name = $2
+	`
+	cmd, err := scanBotCommand(r.db.QueryRowContext(ctx, query, conversationID, name))
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get bot command: %w", err)
+	}
+	return cmd, nil
+}
+
+func (r *integrationRepo) ListCommands(ctx context.Context, conversationID uuid.UUID) ([]model.BotCommand, error) {
+	ctx = statement(ctx, "integrationRepo.ListCommands")
+	query := `
+		SELECT id, conversation_id, bot_id, created_by, name, description, url, secret, created_at
+		FROM bot_commands
+		WHERE conversation_id = $1
+		ORDER BY name
+	`
+	rows, err := r.db.QueryContext(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list bot commands: %w", err)
+	}
+	defer rows.Close()
+
+	cmds := []model.BotCommand{}
+	for rows.Next() {
+		cmd, err := scanBotCommand(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo: scan bot command: %w", err)
+		}
+		cmds = append(cmds, *cmd)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list bot commands rows error: %w", err)
+	}
+	return cmds, nil
+}
+
+func (r *integrationRepo) DeleteCommand(ctx context.Context, conversationID, id uuid.UUID) error {
+	ctx = statement(ctx, "integrationRepo.DeleteCommand")
+	res, err := r.db.ExecContext(ctx, `DELETE FROM bot_commands WHERE id = $1 AND conversation_id = $2`, id, conversationID)
+	if err != nil {
+		return fmt.Errorf("repo: delete bot command: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func scanIncomingWebhook(row rowScanner) (*model.IncomingWebhook, error) {
+	hook := &model.IncomingWebhook{}
+	err := row.Scan(
+		&hook.ID,
+		&hook.ConversationID,
+		&hook.BotID,
+		&hook.CreatedBy,
+		&hook.Name,
+		&hook.TokenHash,
+		&hook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func scanBotCommand(row rowScanner) (*model.BotCommand, error) {
+	cmd := &model.BotCommand{}
+	err := row.Scan(
+		&cmd.ID,
+		&cmd.ConversationID,
+		&cmd.BotID,
+		&cmd.CreatedBy,
+		&cmd.Name,
+		&cmd.Description,
+		&cmd.URL,
+		&cmd.Secret,
+		&cmd.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+type memoryIntegrationRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryIntegrationRepo) CreateIncomingWebhook(_ context.Context, hook *model.IncomingWebhook) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.incomingWebhooks[hook.ID]; ok {
+		return model.ErrConflict
+	}
+	for _, existing := range t.incomingWebhooks {
+		if existing.TokenHash == hook.TokenHash {
+			return model.ErrConflict
+		}
+	}
+	t.incomingWebhooks[hook.ID] = *hook
+	return nil
+}
+
+func (r *memoryIntegrationRepo) GetIncomingWebhook(_ context.Context, id uuid.UUID) (*model.IncomingWebhook, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	hook, ok := t.incomingWebhooks[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &hook, nil
+}
+
+func (r *memoryIntegrationRepo) ListIncomingWebhooks(_ context.Context, conversationID uuid.UUID) ([]model.IncomingWebhook, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	hooks := []model.IncomingWebhook{}
+	for _, hook := range t.incomingWebhooks {
+		if hook.ConversationID == conversationID {
+			hooks = append(hooks, hook)
+		}
+	}
+	slices.SortFunc(hooks, func(a, b model.IncomingWebhook) int {
+		return compareTimeID(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return hooks, nil
+}
+
+func (r *memoryIntegrationRepo) DeleteIncomingWebhook(_ context.Context, conversationID, id uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	hook, ok := t.incomingWebhooks[id]
+	if !ok || hook.ConversationID != conversationID {
+		return model.ErrNotFound
+	}
+	delete(t.incomingWebhooks, id)
+	return nil
+}
+
+func (r *memoryIntegrationRepo) CreateCommand(_ context.Context, cmd *model.BotCommand) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.botCommands[cmd.ID]; ok {
+		return model.ErrConflict
+	}
+	for _, existing := range t.botCommands {
+		if existing.ConversationID == cmd.ConversationID && existing.Name == cmd.Name {
+			return model.ErrConflict
+		}
+	}
+	t.botCommands[cmd.ID] = *cmd
+	return nil
+}
+
+func (r *memoryIntegrationRepo) GetCommand(_ context.Context, conversationID uuid.UUID, name string) (*model.BotCommand, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, cmd := range t.botCommands {
+		if cmd.ConversationID == conversationID && cmd.Name == name {
+			return &cmd, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *memoryIntegrationRepo) ListCommands(_ context.Context, conversationID uuid.UUID) ([]model.BotCommand, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	cmds := []model.BotCommand{}
+	for _, cmd := range t.botCommands {
+		if cmd.ConversationID == conversationID {
+			cmds = append(cmds, cmd)
+		}
+	}
+	slices.SortFunc(cmds, func(a, b model.BotCommand) int { return strings.Compare(a.Name, b.Name) })
+	return cmds, nil
+}
+
+func (r *memoryIntegrationRepo) DeleteCommand(_ context.Context, conversationID, id uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	cmd, ok := t.botCommands[id]
+	if !ok || cmd.ConversationID != conversationID {
+		return model.ErrNotFound
+	}
+	delete(t.botCommands, id)
+	return nil
+}
@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func createTestIncomingWebhook(t *testing.T, s *Store, conversationID, botID, createdBy uuid.UUID, tokenHash string) *model.IncomingWebhook {
+	t.Helper()
+	hook := &model.IncomingWebhook{
+		ID: uuid.New(), ConversationID: conversationID, BotID: botID, CreatedBy: createdBy,
+		Name: "CI", TokenHash: tokenHash, CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Integrations.CreateIncomingWebhook(context.Background(), hook); err != nil {
+		t.Fatalf("create incoming webhook: %v", err)
+	}
+	return hook
+}
+
+func TestIntegrationRepo_IncomingWebhooks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bot := createTestUser(t, store, "CI")
+		convo := createTestConversation(t, store, alice.ID)
+		other := createTestConversation(t, store, alice.ID)
+
+		hook := createTestIncomingWebhook(t, store, convo.ID, bot.ID, alice.ID, "hash-1")
+		createTestIncomingWebhook(t, store, other.ID, bot.ID, alice.ID, "hash-2")
+
+		dup := *hook
+		dup.ID = uuid.New()
+		if err := store.Integrations.CreateIncomingWebhook(ctx, &dup); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected a reused token hash to conflict, got %v", err)
+		}
+
+		got, err := store.Integrations.GetIncomingWebhook(ctx, hook.ID)
+		if err != nil || got.TokenHash != "hash-1" || got.BotID != bot.ID {
+			t.Fatalf("unexpected webhook: %+v, %v", got, err)
+		}
+		hooks, err := store.Integrations.ListIncomingWebhooks(ctx, convo.ID)
+		if err != nil || len(hooks) != 1 || hooks[0].ID != hook.ID {
+			t.Fatalf("expected the conversation's webhook only, got %+v, %v", hooks, err)
+		}
+
+		if err := store.Integrations.DeleteIncomingWebhook(ctx, other.ID, hook.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected a delete scoped to another conversation to miss, got %v", err)
+		}
+		if err := store.Integrations.DeleteIncomingWebhook(ctx, convo.ID, hook.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := store.Integrations.GetIncomingWebhook(ctx, hook.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+}
+
+func TestIntegrationRepo_Commands(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bot := createTestUser(t, store, "Deploy")
+		convo := createTestConversation(t, store, alice.ID)
+		other := createTestConversation(t, store, alice.ID)
+
+		newCommand := func(conversationID uuid.UUID, name string) *model.BotCommand {
+			return &model.BotCommand{
+				ID: uuid.New(), ConversationID: conversationID, BotID: bot.ID, CreatedBy: alice.ID,
+				Name: name, Description: "Ships it", URL: "https://example.com/cmd", Secret: "whsec_test",
+				CreatedAt: time.Now().UTC(),
+			}
+		}
+		deploy := newCommand(convo.ID, "deploy")
+		for _, cmd := range []*model.BotCommand{deploy, newCommand(convo.ID, "build"), newCommand(other.ID, "deploy")} {
+			if err := store.Integrations.CreateCommand(ctx, cmd); err != nil {
+				t.Fatalf("create command: %v", err)
+			}
+		}
+		if err := store.Integrations.CreateCommand(ctx, newCommand(convo.ID, "deploy")); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected a duplicate name to conflict, got %v", err)
+		}
+
+		got, err := store.Integrations.GetCommand(ctx, convo.ID, "deploy")
+		if err != nil || got.ID != deploy.ID || got.Secret != "whsec_test" {
+			t.Fatalf("unexpected command: %+v, %v", got, err)
+		}
+		cmds, err := store.Integrations.ListCommands(ctx, convo.ID)
+		if err != nil || len(cmds) != 2 || cmds[0].Name != "build" || cmds[1].Name != "deploy" {
+			t.Fatalf("expected the conversation's commands by name, got %+v, %v", cmds, err)
+		}
+
+		if err := store.Integrations.DeleteCommand(ctx, convo.ID, deploy.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := store.Integrations.GetCommand(ctx, convo.ID, "deploy"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+		if err := store.Integrations.DeleteCommand(ctx, convo.ID, deploy.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+		}
+	})
+}
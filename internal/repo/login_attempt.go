@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// LoginAttemptRepository defines the data access contract for failed-login counters.
+// RecordFailure must be atomic so that concurrent failures across replicas are all counted.
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, key string) (*model.LoginAttempt, error)
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*model.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type loginAttemptRepo struct {
+	db DBTX
+}
+
+// NewLoginAttemptRepo creates a LoginAttemptRepository backed by the given database.
+// Counters live in a shared table, so every replica sees the same lockouts.
+func NewLoginAttemptRepo(db DBTX) LoginAttemptRepository {
+	return &loginAttemptRepo{db: db}
+}
+
+func (r *loginAttemptRepo) Get(ctx context.Context, key string) (*model.LoginAttempt, error) {
+	ctx = statement(ctx, "loginAttemptRepo.Get")
+	query := `
+		SELECT throttle_key, failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE throttle_key = $1
+	`
+	a := &model.LoginAttempt{}
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&a.Key, &a.Failures, &a.LastFailureAt, &a.LockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get login attempt: %w", err)
+	}
+	return a, nil
+}
+
+func (r *loginAttemptRepo) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*model.LoginAttempt, error) {
+	ctx = statement(ctx, "loginAttemptRepo.RecordFailure")
+	// Failures older than the window are forgotten: the counter restarts at 1.
+	query := `
+		INSERT INTO login_attempts (throttle_key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (throttle_key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = $2
+		RETURNING throttle_key, failures, last_failure_at, locked_until
+	`
+	a := &model.LoginAttempt{}
+	err := r.db.QueryRowContext(ctx, query, key, now, now.Add(-window)).Scan(
+		&a.Key, &a.Failures, &a.LastFailureAt, &a.LockedUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("repo: record login failure: %w", err)
+	}
+	return a, nil
+}
+
+func (r *loginAttemptRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	ctx = statement(ctx, "loginAttemptRepo.Lock")
+	query := `
+		UPDATE login_attempts SET locked_until = $1
+		WHERE throttle_key = $2
+	`
+	if _, err := r.db.ExecContext(ctx, query, until, key); err != nil {
+		return fmt.Errorf("repo: lock login attempts: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepo) Reset(ctx context.Context, key string) error {
+	ctx = statement(ctx, "loginAttemptRepo.Reset")
+	query := `DELETE FROM login_attempts WHERE throttle_key = $1`
+	if _, err := r.db.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("repo: reset login attempts: %w", err)
+	}
+	return nil
+}
+
+type memoryLoginAttemptRepo struct {
+	db *memoryDB
+}
+
+// NewMemoryLoginAttemptRepo creates a process-local LoginAttemptRepository.
+// Suitable for a single replica; counters are lost on restart.
+func NewMemoryLoginAttemptRepo() LoginAttemptRepository {
+	return &memoryLoginAttemptRepo{db: newMemoryDB()}
+}
+
+func (r *memoryLoginAttemptRepo) Get(_ context.Context, key string) (*model.LoginAttempt, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	a, ok := t.loginAttempts[key]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r *memoryLoginAttemptRepo) RecordFailure(_ context.Context, key string, now time.Time, window time.Duration) (*model.LoginAttempt, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	a, ok := t.loginAttempts[key]
+	if !ok || a.LastFailureAt.Before(now.Add(-window)) {
+		a = model.LoginAttempt{Key: key, LockedUntil: a.LockedUntil}
+	}
+	a.Failures++
+	a.LastFailureAt = now
+	t.loginAttempts[key] = a
+	return &a, nil
+}
+
+func (r *memoryLoginAttemptRepo) Lock(_ context.Context, key string, until time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if a, ok := t.loginAttempts[key]; ok {
+		a.LockedUntil = &until
+		t.loginAttempts[key] = a
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepo) Reset(_ context.Context, key string) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	delete(t.loginAttempts, key)
+	return nil
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestLoginAttemptRepo_RecordFailure(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.LoginAttempts
+		window := 15 * time.Minute
+		now := time.Now().UTC().Truncate(time.Second)
+
+		for i := 1; i <= 3; i++ {
+			a, err := repo.RecordFailure(ctx, "account:a@example.com", now.Add(time.Duration(i)*time.Second), window)
+			if err != nil {
+				t.Fatalf("record failure: %v", err)
+			}
+			if a.Failures != i {
+				t.Fatalf("expected %d failures, got %d", i, a.Failures)
+			}
+		}
+
+		// A failure after the window has passed restarts the count.
+		a, err := repo.RecordFailure(ctx, "account:a@example.com", now.Add(window+time.Hour), window)
+		if err != nil {
+			t.Fatalf("record failure: %v", err)
+		}
+		if a.Failures != 1 {
+			t.Errorf("expected counter to restart at 1, got %d", a.Failures)
+		}
+	})
+}
+
+func TestLoginAttemptRepo_LockAndReset(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.LoginAttempts
+		now := time.Now().UTC()
+
+		if _, err := repo.RecordFailure(ctx, "ip:10.0.0.1", now, time.Minute); err != nil {
+			t.Fatalf("record failure: %v", err)
+		}
+		until := now.Add(time.Minute)
+		if err := repo.Lock(ctx, "ip:10.0.0.1", until); err != nil {
+			t.Fatalf("lock: %v", err)
+		}
+
+		a, err := repo.Get(ctx, "ip:10.0.0.1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if a.LockedUntil == nil || !a.LockedUntil.Equal(until) {
+			t.Errorf("expected locked until %v, got %v", until, a.LockedUntil)
+		}
+
+		if err := repo.Reset(ctx, "ip:10.0.0.1"); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		if _, err := repo.Get(ctx, "ip:10.0.0.1"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after reset, got %v", err)
+		}
+	})
+}
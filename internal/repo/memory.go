@@ -0,0 +1,316 @@
+package repo
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// memoryTables holds every row of an in-memory Store. Rows are stored by value
+// and never mutated in place, so a shallow copy of a map is a full snapshot
+// of its table.
+type memoryTables struct {
+	users         map[uuid.UUID]model.User
+	sessions      map[uuid.UUID]model.Session
+	conversations map[uuid.UUID]model.Conversation
+	participants  map[uuid.UUID]model.ConversationParticipant
+	messages      map[uuid.UUID]model.Message
+	deliveries    map[uuid.UUID]model.MessageDelivery
+	blocks        map[uuid.UUID]model.BlockedUser
+	reports       map[uuid.UUID]model.Report
+	identities    map[uuid.UUID]model.UserIdentity
+	loginAttempts map[string]model.LoginAttempt
+	rateLimits    map[string]time.Time
+	tokens        map[uuid.UUID]model.PersonalAccessToken
+
+	idempotencyKeys   map[idempotencyKey]model.IdempotencyRecord
+	webhooks          map[uuid.UUID]model.Webhook
+	webhookDeliveries map[uuid.UUID]model.WebhookDelivery
+	incomingWebhooks  map[uuid.UUID]model.IncomingWebhook
+	botCommands       map[uuid.UUID]model.BotCommand
+	reminders         map[uuid.UUID]model.Reminder
+	polls             map[uuid.UUID]model.Poll
+	pollVotes         map[pollVoteKey]int
+	deviceTokens      map[uuid.UUID]model.DeviceToken
+	mutes             map[muteKey]model.ConversationMute
+	digestSettings    map[uuid.UUID]model.DigestSettings
+	lastSeen          map[uuid.UUID]time.Time
+}
+
+func newMemoryTables() *memoryTables {
+	return &memoryTables{
+		users:         make(map[uuid.UUID]model.User),
+		sessions:      make(map[uuid.UUID]model.Session),
+		conversations: make(map[uuid.UUID]model.Conversation),
+		participants:  make(map[uuid.UUID]model.ConversationParticipant),
+		messages:      make(map[uuid.UUID]model.Message),
+		deliveries:    make(map[uuid.UUID]model.MessageDelivery),
+		blocks:        make(map[uuid.UUID]model.BlockedUser),
+		reports:       make(map[uuid.UUID]model.Report),
+		identities:    make(map[uuid.UUID]model.UserIdentity),
+		loginAttempts: make(map[string]model.LoginAttempt),
+		rateLimits:    make(map[string]time.Time),
+		tokens:        make(map[uuid.UUID]model.PersonalAccessToken),
+
+		idempotencyKeys:   make(map[idempotencyKey]model.IdempotencyRecord),
+		webhooks:          make(map[uuid.UUID]model.Webhook),
+		webhookDeliveries: make(map[uuid.UUID]model.WebhookDelivery),
+		incomingWebhooks:  make(map[uuid.UUID]model.IncomingWebhook),
+		botCommands:       make(map[uuid.UUID]model.BotCommand),
+		reminders:         make(map[uuid.UUID]model.Reminder),
+		polls:             make(map[uuid.UUID]model.Poll),
+		pollVotes:         make(map[pollVoteKey]int),
+		deviceTokens:      make(map[uuid.UUID]model.DeviceToken),
+		mutes:             make(map[muteKey]model.ConversationMute),
+		digestSettings:    make(map[uuid.UUID]model.DigestSettings),
+		lastSeen:          make(map[uuid.UUID]time.Time),
+	}
+}
+
+// memoryTable identifies one table of memoryTables.
+type memoryTable int
+
+const (
+	tableUsers memoryTable = iota
+	tableSessions
+	tableConversations
+	tableParticipants
+	tableMessages
+	tableDeliveries
+	tableBlocks
+	tableReports
+	tableIdentities
+	tableLoginAttempts
+	tableRateLimits
+	tableTokens
+	tableIdempotencyKeys
+	tableWebhooks
+	tableWebhookDeliveries
+	tableIncomingWebhooks
+	tableBotCommands
+	tableReminders
+	tablePolls
+	tablePollVotes
+	tableDeviceTokens
+	tableMutes
+	tableDigestSettings
+	tableLastSeen
+	memoryTableCount
+)
+
+var memoryTableNames = [memoryTableCount]string{
+	tableUsers:             "users",
+	tableSessions:          "sessions",
+	tableConversations:     "conversations",
+	tableParticipants:      "participants",
+	tableMessages:          "messages",
+	tableDeliveries:        "deliveries",
+	tableBlocks:            "blocks",
+	tableReports:           "reports",
+	tableIdentities:        "identities",
+	tableLoginAttempts:     "loginAttempts",
+	tableRateLimits:        "rateLimits",
+	tableTokens:            "tokens",
+	tableIdempotencyKeys:   "idempotencyKeys",
+	tableWebhooks:          "webhooks",
+	tableWebhookDeliveries: "webhookDeliveries",
+	tableIncomingWebhooks:  "incomingWebhooks",
+	tableBotCommands:       "botCommands",
+	tableReminders:         "reminders",
+	tablePolls:             "polls",
+	tablePollVotes:         "pollVotes",
+	tableDeviceTokens:      "deviceTokens",
+	tableMutes:             "mutes",
+	tableDigestSettings:    "digestSettings",
+	tableLastSeen:          "lastSeen",
+}
+
+func (t memoryTable) String() string {
+	if t < 0 || t >= memoryTableCount {
+		return fmt.Sprintf("memoryTable(%d)", int(t))
+	}
+	return memoryTableNames[t]
+}
+
+// detach replaces a table with a copy of itself and returns a function that
+// puts the original back.
+func (t *memoryTables) detach(table memoryTable) func() {
+	switch table {
+	case tableUsers:
+		return detachMap(&t.users)
+	case tableSessions:
+		return detachMap(&t.sessions)
+	case tableConversations:
+		return detachMap(&t.conversations)
+	case tableParticipants:
+		return detachMap(&t.participants)
+	case tableMessages:
+		return detachMap(&t.messages)
+	case tableDeliveries:
+		return detachMap(&t.deliveries)
+	case tableBlocks:
+		return detachMap(&t.blocks)
+	case tableReports:
+		return detachMap(&t.reports)
+	case tableIdentities:
+		return detachMap(&t.identities)
+	case tableLoginAttempts:
+		return detachMap(&t.loginAttempts)
+	case tableRateLimits:
+		return detachMap(&t.rateLimits)
+	case tableTokens:
+		return detachMap(&t.tokens)
+	case tableIdempotencyKeys:
+		return detachMap(&t.idempotencyKeys)
+	case tableWebhooks:
+		return detachMap(&t.webhooks)
+	case tableWebhookDeliveries:
+		return detachMap(&t.webhookDeliveries)
+	case tableIncomingWebhooks:
+		return detachMap(&t.incomingWebhooks)
+	case tableBotCommands:
+		return detachMap(&t.botCommands)
+	case tableReminders:
+		return detachMap(&t.reminders)
+	case tablePolls:
+		return detachMap(&t.polls)
+	case tablePollVotes:
+		return detachMap(&t.pollVotes)
+	case tableDeviceTokens:
+		return detachMap(&t.deviceTokens)
+	case tableMutes:
+		return detachMap(&t.mutes)
+	case tableDigestSettings:
+		return detachMap(&t.digestSettings)
+	case tableLastSeen:
+		return detachMap(&t.lastSeen)
+	}
+	panic("repo: unknown memory table " + table.String())
+}
+
+func detachMap[K comparable, V any](m *map[K]V) func() {
+	orig := *m
+	*m = maps.Clone(orig)
+	return func() { *m = orig }
+}
+
+// memoryDB is the shared state behind the in-memory repositories. A single
+// mutex guards all tables, which keeps cross-table operations consistent.
+//
+// Each repository gets its own handle naming the tables it writes. Within a
+// transaction those tables are copied the first time the repository locks
+// them, so that a rollback can put the originals back; tables the
+// transaction never writes are not copied.
+type memoryDB struct {
+	mu     *sync.Mutex
+	tables *memoryTables
+	writes []memoryTable // tables the repository holding this handle writes
+	tx     *memoryTx     // set within WithTx, whose caller already holds mu
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{mu: &sync.Mutex{}, tables: newMemoryTables()}
+}
+
+// writing returns a handle on the same database for a repository that
+// writes the given tables.
+func (m *memoryDB) writing(tables ...memoryTable) *memoryDB {
+	return &memoryDB{mu: m.mu, tables: m.tables, writes: tables, tx: m.tx}
+}
+
+// lock acquires the database for one repository call and returns the tables
+// along with the function that releases them.
+func (m *memoryDB) lock() (*memoryTables, func()) {
+	if m.tx != nil {
+		m.tx.detach(m.tables, m.writes)
+		return m.tables, func() {}
+	}
+	m.mu.Lock()
+	return m.tables, m.mu.Unlock
+}
+
+// memoryTx is the undo log of a memory transaction: the original of every
+// table it has written to.
+type memoryTx struct {
+	detached [memoryTableCount]bool
+	undo     []func()
+}
+
+func (tx *memoryTx) detach(t *memoryTables, tables []memoryTable) {
+	for _, table := range tables {
+		if !tx.detached[table] {
+			tx.detached[table] = true
+			tx.undo = append(tx.undo, t.detach(table))
+		}
+	}
+}
+
+func (tx *memoryTx) rollback() {
+	for _, undo := range slices.Backward(tx.undo) {
+		undo()
+	}
+}
+
+func newMemoryStore(db *memoryDB) *Store {
+	return &Store{
+		Users:         &memoryUserRepo{db: db.writing(tableUsers)},
+		Sessions:      &memorySessionRepo{db: db.writing(tableSessions)},
+		Conversations: &memoryConversationRepo{db: db.writing(tableConversations, tableParticipants)},
+		Messages:      &memoryMessageRepo{db: db.writing(tableMessages, tableDeliveries)},
+		Moderation:    &memoryModerationRepo{db: db.writing(tableBlocks, tableReports)},
+		Identities:    &memoryIdentityRepo{db: db.writing(tableIdentities)},
+		LoginAttempts: &memoryLoginAttemptRepo{db: db.writing(tableLoginAttempts)},
+		RateLimits:    &memoryRateLimitRepo{db: db.writing(tableRateLimits)},
+		Tokens:        &memoryTokenRepo{db: db.writing(tableTokens)},
+		Accounts:      &memoryAccountRepo{db: db.writing(tableUsers, tableSessions, tableParticipants, tableDeliveries, tableBlocks, tableIdentities, tableLoginAttempts, tableTokens, tableIdempotencyKeys, tableWebhooks, tableWebhookDeliveries, tableIncomingWebhooks, tableBotCommands, tableReminders, tableDeviceTokens, tableMutes, tableDigestSettings)},
+		Idempotency:   &memoryIdempotencyRepo{db: db.writing(tableIdempotencyKeys)},
+		Webhooks:      &memoryWebhookRepo{db: db.writing(tableWebhooks, tableWebhookDeliveries)},
+		Integrations:  &memoryIntegrationRepo{db: db.writing(tableIncomingWebhooks, tableBotCommands)},
+		Reminders:     &memoryReminderRepo{db: db.writing(tableReminders)},
+		Polls:         &memoryPollRepo{db: db.writing(tablePolls, tablePollVotes)},
+		Push:          &memoryPushRepo{db: db.writing(tableDeviceTokens, tableMutes)},
+		Digests:       &memoryDigestRepo{db: db.writing(tableDeliveries, tableDigestSettings, tableLastSeen)},
+		mem:           db,
+	}
+}
+
+// withTx runs fn while holding the database lock, so it sees no concurrent
+// writes, and restores the tables it wrote to if fn fails or panics.
+func (m *memoryDB) withTx(fn func(tx *Store) error) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(newMemoryStore(&memoryDB{mu: m.mu, tables: m.tables, tx: tx})); err != nil {
+		tx.rollback()
+		return err
+	}
+	return nil
+}
+
+// memoryPage builds a Page from items that are already filtered past the cursor
+// and sorted, mirroring the limit+1 lookahead of the SQL repositories.
+func memoryPage[T any](items []T, limit int, cursorOf func(T) string) *model.Page[T] {
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	page := &model.Page[T]{Items: append(make([]T, 0, len(items)), items...), HasMore: hasMore}
+	if hasMore && len(items) > 0 {
+		c := cursorOf(items[len(items)-1])
+		page.NextCursor = &c
+	}
+	return page
+}
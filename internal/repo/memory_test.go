@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestMemoryTx_CopiesOnlyWrittenTables checks that a memory transaction
+// copies the tables it writes and leaves the others shared.
+func TestMemoryTx_CopiesOnlyWrittenTables(t *testing.T) {
+	store, err := NewStore(MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	owner := createTestUser(t, store, "Owner")
+	tables := store.mem.tables
+	users, messages := reflect.ValueOf(tables.users).Pointer(), reflect.ValueOf(tables.messages).Pointer()
+
+	errBoom := errors.New("boom")
+	err = store.WithTx(ctx, func(tx *Store) error {
+		if _, err := tx.Users.GetByID(ctx, owner.ID); err != nil {
+			return err
+		}
+		if reflect.ValueOf(tables.users).Pointer() == users {
+			t.Error("expected the users table to be copied once the users repository is used")
+		}
+		if err := tx.Conversations.Create(ctx, newTestConversation(owner.ID)); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected errBoom, got %v", err)
+	}
+	if reflect.ValueOf(tables.users).Pointer() != users {
+		t.Error("expected the rollback to restore the original users table")
+	}
+	if reflect.ValueOf(tables.messages).Pointer() != messages {
+		t.Error("expected the untouched messages table not to be copied")
+	}
+	if len(tables.conversations) != 0 {
+		t.Errorf("expected the conversation to be rolled back, got %d", len(tables.conversations))
+	}
+}
+
+// TestMemoryStore_DeclaresWrittenTables checks each in-memory repository
+// against its source: every table one of its methods assigns to or deletes
+// from must be among those newMemoryStore says it writes, or a transaction
+// could not roll the write back.
+func TestMemoryStore_DeclaresWrittenTables(t *testing.T) {
+	declared := make(map[string][]string)
+	sv := reflect.ValueOf(newMemoryStore(newMemoryDB())).Elem()
+	for i := range sv.NumField() {
+		f := sv.Field(i)
+		if f.Kind() != reflect.Interface || f.IsNil() {
+			continue
+		}
+		r := f.Elem().Elem()
+		writes := r.FieldByName("db").Elem().FieldByName("writes")
+		for j := range writes.Len() {
+			declared[r.Type().Name()] = append(declared[r.Type().Name()], memoryTable(writes.Index(j).Int()).String())
+		}
+	}
+
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	checked := 0
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Body == nil {
+				continue
+			}
+			star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			recv, ok := star.X.(*ast.Ident)
+			if !ok || !strings.HasPrefix(recv.Name, "memory") || !strings.HasSuffix(recv.Name, "Repo") {
+				continue
+			}
+			checked++
+			for _, table := range writtenTables(fn.Body) {
+				if !slices.Contains(declared[recv.Name], table) {
+					t.Errorf("%s.%s writes %s, which newMemoryStore does not declare", recv.Name, fn.Name.Name, table)
+				}
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("found no memory repository methods")
+	}
+}
+
+// writtenTables returns the t.<table> fields that body assigns to, indexes
+// into on the left of an assignment, or deletes from.
+func writtenTables(body *ast.BlockStmt) []string {
+	var tables []string
+	table := func(e ast.Expr) {
+		if ix, ok := e.(*ast.IndexExpr); ok {
+			e = ix.X
+		}
+		if sel, ok := e.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == "t" {
+				tables = append(tables, sel.Sel.Name)
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				table(lhs)
+			}
+		case *ast.IncDecStmt:
+			table(n.X)
+		case *ast.CallExpr:
+			name := ""
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				name = fun.Name
+			case *ast.SelectorExpr:
+				name = fun.Sel.Name
+			}
+			if (name == "delete" || name == "DeleteFunc" || name == "clear") && len(n.Args) > 0 {
+				table(n.Args[0])
+			}
+		}
+		return true
+	})
+	return tables
+}
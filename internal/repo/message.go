@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,24 +16,28 @@ import (
 type MessageRepository interface {
 	Create(ctx context.Context, msg *model.Message) error
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Message, error)
+	// GetByClientID finds the message a sender created in a conversation with
+	// the given client message ID.
+	GetByClientID(ctx context.Context, conversationID uuid.UUID, senderID uuid.UUID, clientMessageID string) (*model.Message, error)
 	ListByConversation(ctx context.Context, conversationID uuid.UUID, cursor string, limit int) (*model.Page[model.Message], error)
 	CreateDeliveries(ctx context.Context, messageID uuid.UUID, userIDs []uuid.UUID) error
 	UpdateDeliveryStatus(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, status string) error
 }
 
 type messageRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewMessageRepo creates a new MessageRepository backed by the given database.
-func NewMessageRepo(db *sql.DB) MessageRepository {
+func NewMessageRepo(db DBTX) MessageRepository {
 	return &messageRepo{db: db}
 }
 
 func (r *messageRepo) Create(ctx context.Context, msg *model.Message) error {
+	ctx = statement(ctx, "messageRepo.Create")
 	query := `
-		INSERT INTO messages (id, conversation_id, sender_id, body, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO messages (id, conversation_id, sender_id, body, status, created_at, updated_at, client_message_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		msg.ID,
@@ -42,16 +47,21 @@ func (r *messageRepo) Create(ctx context.Context, msg *model.Message) error {
 		msg.Status,
 		msg.CreatedAt,
 		msg.UpdatedAt,
+		msg.ClientMessageID,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("repo: create message: %w", model.ErrConflict)
+		}
 		return fmt.Errorf("repo: create message: %w", err)
 	}
 	return nil
 }
 
 func (r *messageRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Message, error) {
+	ctx = statement(ctx, "messageRepo.GetByID")
 	query := `
-		SELECT id, conversation_id, sender_id, body, status, created_at, updated_at
+		SELECT id, conversation_id, sender_id, body, status, created_at, updated_at, client_message_id
 		FROM messages
 		WHERE id = $1
 	`
@@ -64,6 +74,7 @@ func (r *messageRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Message
 		&m.Status,
 		&m.CreatedAt,
 		&m.UpdatedAt,
+		&m.ClientMessageID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, model.ErrNotFound
@@ -74,7 +85,35 @@ func (r *messageRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Message
 	return m, nil
 }
 
+func (r *messageRepo) GetByClientID(ctx context.Context, conversationID uuid.UUID, senderID uuid.UUID, clientMessageID string) (*model.Message, error) {
+	ctx = statement(ctx, "messageRepo.GetByClientID")
+	query := `
+		SELECT id, conversation_id, sender_id, body, status, created_at, updated_at, client_message_id
+		FROM messages
+		WHERE conversation_id = $1 AND sender_id = $2 AND client_message_id = $3
+	`
+	m := &model.Message{}
+	err := r.db.QueryRowContext(ctx, query, conversationID, senderID, clientMessageID).Scan(
+		&m.ID,
+		&m.ConversationID,
+		&m.SenderID,
+		&m.Body,
+		&m.Status,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+		&m.ClientMessageID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get message by client id: %w", err)
+	}
+	return m, nil
+}
+
 func (r *messageRepo) ListByConversation(ctx context.Context, conversationID uuid.UUID, cursor string, limit int) (*model.Page[model.Message], error) {
+	ctx = statement(ctx, "messageRepo.ListByConversation")
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
@@ -97,7 +136,7 @@ func (r *messageRepo) ListByConversation(ctx context.Context, conversationID uui
 	args = append(args, fetchLimit)
 
 	query := fmt.Sprintf(`
-		SELECT id, conversation_id, sender_id, body, status, created_at, updated_at
+		SELECT id, conversation_id, sender_id, body, status, created_at, updated_at, client_message_id
 		FROM messages
 		WHERE conversation_id = $1%s
 		ORDER BY created_at DESC, id DESC
@@ -113,7 +152,7 @@ func (r *messageRepo) ListByConversation(ctx context.Context, conversationID uui
 	results := make([]model.Message, 0, limit)
 	for rows.Next() {
 		var m model.Message
-		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Body, &m.Status, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Body, &m.Status, &m.CreatedAt, &m.UpdatedAt, &m.ClientMessageID); err != nil {
 			return nil, fmt.Errorf("repo: scan message: %w", err)
 		}
 		results = append(results, m)
@@ -142,18 +181,20 @@ func (r *messageRepo) ListByConversation(ctx context.Context, conversationID uui
 }
 
 func (r *messageRepo) CreateDeliveries(ctx context.Context, messageID uuid.UUID, userIDs []uuid.UUID) error {
+	ctx = statement(ctx, "messageRepo.CreateDeliveries")
 	if len(userIDs) == 0 {
 		return nil
 	}
 
 	valueStrings := make([]string, 0, len(userIDs))
-	args := make([]any, 0, len(userIDs)*2)
+	args := make([]any, 0, len(userIDs)*3)
 	argIdx := 1
 
+	// IDs are generated here rather than by the database so the statement is portable.
 	for _, uid := range userIDs {
-		valueStrings = append(valueStrings, fmt.Sprintf("(gen_random_uuid(), $%d, $%d)", argIdx, argIdx+1))
-		args = append(args, messageID, uid)
-		argIdx += 2
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d)", argIdx, argIdx+1, argIdx+2))
+		args = append(args, uuid.New(), messageID, uid)
+		argIdx += 3
 	}
 
 	query := fmt.Sprintf(`
@@ -169,6 +210,7 @@ func (r *messageRepo) CreateDeliveries(ctx context.Context, messageID uuid.UUID,
 }
 
 func (r *messageRepo) UpdateDeliveryStatus(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, status string) error {
+	ctx = statement(ctx, "messageRepo.UpdateDeliveryStatus")
 	query := `
 		UPDATE message_deliveries
 		SET status = $1, delivered_at = $2
@@ -184,3 +226,134 @@ func (r *messageRepo) UpdateDeliveryStatus(ctx context.Context, messageID uuid.U
 	}
 	return nil
 }
+
+type memoryMessageRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryMessageRepo) Create(_ context.Context, msg *model.Message) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.messages[msg.ID]; ok {
+		return fmt.Errorf("repo: create message: %w", model.ErrConflict)
+	}
+	if msg.ClientMessageID != nil {
+		if _, ok := findMemoryMessageByClientID(t, msg.ConversationID, msg.SenderID, *msg.ClientMessageID); ok {
+			return fmt.Errorf("repo: create message: %w", model.ErrConflict)
+		}
+	}
+	t.messages[msg.ID] = *msg
+	return nil
+}
+
+func (r *memoryMessageRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Message, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	m, ok := t.messages[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (r *memoryMessageRepo) GetByClientID(_ context.Context, conversationID uuid.UUID, senderID uuid.UUID, clientMessageID string) (*model.Message, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	m, ok := findMemoryMessageByClientID(t, conversationID, senderID, clientMessageID)
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &m, nil
+}
+
+func findMemoryMessageByClientID(t *memoryTables, conversationID uuid.UUID, senderID uuid.UUID, clientMessageID string) (model.Message, bool) {
+	for _, m := range t.messages {
+		if m.ConversationID == conversationID && m.SenderID == senderID &&
+			m.ClientMessageID != nil && *m.ClientMessageID == clientMessageID {
+			return m, true
+		}
+	}
+	return model.Message{}, false
+}
+
+func (r *memoryMessageRepo) ListByConversation(_ context.Context, conversationID uuid.UUID, cursor string, limit int) (*model.Page[model.Message], error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	var (
+		cursorTime time.Time
+		cursorID   uuid.UUID
+	)
+	if cursor != "" {
+		var err error
+		cursorTime, cursorID, err = decodeTimeCursor(cursor)
+		if err != nil {
+			return nil, &model.ValidationError{Field: "cursor", Message: "invalid cursor"}
+		}
+	}
+
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	var msgs []model.Message
+	for _, m := range t.messages {
+		if m.ConversationID != conversationID {
+			continue
+		}
+		if cursor != "" && compareTimeID(m.CreatedAt, m.ID, cursorTime, cursorID) >= 0 {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	slices.SortFunc(msgs, func(a, b model.Message) int {
+		return compareTimeID(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+
+	return memoryPage(msgs, limit, func(m model.Message) string {
+		return encodeTimeCursor(m.CreatedAt, m.ID)
+	}), nil
+}
+
+func (r *memoryMessageRepo) CreateDeliveries(_ context.Context, messageID uuid.UUID, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	// Like the single SQL statement, either every delivery is created or none is.
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	for _, d := range t.deliveries {
+		if d.MessageID == messageID {
+			seen[d.UserID] = true
+		}
+	}
+	for _, uid := range userIDs {
+		if seen[uid] {
+			return fmt.Errorf("repo: create deliveries: %w", model.ErrConflict)
+		}
+		seen[uid] = true
+	}
+	for _, uid := range userIDs {
+		id := uuid.New()
+		t.deliveries[id] = model.MessageDelivery{ID: id, MessageID: messageID, UserID: uid, Status: "pending"}
+	}
+	return nil
+}
+
+func (r *memoryMessageRepo) UpdateDeliveryStatus(_ context.Context, messageID uuid.UUID, userID uuid.UUID, status string) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for id, d := range t.deliveries {
+		if d.MessageID == messageID && d.UserID == userID {
+			now := time.Now().UTC()
+			d.Status = status
+			d.DeliveredAt = &now
+			t.deliveries[id] = d
+			return nil
+		}
+	}
+	return model.ErrNotFound
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestMessageRepo_CreateAndGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+
+		msg := newTestMessage(convo.ID, alice.ID, "hello", time.Now().UTC())
+		if err := store.Messages.Create(ctx, msg); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		got, err := store.Messages.GetByID(ctx, msg.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if got.Body != "hello" || !got.CreatedAt.Equal(msg.CreatedAt) {
+			t.Errorf("unexpected message: %+v", got)
+		}
+
+		if _, err := store.Messages.GetByID(ctx, uuid.New()); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestMessageRepo_GetByClientID(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		convo := createTestConversation(t, store, alice.ID, bob.ID)
+
+		clientID := "c-1"
+		msg := newTestMessage(convo.ID, alice.ID, "hello", time.Now().UTC())
+		msg.ClientMessageID = &clientID
+		if err := store.Messages.Create(ctx, msg); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		got, err := store.Messages.GetByClientID(ctx, convo.ID, alice.ID, clientID)
+		if err != nil {
+			t.Fatalf("get by client id: %v", err)
+		}
+		if got.ID != msg.ID || got.ClientMessageID == nil || *got.ClientMessageID != clientID {
+			t.Errorf("unexpected message: %+v", got)
+		}
+		if _, err := store.Messages.GetByClientID(ctx, convo.ID, bob.ID, clientID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected another sender's lookup to miss, got %v", err)
+		}
+
+		dup := newTestMessage(convo.ID, alice.ID, "hello again", time.Now().UTC())
+		dup.ClientMessageID = &clientID
+		if err := store.Messages.Create(ctx, dup); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected a reused client message ID to conflict, got %v", err)
+		}
+
+		// Messages without a client ID never conflict with each other.
+		for i := 0; i < 2; i++ {
+			if err := store.Messages.Create(ctx, newTestMessage(convo.ID, alice.ID, "plain", time.Now().UTC())); err != nil {
+				t.Fatalf("create without client id: %v", err)
+			}
+		}
+	})
+}
+
+func TestMessageRepo_ListByConversationPaginates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+
+		// Mix whole-second and fractional timestamps: ordering must follow time, not text.
+		base := time.Now().UTC().Truncate(time.Second)
+		times := []time.Time{base, base.Add(500 * time.Millisecond), base.Add(time.Second), base.Add(1500 * time.Millisecond)}
+		for i, at := range times {
+			msg := newTestMessage(convo.ID, alice.ID, string(rune('a'+i)), at)
+			if err := store.Messages.Create(ctx, msg); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+		}
+
+		var bodies []string
+		cursor := ""
+		for {
+			page, err := store.Messages.ListByConversation(ctx, convo.ID, cursor, 3)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			for _, m := range page.Items {
+				bodies = append(bodies, m.Body)
+			}
+			if !page.HasMore {
+				break
+			}
+			cursor = *page.NextCursor
+		}
+
+		want := []string{"d", "c", "b", "a"}
+		if len(bodies) != len(want) {
+			t.Fatalf("expected %v, got %v", want, bodies)
+		}
+		for i := range want {
+			if bodies[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, bodies)
+			}
+		}
+	})
+}
+
+func TestMessageRepo_Deliveries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		carol := createTestUser(t, store, "Carol")
+		convo := createTestConversation(t, store, alice.ID, bob.ID, carol.ID)
+
+		msg := newTestMessage(convo.ID, alice.ID, "hello", time.Now().UTC())
+		if err := store.Messages.Create(ctx, msg); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if err := store.Messages.CreateDeliveries(ctx, msg.ID, []uuid.UUID{bob.ID, carol.ID}); err != nil {
+			t.Fatalf("create deliveries: %v", err)
+		}
+		for _, uid := range []uuid.UUID{bob.ID, carol.ID} {
+			if err := store.Messages.UpdateDeliveryStatus(ctx, msg.ID, uid, "delivered"); err != nil {
+				t.Errorf("update delivery status: %v", err)
+			}
+		}
+		if err := store.Messages.UpdateDeliveryStatus(ctx, msg.ID, alice.ID, "delivered"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for the sender, got %v", err)
+		}
+	})
+}
@@ -2,9 +2,9 @@ package repo
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kareempaes/planning/internal/model"
@@ -20,20 +20,22 @@ type ModerationRepository interface {
 }
 
 type moderationRepo struct {
-	db *sql.DB
+	db      DBTX
+	dialect Dialect
 }
 
 // NewModerationRepo creates a new ModerationRepository backed by the given database.
-func NewModerationRepo(db *sql.DB) ModerationRepository {
-	return &moderationRepo{db: db}
+func NewModerationRepo(db DBTX, dialect Dialect) ModerationRepository {
+	return &moderationRepo{db: db, dialect: dialect}
 }
 
 func (r *moderationRepo) Block(ctx context.Context, blockerID uuid.UUID, blockedID uuid.UUID) error {
-	query := `
+	ctx = statement(ctx, "moderationRepo.Block")
+	query := fmt.Sprintf(`
 		INSERT INTO blocked_users (id, blocker_id, blocked_id)
-		VALUES (gen_random_uuid(), $1, $2)
+		VALUES (%s, $1, $2)
 		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
-	`
+	`, r.dialect.NewUUID())
 	_, err := r.db.ExecContext(ctx, query, blockerID, blockedID)
 	if err != nil {
 		return fmt.Errorf("repo: block user: %w", err)
@@ -42,6 +44,7 @@ func (r *moderationRepo) Block(ctx context.Context, blockerID uuid.UUID, blocked
 }
 
 func (r *moderationRepo) Unblock(ctx context.Context, blockerID uuid.UUID, blockedID uuid.UUID) error {
+	ctx = statement(ctx, "moderationRepo.Unblock")
 	query := `
 		DELETE FROM blocked_users
 		WHERE blocker_id = $1 AND blocked_id = $2
@@ -54,6 +57,7 @@ func (r *moderationRepo) Unblock(ctx context.Context, blockerID uuid.UUID, block
 }
 
 func (r *moderationRepo) ListBlocked(ctx context.Context, blockerID uuid.UUID) ([]model.BlockedUser, error) {
+	ctx = statement(ctx, "moderationRepo.ListBlocked")
 	query := `
 		SELECT id, blocker_id, blocked_id, created_at
 		FROM blocked_users
@@ -78,6 +82,7 @@ func (r *moderationRepo) ListBlocked(ctx context.Context, blockerID uuid.UUID) (
 }
 
 func (r *moderationRepo) IsBlocked(ctx context.Context, blockerID uuid.UUID, blockedID uuid.UUID) (bool, error) {
+	ctx = statement(ctx, "moderationRepo.IsBlocked")
 	query := `
 		SELECT EXISTS(
 			SELECT 1 FROM blocked_users
@@ -93,6 +98,7 @@ func (r *moderationRepo) IsBlocked(ctx context.Context, blockerID uuid.UUID, blo
 }
 
 func (r *moderationRepo) CreateReport(ctx context.Context, report *model.Report) error {
+	ctx = statement(ctx, "moderationRepo.CreateReport")
 	query := `
 		INSERT INTO reports (id, reporter_id, target_type, target_id, reason, status, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -107,10 +113,75 @@ func (r *moderationRepo) CreateReport(ctx context.Context, report *model.Report)
 		report.CreatedAt,
 	)
 	if err != nil {
-		if strings.Contains(err.Error(), "unique") || strings.Contains(err.Error(), "duplicate") {
+		if isUniqueViolation(err) {
 			return model.ErrConflict
 		}
 		return fmt.Errorf("repo: create report: %w", err)
 	}
 	return nil
 }
+
+type memoryModerationRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryModerationRepo) Block(_ context.Context, blockerID uuid.UUID, blockedID uuid.UUID) error {
+	if blockerID == blockedID {
+		return fmt.Errorf("repo: block user: blocker and blocked must differ")
+	}
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, b := range t.blocks {
+		if b.BlockerID == blockerID && b.BlockedID == blockedID {
+			return nil
+		}
+	}
+	id := uuid.New()
+	t.blocks[id] = model.BlockedUser{ID: id, BlockerID: blockerID, BlockedID: blockedID, CreatedAt: time.Now().UTC()}
+	return nil
+}
+
+func (r *memoryModerationRepo) Unblock(_ context.Context, blockerID uuid.UUID, blockedID uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for id, b := range t.blocks {
+		if b.BlockerID == blockerID && b.BlockedID == blockedID {
+			delete(t.blocks, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryModerationRepo) ListBlocked(_ context.Context, blockerID uuid.UUID) ([]model.BlockedUser, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	var blocked []model.BlockedUser
+	for _, b := range t.blocks {
+		if b.BlockerID == blockerID {
+			blocked = append(blocked, b)
+		}
+	}
+	slices.SortFunc(blocked, func(a, b model.BlockedUser) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return blocked, nil
+}
+
+func (r *memoryModerationRepo) IsBlocked(_ context.Context, blockerID uuid.UUID, blockedID uuid.UUID) (bool, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, b := range t.blocks {
+		if b.BlockerID == blockerID && b.BlockedID == blockedID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryModerationRepo) CreateReport(_ context.Context, report *model.Report) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.reports[report.ID]; ok {
+		return model.ErrConflict
+	}
+	t.reports[report.ID] = *report
+	return nil
+}
@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestModerationRepo_BlockIsIdempotent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+
+		for range 2 {
+			if err := store.Moderation.Block(ctx, alice.ID, bob.ID); err != nil {
+				t.Fatalf("block: %v", err)
+			}
+		}
+
+		blocked, err := store.Moderation.ListBlocked(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("list blocked: %v", err)
+		}
+		if len(blocked) != 1 || blocked[0].BlockedID != bob.ID {
+			t.Fatalf("expected bob to be blocked once, got %+v", blocked)
+		}
+		if blocked[0].ID == uuid.Nil {
+			t.Errorf("expected a generated block id, got %v", blocked[0].ID)
+		}
+
+		ok, err := store.Moderation.IsBlocked(ctx, alice.ID, bob.ID)
+		if err != nil || !ok {
+			t.Errorf("expected alice to block bob, got %v, %v", ok, err)
+		}
+		ok, err = store.Moderation.IsBlocked(ctx, bob.ID, alice.ID)
+		if err != nil || ok {
+			t.Errorf("expected block to be one-directional, got %v, %v", ok, err)
+		}
+	})
+}
+
+func TestModerationRepo_Unblock(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+
+		if err := store.Moderation.Block(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("block: %v", err)
+		}
+		if err := store.Moderation.Unblock(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("unblock: %v", err)
+		}
+
+		ok, err := store.Moderation.IsBlocked(ctx, alice.ID, bob.ID)
+		if err != nil || ok {
+			t.Errorf("expected no block, got %v, %v", ok, err)
+		}
+	})
+}
+
+func TestModerationRepo_CreateReport(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+
+		report := &model.Report{
+			ID: uuid.New(), ReporterID: alice.ID, TargetType: "user", TargetID: bob.ID,
+			Reason: "spam", Status: "pending", CreatedAt: time.Now().UTC(),
+		}
+		ctx := context.Background()
+		if err := store.Moderation.CreateReport(ctx, report); err != nil {
+			t.Fatalf("create report: %v", err)
+		}
+
+		exp, err := store.Accounts.Export(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("export: %v", err)
+		}
+		if len(exp.Reports) != 1 || exp.Reports[0].TargetID != bob.ID {
+			t.Errorf("expected the report, got %+v", exp.Reports)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// PollRepository defines the data access contract for polls started with
+// /poll and their votes.
+type PollRepository interface {
+	Create(ctx context.Context, poll *model.Poll) error
+	// GetLatest returns the conversation's most recent poll.
+	GetLatest(ctx context.Context, conversationID uuid.UUID) (*model.Poll, error)
+	// Vote records a user's choice, an index into the poll's options,
+	// replacing any earlier vote of theirs.
+	Vote(ctx context.Context, pollID, userID uuid.UUID, choice int, at time.Time) error
+	// Tally returns the number of votes for each choice that has any.
+	Tally(ctx context.Context, pollID uuid.UUID) (map[int]int, error)
+}
+
+type pollRepo struct {
+	db DBTX
+}
+
+// NewPollRepo creates a new PollRepository backed by the given database.
+func NewPollRepo(db DBTX) PollRepository {
+	return &pollRepo{db: db}
+}
+
+func (r *pollRepo) Create(ctx context.Context, poll *model.Poll) error {
+	ctx = statement(ctx, "pollRepo.Create")
+	options, err := json.Marshal(poll.Options)
+	if err != nil {
+		return fmt.Errorf("repo: create poll: encode options: %w", err)
+	}
+	query := `
+		INSERT INTO polls (id, conversation_id, created_by, question, options, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		poll.ID,
+		poll.ConversationID,
+		poll.CreatedBy,
+		poll.Question,
+		string(options),
+		poll.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create poll: %w", err)
+	}
+	return nil
+}
+
+func (r *pollRepo) GetLatest(ctx context.Context, conversationID uuid.UUID) (*model.Poll, error) {
+	ctx = statement(ctx, "pollRepo.GetLatest")
+	query := `
+		SELECT id, conversation_id, created_by, question, options, created_at
+		FROM polls
+		WHERE conversation_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+	poll := &model.Poll{}
+	var options string
+	err := r.db.QueryRowContext(ctx, query, conversationID).Scan(
+		&poll.ID,
+		&poll.ConversationID,
+		&poll.CreatedBy,
+		&poll.Question,
+		&options,
+		&poll.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get latest poll: %w", err)
+	}
+	if err := json.Unmarshal([]byte(options), &poll.Options); err != nil {
+		return nil, fmt.Errorf("repo: get latest poll: decode options: %w", err)
+	}
+	return poll, nil
+}
+
+func (r *pollRepo) Vote(ctx context.Context, pollID, userID uuid.UUID, choice int, at time.Time) error {
+	ctx = statement(ctx, "pollRepo.Vote")
+	query := `
+		INSERT INTO poll_votes (poll_id, user_id, choice, voted_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (poll_id, user_id) DO UPDATE SET
+			choice = excluded.choice,
+			voted_at = excluded.voted_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, pollID, userID, choice, at); err != nil {
+		return fmt.Errorf("repo: vote: %w", err)
+	}
+	return nil
+}
+
+func (r *pollRepo) Tally(ctx context.Context, pollID uuid.UUID) (map[int]int, error) {
+	ctx = statement(ctx, "pollRepo.Tally")
+	query := `
+		SELECT choice, COUNT(*)
+		FROM poll_votes
+		WHERE poll_id = $1
+		GROUP BY choice
+	`
+	rows, err := r.db.QueryContext(ctx, query, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: tally poll: %w", err)
+	}
+	defer rows.Close()
+
+	tally := map[int]int{}
+	for rows.Next() {
+		var choice, votes int
+		if err := rows.Scan(&choice, &votes); err != nil {
+			return nil, fmt.Errorf("repo: scan poll tally: %w", err)
+		}
+		tally[choice] = votes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: tally poll rows error: %w", err)
+	}
+	return tally, nil
+}
+
+// pollVoteKey identifies a vote in the in-memory store.
+type pollVoteKey struct {
+	pollID, userID uuid.UUID
+}
+
+type memoryPollRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryPollRepo) Create(_ context.Context, poll *model.Poll) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.polls[poll.ID]; ok {
+		return model.ErrConflict
+	}
+	stored := *poll
+	stored.Options = slices.Clone(poll.Options)
+	t.polls[poll.ID] = stored
+	return nil
+}
+
+func (r *memoryPollRepo) GetLatest(_ context.Context, conversationID uuid.UUID) (*model.Poll, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	var latest *model.Poll
+	for _, poll := range t.polls {
+		if poll.ConversationID != conversationID {
+			continue
+		}
+		if latest == nil || compareTimeID(poll.CreatedAt, poll.ID, latest.CreatedAt, latest.ID) > 0 {
+			latest = &poll
+		}
+	}
+	if latest == nil {
+		return nil, model.ErrNotFound
+	}
+	latest.Options = slices.Clone(latest.Options)
+	return latest, nil
+}
+
+func (r *memoryPollRepo) Vote(_ context.Context, pollID, userID uuid.UUID, choice int, _ time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.polls[pollID]; !ok {
+		return fmt.Errorf("repo: vote: unknown poll %s", pollID)
+	}
+	t.pollVotes[pollVoteKey{pollID: pollID, userID: userID}] = choice
+	return nil
+}
+
+func (r *memoryPollRepo) Tally(_ context.Context, pollID uuid.UUID) (map[int]int, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	tally := map[int]int{}
+	for key, choice := range t.pollVotes {
+		if key.pollID == pollID {
+			tally[choice]++
+		}
+	}
+	return tally, nil
+}
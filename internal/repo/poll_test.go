@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestPollRepo_VoteAndTally(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		convo := createTestConversation(t, store, alice.ID)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		if _, err := store.Polls.GetLatest(ctx, convo.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound without polls, got %v", err)
+		}
+
+		var latest *model.Poll
+		for i, question := range []string{"Lunch?", "Dinner?"} {
+			latest = &model.Poll{
+				ID: uuid.New(), ConversationID: convo.ID, CreatedBy: alice.ID,
+				Question: question, Options: []string{"Pizza", "Tacos"}, CreatedAt: now.Add(time.Duration(i) * time.Minute),
+			}
+			if err := store.Polls.Create(ctx, latest); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+		}
+
+		got, err := store.Polls.GetLatest(ctx, convo.ID)
+		if err != nil || got.ID != latest.ID || len(got.Options) != 2 || got.Options[1] != "Tacos" {
+			t.Fatalf("expected the latest poll, got %+v, %v", got, err)
+		}
+
+		for _, vote := range []struct {
+			user   uuid.UUID
+			choice int
+		}{{alice.ID, 0}, {bob.ID, 0}, {bob.ID, 1}} {
+			if err := store.Polls.Vote(ctx, latest.ID, vote.user, vote.choice, now); err != nil {
+				t.Fatalf("vote: %v", err)
+			}
+		}
+		tally, err := store.Polls.Tally(ctx, latest.ID)
+		if err != nil || !maps.Equal(tally, map[int]int{0: 1, 1: 1}) {
+			t.Errorf("expected a changed vote to replace the first, got %v, %v", tally, err)
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// PushRepository defines the data access contract for push notification
+// devices and the conversation mutes that silence them.
+type PushRepository interface {
+	// UpsertDevice registers a device token for its user. A token already
+	// registered, by anyone, is moved to the user and keeps its ID; device is
+	// updated with the stored ID and creation time.
+	UpsertDevice(ctx context.Context, device *model.DeviceToken) error
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]model.DeviceToken, error)
+	DeleteDevice(ctx context.Context, userID, id uuid.UUID) error
+	// DeleteDeviceToken forgets a token the provider reported as no longer
+	// valid. Deleting an unknown token is not an error.
+	DeleteDeviceToken(ctx context.Context, platform, token string) error
+
+	// SetMute creates or replaces a user's mute of a conversation.
+	SetMute(ctx context.Context, mute *model.ConversationMute) error
+	GetMute(ctx context.Context, conversationID, userID uuid.UUID) (*model.ConversationMute, error)
+	DeleteMute(ctx context.Context, conversationID, userID uuid.UUID) error
+}
+
+type pushRepo struct {
+	db DBTX
+}
+
+// NewPushRepo creates a new PushRepository backed by the given database.
+func NewPushRepo(db DBTX) PushRepository {
+	return &pushRepo{db: db}
+}
+
+func (r *pushRepo) UpsertDevice(ctx context.Context, device *model.DeviceToken) error {
+	ctx = statement(ctx, "pushRepo.UpsertDevice")
+	query := `
+		INSERT INTO device_tokens (id, user_id, platform, token, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (platform, token) DO UPDATE SET
+			user_id = excluded.user_id,
+			updated_at = excluded.updated_at
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		device.ID,
+		device.UserID,
+		device.Platform,
+		device.Token,
+		device.CreatedAt,
+		device.UpdatedAt,
+	).Scan(&device.ID, &device.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repo: upsert device: %w", err)
+	}
+	return nil
+}
+
+func (r *pushRepo) ListDevices(ctx context.Context, userID uuid.UUID) ([]model.DeviceToken, error) {
+	ctx = statement(ctx, "pushRepo.ListDevices")
+	query := `
+		SELECT id, user_id, platform, token, created_at, updated_at
+		FROM device_tokens
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []model.DeviceToken{}
+	for rows.Next() {
+		var d model.DeviceToken
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("repo: scan device: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list devices rows error: %w", err)
+	}
+	return devices, nil
+}
+
+func (r *pushRepo) DeleteDevice(ctx context.Context, userID, id uuid.UUID) error {
+	ctx = statement(ctx, "pushRepo.DeleteDevice")
+	res, err := r.db.ExecContext(ctx, `DELETE FROM device_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("repo: delete device: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *pushRepo) DeleteDeviceToken(ctx context.Context, platform, token string) error {
+	ctx = statement(ctx, "pushRepo.DeleteDeviceToken")
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM device_tokens WHERE platform = $1 AND token = $2`, platform, token); err != nil {
+		return fmt.Errorf("repo: delete device token: %w", err)
+	}
+	return nil
+}
+
+func (r *pushRepo) SetMute(ctx context.Context, mute *model.ConversationMute) error {
+	ctx = statement(ctx, "pushRepo.SetMute")
+	query := `
+		INSERT INTO conversation_mutes (conversation_id, user_id, muted_until, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (conversation_id, user_id) DO UPDATE SET
+			muted_until = excluded.muted_until,
+			created_at = excluded.created_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, mute.ConversationID, mute.UserID, mute.MutedUntil, mute.CreatedAt); err != nil {
+		return fmt.Errorf("repo: set mute: %w", err)
+	}
+	return nil
+}
+
+func (r *pushRepo) GetMute(ctx context.Context, conversationID, userID uuid.UUID) (*model.ConversationMute, error) {
+	ctx = statement(ctx, "pushRepo.GetMute")
+	query := `
+		SELECT conversation_id, user_id, muted_until, created_at
+		FROM conversation_mutes
+		WHERE conversation_id = $1 AND user_id = $2
+	`
+	m := &model.ConversationMute{}
+	var until sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, conversationID, userID).Scan(&m.ConversationID, &m.UserID, &until, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get mute: %w", err)
+	}
+	if until.Valid {
+		m.MutedUntil = &until.Time
+	}
+	return m, nil
+}
+
+func (r *pushRepo) DeleteMute(ctx context.Context, conversationID, userID uuid.UUID) error {
+	ctx = statement(ctx, "pushRepo.DeleteMute")
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM conversation_mutes WHERE conversation_id = $1 AND user_id = $2`, conversationID, userID); err != nil {
+		return fmt.Errorf("repo: delete mute: %w", err)
+	}
+	return nil
+}
+
+// muteKey identifies a mute in the in-memory store.
+type muteKey struct {
+	conversationID, userID uuid.UUID
+}
+
+type memoryPushRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryPushRepo) UpsertDevice(_ context.Context, device *model.DeviceToken) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for id, existing := range t.deviceTokens {
+		if existing.Platform == device.Platform && existing.Token == device.Token {
+			existing.UserID = device.UserID
+			existing.UpdatedAt = device.UpdatedAt
+			t.deviceTokens[id] = existing
+			device.ID, device.CreatedAt = existing.ID, existing.CreatedAt
+			return nil
+		}
+	}
+	t.deviceTokens[device.ID] = *device
+	return nil
+}
+
+func (r *memoryPushRepo) ListDevices(_ context.Context, userID uuid.UUID) ([]model.DeviceToken, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	devices := []model.DeviceToken{}
+	for _, d := range t.deviceTokens {
+		if d.UserID == userID {
+			devices = append(devices, d)
+		}
+	}
+	slices.SortFunc(devices, func(a, b model.DeviceToken) int {
+		return compareTimeID(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return devices, nil
+}
+
+func (r *memoryPushRepo) DeleteDevice(_ context.Context, userID, id uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	d, ok := t.deviceTokens[id]
+	if !ok || d.UserID != userID {
+		return model.ErrNotFound
+	}
+	delete(t.deviceTokens, id)
+	return nil
+}
+
+func (r *memoryPushRepo) DeleteDeviceToken(_ context.Context, platform, token string) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for id, d := range t.deviceTokens {
+		if d.Platform == platform && d.Token == token {
+			delete(t.deviceTokens, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryPushRepo) SetMute(_ context.Context, mute *model.ConversationMute) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	stored := *mute
+	if mute.MutedUntil != nil {
+		until := *mute.MutedUntil
+		stored.MutedUntil = &until
+	}
+	t.mutes[muteKey{conversationID: mute.ConversationID, userID: mute.UserID}] = stored
+	return nil
+}
+
+func (r *memoryPushRepo) GetMute(_ context.Context, conversationID, userID uuid.UUID) (*model.ConversationMute, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	m, ok := t.mutes[muteKey{conversationID: conversationID, userID: userID}]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	if m.MutedUntil != nil {
+		until := *m.MutedUntil
+		m.MutedUntil = &until
+	}
+	return &m, nil
+}
+
+func (r *memoryPushRepo) DeleteMute(_ context.Context, conversationID, userID uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	delete(t.mutes, muteKey{conversationID: conversationID, userID: userID})
+	return nil
+}
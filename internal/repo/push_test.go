@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func newTestDevice(userID uuid.UUID, platform, token string) *model.DeviceToken {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &model.DeviceToken{ID: uuid.New(), UserID: userID, Platform: platform, Token: token, CreatedAt: now, UpdatedAt: now}
+}
+
+func TestPushRepo_Devices(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+
+		phone := newTestDevice(alice.ID, model.PlatformAPNs, "apns-1")
+		if err := store.Push.UpsertDevice(ctx, phone); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+		if err := store.Push.UpsertDevice(ctx, newTestDevice(alice.ID, model.PlatformFCM, "fcm-1")); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+
+		// The same token registered by another user moves to them.
+		moved := newTestDevice(bob.ID, model.PlatformAPNs, "apns-1")
+		if err := store.Push.UpsertDevice(ctx, moved); err != nil {
+			t.Fatalf("upsert existing: %v", err)
+		}
+		if moved.ID != phone.ID {
+			t.Errorf("expected the existing ID %s, got %s", phone.ID, moved.ID)
+		}
+		devices, err := store.Push.ListDevices(ctx, alice.ID)
+		if err != nil || len(devices) != 1 || devices[0].Platform != model.PlatformFCM {
+			t.Errorf("expected only alice's FCM device, got %+v, %v", devices, err)
+		}
+		devices, err = store.Push.ListDevices(ctx, bob.ID)
+		if err != nil || len(devices) != 1 || devices[0].ID != phone.ID {
+			t.Errorf("expected bob to own the moved device, got %+v, %v", devices, err)
+		}
+
+		if err := store.Push.DeleteDevice(ctx, alice.ID, phone.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound deleting another user's device, got %v", err)
+		}
+		if err := store.Push.DeleteDevice(ctx, bob.ID, phone.ID); err != nil {
+			t.Errorf("delete: %v", err)
+		}
+		if err := store.Push.DeleteDeviceToken(ctx, model.PlatformFCM, "fcm-1"); err != nil {
+			t.Errorf("delete token: %v", err)
+		}
+		if err := store.Push.DeleteDeviceToken(ctx, model.PlatformFCM, "fcm-1"); err != nil {
+			t.Errorf("expected deleting an unknown token to succeed, got %v", err)
+		}
+		if devices, _ := store.Push.ListDevices(ctx, alice.ID); len(devices) != 0 {
+			t.Errorf("expected no devices left, got %+v", devices)
+		}
+	})
+}
+
+func TestPushRepo_Mutes(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		if _, err := store.Push.GetMute(ctx, convo.ID, alice.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound without a mute, got %v", err)
+		}
+
+		until := now.Add(time.Hour)
+		if err := store.Push.SetMute(ctx, &model.ConversationMute{ConversationID: convo.ID, UserID: alice.ID, MutedUntil: &until, CreatedAt: now}); err != nil {
+			t.Fatalf("set mute: %v", err)
+		}
+		got, err := store.Push.GetMute(ctx, convo.ID, alice.ID)
+		if err != nil || got.MutedUntil == nil || !got.MutedUntil.Equal(until) {
+			t.Fatalf("expected a mute until %v, got %+v, %v", until, got, err)
+		}
+		if !got.ActiveAt(now) || got.ActiveAt(until) {
+			t.Errorf("expected the mute to lapse at %v", until)
+		}
+
+		if err := store.Push.SetMute(ctx, &model.ConversationMute{ConversationID: convo.ID, UserID: alice.ID, CreatedAt: now}); err != nil {
+			t.Fatalf("replace mute: %v", err)
+		}
+		got, err = store.Push.GetMute(ctx, convo.ID, alice.ID)
+		if err != nil || got.MutedUntil != nil {
+			t.Errorf("expected an indefinite mute, got %+v, %v", got, err)
+		}
+
+		if err := store.Push.DeleteMute(ctx, convo.ID, alice.ID); err != nil {
+			t.Fatalf("delete mute: %v", err)
+		}
+		if err := store.Push.DeleteMute(ctx, convo.ID, alice.ID); err != nil {
+			t.Errorf("expected deleting a missing mute to succeed, got %v", err)
+		}
+		if _, err := store.Push.GetMute(ctx, convo.ID, alice.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after unmuting, got %v", err)
+		}
+	})
+}
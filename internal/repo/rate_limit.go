@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RateLimitRepository defines the data access contract for rate limit buckets.
+// A bucket is kept as its theoretical arrival time (TAT) in the generic cell
+// rate algorithm, which behaves like a token bucket but needs a single value:
+// the instant at which the bucket will be full again.
+type RateLimitRepository interface {
+	// Take admits one request at now if the bucket for key has a token, where
+	// a token is refilled every interval and at most burst accumulate. It
+	// returns the bucket's TAT after the call and whether the request was
+	// admitted. Take must be atomic so that replicas sharing the store never
+	// admit more than the limit between them.
+	Take(ctx context.Context, key string, now time.Time, interval time.Duration, burst int) (time.Time, bool, error)
+	// Prune removes buckets that are full as of before, returning how many.
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+type rateLimitRepo struct {
+	db DBTX
+}
+
+// NewRateLimitRepo creates a RateLimitRepository backed by the given database.
+// Buckets live in a shared table, so every replica draws from the same tokens.
+func NewRateLimitRepo(db DBTX) RateLimitRepository {
+	return &rateLimitRepo{db: db}
+}
+
+func (r *rateLimitRepo) Take(ctx context.Context, key string, now time.Time, interval time.Duration, burst int) (time.Time, bool, error) {
+	ctx = statement(ctx, "rateLimitRepo.Take")
+	// The update only applies while the advanced TAT stays within the burst
+	// capacity; otherwise no row is returned and the request is rejected.
+	query := `
+		INSERT INTO rate_limits (bucket_key, tat_micros)
+		VALUES ($1, $3)
+		ON CONFLICT (bucket_key) DO UPDATE SET
+			tat_micros = CASE
+				WHEN rate_limits.tat_micros > $2 THEN rate_limits.tat_micros
+				ELSE $2
+			END + $4
+		WHERE CASE
+				WHEN rate_limits.tat_micros > $2 THEN rate_limits.tat_micros
+				ELSE $2
+			END + $4 <= $5
+		RETURNING tat_micros
+	`
+	nowMicros, step := now.UnixMicro(), interval.Microseconds()
+	capacity := step * int64(burst)
+
+	var tat int64
+	err := r.db.QueryRowContext(ctx, query, key, nowMicros, nowMicros+step, step, nowMicros+capacity).Scan(&tat)
+	if err == nil {
+		return time.UnixMicro(tat).UTC(), true, nil
+	}
+	if err != sql.ErrNoRows {
+		return time.Time{}, false, fmt.Errorf("repo: take rate limit token: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx, `SELECT tat_micros FROM rate_limits WHERE bucket_key = $1`, key).Scan(&tat)
+	if err == sql.ErrNoRows {
+		// Pruned since the rejected update; the bucket was full a moment ago.
+		return now, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("repo: get rate limit bucket: %w", err)
+	}
+	return time.UnixMicro(tat).UTC(), false, nil
+}
+
+func (r *rateLimitRepo) Prune(ctx context.Context, before time.Time) (int, error) {
+	ctx = statement(ctx, "rateLimitRepo.Prune")
+	res, err := r.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE tat_micros <= $1`, before.UnixMicro())
+	if err != nil {
+		return 0, fmt.Errorf("repo: prune rate limits: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: prune rate limits: %w", err)
+	}
+	return int(n), nil
+}
+
+type memoryRateLimitRepo struct {
+	db *memoryDB
+}
+
+// NewMemoryRateLimitRepo creates a process-local RateLimitRepository.
+// Suitable for a single replica; each replica enforces its own limits.
+func NewMemoryRateLimitRepo() RateLimitRepository {
+	return &memoryRateLimitRepo{db: newMemoryDB()}
+}
+
+func (r *memoryRateLimitRepo) Take(_ context.Context, key string, now time.Time, interval time.Duration, burst int) (time.Time, bool, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	// Truncate like the SQL repository, so both stores agree to the microsecond.
+	now = now.Truncate(time.Microsecond)
+	interval = interval.Truncate(time.Microsecond)
+
+	tat, ok := t.rateLimits[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	if next.After(now.Add(interval * time.Duration(burst))) {
+		return tat.UTC(), false, nil
+	}
+	t.rateLimits[key] = next
+	return next.UTC(), true, nil
+}
+
+func (r *memoryRateLimitRepo) Prune(_ context.Context, before time.Time) (int, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	n := 0
+	for key, tat := range t.rateLimits {
+		if !tat.After(before) {
+			delete(t.rateLimits, key)
+			n++
+		}
+	}
+	return n, nil
+}
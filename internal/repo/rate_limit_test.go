@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRepo_Take(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.RateLimits
+		interval := time.Second
+		now := time.Now().UTC().Truncate(time.Second)
+
+		// A fresh bucket admits a full burst, then rejects.
+		for i := 1; i <= 3; i++ {
+			tat, ok, err := repo.Take(ctx, "messages:user:a", now, interval, 3)
+			if err != nil {
+				t.Fatalf("take: %v", err)
+			}
+			if !ok {
+				t.Fatalf("expected request %d to be admitted", i)
+			}
+			if want := now.Add(time.Duration(i) * interval); !tat.Equal(want) {
+				t.Errorf("expected tat %v, got %v", want, tat)
+			}
+		}
+		tat, ok, err := repo.Take(ctx, "messages:user:a", now, interval, 3)
+		if err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		if ok {
+			t.Fatal("expected the emptied bucket to reject")
+		}
+		if want := now.Add(3 * interval); !tat.Equal(want) {
+			t.Errorf("expected a rejection to leave tat at %v, got %v", want, tat)
+		}
+
+		// One interval later a single token has been refilled.
+		if _, ok, _ := repo.Take(ctx, "messages:user:a", now.Add(interval), interval, 3); !ok {
+			t.Error("expected a refilled token to be admitted")
+		}
+		if _, ok, _ := repo.Take(ctx, "messages:user:a", now.Add(interval), interval, 3); ok {
+			t.Error("expected only one token to have been refilled")
+		}
+
+		// Buckets are independent.
+		if _, ok, _ := repo.Take(ctx, "messages:user:b", now, interval, 3); !ok {
+			t.Error("expected another key's bucket to be full")
+		}
+	})
+}
+
+func TestRateLimitRepo_Prune(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		repo := store.RateLimits
+		now := time.Now().UTC().Truncate(time.Second)
+
+		if _, _, err := repo.Take(ctx, "auth:ip:10.0.0.1", now, time.Second, 5); err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		if _, _, err := repo.Take(ctx, "auth:ip:10.0.0.2", now, time.Minute, 5); err != nil {
+			t.Fatalf("take: %v", err)
+		}
+
+		n, err := repo.Prune(ctx, now.Add(time.Second))
+		if err != nil {
+			t.Fatalf("prune: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 full bucket pruned, got %d", n)
+		}
+
+		// The surviving bucket still remembers its spent token.
+		tat, _, err := repo.Take(ctx, "auth:ip:10.0.0.2", now, time.Minute, 5)
+		if err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		if want := now.Add(2 * time.Minute); !tat.Equal(want) {
+			t.Errorf("expected tat %v, got %v", want, tat)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// ReminderRepository defines the data access contract for reminders set with /remind.
+type ReminderRepository interface {
+	Create(ctx context.Context, reminder *model.Reminder) error
+	// ListDue returns up to limit reminders due by now, earliest first.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]model.Reminder, error)
+	// Delete removes a reminder. It returns ErrNotFound if it is already
+	// gone, which tells concurrent dispatchers which of them claimed it.
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type reminderRepo struct {
+	db DBTX
+}
+
+// NewReminderRepo creates a new ReminderRepository backed by the given database.
+func NewReminderRepo(db DBTX) ReminderRepository {
+	return &reminderRepo{db: db}
+}
+
+func (r *reminderRepo) Create(ctx context.Context, reminder *model.Reminder) error {
+	ctx = statement(ctx, "reminderRepo.Create")
+	query := `
+		INSERT INTO reminders (id, conversation_id, user_id, body, remind_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		reminder.ID,
+		reminder.ConversationID,
+		reminder.UserID,
+		reminder.Body,
+		reminder.RemindAt,
+		reminder.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create reminder: %w", err)
+	}
+	return nil
+}
+
+func (r *reminderRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]model.Reminder, error) {
+	ctx = statement(ctx, "reminderRepo.ListDue")
+	query := `
+		SELECT id, conversation_id, user_id, body, remind_at, created_at
+		FROM reminders
+		WHERE remind_at <= $1
+		ORDER BY remind_at, id
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []model.Reminder{}
+	for rows.Next() {
+		var rem model.Reminder
+		if err := rows.Scan(&rem.ID, &rem.ConversationID, &rem.UserID, &rem.Body, &rem.RemindAt, &rem.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repo: scan reminder: %w", err)
+		}
+		reminders = append(reminders, rem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list due reminders rows error: %w", err)
+	}
+	return reminders, nil
+}
+
+func (r *reminderRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx = statement(ctx, "reminderRepo.Delete")
+	res, err := r.db.ExecContext(ctx, `DELETE FROM reminders WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("repo: delete reminder: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+type memoryReminderRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryReminderRepo) Create(_ context.Context, reminder *model.Reminder) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.reminders[reminder.ID]; ok {
+		return model.ErrConflict
+	}
+	t.reminders[reminder.ID] = *reminder
+	return nil
+}
+
+func (r *memoryReminderRepo) ListDue(_ context.Context, now time.Time, limit int) ([]model.Reminder, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	reminders := []model.Reminder{}
+	for _, rem := range t.reminders {
+		if !rem.RemindAt.After(now) {
+			reminders = append(reminders, rem)
+		}
+	}
+	slices.SortFunc(reminders, func(a, b model.Reminder) int {
+		return compareTimeID(a.RemindAt, a.ID, b.RemindAt, b.ID)
+	})
+	return reminders[:min(limit, len(reminders))], nil
+}
+
+func (r *memoryReminderRepo) Delete(_ context.Context, id uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.reminders[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(t.reminders, id)
+	return nil
+}
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestReminderRepo_ListDueAndDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		var ids []uuid.UUID
+		for _, at := range []time.Time{now.Add(-time.Minute), now.Add(-time.Hour), now.Add(time.Minute)} {
+			rem := &model.Reminder{
+				ID: uuid.New(), ConversationID: convo.ID, UserID: alice.ID,
+				Body: "stretch", RemindAt: at, CreatedAt: now.Add(-2 * time.Hour),
+			}
+			if err := store.Reminders.Create(ctx, rem); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			ids = append(ids, rem.ID)
+		}
+
+		due, err := store.Reminders.ListDue(ctx, now, 10)
+		if err != nil || len(due) != 2 || due[0].ID != ids[1] || due[1].ID != ids[0] {
+			t.Fatalf("expected the two due reminders, earliest first, got %+v, %v", due, err)
+		}
+		if due, err := store.Reminders.ListDue(ctx, now, 1); err != nil || len(due) != 1 {
+			t.Errorf("expected the limit to apply, got %+v, %v", due, err)
+		}
+
+		if err := store.Reminders.Delete(ctx, ids[1]); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if err := store.Reminders.Delete(ctx, ids[1]); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected a second claim to miss, got %v", err)
+		}
+		if due, err := store.Reminders.ListDue(ctx, now, 10); err != nil || len(due) != 1 || due[0].ID != ids[0] {
+			t.Errorf("expected one reminder left due, got %+v, %v", due, err)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/db/migrations"
+	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// forEachStore runs fn against every Store backend: in memory, a freshly
+// migrated in-memory SQLite database and, when TEST_POSTGRES_DSN is set, an
+// isolated Postgres schema. Tests written against it form the conformance
+// suite that keeps the backends behaving alike.
+func forEachStore(t *testing.T, fn func(t *testing.T, store *Store)) {
+	t.Helper()
+	t.Run("memory", func(t *testing.T) {
+		store, err := NewStore(MemoryStore, nil, 0)
+		if err != nil {
+			t.Fatalf("new store: %v", err)
+		}
+		fn(t, store)
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, openSQLiteTestStore(t))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN not set")
+		}
+		fn(t, openPostgresTestStore(t, dsn))
+	})
+}
+
+func openSQLiteTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := infra.NewDB(context.Background(), infra.SQLite, ":memory:", infra.PoolConfig{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return migrateTestStore(t, db, "sqlite", DialectSQLite)
+}
+
+func openPostgresTestStore(t *testing.T, dsn string) *Store {
+	t.Helper()
+	ctx := context.Background()
+
+	admin, err := infra.NewDB(ctx, infra.Postgres, dsn, infra.PoolConfig{})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	db, err := infra.NewDB(ctx, infra.Postgres, withSearchPath(dsn, schema), infra.PoolConfig{})
+	if err != nil {
+		t.Fatalf("open postgres schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return migrateTestStore(t, db, "pgx", DialectPostgres)
+}
+
+// withSearchPath points every connection opened with dsn at the given schema.
+func withSearchPath(dsn, schema string) string {
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+func migrateTestStore(t *testing.T, db *sql.DB, driver string, dialect Dialect) *Store {
+	t.Helper()
+	if err := infra.RunMigrations(db, driver, migrations.FS); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	store, err := NewStore(SQLStore, db, dialect)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	return store
+}
+
+// ---------------------------------------------------------------------------
+// Fixtures
+// ---------------------------------------------------------------------------
+
+func createTestUser(t *testing.T, s *Store, displayName string) *model.User {
+	t.Helper()
+	now := time.Now().UTC()
+	u := &model.User{
+		ID:           uuid.New(),
+		Email:        strings.ToLower(strings.ReplaceAll(displayName, " ", ".")) + "-" + uuid.NewString()[:8] + "@example.com",
+		PasswordHash: "hash",
+		DisplayName:  displayName,
+		Status:       "offline",
+		Type:         model.UserTypeHuman,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.Users.Create(context.Background(), u); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return u
+}
+
+func createTestConversation(t *testing.T, s *Store, owner uuid.UUID, members ...uuid.UUID) *model.Conversation {
+	t.Helper()
+	ctx := context.Background()
+	c := newTestConversation(owner)
+	if err := s.Conversations.Create(ctx, c); err != nil {
+		t.Fatalf("create conversation: %v", err)
+	}
+	for _, uid := range append([]uuid.UUID{owner}, members...) {
+		if err := s.Conversations.AddParticipant(ctx, newTestParticipant(c.ID, uid)); err != nil {
+			t.Fatalf("add participant: %v", err)
+		}
+	}
+	return c
+}
+
+func newTestConversation(createdBy uuid.UUID) *model.Conversation {
+	now := time.Now().UTC()
+	return &model.Conversation{ID: uuid.New(), Type: "group", CreatedBy: createdBy, CreatedAt: now, UpdatedAt: now}
+}
+
+func newTestParticipant(conversationID, userID uuid.UUID) *model.ConversationParticipant {
+	return &model.ConversationParticipant{
+		ID: uuid.New(), ConversationID: conversationID, UserID: userID, Role: "member", JoinedAt: time.Now().UTC(),
+	}
+}
+
+func newTestMessage(conversationID, senderID uuid.UUID, body string, at time.Time) *model.Message {
+	return &model.Message{
+		ID: uuid.New(), ConversationID: conversationID, SenderID: senderID,
+		Body: body, Status: "sent", CreatedAt: at, UpdatedAt: at,
+	}
+}
@@ -19,15 +19,16 @@ type SessionRepository interface {
 }
 
 type sessionRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewSessionRepo creates a new SessionRepository backed by the given database.
-func NewSessionRepo(db *sql.DB) SessionRepository {
+func NewSessionRepo(db DBTX) SessionRepository {
 	return &sessionRepo{db: db}
 }
 
 func (r *sessionRepo) Create(ctx context.Context, session *model.Session) error {
+	ctx = statement(ctx, "sessionRepo.Create")
 	query := `
 		INSERT INTO sessions (id, user_id, refresh_token_hash, expires_at, created_at)
 		VALUES ($1, $2, $3, $4, $5)
@@ -46,6 +47,7 @@ func (r *sessionRepo) Create(ctx context.Context, session *model.Session) error
 }
 
 func (r *sessionRepo) GetByToken(ctx context.Context, tokenHash string) (*model.Session, error) {
+	ctx = statement(ctx, "sessionRepo.GetByToken")
 	query := `
 		SELECT id, user_id, refresh_token_hash, expires_at, created_at, revoked_at
 		FROM sessions
@@ -72,6 +74,7 @@ func (r *sessionRepo) GetByToken(ctx context.Context, tokenHash string) (*model.
 }
 
 func (r *sessionRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	ctx = statement(ctx, "sessionRepo.Revoke")
 	query := `
 		UPDATE sessions SET revoked_at = $1
 		WHERE id = $2 AND revoked_at IS NULL
@@ -88,6 +91,7 @@ func (r *sessionRepo) Revoke(ctx context.Context, id uuid.UUID) error {
 }
 
 func (r *sessionRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	ctx = statement(ctx, "sessionRepo.RevokeAllForUser")
 	query := `
 		UPDATE sessions SET revoked_at = $1
 		WHERE user_id = $2 AND revoked_at IS NULL
@@ -98,3 +102,57 @@ func (r *sessionRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) er
 	}
 	return nil
 }
+
+type memorySessionRepo struct {
+	db *memoryDB
+}
+
+func (r *memorySessionRepo) Create(_ context.Context, session *model.Session) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, s := range t.sessions {
+		if s.ID == session.ID || s.RefreshTokenHash == session.RefreshTokenHash {
+			return fmt.Errorf("repo: create session: %w", model.ErrConflict)
+		}
+	}
+	t.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *memorySessionRepo) GetByToken(_ context.Context, tokenHash string) (*model.Session, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	now := time.Now().UTC()
+	for _, s := range t.sessions {
+		if s.RefreshTokenHash == tokenHash && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			return &s, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *memorySessionRepo) Revoke(_ context.Context, id uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	s, ok := t.sessions[id]
+	if !ok || s.RevokedAt != nil {
+		return model.ErrNotFound
+	}
+	now := time.Now().UTC()
+	s.RevokedAt = &now
+	t.sessions[id] = s
+	return nil
+}
+
+func (r *memorySessionRepo) RevokeAllForUser(_ context.Context, userID uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	now := time.Now().UTC()
+	for id, s := range t.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			t.sessions[id] = s
+		}
+	}
+	return nil
+}
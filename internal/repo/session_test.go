@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func createTestSession(t *testing.T, s *Store, userID uuid.UUID, expiresAt time.Time) *model.Session {
+	t.Helper()
+	sess := &model.Session{
+		ID: uuid.New(), UserID: userID, RefreshTokenHash: uuid.NewString(),
+		ExpiresAt: expiresAt, CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Sessions.Create(context.Background(), sess); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return sess
+}
+
+func TestSessionRepo_GetByTokenSkipsExpired(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+
+		// A whole-second expiry just in the past must compare as expired.
+		expired := createTestSession(t, store, alice.ID, time.Now().UTC().Truncate(time.Second))
+		live := createTestSession(t, store, alice.ID, time.Now().UTC().Add(time.Hour))
+
+		if _, err := store.Sessions.GetByToken(ctx, expired.RefreshTokenHash); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for expired session, got %v", err)
+		}
+		got, err := store.Sessions.GetByToken(ctx, live.RefreshTokenHash)
+		if err != nil {
+			t.Fatalf("get live session: %v", err)
+		}
+		if got.ID != live.ID {
+			t.Errorf("expected %s, got %s", live.ID, got.ID)
+		}
+	})
+}
+
+func TestSessionRepo_Revoke(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		first := createTestSession(t, store, alice.ID, time.Now().UTC().Add(time.Hour))
+		second := createTestSession(t, store, alice.ID, time.Now().UTC().Add(time.Hour))
+
+		if err := store.Sessions.Revoke(ctx, first.ID); err != nil {
+			t.Fatalf("revoke: %v", err)
+		}
+		if err := store.Sessions.Revoke(ctx, first.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound on second revoke, got %v", err)
+		}
+
+		if err := store.Sessions.RevokeAllForUser(ctx, alice.ID); err != nil {
+			t.Fatalf("revoke all: %v", err)
+		}
+		if _, err := store.Sessions.GetByToken(ctx, second.RefreshTokenHash); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after revoke all, got %v", err)
+		}
+	})
+}
@@ -1,7 +1,9 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 )
 
@@ -10,8 +12,22 @@ type StoreType int
 
 const (
 	SQLStore StoreType = iota
+	MemoryStore
 )
 
+// DBTX is the query surface shared by *sql.DB and *sql.Tx, so that every SQL
+// repository can run either standalone or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor runs a unit of work against a Store whose repositories share one transaction.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(tx *Store) error) error
+}
+
 // Store aggregates all repository instances.
 type Store struct {
 	Users         UserRepository
@@ -19,20 +35,98 @@ type Store struct {
 	Conversations ConversationRepository
 	Messages      MessageRepository
 	Moderation    ModerationRepository
+	Identities    IdentityRepository
+	LoginAttempts LoginAttemptRepository
+	RateLimits    RateLimitRepository
+	Tokens        TokenRepository
+	Accounts      AccountRepository
+	Idempotency   IdempotencyRepository
+	Webhooks      WebhookRepository
+	Integrations  IntegrationRepository
+	Reminders     ReminderRepository
+	Polls         PollRepository
+	Push          PushRepository
+	Digests       DigestRepository
+
+	db      *sql.DB // nil inside a transaction and for stores not backed by SQL
+	dialect Dialect
+	mem     *memoryDB // set for MemoryStore
 }
 
-// NewStore creates a Store based on the given backend type.
-func NewStore(storeType StoreType, db *sql.DB) (*Store, error) {
+// NewStore creates a Store based on the given backend type. For SQLStore,
+// dialect selects the SQL flavour spoken by db. MemoryStore ignores both and
+// keeps all data in process memory, which suits tests and demos.
+func NewStore(storeType StoreType, db *sql.DB, dialect Dialect) (*Store, error) {
 	switch storeType {
 	case SQLStore:
-		return &Store{
-			Users:         NewUserRepo(db),
-			Sessions:      NewSessionRepo(db),
-			Conversations: NewConversationRepo(db),
-			Messages:      NewMessageRepo(db),
-			Moderation:    NewModerationRepo(db),
-		}, nil
+		s := newSQLStore(db, dialect)
+		s.db = db
+		return s, nil
+	case MemoryStore:
+		return newMemoryStore(newMemoryDB()), nil
 	default:
 		return nil, fmt.Errorf("unknown store type: %d", storeType)
 	}
 }
+
+func newSQLStore(db DBTX, dialect Dialect) *Store {
+	db = traceDB(db, dialect)
+	return &Store{
+		Users:         NewUserRepo(db, dialect),
+		Sessions:      NewSessionRepo(db),
+		Conversations: NewConversationRepo(db),
+		Messages:      NewMessageRepo(db),
+		Moderation:    NewModerationRepo(db, dialect),
+		Identities:    NewIdentityRepo(db),
+		LoginAttempts: NewLoginAttemptRepo(db),
+		RateLimits:    NewRateLimitRepo(db),
+		Tokens:        NewTokenRepo(db),
+		Accounts:      NewAccountRepo(db),
+		Idempotency:   NewIdempotencyRepo(db),
+		Webhooks:      NewWebhookRepo(db),
+		Integrations:  NewIntegrationRepo(db),
+		Reminders:     NewReminderRepo(db),
+		Polls:         NewPollRepo(db),
+		Push:          NewPushRepo(db),
+		Digests:       NewDigestRepo(db),
+		dialect:       dialect,
+	}
+}
+
+// WithTx runs fn with a Store whose repositories share a single database
+// transaction. The transaction commits if fn returns nil and rolls back if it
+// returns an error or panics. Calling WithTx on a transactional Store joins the
+// outer transaction, and a Store without a database runs fn directly against itself.
+//
+// A MemoryStore transaction holds the store's lock for its whole duration and
+// restores the tables it wrote to on failure.
+func (s *Store) WithTx(ctx context.Context, fn func(tx *Store) error) (err error) {
+	if s.mem != nil && s.mem.tx == nil {
+		return s.mem.withTx(fn)
+	}
+	if s.db == nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(newSQLStore(tx, s.dialect)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("repo: rollback: %w", rbErr))
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestWithTx_CommitsOnSuccess(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		owner := createTestUser(t, store, "Owner")
+		convo := newTestConversation(owner.ID)
+
+		err := store.WithTx(ctx, func(tx *Store) error {
+			if err := tx.Conversations.Create(ctx, convo); err != nil {
+				return err
+			}
+			return tx.Conversations.AddParticipant(ctx, newTestParticipant(convo.ID, owner.ID))
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := store.Conversations.GetByID(ctx, convo.ID); err != nil {
+			t.Errorf("expected conversation to be committed, got %v", err)
+		}
+		if ok, _ := store.Conversations.IsParticipant(ctx, convo.ID, owner.ID); !ok {
+			t.Error("expected participant to be committed")
+		}
+	})
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		owner := createTestUser(t, store, "Owner")
+		convo := newTestConversation(owner.ID)
+		errBoom := errors.New("boom")
+
+		err := store.WithTx(ctx, func(tx *Store) error {
+			if err := tx.Conversations.Create(ctx, convo); err != nil {
+				return err
+			}
+			return errBoom
+		})
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("expected errBoom, got %v", err)
+		}
+
+		if _, err := store.Conversations.GetByID(ctx, convo.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected conversation to be rolled back, got %v", err)
+		}
+	})
+}
+
+func TestWithTx_RollsBackOnStatementFailure(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+		convo := createTestConversation(t, store, alice.ID, bob.ID)
+
+		// The duplicate recipient makes the delivery insert fail after the
+		// message row has been written.
+		msg := newTestMessage(convo.ID, alice.ID, "hello", time.Now().UTC())
+		err := store.WithTx(ctx, func(tx *Store) error {
+			if err := tx.Messages.Create(ctx, msg); err != nil {
+				return err
+			}
+			return tx.Messages.CreateDeliveries(ctx, msg.ID, []uuid.UUID{bob.ID, bob.ID})
+		})
+		if err == nil {
+			t.Fatal("expected delivery insert to fail")
+		}
+
+		if _, err := store.Messages.GetByID(ctx, msg.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected no orphaned message, got %v", err)
+		}
+	})
+}
+
+func TestWithTx_RollsBackOnPanic(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		owner := createTestUser(t, store, "Owner")
+		convo := newTestConversation(owner.ID)
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic to propagate")
+				}
+			}()
+			store.WithTx(ctx, func(tx *Store) error {
+				if err := tx.Conversations.Create(ctx, convo); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		}()
+
+		if _, err := store.Conversations.GetByID(ctx, convo.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected conversation to be rolled back, got %v", err)
+		}
+		// The connection must have been released for further use.
+		if err := store.WithTx(ctx, func(tx *Store) error { return nil }); err != nil {
+			t.Errorf("expected store to be usable after panic, got %v", err)
+		}
+	})
+}
+
+func TestWithTx_NestedJoinsOuterTransaction(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		owner := createTestUser(t, store, "Owner")
+		convo := newTestConversation(owner.ID)
+		errBoom := errors.New("boom")
+
+		err := store.WithTx(ctx, func(tx *Store) error {
+			err := tx.WithTx(ctx, func(inner *Store) error {
+				return inner.Conversations.Create(ctx, convo)
+			})
+			if err != nil {
+				return err
+			}
+			return errBoom
+		})
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("expected errBoom, got %v", err)
+		}
+
+		if _, err := store.Conversations.GetByID(ctx, convo.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected inner write to roll back with the outer transaction, got %v", err)
+		}
+	})
+}
+
+func TestWithTx_DuplicateParticipantKeepsTransactionUsable(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		owner := createTestUser(t, store, "Owner")
+		member := createTestUser(t, store, "Member")
+		convo := newTestConversation(owner.ID)
+
+		err := store.WithTx(ctx, func(tx *Store) error {
+			if err := tx.Conversations.Create(ctx, convo); err != nil {
+				return err
+			}
+			if err := tx.Conversations.AddParticipant(ctx, newTestParticipant(convo.ID, owner.ID)); err != nil {
+				return err
+			}
+			if err := tx.Conversations.AddParticipant(ctx, newTestParticipant(convo.ID, owner.ID)); !errors.Is(err, model.ErrConflict) {
+				t.Errorf("expected ErrConflict for duplicate participant, got %v", err)
+			}
+			return tx.Conversations.AddParticipant(ctx, newTestParticipant(convo.ID, member.ID))
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		participants, err := store.Conversations.GetParticipants(ctx, convo.ID)
+		if err != nil || len(participants) != 2 {
+			t.Errorf("expected 2 participants, got %d, %v", len(participants), err)
+		}
+	})
+}
+
+func TestWithTx_WithoutDatabaseRunsDirectly(t *testing.T) {
+	store := &Store{}
+	called := false
+
+	err := store.WithTx(context.Background(), func(tx *Store) error {
+		called = true
+		if tx != store {
+			t.Error("expected fn to receive the store itself")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("expected fn to be called")
+	}
+}
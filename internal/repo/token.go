@@ -0,0 +1,222 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// TokenRepository defines the data access contract for personal access tokens.
+type TokenRepository interface {
+	Create(ctx context.Context, token *model.PersonalAccessToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error)
+	ListByCreator(ctx context.Context, createdBy uuid.UUID) ([]model.PersonalAccessToken, error)
+	Revoke(ctx context.Context, id uuid.UUID, createdBy uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type tokenRepo struct {
+	db DBTX
+}
+
+// NewTokenRepo creates a new TokenRepository backed by the given database.
+func NewTokenRepo(db DBTX) TokenRepository {
+	return &tokenRepo{db: db}
+}
+
+func (r *tokenRepo) Create(ctx context.Context, token *model.PersonalAccessToken) error {
+	ctx = statement(ctx, "tokenRepo.Create")
+	query := `
+		INSERT INTO personal_access_tokens (id, user_id, created_by, name, token_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		token.ID,
+		token.UserID,
+		token.CreatedBy,
+		token.Name,
+		token.TokenHash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash returns an unrevoked token by its hash. Expiry is checked by the caller.
+func (r *tokenRepo) GetByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	ctx = statement(ctx, "tokenRepo.GetByHash")
+	query := `
+		SELECT id, user_id, created_by, name, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+		FROM personal_access_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL
+	`
+	t, err := scanToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get token by hash: %w", err)
+	}
+	return t, nil
+}
+
+func (r *tokenRepo) ListByCreator(ctx context.Context, createdBy uuid.UUID) ([]model.PersonalAccessToken, error) {
+	ctx = statement(ctx, "tokenRepo.ListByCreator")
+	query := `
+		SELECT id, user_id, created_by, name, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+		FROM personal_access_tokens
+		WHERE created_by = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []model.PersonalAccessToken{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo: scan token: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list tokens rows error: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *tokenRepo) Revoke(ctx context.Context, id uuid.UUID, createdBy uuid.UUID) error {
+	ctx = statement(ctx, "tokenRepo.Revoke")
+	query := `
+		UPDATE personal_access_tokens SET revoked_at = $1
+		WHERE id = $2 AND created_by = $3 AND revoked_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id, createdBy)
+	if err != nil {
+		return fmt.Errorf("repo: revoke token: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *tokenRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	ctx = statement(ctx, "tokenRepo.TouchLastUsed")
+	query := `UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2`
+	if _, err := r.db.ExecContext(ctx, query, at, id); err != nil {
+		return fmt.Errorf("repo: touch token: %w", err)
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanToken(row rowScanner) (*model.PersonalAccessToken, error) {
+	t := &model.PersonalAccessToken{}
+	var scopes string
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.CreatedBy,
+		&t.Name,
+		&t.TokenHash,
+		&scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.CreatedAt,
+		&t.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.Scopes = strings.Fields(scopes)
+	return t, nil
+}
+
+type memoryTokenRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryTokenRepo) Create(_ context.Context, token *model.PersonalAccessToken) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, tok := range t.tokens {
+		if tok.ID == token.ID || tok.TokenHash == token.TokenHash {
+			return model.ErrConflict
+		}
+	}
+	stored := *token
+	stored.Scopes = slices.Clone(token.Scopes)
+	t.tokens[token.ID] = stored
+	return nil
+}
+
+func (r *memoryTokenRepo) GetByHash(_ context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, tok := range t.tokens {
+		if tok.TokenHash == tokenHash && tok.RevokedAt == nil {
+			tok.Scopes = slices.Clone(tok.Scopes)
+			return &tok, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *memoryTokenRepo) ListByCreator(_ context.Context, createdBy uuid.UUID) ([]model.PersonalAccessToken, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	tokens := []model.PersonalAccessToken{}
+	for _, tok := range t.tokens {
+		if tok.CreatedBy == createdBy && tok.RevokedAt == nil {
+			tok.Scopes = slices.Clone(tok.Scopes)
+			tokens = append(tokens, tok)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b model.PersonalAccessToken) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return tokens, nil
+}
+
+func (r *memoryTokenRepo) Revoke(_ context.Context, id uuid.UUID, createdBy uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	tok, ok := t.tokens[id]
+	if !ok || tok.CreatedBy != createdBy || tok.RevokedAt != nil {
+		return model.ErrNotFound
+	}
+	now := time.Now().UTC()
+	tok.RevokedAt = &now
+	t.tokens[id] = tok
+	return nil
+}
+
+func (r *memoryTokenRepo) TouchLastUsed(_ context.Context, id uuid.UUID, at time.Time) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if tok, ok := t.tokens[id]; ok {
+		tok.LastUsedAt = &at
+		t.tokens[id] = tok
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestTokenRepo_CreateAndRevoke(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		bob := createTestUser(t, store, "Bob")
+
+		tok := &model.PersonalAccessToken{
+			ID: uuid.New(), UserID: alice.ID, CreatedBy: alice.ID, Name: "ci",
+			TokenHash: "hash", Scopes: []string{model.ScopeUsersRead, model.ScopeMessagesWrite},
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := store.Tokens.Create(ctx, tok); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		got, err := store.Tokens.GetByHash(ctx, "hash")
+		if err != nil {
+			t.Fatalf("get by hash: %v", err)
+		}
+		if len(got.Scopes) != 2 || got.Scopes[1] != model.ScopeMessagesWrite || got.ExpiresAt != nil {
+			t.Errorf("unexpected token: %+v", got)
+		}
+
+		dup := *tok
+		dup.ID = uuid.New()
+		if err := store.Tokens.Create(ctx, &dup); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected ErrConflict for duplicate hash, got %v", err)
+		}
+
+		if err := store.Tokens.Revoke(ctx, tok.ID, bob.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound revoking another user's token, got %v", err)
+		}
+		if err := store.Tokens.Revoke(ctx, tok.ID, alice.ID); err != nil {
+			t.Fatalf("revoke: %v", err)
+		}
+		if _, err := store.Tokens.GetByHash(ctx, "hash"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after revoke, got %v", err)
+		}
+	})
+}
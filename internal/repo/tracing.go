@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/kareempaes/planning/internal/repo")
+
+// tracedDB wraps a DBTX so that every statement runs in a client span named
+// after the repository method that issued it, such as "userRepo.GetByID", as
+// passed to statement.
+// Spans end when the driver returns: for QueryContext that is once the first
+// rows are available, not when they have all been read.
+type tracedDB struct {
+	db     DBTX
+	system attribute.KeyValue
+}
+
+func traceDB(db DBTX, dialect Dialect) DBTX {
+	system := semconv.DBSystemNameSQLite
+	if dialect == DialectPostgres {
+		system = semconv.DBSystemNamePostgreSQL
+	}
+	return &tracedDB{db: db, system: system}
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	res, err := t.db.ExecContext(ctx, query, args...)
+	recordError(span, err)
+	return res, err
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordError(span, err)
+	return rows, err
+}
+
+// QueryRowContext cannot see the statement's error, which only surfaces when
+// the caller scans the row.
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	return t.db.QueryRowContext(ctx, query, args...)
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name, _ := ctx.Value(statementKey{}).(string)
+	if name == "" {
+		name = "query"
+	}
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(t.system, semconv.DBOperationName(name), semconv.DBQueryText(strings.Join(strings.Fields(query), " "))),
+	)
+}
+
+type statementKey struct{}
+
+// statement names the statements run with ctx after the repository method
+// issuing them. Every SQL repository method calls it first; statements run
+// without a name are traced as "query".
+func statement(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, statementKey{}, name)
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedDB_NamesSpansAfterRepositoryMethod(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	store := openSQLiteTestStore(t)
+	u := createTestUser(t, store, "Alice")
+	if _, err := store.Users.GetByID(context.Background(), u.ID); err != nil {
+		t.Fatalf("get by id: %v", err)
+	}
+
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	if len(names) != 2 || names[0] != "userRepo.Create" || names[1] != "userRepo.GetByID" {
+		t.Errorf("expected userRepo.Create and userRepo.GetByID spans, got %v", names)
+	}
+}
+
+func TestSQLRepos_NameTheirStatements(t *testing.T) {
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	checked := 0
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Body == nil || !fn.Name.IsExported() {
+				continue
+			}
+			star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			recv, ok := star.X.(*ast.Ident)
+			if !ok || strings.HasPrefix(recv.Name, "memory") || !strings.HasSuffix(recv.Name, "Repo") {
+				continue
+			}
+			checked++
+			want := recv.Name + "." + fn.Name.Name
+			if got := statementNameOf(fn.Body); got != want {
+				t.Errorf("%s must start with ctx = statement(ctx, %q), got %q", want, want, got)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("found no SQL repository methods")
+	}
+}
+
+// statementNameOf returns the name passed to statement by body's first
+// statement, or "" if it does not start with ctx = statement(ctx, "...").
+func statementNameOf(body *ast.BlockStmt) string {
+	if len(body.List) == 0 {
+		return ""
+	}
+	assign, ok := body.List[0].(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 {
+		return ""
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 {
+		return ""
+	}
+	if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "statement" {
+		return ""
+	}
+	lit, ok := call.Args[1].(*ast.BasicLit)
+	if !ok {
+		return ""
+	}
+	name, _ := strconv.Unquote(lit.Value)
+	return name
+}
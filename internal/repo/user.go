@@ -1,10 +1,12 @@
 package repo
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,21 +21,25 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	Update(ctx context.Context, id uuid.UUID, params model.UpdateProfileParams) (*model.User, error)
 	Search(ctx context.Context, query string, cursor string, limit int) (*model.Page[model.UserSearchResult], error)
+	ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.User, error)
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, hash string) error
 }
 
 type userRepo struct {
-	db *sql.DB
+	db      DBTX
+	dialect Dialect
 }
 
 // NewUserRepo creates a new UserRepository backed by the given database.
-func NewUserRepo(db *sql.DB) UserRepository {
-	return &userRepo{db: db}
+func NewUserRepo(db DBTX, dialect Dialect) UserRepository {
+	return &userRepo{db: db, dialect: dialect}
 }
 
 func (r *userRepo) Create(ctx context.Context, user *model.User) error {
+	ctx = statement(ctx, "userRepo.Create")
 	query := `
-		INSERT INTO users (id, email, password_hash, display_name, avatar_url, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (id, email, password_hash, display_name, avatar_url, status, type, owner_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		user.ID,
@@ -42,11 +48,13 @@ func (r *userRepo) Create(ctx context.Context, user *model.User) error {
 		user.DisplayName,
 		user.AvatarURL,
 		user.Status,
+		user.Type,
+		user.OwnerID,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
 	if err != nil {
-		if strings.Contains(err.Error(), "unique") || strings.Contains(err.Error(), "duplicate") {
+		if isUniqueViolation(err) {
 			return model.ErrConflict
 		}
 		return fmt.Errorf("repo: create user: %w", err)
@@ -55,8 +63,9 @@ func (r *userRepo) Create(ctx context.Context, user *model.User) error {
 }
 
 func (r *userRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	ctx = statement(ctx, "userRepo.GetByID")
 	query := `
-		SELECT id, email, password_hash, display_name, avatar_url, status, created_at, updated_at
+		SELECT id, email, password_hash, display_name, avatar_url, status, type, owner_id, created_at, updated_at, deletion_scheduled_at
 		FROM users
 		WHERE id = $1
 	`
@@ -68,8 +77,11 @@ func (r *userRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User, erro
 		&user.DisplayName,
 		&user.AvatarURL,
 		&user.Status,
+		&user.Type,
+		&user.OwnerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, model.ErrNotFound
@@ -81,8 +93,9 @@ func (r *userRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.User, erro
 }
 
 func (r *userRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx = statement(ctx, "userRepo.GetByEmail")
 	query := `
-		SELECT id, email, password_hash, display_name, avatar_url, status, created_at, updated_at
+		SELECT id, email, password_hash, display_name, avatar_url, status, type, owner_id, created_at, updated_at, deletion_scheduled_at
 		FROM users
 		WHERE email = $1
 	`
@@ -94,8 +107,11 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*model.User, e
 		&user.DisplayName,
 		&user.AvatarURL,
 		&user.Status,
+		&user.Type,
+		&user.OwnerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, model.ErrNotFound
@@ -107,6 +123,7 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*model.User, e
 }
 
 func (r *userRepo) Update(ctx context.Context, id uuid.UUID, params model.UpdateProfileParams) (*model.User, error) {
+	ctx = statement(ctx, "userRepo.Update")
 	setClauses := []string{}
 	args := []any{}
 	argIdx := 1
@@ -137,7 +154,7 @@ func (r *userRepo) Update(ctx context.Context, id uuid.UUID, params model.Update
 		UPDATE users
 		SET %s
 		WHERE id = $%d
-		RETURNING id, email, password_hash, display_name, avatar_url, status, created_at, updated_at
+		RETURNING id, email, password_hash, display_name, avatar_url, status, type, owner_id, created_at, updated_at, deletion_scheduled_at
 	`, strings.Join(setClauses, ", "), argIdx)
 
 	user := &model.User{}
@@ -148,8 +165,11 @@ func (r *userRepo) Update(ctx context.Context, id uuid.UUID, params model.Update
 		&user.DisplayName,
 		&user.AvatarURL,
 		&user.Status,
+		&user.Type,
+		&user.OwnerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, model.ErrNotFound
@@ -160,7 +180,25 @@ func (r *userRepo) Update(ctx context.Context, id uuid.UUID, params model.Update
 	return user, nil
 }
 
+func (r *userRepo) UpdatePasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	ctx = statement(ctx, "userRepo.UpdatePasswordHash")
+	query := `
+		UPDATE users SET password_hash = $1, updated_at = $2
+		WHERE id = $3
+	`
+	res, err := r.db.ExecContext(ctx, query, hash, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("repo: update password hash: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
 func (r *userRepo) Search(ctx context.Context, query string, cursor string, limit int) (*model.Page[model.UserSearchResult], error) {
+	ctx = statement(ctx, "userRepo.Search")
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
@@ -169,7 +207,7 @@ func (r *userRepo) Search(ctx context.Context, query string, cursor string, limi
 	args := []any{}
 	argIdx := 1
 
-	whereClauses := []string{fmt.Sprintf("display_name ILIKE $%d", argIdx)}
+	whereClauses := []string{r.dialect.ILike("display_name", fmt.Sprintf("$%d", argIdx))}
 	args = append(args, query+"%")
 	argIdx++
 
@@ -232,6 +270,37 @@ func (r *userRepo) Search(ctx context.Context, query string, cursor string, limi
 	}, nil
 }
 
+func (r *userRepo) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]model.User, error) {
+	ctx = statement(ctx, "userRepo.ListByOwner")
+	query := `
+		SELECT id, email, password_hash, display_name, avatar_url, status, type, owner_id, created_at, updated_at, deletion_scheduled_at
+		FROM users
+		WHERE owner_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list users by owner: %w", err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(
+			&u.ID, &u.Email, &u.PasswordHash, &u.DisplayName, &u.AvatarURL,
+			&u.Status, &u.Type, &u.OwnerID, &u.CreatedAt, &u.UpdatedAt, &u.DeletionScheduledAt,
+		); err != nil {
+			return nil, fmt.Errorf("repo: scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list users by owner rows error: %w", err)
+	}
+	return users, nil
+}
+
 func decodeCursor(cursor string) (string, uuid.UUID, error) {
 	raw, err := base64.URLEncoding.DecodeString(cursor)
 	if err != nil {
@@ -252,3 +321,131 @@ func encodeCursor(displayName string, id uuid.UUID) string {
 	raw := displayName + "|" + id.String()
 	return base64.URLEncoding.EncodeToString([]byte(raw))
 }
+
+type memoryUserRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryUserRepo) Create(_ context.Context, user *model.User) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.users[user.ID]; ok {
+		return model.ErrConflict
+	}
+	for _, u := range t.users {
+		if u.Email == user.Email {
+			return model.ErrConflict
+		}
+	}
+	t.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepo) GetByID(_ context.Context, id uuid.UUID) (*model.User, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	u, ok := t.users[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *memoryUserRepo) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	for _, u := range t.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *memoryUserRepo) Update(_ context.Context, id uuid.UUID, params model.UpdateProfileParams) (*model.User, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	u, ok := t.users[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	if params.DisplayName == nil && params.AvatarURL == nil {
+		return &u, nil
+	}
+	if params.DisplayName != nil {
+		u.DisplayName = *params.DisplayName
+	}
+	if params.AvatarURL != nil {
+		avatar := *params.AvatarURL
+		u.AvatarURL = &avatar
+	}
+	u.UpdatedAt = time.Now().UTC()
+	t.users[id] = u
+	return &u, nil
+}
+
+func (r *memoryUserRepo) UpdatePasswordHash(_ context.Context, id uuid.UUID, hash string) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	u, ok := t.users[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	u.PasswordHash = hash
+	u.UpdatedAt = time.Now().UTC()
+	t.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepo) Search(_ context.Context, query string, cursor string, limit int) (*model.Page[model.UserSearchResult], error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var (
+		cursorName string
+		cursorID   uuid.UUID
+	)
+	if cursor != "" {
+		var err error
+		cursorName, cursorID, err = decodeCursor(cursor)
+		if err != nil {
+			return nil, &model.ValidationError{Field: "cursor", Message: "invalid cursor"}
+		}
+	}
+
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	prefix := strings.ToLower(query)
+	var results []model.UserSearchResult
+	for _, u := range t.users {
+		if !strings.HasPrefix(strings.ToLower(u.DisplayName), prefix) {
+			continue
+		}
+		if cursor != "" && !(u.DisplayName > cursorName || (u.DisplayName == cursorName && u.ID.String() > cursorID.String())) {
+			continue
+		}
+		results = append(results, model.UserSearchResult{ID: u.ID, DisplayName: u.DisplayName, AvatarURL: u.AvatarURL})
+	}
+	slices.SortFunc(results, func(a, b model.UserSearchResult) int {
+		return cmp.Or(strings.Compare(a.DisplayName, b.DisplayName), strings.Compare(a.ID.String(), b.ID.String()))
+	})
+
+	return memoryPage(results, limit, func(u model.UserSearchResult) string {
+		return encodeCursor(u.DisplayName, u.ID)
+	}), nil
+}
+
+func (r *memoryUserRepo) ListByOwner(_ context.Context, ownerID uuid.UUID) ([]model.User, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	users := []model.User{}
+	for _, u := range t.users {
+		if u.OwnerID != nil && *u.OwnerID == ownerID {
+			users = append(users, u)
+		}
+	}
+	slices.SortFunc(users, func(a, b model.User) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return users, nil
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func TestUserRepo_CreateAndGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		u := createTestUser(t, store, "Alice")
+
+		byID, err := store.Users.GetByID(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("get by id: %v", err)
+		}
+		if byID.Email != u.Email || byID.Type != model.UserTypeHuman {
+			t.Errorf("unexpected user: %+v", byID)
+		}
+		if !byID.CreatedAt.Equal(u.CreatedAt) {
+			t.Errorf("expected created_at %v, got %v", u.CreatedAt, byID.CreatedAt)
+		}
+
+		byEmail, err := store.Users.GetByEmail(ctx, u.Email)
+		if err != nil {
+			t.Fatalf("get by email: %v", err)
+		}
+		if byEmail.ID != u.ID {
+			t.Errorf("expected id %s, got %s", u.ID, byEmail.ID)
+		}
+
+		if _, err := store.Users.GetByID(ctx, uuid.New()); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestUserRepo_CreateDuplicateEmail(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		u := createTestUser(t, store, "Alice")
+
+		dup := *u
+		dup.ID = uuid.New()
+		if err := store.Users.Create(context.Background(), &dup); !errors.Is(err, model.ErrConflict) {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+	})
+}
+
+func TestUserRepo_Update(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		u := createTestUser(t, store, "Alice")
+		name := "Alicia"
+
+		updated, err := store.Users.Update(context.Background(), u.ID, model.UpdateProfileParams{DisplayName: &name})
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if updated.DisplayName != name {
+			t.Errorf("expected display name %q, got %q", name, updated.DisplayName)
+		}
+		if updated.AvatarURL != nil {
+			t.Errorf("expected avatar to be unchanged, got %v", *updated.AvatarURL)
+		}
+	})
+}
+
+func TestUserRepo_SearchIsCaseInsensitive(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		createTestUser(t, store, "alice")
+		createTestUser(t, store, "Alicia")
+		createTestUser(t, store, "Bob")
+
+		page, err := store.Users.Search(ctx, "ALI", "", 10)
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(page.Items) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(page.Items))
+		}
+	})
+}
+
+func TestUserRepo_SearchPaginates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		for _, name := range []string{"Sam A", "Sam B", "Sam C"} {
+			createTestUser(t, store, name)
+		}
+
+		first, err := store.Users.Search(ctx, "sam", "", 2)
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(first.Items) != 2 || !first.HasMore || first.NextCursor == nil {
+			t.Fatalf("unexpected first page: %+v", first)
+		}
+
+		second, err := store.Users.Search(ctx, "sam", *first.NextCursor, 2)
+		if err != nil {
+			t.Fatalf("search page 2: %v", err)
+		}
+		if len(second.Items) != 1 || second.HasMore {
+			t.Fatalf("unexpected second page: %+v", second)
+		}
+		if second.Items[0].DisplayName != "Sam C" {
+			t.Errorf("expected Sam C, got %s", second.Items[0].DisplayName)
+		}
+	})
+}
+
+func TestUserRepo_ListByOwner(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		owner := createTestUser(t, store, "Owner")
+		now := time.Now().UTC()
+		bot := &model.User{
+			ID: uuid.New(), Email: "bot-" + uuid.NewString() + "@bots.invalid", DisplayName: "Bot",
+			Status: "offline", Type: model.UserTypeBot, OwnerID: &owner.ID, CreatedAt: now, UpdatedAt: now,
+		}
+		if err := store.Users.Create(ctx, bot); err != nil {
+			t.Fatalf("create bot: %v", err)
+		}
+
+		bots, err := store.Users.ListByOwner(ctx, owner.ID)
+		if err != nil {
+			t.Fatalf("list by owner: %v", err)
+		}
+		if len(bots) != 1 || bots[0].ID != bot.ID {
+			t.Fatalf("expected the bot, got %+v", bots)
+		}
+		if bots[0].OwnerID == nil || *bots[0].OwnerID != owner.ID {
+			t.Errorf("expected owner id %s, got %v", owner.ID, bots[0].OwnerID)
+		}
+	})
+}
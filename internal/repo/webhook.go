@@ -0,0 +1,495 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// WebhookRepository defines the data access contract for webhooks and their
+// delivery queue.
+type WebhookRepository interface {
+	Create(ctx context.Context, hook *model.Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error)
+	ListByConversation(ctx context.Context, conversationID uuid.UUID) ([]model.Webhook, error)
+	// Delete removes a webhook along with its deliveries.
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	CreateDelivery(ctx context.Context, d *model.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error)
+	// ListDeliveries returns a webhook's deliveries, newest first.
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, cursor string, limit int) (*model.Page[model.WebhookDelivery], error)
+	// ClaimDue returns up to limit pending deliveries due by now and moves
+	// their next attempt to leaseUntil, so that a concurrent dispatcher skips
+	// them while they are in flight.
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error)
+	// UpdateDelivery stores the outcome of an attempt, or a reset for redelivery.
+	UpdateDelivery(ctx context.Context, d *model.WebhookDelivery) error
+}
+
+type webhookRepo struct {
+	db DBTX
+}
+
+// NewWebhookRepo creates a new WebhookRepository backed by the given database.
+func NewWebhookRepo(db DBTX) WebhookRepository {
+	return &webhookRepo{db: db}
+}
+
+func (r *webhookRepo) Create(ctx context.Context, hook *model.Webhook) error {
+	ctx = statement(ctx, "webhookRepo.Create")
+	query := `
+		INSERT INTO webhooks (id, conversation_id, created_by, url, secret, events, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		hook.ID,
+		hook.ConversationID,
+		hook.CreatedBy,
+		hook.URL,
+		hook.Secret,
+		strings.Join(hook.Events, " "),
+		hook.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Webhook, error) {
+	ctx = statement(ctx, "webhookRepo.GetByID")
+	query := `
+		SELECT id, conversation_id, created_by, url, secret, events, created_at
+		FROM webhooks
+		WHERE id = $1
+	`
+	hook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (r *webhookRepo) ListByConversation(ctx context.Context, conversationID uuid.UUID) ([]model.Webhook, error) {
+	ctx = statement(ctx, "webhookRepo.ListByConversation")
+	query := `
+		SELECT id, conversation_id, created_by, url, secret, events, created_at
+		FROM webhooks
+		WHERE conversation_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []model.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo: scan webhook: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: list webhooks rows error: %w", err)
+	}
+	return hooks, nil
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx = statement(ctx, "webhookRepo.Delete")
+	// Deliveries are removed explicitly: SQLite does not enforce the cascade
+	// unless foreign keys are switched on for the connection.
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = $1`, id); err != nil {
+		return fmt.Errorf("repo: delete webhook deliveries: %w", err)
+	}
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("repo: delete webhook: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepo) CreateDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	ctx = statement(ctx, "webhookRepo.CreateDelivery")
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		d.ID,
+		d.WebhookID,
+		d.EventType,
+		string(d.Payload),
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ErrConflict
+		}
+		return fmt.Errorf("repo: create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event_type, payload, status, attempts, next_attempt_at,
+	last_attempt_at, response_status, last_error, created_at`
+
+func (r *webhookRepo) GetDelivery(ctx context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	ctx = statement(ctx, "webhookRepo.GetDelivery")
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: get webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (r *webhookRepo) ListDeliveries(ctx context.Context, webhookID uuid.UUID, cursor string, limit int) (*model.Page[model.WebhookDelivery], error) {
+	ctx = statement(ctx, "webhookRepo.ListDeliveries")
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	args := []any{webhookID}
+	whereCursor := ""
+	if cursor != "" {
+		cursorTime, cursorID, err := decodeTimeCursor(cursor)
+		if err != nil {
+			return nil, &model.ValidationError{Field: "cursor", Message: "invalid cursor"}
+		}
+		whereCursor = " AND (created_at, id) < ($2, $3)"
+		args = append(args, cursorTime, cursorID)
+	}
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries
+		WHERE webhook_id = $1%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, webhookDeliveryColumns, whereCursor, len(args))
+
+	deliveries, err := r.queryDeliveries(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list webhook deliveries: %w", err)
+	}
+
+	hasMore := len(deliveries) > limit
+	if hasMore {
+		deliveries = deliveries[:limit]
+	}
+	page := &model.Page[model.WebhookDelivery]{Items: deliveries, HasMore: hasMore}
+	if hasMore {
+		last := deliveries[len(deliveries)-1]
+		c := encodeTimeCursor(last.CreatedAt, last.ID)
+		page.NextCursor = &c
+	}
+	return page, nil
+}
+
+func (r *webhookRepo) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error) {
+	ctx = statement(ctx, "webhookRepo.ClaimDue")
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, id
+		LIMIT $3
+	`
+	due, err := r.queryDeliveries(ctx, query, model.WebhookDeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list due webhook deliveries: %w", err)
+	}
+
+	// A row another dispatcher claimed in the meantime is no longer due, so
+	// the conditional update skips it.
+	claimed := make([]model.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		res, err := r.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET next_attempt_at = $1
+			WHERE id = $2 AND status = $3 AND next_attempt_at <= $4
+		`, leaseUntil, d.ID, model.WebhookDeliveryPending, now)
+		if err != nil {
+			return nil, fmt.Errorf("repo: claim webhook delivery: %w", err)
+		}
+		if rows, _ := res.RowsAffected(); rows == 1 {
+			d.NextAttemptAt = leaseUntil
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *webhookRepo) UpdateDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	ctx = statement(ctx, "webhookRepo.UpdateDelivery")
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4, response_status = $5, last_error = $6
+		WHERE id = $7
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastAttemptAt,
+		d.ResponseStatus,
+		d.LastError,
+		d.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("repo: update webhook delivery: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepo) queryDeliveries(ctx context.Context, query string, args ...any) ([]model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row rowScanner) (*model.Webhook, error) {
+	hook := &model.Webhook{}
+	var events string
+	err := row.Scan(
+		&hook.ID,
+		&hook.ConversationID,
+		&hook.CreatedBy,
+		&hook.URL,
+		&hook.Secret,
+		&events,
+		&hook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	hook.Events = strings.Fields(events)
+	return hook, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*model.WebhookDelivery, error) {
+	d := &model.WebhookDelivery{}
+	var payload string
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastAttemptAt,
+		&d.ResponseStatus,
+		&d.LastError,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = []byte(payload)
+	return d, nil
+}
+
+type memoryWebhookRepo struct {
+	db *memoryDB
+}
+
+func (r *memoryWebhookRepo) Create(_ context.Context, hook *model.Webhook) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.webhooks[hook.ID]; ok {
+		return model.ErrConflict
+	}
+	stored := *hook
+	stored.Events = slices.Clone(hook.Events)
+	t.webhooks[hook.ID] = stored
+	return nil
+}
+
+func (r *memoryWebhookRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Webhook, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	hook, ok := t.webhooks[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	hook.Events = slices.Clone(hook.Events)
+	return &hook, nil
+}
+
+func (r *memoryWebhookRepo) ListByConversation(_ context.Context, conversationID uuid.UUID) ([]model.Webhook, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	hooks := []model.Webhook{}
+	for _, hook := range t.webhooks {
+		if hook.ConversationID == conversationID {
+			hook.Events = slices.Clone(hook.Events)
+			hooks = append(hooks, hook)
+		}
+	}
+	slices.SortFunc(hooks, func(a, b model.Webhook) int {
+		return compareTimeID(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return hooks, nil
+}
+
+func (r *memoryWebhookRepo) Delete(_ context.Context, id uuid.UUID) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.webhooks[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(t.webhooks, id)
+	maps.DeleteFunc(t.webhookDeliveries, func(_ uuid.UUID, d model.WebhookDelivery) bool { return d.WebhookID == id })
+	return nil
+}
+
+func (r *memoryWebhookRepo) CreateDelivery(_ context.Context, d *model.WebhookDelivery) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	if _, ok := t.webhookDeliveries[d.ID]; ok {
+		return model.ErrConflict
+	}
+	if _, ok := t.webhooks[d.WebhookID]; !ok {
+		return fmt.Errorf("repo: create webhook delivery: unknown webhook %s", d.WebhookID)
+	}
+	stored := *d
+	stored.Payload = slices.Clone(d.Payload)
+	t.webhookDeliveries[d.ID] = stored
+	return nil
+}
+
+func (r *memoryWebhookRepo) GetDelivery(_ context.Context, id uuid.UUID) (*model.WebhookDelivery, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+	d, ok := t.webhookDeliveries[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	d.Payload = slices.Clone(d.Payload)
+	return &d, nil
+}
+
+func (r *memoryWebhookRepo) ListDeliveries(_ context.Context, webhookID uuid.UUID, cursor string, limit int) (*model.Page[model.WebhookDelivery], error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	var (
+		cursorTime time.Time
+		cursorID   uuid.UUID
+	)
+	if cursor != "" {
+		var err error
+		cursorTime, cursorID, err = decodeTimeCursor(cursor)
+		if err != nil {
+			return nil, &model.ValidationError{Field: "cursor", Message: "invalid cursor"}
+		}
+	}
+
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	var deliveries []model.WebhookDelivery
+	for _, d := range t.webhookDeliveries {
+		if d.WebhookID != webhookID {
+			continue
+		}
+		if cursor != "" && compareTimeID(d.CreatedAt, d.ID, cursorTime, cursorID) >= 0 {
+			continue
+		}
+		d.Payload = slices.Clone(d.Payload)
+		deliveries = append(deliveries, d)
+	}
+	slices.SortFunc(deliveries, func(a, b model.WebhookDelivery) int {
+		return compareTimeID(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+
+	return memoryPage(deliveries, limit, func(d model.WebhookDelivery) string {
+		return encodeTimeCursor(d.CreatedAt, d.ID)
+	}), nil
+}
+
+func (r *memoryWebhookRepo) ClaimDue(_ context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookDelivery, error) {
+	t, unlock := r.db.lock()
+	defer unlock()
+
+	var due []model.WebhookDelivery
+	for _, d := range t.webhookDeliveries {
+		if d.Status == model.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(a, b model.WebhookDelivery) int {
+		return compareTimeID(a.NextAttemptAt, a.ID, b.NextAttemptAt, b.ID)
+	})
+
+	claimed := make([]model.WebhookDelivery, 0, min(limit, len(due)))
+	for _, d := range due[:min(limit, len(due))] {
+		d.NextAttemptAt = leaseUntil
+		t.webhookDeliveries[d.ID] = d
+		d.Payload = slices.Clone(d.Payload)
+		claimed = append(claimed, d)
+	}
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepo) UpdateDelivery(_ context.Context, d *model.WebhookDelivery) error {
+	t, unlock := r.db.lock()
+	defer unlock()
+	existing, ok := t.webhookDeliveries[d.ID]
+	if !ok {
+		return model.ErrNotFound
+	}
+	existing.Status = d.Status
+	existing.Attempts = d.Attempts
+	existing.NextAttemptAt = d.NextAttemptAt
+	existing.LastAttemptAt = d.LastAttemptAt
+	existing.ResponseStatus = d.ResponseStatus
+	existing.LastError = d.LastError
+	t.webhookDeliveries[d.ID] = existing
+	return nil
+}
@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+func createTestWebhook(t *testing.T, s *Store, conversationID, createdBy uuid.UUID, events ...string) *model.Webhook {
+	t.Helper()
+	hook := &model.Webhook{
+		ID: uuid.New(), ConversationID: conversationID, CreatedBy: createdBy,
+		URL: "https://example.com/hook", Secret: "whsec_test", Events: events,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.Webhooks.Create(context.Background(), hook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	return hook
+}
+
+func newTestDelivery(webhookID uuid.UUID, at time.Time) *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		ID: uuid.New(), WebhookID: webhookID, EventType: model.WebhookEventMessageCreated,
+		Payload: []byte(`{"type":"message.created"}`), Status: model.WebhookDeliveryPending,
+		NextAttemptAt: at, CreatedAt: at,
+	}
+}
+
+func TestWebhookRepo_CreateListAndDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+		other := createTestConversation(t, store, alice.ID)
+
+		hook := createTestWebhook(t, store, convo.ID, alice.ID, model.WebhookEventMessageCreated, model.WebhookEventConversationRenamed)
+		createTestWebhook(t, store, other.ID, alice.ID, model.WebhookEventMessageCreated)
+
+		got, err := store.Webhooks.GetByID(ctx, hook.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if got.Secret != "whsec_test" || len(got.Events) != 2 || !got.Subscribes(model.WebhookEventConversationRenamed) {
+			t.Errorf("unexpected webhook: %+v", got)
+		}
+
+		hooks, err := store.Webhooks.ListByConversation(ctx, convo.ID)
+		if err != nil || len(hooks) != 1 || hooks[0].ID != hook.ID {
+			t.Fatalf("expected the conversation's webhook only, got %+v, %v", hooks, err)
+		}
+
+		if err := store.Webhooks.CreateDelivery(ctx, newTestDelivery(hook.ID, time.Now().UTC())); err != nil {
+			t.Fatalf("create delivery: %v", err)
+		}
+		if err := store.Webhooks.Delete(ctx, hook.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := store.Webhooks.GetByID(ctx, hook.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+		if err := store.Webhooks.Delete(ctx, hook.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+		}
+		page, err := store.Webhooks.ListDeliveries(ctx, hook.ID, "", 10)
+		if err != nil || len(page.Items) != 0 {
+			t.Errorf("expected the deliveries to go with the webhook, got %+v, %v", page, err)
+		}
+	})
+}
+
+func TestWebhookRepo_ClaimDue(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+		hook := createTestWebhook(t, store, convo.ID, alice.ID, model.WebhookEventMessageCreated)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		due := newTestDelivery(hook.ID, now.Add(-time.Minute))
+		later := newTestDelivery(hook.ID, now.Add(time.Minute))
+		for _, d := range []*model.WebhookDelivery{due, later} {
+			if err := store.Webhooks.CreateDelivery(ctx, d); err != nil {
+				t.Fatalf("create delivery: %v", err)
+			}
+		}
+
+		lease := now.Add(30 * time.Second)
+		claimed, err := store.Webhooks.ClaimDue(ctx, now, lease, 10)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != due.ID || string(claimed[0].Payload) != string(due.Payload) {
+			t.Fatalf("expected only the due delivery, got %+v", claimed)
+		}
+		if again, err := store.Webhooks.ClaimDue(ctx, now, lease, 10); err != nil || len(again) != 0 {
+			t.Errorf("expected a claimed delivery to be skipped until its lease ends, got %d, %v", len(again), err)
+		}
+		if again, err := store.Webhooks.ClaimDue(ctx, lease, lease.Add(time.Minute), 10); err != nil || len(again) != 1 || again[0].ID != due.ID {
+			t.Errorf("expected an expired lease to be claimable, got %+v, %v", again, err)
+		}
+
+		// A finished delivery is never due again.
+		d := claimed[0]
+		status := 204
+		d.Status = model.WebhookDeliverySucceeded
+		d.Attempts = 1
+		d.LastAttemptAt = &now
+		d.ResponseStatus = &status
+		if err := store.Webhooks.UpdateDelivery(ctx, &d); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if again, err := store.Webhooks.ClaimDue(ctx, now.Add(time.Hour), now.Add(2*time.Hour), 10); err != nil || len(again) != 1 || again[0].ID != later.ID {
+			t.Errorf("expected only the later delivery, got %+v, %v", again, err)
+		}
+
+		got, err := store.Webhooks.GetDelivery(ctx, due.ID)
+		if err != nil {
+			t.Fatalf("get delivery: %v", err)
+		}
+		if got.Status != model.WebhookDeliverySucceeded || got.Attempts != 1 || got.ResponseStatus == nil || *got.ResponseStatus != 204 || got.LastError != nil {
+			t.Errorf("unexpected delivery: %+v", got)
+		}
+	})
+}
+
+func TestWebhookRepo_ListDeliveriesPaginates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store *Store) {
+		ctx := context.Background()
+		alice := createTestUser(t, store, "Alice")
+		convo := createTestConversation(t, store, alice.ID)
+		hook := createTestWebhook(t, store, convo.ID, alice.ID, model.WebhookEventMessageCreated)
+		base := time.Now().UTC().Truncate(time.Second)
+
+		var ids []uuid.UUID
+		for i := range 5 {
+			d := newTestDelivery(hook.ID, base.Add(time.Duration(i)*time.Second))
+			if err := store.Webhooks.CreateDelivery(ctx, d); err != nil {
+				t.Fatalf("create delivery: %v", err)
+			}
+			ids = append(ids, d.ID)
+		}
+
+		var got []uuid.UUID
+		cursor := ""
+		for {
+			page, err := store.Webhooks.ListDeliveries(ctx, hook.ID, cursor, 2)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			for _, d := range page.Items {
+				got = append(got, d.ID)
+			}
+			if !page.HasMore {
+				break
+			}
+			cursor = *page.NextCursor
+		}
+		if len(got) != 5 || got[0] != ids[4] || got[4] != ids[0] {
+			t.Errorf("expected all deliveries newest first, got %v", got)
+		}
+	})
+}
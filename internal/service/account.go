@@ -0,0 +1,154 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// purgeBatchSize bounds how many accounts a single PurgeDue call anonymizes.
+const purgeBatchSize = 100
+
+// AccountConfig holds configuration for account lifecycle.
+type AccountConfig struct {
+	// DeletionGrace is how long a DELETE /users/me request can be cancelled
+	// before the account is anonymized. Zero means 14 days.
+	DeletionGrace time.Duration
+}
+
+// AccountService handles personal data export and account deletion.
+type AccountService struct {
+	users    repo.UserRepository
+	accounts repo.AccountRepository
+	tx       repo.Transactor
+	grace    time.Duration
+}
+
+// NewAccountService creates a new AccountService. Deletion requests take effect
+// after the grace period, during which they can be cancelled.
+func NewAccountService(users repo.UserRepository, accounts repo.AccountRepository, tx repo.Transactor, config AccountConfig) *AccountService {
+	grace := config.DeletionGrace
+	if grace == 0 {
+		grace = 14 * 24 * time.Hour
+	}
+	return &AccountService{users: users, accounts: accounts, tx: tx, grace: grace}
+}
+
+// Export writes a zip archive of the user's personal data to w, one JSON file per category.
+func (s *AccountService) Export(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	ctx, span := tracer.Start(ctx, "AccountService.Export")
+	defer span.End()
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	data, err := s.accounts.Export(ctx, userID)
+	if err != nil {
+		return err
+	}
+	data.Profile = *user
+
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"profile.json", data.Profile},
+		{"sessions.json", data.Sessions},
+		{"conversations.json", data.Conversations},
+		{"messages.json", data.Messages},
+		{"blocks.json", data.Blocks},
+		{"reports.json", data.Reports},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("account: create %s: %w", f.name, err)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return fmt.Errorf("account: write %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("account: finish archive: %w", err)
+	}
+	return nil
+}
+
+// RequestDeletion schedules the account for anonymization after the grace period
+// and returns when it takes effect. Repeated requests keep the original schedule.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.RequestDeletion")
+	defer span.End()
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	at := time.Now().UTC().Add(s.grace)
+	if err := s.accounts.ScheduleDeletion(ctx, userID, at); err != nil {
+		return time.Time{}, err
+	}
+	return at, nil
+}
+
+// CancelDeletion cancels a pending deletion request. Returns ErrNotFound if none is pending.
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "AccountService.CancelDeletion")
+	defer span.End()
+
+	return s.accounts.CancelDeletion(ctx, userID)
+}
+
+// PurgeDue anonymizes accounts whose grace period has elapsed, together with the
+// bots they own, and returns how many accounts were anonymized.
+func (s *AccountService) PurgeDue(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.PurgeDue")
+	defer span.End()
+
+	now := time.Now().UTC()
+	ids, err := s.accounts.ListDueForDeletion(ctx, now, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		// Each account and its bots are scrubbed atomically.
+		err := s.tx.WithTx(ctx, func(tx *repo.Store) error {
+			bots, err := tx.Users.ListByOwner(ctx, id)
+			if err != nil {
+				return err
+			}
+			for _, bot := range bots {
+				if bot.Status == model.UserStatusDeleted {
+					continue
+				}
+				if err := tx.Accounts.Anonymize(ctx, bot.ID, now); err != nil {
+					return err
+				}
+			}
+			return tx.Accounts.Anonymize(ctx, id, now)
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// ---------------------------------------------------------------------------
+// Mock: AccountRepository
+// ---------------------------------------------------------------------------
+
+type mockAccountRepo struct {
+	mu         sync.Mutex
+	users      *mockUserRepo
+	exports    map[uuid.UUID]*model.AccountExport
+	anonymized []uuid.UUID
+}
+
+func newMockAccountRepo(users *mockUserRepo) *mockAccountRepo {
+	return &mockAccountRepo{users: users, exports: make(map[uuid.UUID]*model.AccountExport)}
+}
+
+func (m *mockAccountRepo) Export(_ context.Context, userID uuid.UUID) (*model.AccountExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if exp, ok := m.exports[userID]; ok {
+		cp := *exp
+		return &cp, nil
+	}
+	return &model.AccountExport{}, nil
+}
+
+func (m *mockAccountRepo) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	u, err := m.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	u.DeletionScheduledAt = &at
+	return nil
+}
+
+func (m *mockAccountRepo) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	u, err := m.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.DeletionScheduledAt == nil {
+		return model.ErrNotFound
+	}
+	u.DeletionScheduledAt = nil
+	return nil
+}
+
+func (m *mockAccountRepo) ListDueForDeletion(_ context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	m.users.mu.Lock()
+	defer m.users.mu.Unlock()
+	var ids []uuid.UUID
+	for _, u := range m.users.byID {
+		if u.DeletionScheduledAt != nil && !u.DeletionScheduledAt.After(now) && len(ids) < limit {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockAccountRepo) Anonymize(ctx context.Context, userID uuid.UUID, _ time.Time) error {
+	u, err := m.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u.DisplayName = model.DeletedUserDisplayName
+	u.Status = model.UserStatusDeleted
+	u.DeletionScheduledAt = nil
+	m.anonymized = append(m.anonymized, userID)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+func newAccountTestService(t *testing.T, grace time.Duration) (*AccountService, *mockUserRepo, *mockAccountRepo, *model.User) {
+	t.Helper()
+	users := newMockUserRepo()
+	user := &model.User{ID: uuid.New(), Email: "erin@example.com", DisplayName: "Erin", Type: model.UserTypeHuman}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	accounts := newMockAccountRepo(users)
+	return NewAccountService(users, accounts, &repo.Store{Users: users, Accounts: accounts}, AccountConfig{DeletionGrace: grace}), users, accounts, user
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Export
+// ---------------------------------------------------------------------------
+
+func TestExport_WritesZipOfJSONFiles(t *testing.T) {
+	svc, _, accounts, user := newAccountTestService(t, time.Hour)
+	convID := uuid.New()
+	accounts.exports[user.ID] = &model.AccountExport{
+		Messages: []model.Message{{ID: uuid.New(), ConversationID: convID, SenderID: user.ID, Body: "hello"}},
+	}
+
+	var buf bytes.Buffer
+	if err := svc.Export(context.Background(), user.ID, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a valid zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	for _, name := range []string{"profile.json", "sessions.json", "conversations.json", "messages.json", "blocks.json", "reports.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in archive", name)
+		}
+	}
+
+	var profile map[string]any
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatalf("profile.json: %v", err)
+	}
+	if profile["email"] != "erin@example.com" {
+		t.Errorf("expected profile email, got %v", profile["email"])
+	}
+	if _, leaked := profile["password_hash"]; leaked {
+		t.Error("password hash must not be exported")
+	}
+
+	var msgs []model.Message
+	if err := json.Unmarshal(files["messages.json"], &msgs); err != nil {
+		t.Fatalf("messages.json: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Body != "hello" {
+		t.Errorf("expected exported message, got %+v", msgs)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Deletion
+// ---------------------------------------------------------------------------
+
+func TestRequestDeletion_SchedulesAfterGraceAndIsIdempotent(t *testing.T) {
+	svc, _, _, user := newAccountTestService(t, 48*time.Hour)
+	ctx := context.Background()
+
+	at, err := svc.RequestDeletion(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Until(at); d < 47*time.Hour || d > 48*time.Hour {
+		t.Errorf("expected deletion in ~48h, got %v", d)
+	}
+
+	again, err := svc.RequestDeletion(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !again.Equal(at) {
+		t.Errorf("expected repeated request to keep %v, got %v", at, again)
+	}
+}
+
+func TestCancelDeletion(t *testing.T) {
+	svc, _, _, user := newAccountTestService(t, time.Hour)
+	ctx := context.Background()
+
+	if err := svc.CancelDeletion(ctx, user.ID); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound with nothing pending, got %v", err)
+	}
+
+	svc.RequestDeletion(ctx, user.ID)
+	if err := svc.CancelDeletion(ctx, user.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if user.DeletionScheduledAt != nil {
+		t.Error("expected schedule to be cleared")
+	}
+}
+
+func TestPurgeDue_AnonymizesOnlyAfterGrace(t *testing.T) {
+	svc, _, accounts, user := newAccountTestService(t, time.Hour)
+	ctx := context.Background()
+
+	svc.RequestDeletion(ctx, user.ID)
+	n, err := svc.PurgeDue(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 0 || user.Status == model.UserStatusDeleted {
+		t.Fatalf("expected nothing purged during the grace period, got %d", n)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	user.DeletionScheduledAt = &past
+	n, err = svc.PurgeDue(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 account purged, got %d", n)
+	}
+	if user.DisplayName != model.DeletedUserDisplayName || user.Status != model.UserStatusDeleted {
+		t.Errorf("expected user anonymized, got %+v", user)
+	}
+	if len(accounts.anonymized) != 1 {
+		t.Errorf("expected a single anonymization, got %d", len(accounts.anonymized))
+	}
+}
+
+func TestPurgeDue_AnonymizesOwnedBots(t *testing.T) {
+	svc, users, accounts, user := newAccountTestService(t, time.Hour)
+	ctx := context.Background()
+
+	bot := &model.User{ID: uuid.New(), Email: "bot@bots.invalid", DisplayName: "Bot", Type: model.UserTypeBot, OwnerID: &user.ID}
+	users.Create(ctx, bot)
+
+	past := time.Now().Add(-time.Minute)
+	user.DeletionScheduledAt = &past
+	if _, err := svc.PurgeDue(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bot.Status != model.UserStatusDeleted {
+		t.Error("expected owned bot to be anonymized")
+	}
+	if len(accounts.anonymized) != 2 || accounts.anonymized[0] != bot.ID {
+		t.Errorf("expected bot then owner to be anonymized, got %v", accounts.anonymized)
+	}
+}
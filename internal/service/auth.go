@@ -5,8 +5,10 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -21,6 +23,10 @@ type AuthConfig struct {
 	JWTSecret          string
 	AccessTokenExpiry  time.Duration
 	RefreshTokenExpiry time.Duration
+	OIDCProviders      []OIDCProviderConfig
+	Lockout            LockoutPolicy
+	PasswordPolicy     PasswordPolicy
+	BcryptCost         int // bcrypt work factor for new hashes; lower-cost hashes are upgraded on login
 }
 
 // AuthTokens is the token pair returned to the client.
@@ -40,41 +46,61 @@ type AuthResult struct {
 type AuthService struct {
 	users    repo.UserRepository
 	sessions repo.SessionRepository
+	attempts repo.LoginAttemptRepository
+	tx       repo.Transactor
 	config   AuthConfig
+	metrics  Metrics
+
+	// dummyHash is compared against when there is no password to check, so
+	// that a login takes as long whether or not the email has an account.
+	dummyHash func() []byte
 }
 
 // NewAuthService creates a new AuthService.
-func NewAuthService(users repo.UserRepository, sessions repo.SessionRepository, config AuthConfig) *AuthService {
+func NewAuthService(users repo.UserRepository, sessions repo.SessionRepository, attempts repo.LoginAttemptRepository, tx repo.Transactor, config AuthConfig) *AuthService {
 	if config.AccessTokenExpiry == 0 {
 		config.AccessTokenExpiry = 15 * time.Minute
 	}
 	if config.RefreshTokenExpiry == 0 {
 		config.RefreshTokenExpiry = 7 * 24 * time.Hour
 	}
-	return &AuthService{users: users, sessions: sessions, config: config}
+	if config.BcryptCost == 0 {
+		config.BcryptCost = bcrypt.DefaultCost
+	}
+	config.Lockout = config.Lockout.withDefaults()
+	config.PasswordPolicy = config.PasswordPolicy.withDefaults()
+	s := &AuthService{users: users, sessions: sessions, attempts: attempts, tx: tx, config: config, metrics: noopMetrics{}}
+	s.dummyHash = sync.OnceValue(func() []byte {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("not a password"), config.BcryptCost)
+		return hash
+	})
+	return s
 }
 
 // Register creates a new user account and returns tokens.
 func (s *AuthService) Register(ctx context.Context, email, password, displayName string) (*AuthResult, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
+
 	email = strings.TrimSpace(strings.ToLower(email))
 	displayName = strings.TrimSpace(displayName)
 
 	if email == "" {
 		return nil, &model.ValidationError{Field: "email", Message: "must not be empty"}
 	}
-	if password == "" {
-		return nil, &model.ValidationError{Field: "password", Message: "must not be empty"}
+	if isBotEmail(email) {
+		return nil, &model.ValidationError{Field: "email", Message: "uses a domain reserved for bots"}
 	}
-	if len(password) < 8 {
-		return nil, &model.ValidationError{Field: "password", Message: "must be at least 8 characters"}
+	if err := s.config.PasswordPolicy.Validate("password", password); err != nil {
+		return nil, err
 	}
 	if displayName == "" {
 		return nil, &model.ValidationError{Field: "display_name", Message: "must not be empty"}
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := s.hashPassword(password)
 	if err != nil {
-		return nil, fmt.Errorf("auth: hash password: %w", err)
+		return nil, err
 	}
 
 	now := time.Now().UTC()
@@ -84,6 +110,7 @@ func (s *AuthService) Register(ctx context.Context, email, password, displayName
 		PasswordHash: string(hash),
 		DisplayName:  displayName,
 		Status:       "offline",
+		Type:         model.UserTypeHuman,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -91,6 +118,7 @@ func (s *AuthService) Register(ctx context.Context, email, password, displayName
 	if err := s.users.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	s.metrics.UserRegistered("password")
 
 	tokens, err := s.issueTokens(ctx, user.ID)
 	if err != nil {
@@ -101,7 +129,11 @@ func (s *AuthService) Register(ctx context.Context, email, password, displayName
 }
 
 // Login authenticates an existing user and returns tokens.
-func (s *AuthService) Login(ctx context.Context, email, password string) (*AuthResult, error) {
+// Repeated failures for the same account or client IP lock further attempts out.
+func (s *AuthService) Login(ctx context.Context, email, password, clientIP string) (*AuthResult, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
 	email = strings.TrimSpace(strings.ToLower(email))
 
 	if email == "" {
@@ -111,25 +143,61 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (*AuthR
 		return nil, &model.ValidationError{Field: "password", Message: "must not be empty"}
 	}
 
+	// Check lockouts before doing any bcrypt work.
+	if err := s.checkLockout(ctx, email, clientIP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.users.GetByEmail(ctx, email)
-	if err != nil {
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
 		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	// Accounts without a password, and emails without an account, pay for a
+	// comparison too, or the response time would tell them apart.
+	hasPassword := user != nil && user.PasswordHash != ""
+	var hash []byte
+	if hasPassword {
+		hash = []byte(user.PasswordHash)
+	} else {
+		hash = s.dummyHash()
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || !hasPassword {
+		// Unknown accounts count too, so probing for emails is throttled the same way.
+		if err := s.recordFailure(ctx, email, clientIP); err != nil {
+			return nil, err
+		}
 		return nil, model.ErrNotFound
 	}
 
+	if err := s.clearAccountFailures(ctx, email); err != nil {
+		return nil, err
+	}
+
+	// Upgrade hashes created under a lower cost while we have the plaintext.
+	// Best effort: a failed rehash must not fail the login.
+	if cost, err := bcrypt.Cost([]byte(user.PasswordHash)); err == nil && cost < s.config.BcryptCost {
+		if hash, err := s.hashPassword(password); err == nil {
+			if s.users.UpdatePasswordHash(ctx, user.ID, hash) == nil {
+				user.PasswordHash = hash
+			}
+		}
+	}
+
 	tokens, err := s.issueTokens(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	s.metrics.LoginSucceeded("password")
 
 	return &AuthResult{User: user, Tokens: *tokens}, nil
 }
 
 // RefreshToken validates a refresh token and issues a new token pair.
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*AuthTokens, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RefreshToken")
+	defer span.End()
+
 	if refreshToken == "" {
 		return nil, &model.ValidationError{Field: "refresh_token", Message: "must not be empty"}
 	}
@@ -149,6 +217,9 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*A
 
 // Logout revokes the session associated with the given refresh token.
 func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
 	if refreshToken == "" {
 		return &model.ValidationError{Field: "refresh_token", Message: "must not be empty"}
 	}
@@ -163,6 +234,71 @@ func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
 	return s.sessions.Revoke(ctx, session.ID)
 }
 
+// ChangePassword replaces the user's password after verifying the current one.
+// All existing sessions are revoked and a fresh token pair is returned for the caller.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword, clientIP string) (*AuthTokens, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	if currentPassword == "" {
+		return nil, &model.ValidationError{Field: "current_password", Message: "must not be empty"}
+	}
+	if err := s.config.PasswordPolicy.Validate("new_password", newPassword); err != nil {
+		return nil, err
+	}
+	if newPassword == currentPassword {
+		return nil, &model.ValidationError{Field: "new_password", Message: "must differ from the current password"}
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordHash == "" {
+		return nil, &model.ValidationError{Field: "current_password", Message: "account has no password set"}
+	}
+
+	// A stolen access token must not make guessing the password cheaper than
+	// logging in does, so wrong guesses count towards the same lockouts.
+	if err := s.checkLockout(ctx, user.Email, clientIP); err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		if err := s.recordFailure(ctx, user.Email, clientIP); err != nil {
+			return nil, err
+		}
+		return nil, &model.ValidationError{Field: "current_password", Message: "is incorrect"}
+	}
+	if err := s.clearAccountFailures(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	hash, err := s.hashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tx.WithTx(ctx, func(tx *repo.Store) error {
+		if err := tx.Users.UpdatePasswordHash(ctx, userID, hash); err != nil {
+			return err
+		}
+		return tx.Sessions.RevokeAllForUser(ctx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, userID)
+}
+
+// hashPassword hashes a password with the configured bcrypt cost.
+func (s *AuthService) hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.config.BcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("auth: hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
 // issueTokens generates a JWT access token and a random refresh token, persisting the session.
 func (s *AuthService) issueTokens(ctx context.Context, userID uuid.UUID) (*AuthTokens, error) {
 	now := time.Now().UTC()
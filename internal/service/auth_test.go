@@ -3,12 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -81,6 +83,29 @@ func (m *mockUserRepo) Search(_ context.Context, _ string, _ string, _ int) (*mo
 	return &model.Page[model.UserSearchResult]{Items: []model.UserSearchResult{}}, nil
 }
 
+func (m *mockUserRepo) UpdatePasswordHash(_ context.Context, id uuid.UUID, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.byID[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+func (m *mockUserRepo) ListByOwner(_ context.Context, ownerID uuid.UUID) ([]model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []model.User
+	for _, u := range m.byID {
+		if u.OwnerID != nil && *u.OwnerID == ownerID {
+			users = append(users, *u)
+		}
+	}
+	return users, nil
+}
+
 // ---------------------------------------------------------------------------
 // Mock: SessionRepository
 // ---------------------------------------------------------------------------
@@ -156,7 +181,7 @@ func testAuthConfig() AuthConfig {
 func TestRegister_Success(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
 	result, err := svc.Register(context.Background(), "alice@example.com", "strongpass", "Alice")
 	if err != nil {
@@ -190,7 +215,7 @@ func TestRegister_Success(t *testing.T) {
 func TestRegister_EmptyEmail(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
 	_, err := svc.Register(context.Background(), "", "strongpass", "Alice")
 	if err == nil {
@@ -212,7 +237,7 @@ func TestRegister_EmptyEmail(t *testing.T) {
 func TestRegister_ShortPassword(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
 	_, err := svc.Register(context.Background(), "alice@example.com", "short", "Alice")
 	if err == nil {
@@ -234,7 +259,7 @@ func TestRegister_ShortPassword(t *testing.T) {
 func TestRegister_DuplicateEmail(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
 	// Register the first user.
 	_, err := svc.Register(context.Background(), "alice@example.com", "strongpass", "Alice")
@@ -252,6 +277,20 @@ func TestRegister_DuplicateEmail(t *testing.T) {
 	}
 }
 
+func TestRegister_ReservedBotDomain(t *testing.T) {
+	users := newMockUserRepo()
+	sessions := newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+
+	for _, email := range []string{BuiltinBotEmail, "Someone@Bots.Invalid", "x@sub.bots.invalid"} {
+		_, err := svc.Register(context.Background(), email, "strongpass", "Mallory")
+		var ve *model.ValidationError
+		if !errors.As(err, &ve) || ve.Field != "email" {
+			t.Errorf("%s: expected email ValidationError, got %v", email, err)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Tests: Login
 // ---------------------------------------------------------------------------
@@ -259,7 +298,7 @@ func TestRegister_DuplicateEmail(t *testing.T) {
 func TestLogin_Success(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
 	// Register a user first so there is a valid password hash.
 	_, err := svc.Register(context.Background(), "bob@example.com", "correctpass", "Bob")
@@ -267,7 +306,7 @@ func TestLogin_Success(t *testing.T) {
 		t.Fatalf("register failed: %v", err)
 	}
 
-	result, err := svc.Login(context.Background(), "bob@example.com", "correctpass")
+	result, err := svc.Login(context.Background(), "bob@example.com", "correctpass", "")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -288,7 +327,7 @@ func TestLogin_Success(t *testing.T) {
 func TestLogin_WrongPassword(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
 	// Seed a user with a known password hash.
 	hash, _ := bcrypt.GenerateFromPassword([]byte("correctpass"), bcrypt.DefaultCost)
@@ -307,7 +346,7 @@ func TestLogin_WrongPassword(t *testing.T) {
 	users.byID[user.ID] = user
 	users.mu.Unlock()
 
-	_, err := svc.Login(context.Background(), "carol@example.com", "wrongpass")
+	_, err := svc.Login(context.Background(), "carol@example.com", "wrongpass", "")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -319,9 +358,9 @@ func TestLogin_WrongPassword(t *testing.T) {
 func TestLogin_NotFound(t *testing.T) {
 	users := newMockUserRepo()
 	sessions := newMockSessionRepo()
-	svc := NewAuthService(users, sessions, testAuthConfig())
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
 
-	_, err := svc.Login(context.Background(), "nobody@example.com", "anypass")
+	_, err := svc.Login(context.Background(), "nobody@example.com", "anypass", "")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -329,3 +368,61 @@ func TestLogin_NotFound(t *testing.T) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
+
+func TestLogin_HashesWithoutAPassword(t *testing.T) {
+	users := newMockUserRepo()
+	sessions := newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+	dummy := svc.dummyHash
+	compared := 0
+	svc.dummyHash = func() []byte { compared++; return dummy() }
+
+	sso := &model.User{ID: uuid.New(), Email: "sso@example.com", DisplayName: "SSO"}
+	if err := users.Create(context.Background(), sso); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for _, email := range []string{"nobody@example.com", "sso@example.com"} {
+		if _, err := svc.Login(context.Background(), email, "anypass", ""); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", email, err)
+		}
+	}
+	if compared != 2 {
+		t.Errorf("expected both logins to compare against the dummy hash, got %d", compared)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Mock: Metrics
+// ---------------------------------------------------------------------------
+
+type mockMetrics struct {
+	events []string
+}
+
+func (m *mockMetrics) MessageSent()                 { m.events = append(m.events, "message") }
+func (m *mockMetrics) UserRegistered(method string) { m.events = append(m.events, "register:"+method) }
+func (m *mockMetrics) LoginSucceeded(method string) { m.events = append(m.events, "login:"+method) }
+
+func TestAuthService_RecordsMetrics(t *testing.T) {
+	metrics := &mockMetrics{}
+	users, sessions := newMockUserRepo(), newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+	svc.metrics = metrics
+	ctx := context.Background()
+
+	if _, err := svc.Register(ctx, "alice@example.com", "strongpass", "Alice"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if _, err := svc.Login(ctx, "alice@example.com", "wrongpass", "10.0.0.1"); err == nil {
+		t.Fatal("expected wrong password to fail")
+	}
+	if _, err := svc.Login(ctx, "alice@example.com", "strongpass", "10.0.0.1"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	want := []string{"register:password", "login:password"}
+	if !slices.Equal(metrics.events, want) {
+		t.Errorf("expected events %v, got %v", want, metrics.events)
+	}
+}
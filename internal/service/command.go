@@ -0,0 +1,464 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CommandPrefix starts a message that invokes a slash command.
+const CommandPrefix = "/"
+
+// BuiltinBotEmail identifies the bot account that answers built-in commands.
+// It is created the first time a command needs it.
+const BuiltinBotEmail = "commands@" + botEmailDomain
+
+const (
+	builtinBotName             = "Commands"
+	maxCommandsPerConversation = 20
+	maxCommandDescriptionLen   = 200
+
+	// commandTimeout bounds an HTTP command's callback, including reading
+	// its reply.
+	commandTimeout = 5 * time.Second
+
+	// commandWorkers is how many slash commands run at once.
+	commandWorkers = 8
+	// commandQueueSize is how many slash commands may wait for a worker.
+	// Commands sent while the queue is full get no reply.
+	commandQueueSize = 256
+)
+
+// commandNamePattern is what may follow the slash. Anything else, like a
+// path, is an ordinary message.
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// CommandRequest is one invocation of a slash command.
+type CommandRequest struct {
+	Name           string    `json:"command"` // without the slash
+	Args           string    `json:"args"`    // the rest of the message
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`    // who sent the command
+	MessageID      uuid.UUID `json:"message_id"` // the message that invoked it
+}
+
+// CommandReply is what an HTTP command's endpoint responds with. An empty
+// text posts nothing.
+type CommandReply struct {
+	Text string `json:"text"`
+}
+
+// Command is a slash command implemented in Go. Its reply, if not empty, is
+// posted to the conversation by the built-in bot. A *model.ValidationError is
+// shown to the conversation as a usage hint; other errors are not.
+type Command interface {
+	Run(ctx context.Context, req CommandRequest) (string, error)
+}
+
+// CommandFunc adapts a function to the Command interface.
+type CommandFunc func(ctx context.Context, req CommandRequest) (string, error)
+
+// Run calls f.
+func (f CommandFunc) Run(ctx context.Context, req CommandRequest) (string, error) {
+	return f(ctx, req)
+}
+
+type builtinCommand struct {
+	usage       string // arguments, for /help
+	description string
+	cmd         Command
+}
+
+// CreateCommandParams holds the inputs for registering an HTTP command.
+type CreateCommandParams struct {
+	Name        string
+	Description string
+	URL         string
+}
+
+// CommandService routes messages that start with a slash to built-in
+// commands, or to the HTTP commands registered for the conversation, and
+// posts their replies as bots. It also manages HTTP commands and delivers the
+// built-in commands' reminders.
+type CommandService struct {
+	integrations repo.IntegrationRepository
+	convos       repo.ConversationRepository
+	users        repo.UserRepository
+	reminders    repo.ReminderRepository
+	polls        repo.PollRepository
+	tx           repo.Transactor
+	messages     *MessageService
+	client       *http.Client
+	now          func() time.Time
+
+	builtins map[string]builtinCommand
+
+	botMu sync.Mutex
+	botID uuid.UUID // the built-in bot, once known
+
+	queueMu sync.Mutex
+	queue   chan commandJob // created with the workers by the first command
+	closed  bool            // set by Shutdown
+	workers sync.WaitGroup
+}
+
+// commandJob is a slash command message waiting for a worker.
+type commandJob struct {
+	ctx context.Context
+	msg *model.Message
+}
+
+// NewCommandService creates a new CommandService with the built-in commands
+// registered. Replies are posted through messages. HTTP commands are called
+// with client, or if it is nil with NewOutboundClient's default client.
+func NewCommandService(integrations repo.IntegrationRepository, convos repo.ConversationRepository, users repo.UserRepository,
+	reminders repo.ReminderRepository, polls repo.PollRepository, tx repo.Transactor, messages *MessageService, client *http.Client) *CommandService {
+	if client == nil {
+		client = NewOutboundClient(OutboundConfig{})
+	}
+	s := &CommandService{
+		integrations: integrations,
+		convos:       convos,
+		users:        users,
+		reminders:    reminders,
+		polls:        polls,
+		tx:           tx,
+		messages:     messages,
+		client:       client,
+		now:          time.Now,
+		builtins:     make(map[string]builtinCommand),
+	}
+	s.registerBuiltins()
+	return s
+}
+
+// Register adds a built-in command. It panics if the name is malformed or
+// taken, like http.Handle does for patterns.
+func (s *CommandService) Register(name, usage, description string, cmd Command) {
+	if !commandNamePattern.MatchString(name) {
+		panic("service: invalid command name " + name)
+	}
+	if _, ok := s.builtins[name]; ok {
+		panic("service: command /" + name + " registered twice")
+	}
+	s.builtins[name] = builtinCommand{usage: usage, description: description, cmd: cmd}
+}
+
+// run answers a message that may be a command. It runs after the message's
+// request has finished, so failures are recorded on its span.
+func (s *CommandService) run(ctx context.Context, msg *model.Message) {
+	ctx, span := tracer.Start(ctx, "CommandService.run")
+	defer span.End()
+
+	req, ok := parseCommand(msg)
+	if !ok {
+		return
+	}
+	botID, reply, err := s.execute(ctx, req)
+	if err == nil && reply != "" {
+		_, err = s.messages.PostAsBot(ctx, botID, req.ConversationID, reply)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "command failed")
+	}
+}
+
+// enqueue hands a slash command message to the workers, starting them on
+// first use. It reports false if the queue is full or shut down, in which
+// case the command is not run.
+func (s *CommandService) enqueue(ctx context.Context, msg *model.Message) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.queue == nil {
+		s.queue = make(chan commandJob, commandQueueSize)
+		for range commandWorkers {
+			s.workers.Go(s.work)
+		}
+	}
+	select {
+	case s.queue <- commandJob{ctx: context.WithoutCancel(ctx), msg: msg}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *CommandService) work() {
+	for job := range s.queue {
+		s.run(job.ctx, job.msg)
+	}
+}
+
+// Shutdown stops taking slash commands and waits until the queued ones have
+// run, or until ctx is done.
+func (s *CommandService) Shutdown(ctx context.Context) error {
+	s.queueMu.Lock()
+	if !s.closed {
+		s.closed = true
+		if s.queue != nil {
+			close(s.queue)
+		}
+	}
+	s.queueMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute runs a command and returns its reply and the bot to post it as.
+func (s *CommandService) execute(ctx context.Context, req CommandRequest) (uuid.UUID, string, error) {
+	if builtin, ok := s.builtins[req.Name]; ok {
+		botID, err := s.builtinBot(ctx)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		reply, err := builtin.cmd.Run(ctx, req)
+		var ve *model.ValidationError
+		if errors.As(err, &ve) {
+			return botID, fmt.Sprintf("/%s: %s. Usage: /%s %s", req.Name, ve.Message, req.Name, builtin.usage), nil
+		}
+		return botID, reply, err
+	}
+
+	cmd, err := s.integrations.GetCommand(ctx, req.ConversationID, req.Name)
+	if errors.Is(err, model.ErrNotFound) {
+		botID, err := s.builtinBot(ctx)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		return botID, fmt.Sprintf("Unknown command /%s. Type /help to see the commands available here.", req.Name), nil
+	}
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	reply, err := s.callback(ctx, cmd, req)
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		return cmd.BotID, fmt.Sprintf("/%s is not responding right now.", cmd.Name), nil
+	}
+	return cmd.BotID, reply, nil
+}
+
+// callback POSTs a command to its endpoint, signed like a webhook delivery,
+// and returns the text of the reply.
+func (s *CommandService) callback(ctx context.Context, cmd *model.BotCommand, req CommandRequest) (string, error) {
+	ctx, span := tracer.Start(ctx, "CommandService.callback")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("command: encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cmd.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(WebhookSignatureHeader, WebhookSignature(cmd.Secret, s.now(), body))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("endpoint responded %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var reply CommandReply
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return "", nil
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return "", fmt.Errorf("decode reply: %w", err)
+	}
+	return reply.Text, nil
+}
+
+// builtinBot returns the ID of the bot that answers built-in commands,
+// creating its account the first time.
+func (s *CommandService) builtinBot(ctx context.Context) (uuid.UUID, error) {
+	s.botMu.Lock()
+	defer s.botMu.Unlock()
+	if s.botID != uuid.Nil {
+		return s.botID, nil
+	}
+
+	bot, err := s.users.GetByEmail(ctx, BuiltinBotEmail)
+	if errors.Is(err, model.ErrNotFound) {
+		now := time.Now().UTC()
+		bot = &model.User{
+			ID:          uuid.New(),
+			Email:       BuiltinBotEmail,
+			DisplayName: builtinBotName,
+			Status:      "offline",
+			Type:        model.UserTypeBot,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		err = s.users.Create(ctx, bot)
+		if errors.Is(err, model.ErrConflict) {
+			// Another instance created it first.
+			bot, err = s.users.GetByEmail(ctx, BuiltinBotEmail)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if bot.Type != model.UserTypeBot {
+		// Only possible if the address was taken before the bot domain was
+		// reserved. Posting as that account would put words in a person's mouth.
+		return uuid.Nil, fmt.Errorf("commands: %s belongs to a %s account, not the built-in bot", BuiltinBotEmail, bot.Type)
+	}
+	s.botID = bot.ID
+	return s.botID, nil
+}
+
+// CreateCommand registers an HTTP command for a conversation the caller owns,
+// along with the bot that posts its replies. The returned command carries the
+// secret its requests are signed with, which is not shown again.
+func (s *CommandService) CreateCommand(ctx context.Context, userID, conversationID uuid.UUID, params CreateCommandParams) (*model.BotCommand, error) {
+	ctx, span := tracer.Start(ctx, "CommandService.CreateCommand")
+	defer span.End()
+
+	name := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(params.Name)), CommandPrefix)
+	if !commandNamePattern.MatchString(name) {
+		return nil, &model.ValidationError{Field: "name", Message: "must be 1-32 lowercase letters, digits, dashes or underscores"}
+	}
+	if _, ok := s.builtins[name]; ok {
+		return nil, &model.ValidationError{Field: "name", Message: "is a built-in command"}
+	}
+	description := strings.TrimSpace(params.Description)
+	if len(description) > maxCommandDescriptionLen {
+		return nil, &model.ValidationError{Field: "description", Message: fmt.Sprintf("must be %d characters or fewer", maxCommandDescriptionLen)}
+	}
+	if err := validateWebhookURL(params.URL); err != nil {
+		return nil, err
+	}
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.integrations.ListCommands(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxCommandsPerConversation {
+		return nil, &model.ValidationError{Field: "name", Message: fmt.Sprintf("a conversation can have at most %d commands", maxCommandsPerConversation)}
+	}
+	if slices.ContainsFunc(existing, func(c model.BotCommand) bool { return c.Name == name }) {
+		return nil, model.ErrConflict
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("command: generate secret: %w", err)
+	}
+	bot := newBot(userID, CommandPrefix+name)
+	cmd := &model.BotCommand{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		BotID:          bot.ID,
+		CreatedBy:      userID,
+		Name:           name,
+		Description:    description,
+		URL:            params.URL,
+		Secret:         WebhookSecretPrefix + hex.EncodeToString(secret),
+		CreatedAt:      time.Now().UTC(),
+	}
+	// The bot and its command are created together or not at all.
+	err = s.tx.WithTx(ctx, func(tx *repo.Store) error {
+		if err := tx.Users.Create(ctx, bot); err != nil {
+			return err
+		}
+		return tx.Integrations.CreateCommand(ctx, cmd)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// ListCommands returns the HTTP commands of a conversation the caller owns.
+func (s *CommandService) ListCommands(ctx context.Context, userID, conversationID uuid.UUID) ([]model.BotCommand, error) {
+	ctx, span := tracer.Start(ctx, "CommandService.ListCommands")
+	defer span.End()
+
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.integrations.ListCommands(ctx, conversationID)
+}
+
+// DeleteCommand removes an HTTP command from a conversation the caller owns.
+// Its bot stays, so that the messages it posted keep their sender.
+func (s *CommandService) DeleteCommand(ctx context.Context, userID, conversationID, commandID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "CommandService.DeleteCommand")
+	defer span.End()
+
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return err
+	}
+	return s.integrations.DeleteCommand(ctx, conversationID, commandID)
+}
+
+// parseCommand splits a message like "/poll Lunch? | Pizza | Tacos" into a
+// command request. It reports false for messages that are not commands.
+func parseCommand(msg *model.Message) (CommandRequest, bool) {
+	text, ok := strings.CutPrefix(msg.Body, CommandPrefix)
+	if !ok {
+		return CommandRequest{}, false
+	}
+	name, args := text, ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		name, args = text[:i], text[i:]
+	}
+	name = strings.ToLower(name)
+	if !commandNamePattern.MatchString(name) {
+		return CommandRequest{}, false
+	}
+	return CommandRequest{
+		Name:           name,
+		Args:           strings.TrimSpace(args),
+		ConversationID: msg.ConversationID,
+		UserID:         msg.SenderID,
+		MessageID:      msg.ID,
+	}, true
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// ReminderBatchSize is the most reminders one DeliverDueReminders call posts.
+const ReminderBatchSize = 50
+
+const (
+	maxPollOptions      = 10
+	maxPollQuestionLen  = 300
+	maxReminderDelay    = 30 * 24 * time.Hour
+	maxReminderTextLen  = 1000
+	pollOptionSeparator = "|"
+)
+
+func (s *CommandService) registerBuiltins() {
+	s.Register("help", "", "List the commands available here", CommandFunc(s.help))
+	s.Register("poll", "Question | Option | Option…", "Start a poll", CommandFunc(s.poll))
+	s.Register("vote", "<number>", "Vote in the latest poll", CommandFunc(s.vote))
+	s.Register("remind", "<duration> <text>", "Post a reminder later, e.g. /remind 30m stand-up", CommandFunc(s.remind))
+}
+
+// help lists the built-in commands and the conversation's HTTP commands.
+func (s *CommandService) help(ctx context.Context, req CommandRequest) (string, error) {
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, name := range slices.Sorted(func(yield func(string) bool) {
+		for name := range s.builtins {
+			if !yield(name) {
+				return
+			}
+		}
+	}) {
+		c := s.builtins[name]
+		fmt.Fprintf(&b, "\n/%s", name)
+		if c.usage != "" {
+			b.WriteString(" " + c.usage)
+		}
+		b.WriteString(" — " + c.description)
+	}
+
+	cmds, err := s.integrations.ListCommands(ctx, req.ConversationID)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(&b, "\n/%s", c.Name)
+		if c.Description != "" {
+			b.WriteString(" — " + c.Description)
+		}
+	}
+	return b.String(), nil
+}
+
+// poll starts a poll from "Question | Option | Option".
+func (s *CommandService) poll(ctx context.Context, req CommandRequest) (string, error) {
+	var parts []string
+	for part := range strings.SplitSeq(req.Args, pollOptionSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) < 3 {
+		return "", &model.ValidationError{Field: "args", Message: "a poll needs a question and at least two options"}
+	}
+	question, options := parts[0], parts[1:]
+	if len(question) > maxPollQuestionLen {
+		return "", &model.ValidationError{Field: "args", Message: fmt.Sprintf("the question must be %d characters or fewer", maxPollQuestionLen)}
+	}
+	if len(options) > maxPollOptions {
+		return "", &model.ValidationError{Field: "args", Message: fmt.Sprintf("a poll can have at most %d options", maxPollOptions)}
+	}
+
+	p := &model.Poll{
+		ID:             uuid.New(),
+		ConversationID: req.ConversationID,
+		CreatedBy:      req.UserID,
+		Question:       question,
+		Options:        options,
+		CreatedAt:      s.now().UTC(),
+	}
+	if err := s.polls.Create(ctx, p); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Poll: " + question)
+	for i, option := range options {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, option)
+	}
+	b.WriteString("\nVote with /vote <number>.")
+	return b.String(), nil
+}
+
+// vote records a vote in the conversation's latest poll and shows the tally.
+func (s *CommandService) vote(ctx context.Context, req CommandRequest) (string, error) {
+	p, err := s.polls.GetLatest(ctx, req.ConversationID)
+	if errors.Is(err, model.ErrNotFound) {
+		return "There is no poll to vote in. Start one with /poll.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	n, err := strconv.Atoi(req.Args)
+	if err != nil || n < 1 || n > len(p.Options) {
+		return "", &model.ValidationError{Field: "args", Message: fmt.Sprintf("pick an option from 1 to %d", len(p.Options))}
+	}
+	if err := s.polls.Vote(ctx, p.ID, req.UserID, n-1, s.now().UTC()); err != nil {
+		return "", err
+	}
+
+	tally, err := s.polls.Tally(ctx, p.ID)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("Poll: " + p.Question)
+	for i, option := range p.Options {
+		fmt.Fprintf(&b, "\n%d. %s — %d", i+1, option, tally[i])
+	}
+	return b.String(), nil
+}
+
+// remind schedules a reminder from "<duration> <text>".
+func (s *CommandService) remind(ctx context.Context, req CommandRequest) (string, error) {
+	when, text, _ := strings.Cut(req.Args, " ")
+	text = strings.TrimSpace(text)
+	delay, err := parseReminderDelay(when)
+	if errors.Is(err, errReminderTooFar) {
+		return "", &model.ValidationError{Field: "args", Message: "reminders can be at most 30 days away"}
+	}
+	if err != nil || text == "" {
+		return "", &model.ValidationError{Field: "args", Message: "give a duration such as 10m, 2h or 1d, then the text"}
+	}
+	if len(text) > maxReminderTextLen {
+		return "", &model.ValidationError{Field: "args", Message: fmt.Sprintf("the text must be %d characters or fewer", maxReminderTextLen)}
+	}
+
+	now := s.now().UTC()
+	rem := &model.Reminder{
+		ID:             uuid.New(),
+		ConversationID: req.ConversationID,
+		UserID:         req.UserID,
+		Body:           text,
+		RemindAt:       now.Add(delay),
+		CreatedAt:      now,
+	}
+	if err := s.reminders.Create(ctx, rem); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("OK, I'll post a reminder here in %s.", when), nil
+}
+
+// errReminderTooFar is returned by parseReminderDelay for a duration beyond
+// maxReminderDelay.
+var errReminderTooFar = errors.New("reminder too far away")
+
+// parseReminderDelay parses a positive duration of at most maxReminderDelay,
+// allowing whole days as "3d". Days are bounded before they are converted,
+// so that a huge count cannot overflow into a negative duration.
+func parseReminderDelay(s string) (time.Duration, error) {
+	const day = 24 * time.Hour
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		if n > int(maxReminderDelay/day) {
+			return 0, errReminderTooFar
+		}
+		return time.Duration(n) * day, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if d > maxReminderDelay {
+		return 0, errReminderTooFar
+	}
+	return d, nil
+}
+
+// DeliverDueReminders posts the reminders that are due and returns how many
+// it posted. A reminder is claimed by deleting it, so that concurrent
+// dispatchers post it once; one that fails to post is put back to be retried.
+func (s *CommandService) DeliverDueReminders(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "CommandService.DeliverDueReminders")
+	defer span.End()
+
+	due, err := s.reminders.ListDue(ctx, s.now().UTC(), ReminderBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+	botID, err := s.builtinBot(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	for _, rem := range due {
+		err := s.reminders.Delete(ctx, rem.ID)
+		if errors.Is(err, model.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return posted, err
+		}
+
+		body := "Reminder: " + rem.Body
+		if u, err := s.users.GetByID(ctx, rem.UserID); err == nil {
+			body = fmt.Sprintf("Reminder from %s: %s", u.DisplayName, rem.Body)
+		}
+		if _, err := s.messages.PostAsBot(ctx, botID, rem.ConversationID, body); err != nil {
+			// Put the reminder back even if ctx was cancelled for shutdown,
+			// or it would be lost.
+			if putErr := s.reminders.Create(context.WithoutCancel(ctx), &rem); putErr != nil {
+				err = errors.Join(err, putErr)
+			}
+			return posted, err
+		}
+		posted++
+	}
+	return posted, nil
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// postedMessages is a MessageListener that hands bot messages to the test.
+type postedMessages chan *model.Message
+
+func (p postedMessages) MessagePosted(_ context.Context, msg *model.Message, _ []uuid.UUID) {
+	p <- msg
+}
+
+// next waits for the next bot message.
+func (p postedMessages) next(t *testing.T) *model.Message {
+	t.Helper()
+	select {
+	case msg := <-p:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a bot message")
+		return nil
+	}
+}
+
+// failingIntegrations fails to store new commands and incoming webhooks.
+type failingIntegrations struct{ repo.IntegrationRepository }
+
+func (failingIntegrations) CreateCommand(context.Context, *model.BotCommand) error {
+	return errors.New("disk full")
+}
+
+func (failingIntegrations) CreateIncomingWebhook(context.Context, *model.IncomingWebhook) error {
+	return errors.New("disk full")
+}
+
+// failingIntegrationsTx runs transactions on store with failingIntegrations.
+type failingIntegrationsTx struct{ store *repo.Store }
+
+func (f failingIntegrationsTx) WithTx(ctx context.Context, fn func(tx *repo.Store) error) error {
+	return f.store.WithTx(ctx, func(tx *repo.Store) error {
+		failing := *tx
+		failing.Integrations = failingIntegrations{tx.Integrations}
+		return fn(&failing)
+	})
+}
+
+type testBots struct {
+	store    *repo.Store
+	messages *MessageService
+	commands *CommandService
+	hooks    *IncomingWebhookService
+	posted   postedMessages
+	now      *time.Time
+	convoID  uuid.UUID
+	owner    uuid.UUID
+	member   uuid.UUID
+}
+
+// newTestBots wires the message, command and incoming webhook services on an
+// in-memory store, with a group conversation and a clock the test controls.
+func newTestBots(t *testing.T, client *http.Client) *testBots {
+	t.Helper()
+	store, owner, members, convoID := newTestGroup(t, "Olive", "Max")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	posted := make(postedMessages, 10)
+	messages := NewMessageService(store.Messages, store.Conversations, store)
+	messages.SetListener(posted)
+	commands := NewCommandService(store.Integrations, store.Conversations, store.Users, store.Reminders, store.Polls, store, messages, client)
+	commands.now = func() time.Time { return now }
+	messages.commands = commands
+
+	return &testBots{
+		store:    store,
+		messages: messages,
+		commands: commands,
+		hooks:    NewIncomingWebhookService(store.Integrations, store.Conversations, store, messages),
+		posted:   posted,
+		now:      &now,
+		convoID:  convoID,
+		owner:    owner,
+		member:   members[0],
+	}
+}
+
+// send sends body as userID and returns the bot's reply.
+func (b *testBots) send(t *testing.T, userID uuid.UUID, body string) *model.Message {
+	t.Helper()
+	if _, _, err := b.messages.Send(context.Background(), userID, b.convoID, body, ""); err != nil {
+		t.Fatal(err)
+	}
+	return b.posted.next(t)
+}
+
+func TestCommandService_Builtins(t *testing.T) {
+	b := newTestBots(t, nil)
+
+	help := b.send(t, b.member, "/help")
+	bot, err := b.store.Users.GetByEmail(context.Background(), BuiltinBotEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if help.SenderID != bot.ID || bot.Type != model.UserTypeBot {
+		t.Errorf("expected the built-in bot to answer, got sender %s", help.SenderID)
+	}
+	for _, name := range []string{"/help", "/poll", "/remind", "/vote"} {
+		if !strings.Contains(help.Body, name) {
+			t.Errorf("expected /help to list %s, got %q", name, help.Body)
+		}
+	}
+
+	poll := b.send(t, b.owner, "/POLL Lunch? | Pizza | Tacos")
+	if poll.Body != "Poll: Lunch?\n1. Pizza\n2. Tacos\nVote with /vote <number>." {
+		t.Errorf("unexpected poll reply %q", poll.Body)
+	}
+	b.send(t, b.owner, "/vote 2")
+	if tally := b.send(t, b.member, "/vote 2"); !strings.Contains(tally.Body, "2. Tacos — 2") {
+		t.Errorf("expected two votes for tacos, got %q", tally.Body)
+	}
+	if tally := b.send(t, b.member, "/vote 1"); !strings.Contains(tally.Body, "1. Pizza — 1") || !strings.Contains(tally.Body, "2. Tacos — 1") {
+		t.Errorf("expected a changed vote to move, got %q", tally.Body)
+	}
+
+	if usage := b.send(t, b.member, "/vote 3"); !strings.Contains(usage.Body, "Usage: /vote <number>") {
+		t.Errorf("expected a usage hint, got %q", usage.Body)
+	}
+	if unknown := b.send(t, b.member, "/nope"); !strings.HasPrefix(unknown.Body, "Unknown command /nope.") {
+		t.Errorf("unexpected reply to an unknown command %q", unknown.Body)
+	}
+
+	// A path is an ordinary message.
+	if _, _, err := b.messages.Send(context.Background(), b.member, b.convoID, "/usr/bin is full", ""); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-b.posted:
+		t.Errorf("expected no reply to a path, got %q", msg.Body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCommandService_Reminders(t *testing.T) {
+	b := newTestBots(t, nil)
+	ctx := context.Background()
+
+	if reply := b.send(t, b.owner, "/remind 2h ship it"); reply.Body != "OK, I'll post a reminder here in 2h." {
+		t.Errorf("unexpected confirmation %q", reply.Body)
+	}
+	for _, args := range []string{"/remind soon ship it", "/remind 2h", "/remind 0d later", "/remind -5m later"} {
+		if reply := b.send(t, b.owner, args); !strings.Contains(reply.Body, "Usage: /remind") {
+			t.Errorf("expected %q to be rejected, got %q", args, reply.Body)
+		}
+	}
+	// Counts that would overflow a time.Duration are too far away too.
+	for _, args := range []string{"/remind 31d later", "/remind 721h later", "/remind 106752d later", "/remind 9223372036854775807d later"} {
+		if reply := b.send(t, b.owner, args); !strings.Contains(reply.Body, "at most 30 days away") {
+			t.Errorf("expected %q to be too far away, got %q", args, reply.Body)
+		}
+	}
+
+	*b.now = b.now.Add(time.Hour)
+	if n, err := b.commands.DeliverDueReminders(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing due yet, got %d, %v", n, err)
+	}
+	*b.now = b.now.Add(time.Hour)
+	if n, err := b.commands.DeliverDueReminders(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the reminder to be posted, got %d, %v", n, err)
+	}
+	if msg := b.posted.next(t); msg.Body != "Reminder from Olive: ship it" {
+		t.Errorf("unexpected reminder %q", msg.Body)
+	}
+	if n, err := b.commands.DeliverDueReminders(ctx); err != nil || n != 0 {
+		t.Errorf("expected a reminder to be posted once, got %d, %v", n, err)
+	}
+}
+
+func TestCommandService_HTTPCommands(t *testing.T) {
+	requests := make(chan receivedWebhook, 10)
+	var failing atomic.Bool
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- receivedWebhook{header: r.Header.Clone(), body: body}
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var req CommandRequest
+		_ = json.Unmarshal(body, &req)
+		json.NewEncoder(w).Encode(CommandReply{Text: "deployed " + req.Args})
+	}))
+	t.Cleanup(endpoint.Close)
+	b := newTestBots(t, endpoint.Client())
+	ctx := context.Background()
+
+	if _, err := b.commands.CreateCommand(ctx, b.member, b.convoID, CreateCommandParams{Name: "deploy", URL: endpoint.URL}); !errors.Is(err, model.ErrForbidden) {
+		t.Errorf("expected a member to be forbidden, got %v", err)
+	}
+	for _, params := range []CreateCommandParams{
+		{Name: "help", URL: endpoint.URL},
+		{Name: "no spaces", URL: endpoint.URL},
+		{Name: "deploy", URL: "ftp://example.com"},
+	} {
+		var verr *model.ValidationError
+		if _, err := b.commands.CreateCommand(ctx, b.owner, b.convoID, params); !errors.As(err, &verr) {
+			t.Errorf("expected %+v to be rejected, got %v", params, err)
+		}
+	}
+
+	cmd, err := b.commands.CreateCommand(ctx, b.owner, b.convoID, CreateCommandParams{Name: "/Deploy", Description: "Ship a branch", URL: endpoint.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Name != "deploy" || !strings.HasPrefix(cmd.Secret, WebhookSecretPrefix) {
+		t.Errorf("unexpected command %+v", cmd)
+	}
+	if _, err := b.commands.CreateCommand(ctx, b.owner, b.convoID, CreateCommandParams{Name: "deploy", URL: endpoint.URL}); !errors.Is(err, model.ErrConflict) {
+		t.Errorf("expected a duplicate name to conflict, got %v", err)
+	}
+
+	reply := b.send(t, b.member, "/deploy main")
+	if reply.SenderID != cmd.BotID || reply.Body != "deployed main" {
+		t.Errorf("expected the command's bot to reply, got %+v", reply)
+	}
+	req := <-requests
+	if sig := req.header.Get(WebhookSignatureHeader); sig != WebhookSignature(cmd.Secret, *b.now, req.body) {
+		t.Errorf("signature %q does not match the body", sig)
+	}
+	var got CommandRequest
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "deploy" || got.Args != "main" || got.UserID != b.member || got.ConversationID != b.convoID {
+		t.Errorf("unexpected callback request %+v", got)
+	}
+	if help := b.send(t, b.member, "/help"); !strings.Contains(help.Body, "/deploy — Ship a branch") {
+		t.Errorf("expected /help to list the HTTP command, got %q", help.Body)
+	}
+
+	failing.Store(true)
+	if reply := b.send(t, b.member, "/deploy main"); reply.Body != "/deploy is not responding right now." {
+		t.Errorf("unexpected reply from a failing command %q", reply.Body)
+	}
+
+	if err := b.commands.DeleteCommand(ctx, b.owner, b.convoID, cmd.ID); err != nil {
+		t.Fatal(err)
+	}
+	if reply := b.send(t, b.member, "/deploy main"); !strings.HasPrefix(reply.Body, "Unknown command") {
+		t.Errorf("expected a deleted command to be unknown, got %q", reply.Body)
+	}
+}
+
+func TestCommandService_RefusesPrivateAddresses(t *testing.T) {
+	var called atomic.Bool
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+		io.WriteString(w, "internal secrets")
+	}))
+	defer endpoint.Close()
+
+	b := newTestBots(t, nil)
+	if _, err := b.commands.CreateCommand(context.Background(), b.owner, b.convoID, CreateCommandParams{Name: "peek", URL: endpoint.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if reply := b.send(t, b.member, "/peek"); reply.Body != "/peek is not responding right now." {
+		t.Errorf("expected the loopback callback to be refused, got %q", reply.Body)
+	}
+	if called.Load() {
+		t.Error("expected no request to reach the loopback endpoint")
+	}
+}
+
+func TestCommandService_ShutdownDrainsQueue(t *testing.T) {
+	b := newTestBots(t, nil)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, _, err := b.messages.Send(ctx, b.member, b.convoID, "/help", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.commands.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if n := len(b.posted); n != 3 {
+		t.Fatalf("expected the queued commands to be answered before shutdown returned, got %d replies", n)
+	}
+
+	if _, _, err := b.messages.Send(ctx, b.member, b.convoID, "/help", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.commands.Shutdown(ctx); err != nil {
+		t.Fatalf("second shutdown: %v", err)
+	}
+	if n := len(b.posted); n != 3 {
+		t.Errorf("expected commands after shutdown to be dropped, got %d replies", n)
+	}
+}
+
+func TestIntegrations_CreateLeavesNoBotOnFailure(t *testing.T) {
+	b := newTestBots(t, nil)
+	ctx := context.Background()
+	b.commands.tx = failingIntegrationsTx{b.store}
+	b.hooks.tx = failingIntegrationsTx{b.store}
+
+	if _, err := b.commands.CreateCommand(ctx, b.owner, b.convoID, CreateCommandParams{Name: "deploy", URL: "https://example.com/deploy"}); err == nil {
+		t.Fatal("expected the command's insert to fail")
+	}
+	if _, _, err := b.hooks.Create(ctx, b.owner, b.convoID, "CI"); err == nil {
+		t.Fatal("expected the webhook's insert to fail")
+	}
+	if bots, err := b.store.Users.ListByOwner(ctx, b.owner); err != nil || len(bots) != 0 {
+		t.Errorf("expected the bots to be rolled back, got %+v, %v", bots, err)
+	}
+}
+
+func TestIncomingWebhookService_Post(t *testing.T) {
+	b := newTestBots(t, nil)
+	ctx := context.Background()
+
+	if _, _, err := b.hooks.Create(ctx, b.member, b.convoID, "CI"); !errors.Is(err, model.ErrForbidden) {
+		t.Errorf("expected a member to be forbidden, got %v", err)
+	}
+	hook, token, err := b.hooks.Create(ctx, b.owner, b.convoID, " CI ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.Name != "CI" || !strings.HasPrefix(token, IncomingWebhookTokenPrefix) || hook.TokenHash == token {
+		t.Errorf("unexpected webhook %+v with token %q", hook, token)
+	}
+
+	msg, err := b.hooks.Post(ctx, hook.ID, token, "build passed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SenderID != hook.BotID || msg.ConversationID != b.convoID {
+		t.Errorf("expected the webhook's bot to post, got %+v", msg)
+	}
+	if posted := b.posted.next(t); posted.ID != msg.ID {
+		t.Errorf("expected the listener to be told, got %+v", posted)
+	}
+	page, err := b.messages.GetHistory(ctx, b.member, b.convoID, "", 0)
+	if err != nil || len(page.Items) != 1 || page.Items[0].Body != "build passed" {
+		t.Errorf("expected the message in the history, got %+v, %v", page, err)
+	}
+
+	for _, tc := range []struct {
+		id    uuid.UUID
+		token string
+	}{
+		{hook.ID, token + "x"},
+		{hook.ID, "pat_" + strings.TrimPrefix(token, IncomingWebhookTokenPrefix)},
+		{uuid.New(), token},
+	} {
+		if _, err := b.hooks.Post(ctx, tc.id, tc.token, "hi"); !errors.Is(err, model.ErrUnauthenticated) {
+			t.Errorf("expected %v to be rejected, got %v", tc, err)
+		}
+	}
+
+	if err := b.hooks.Delete(ctx, b.owner, b.convoID, hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.hooks.Post(ctx, hook.ID, token, "hi"); !errors.Is(err, model.ErrUnauthenticated) {
+		t.Errorf("expected a deleted webhook's token to stop working, got %v", err)
+	}
+}
+
+func TestCommandService_RefusesHumanBuiltinBotAddress(t *testing.T) {
+	b := newTestBots(t, nil)
+	ctx := context.Background()
+
+	// An account that took the address before the bot domain was reserved.
+	squatter := &model.User{ID: uuid.New(), Email: BuiltinBotEmail, DisplayName: "Mallory", Status: "offline", Type: model.UserTypeHuman}
+	if err := b.store.Users.Create(ctx, squatter); err != nil {
+		t.Fatal(err)
+	}
+	if botID, _, err := b.commands.execute(ctx, CommandRequest{Name: "help", ConversationID: b.convoID, UserID: b.member}); err == nil {
+		t.Fatalf("expected an error instead of replying as %s", botID)
+	}
+}
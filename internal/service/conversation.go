@@ -14,11 +14,12 @@ import (
 // ConversationService handles conversation business logic.
 type ConversationService struct {
 	convos repo.ConversationRepository
+	tx     repo.Transactor
 }
 
 // NewConversationService creates a new ConversationService.
-func NewConversationService(convos repo.ConversationRepository) *ConversationService {
-	return &ConversationService{convos: convos}
+func NewConversationService(convos repo.ConversationRepository, tx repo.Transactor) *ConversationService {
+	return &ConversationService{convos: convos, tx: tx}
 }
 
 // CreateResult holds the created conversation plus whether it was existing (for direct convos).
@@ -30,6 +31,9 @@ type CreateResult struct {
 
 // Create creates a new conversation. For direct conversations, returns the existing one if it already exists.
 func (s *ConversationService) Create(ctx context.Context, userID uuid.UUID, convoType string, name *string, participantIDs []uuid.UUID) (*CreateResult, error) {
+	ctx, span := tracer.Start(ctx, "ConversationService.Create")
+	defer span.End()
+
 	convoType = strings.TrimSpace(strings.ToLower(convoType))
 
 	if convoType != "direct" && convoType != "group" {
@@ -71,37 +75,45 @@ func (s *ConversationService) Create(ctx context.Context, userID uuid.UUID, conv
 		UpdatedAt: now,
 	}
 
-	if err := s.convos.Create(ctx, convo); err != nil {
-		return nil, err
-	}
-
-	// Add the creator as owner.
-	ownerParticipant := &model.ConversationParticipant{
-		ID:             uuid.New(),
-		ConversationID: convo.ID,
-		UserID:         userID,
-		Role:           "owner",
-		JoinedAt:       now,
-	}
-	if err := s.convos.AddParticipant(ctx, ownerParticipant); err != nil {
-		return nil, err
-	}
-
-	allParticipants := []model.ConversationParticipant{*ownerParticipant}
+	// The conversation and all of its participants are written atomically.
+	var allParticipants []model.ConversationParticipant
+	err := s.tx.WithTx(ctx, func(tx *repo.Store) error {
+		if err := tx.Conversations.Create(ctx, convo); err != nil {
+			return err
+		}
 
-	// Add the other participants as members.
-	for _, pid := range participantIDs {
-		p := &model.ConversationParticipant{
+		// Add the creator as owner.
+		ownerParticipant := &model.ConversationParticipant{
 			ID:             uuid.New(),
 			ConversationID: convo.ID,
-			UserID:         pid,
-			Role:           "member",
+			UserID:         userID,
+			Role:           "owner",
 			JoinedAt:       now,
 		}
-		if err := s.convos.AddParticipant(ctx, p); err != nil {
-			return nil, err
+		if err := tx.Conversations.AddParticipant(ctx, ownerParticipant); err != nil {
+			return err
+		}
+
+		allParticipants = []model.ConversationParticipant{*ownerParticipant}
+
+		// Add the other participants as members.
+		for _, pid := range participantIDs {
+			p := &model.ConversationParticipant{
+				ID:             uuid.New(),
+				ConversationID: convo.ID,
+				UserID:         pid,
+				Role:           "member",
+				JoinedAt:       now,
+			}
+			if err := tx.Conversations.AddParticipant(ctx, p); err != nil {
+				return err
+			}
+			allParticipants = append(allParticipants, *p)
 		}
-		allParticipants = append(allParticipants, *p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &CreateResult{Conversation: convo, Participants: allParticipants, Existing: false}, nil
@@ -109,6 +121,9 @@ func (s *ConversationService) Create(ctx context.Context, userID uuid.UUID, conv
 
 // List returns the authenticated user's conversations with cursor-based pagination.
 func (s *ConversationService) List(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*model.Page[model.ConversationSummary], error) {
+	ctx, span := tracer.Start(ctx, "ConversationService.List")
+	defer span.End()
+
 	if limit <= 0 {
 		limit = 20
 	}
@@ -120,6 +135,9 @@ func (s *ConversationService) List(ctx context.Context, userID uuid.UUID, cursor
 
 // GetByID returns a conversation and its participants. The caller must be a participant.
 func (s *ConversationService) GetByID(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID) (*model.Conversation, []model.ConversationParticipant, error) {
+	ctx, span := tracer.Start(ctx, "ConversationService.GetByID")
+	defer span.End()
+
 	ok, err := s.convos.IsParticipant(ctx, conversationID, userID)
 	if err != nil {
 		return nil, nil, err
@@ -143,6 +161,9 @@ func (s *ConversationService) GetByID(ctx context.Context, userID uuid.UUID, con
 
 // Update renames a group conversation. The caller must be a participant.
 func (s *ConversationService) Update(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID, name string) (*model.Conversation, error) {
+	ctx, span := tracer.Start(ctx, "ConversationService.Update")
+	defer span.End()
+
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return nil, &model.ValidationError{Field: "name", Message: "must not be empty"}
@@ -159,8 +180,18 @@ func (s *ConversationService) Update(ctx context.Context, userID uuid.UUID, conv
 	return s.convos.Update(ctx, conversationID, name)
 }
 
+// AddParticipantsResult holds a conversation's participants after an add,
+// along with those the add introduced.
+type AddParticipantsResult struct {
+	Participants []model.ConversationParticipant
+	Added        []model.ConversationParticipant
+}
+
 // AddParticipants adds users to a group conversation. The caller must be a participant.
-func (s *ConversationService) AddParticipants(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID, newUserIDs []uuid.UUID) ([]model.ConversationParticipant, error) {
+func (s *ConversationService) AddParticipants(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID, newUserIDs []uuid.UUID) (*AddParticipantsResult, error) {
+	ctx, span := tracer.Start(ctx, "ConversationService.AddParticipants")
+	defer span.End()
+
 	if len(newUserIDs) == 0 {
 		return nil, &model.ValidationError{Field: "user_ids", Message: "must provide at least one user"}
 	}
@@ -182,27 +213,40 @@ func (s *ConversationService) AddParticipants(ctx context.Context, userID uuid.U
 	}
 
 	now := time.Now().UTC()
-	for _, uid := range newUserIDs {
-		p := &model.ConversationParticipant{
-			ID:             uuid.New(),
-			ConversationID: conversationID,
-			UserID:         uid,
-			Role:           "member",
-			JoinedAt:       now,
-		}
-		if err := s.convos.AddParticipant(ctx, p); err != nil {
-			if errors.Is(err, model.ErrConflict) {
-				continue // already a participant, skip
+	result := &AddParticipantsResult{}
+	err = s.tx.WithTx(ctx, func(tx *repo.Store) error {
+		for _, uid := range newUserIDs {
+			p := &model.ConversationParticipant{
+				ID:             uuid.New(),
+				ConversationID: conversationID,
+				UserID:         uid,
+				Role:           "member",
+				JoinedAt:       now,
 			}
-			return nil, err
+			if err := tx.Conversations.AddParticipant(ctx, p); err != nil {
+				if errors.Is(err, model.ErrConflict) {
+					continue // already a participant, skip
+				}
+				return err
+			}
+			result.Added = append(result.Added, *p)
 		}
-	}
 
-	return s.convos.GetParticipants(ctx, conversationID)
+		var err error
+		result.Participants, err = tx.Conversations.GetParticipants(ctx, conversationID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // RemoveParticipant removes a user from a group conversation. The caller must be a participant.
 func (s *ConversationService) RemoveParticipant(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID, targetUserID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "ConversationService.RemoveParticipant")
+	defer span.End()
+
 	ok, err := s.convos.IsParticipant(ctx, conversationID, userID)
 	if err != nil {
 		return err
@@ -221,3 +265,22 @@ func (s *ConversationService) RemoveParticipant(ctx context.Context, userID uuid
 
 	return s.convos.RemoveParticipant(ctx, conversationID, targetUserID)
 }
+
+// requireConversationOwner allows the conversation's owner through. Other
+// participants are forbidden, and everyone else sees the conversation as not found.
+func requireConversationOwner(ctx context.Context, convos repo.ConversationRepository, userID, conversationID uuid.UUID) error {
+	participants, err := convos.GetParticipants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	for _, p := range participants {
+		if p.UserID != userID {
+			continue
+		}
+		if p.Role != "owner" {
+			return model.ErrForbidden
+		}
+		return nil
+	}
+	return model.ErrNotFound
+}
@@ -3,11 +3,13 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
 )
 
 // ---------------------------------------------------------------------------
@@ -123,13 +125,42 @@ func (m *mockConversationRepo) IsParticipant(_ context.Context, conversationID u
 	return false, nil
 }
 
+// ---------------------------------------------------------------------------
+// Fixture: group conversation
+// ---------------------------------------------------------------------------
+
+// newTestGroup creates an in-memory store with a group conversation named
+// "Team" that owner started with members, each a user of that display name.
+func newTestGroup(t *testing.T, owner string, members ...string) (store *repo.Store, ownerID uuid.UUID, memberIDs []uuid.UUID, convoID uuid.UUID) {
+	t.Helper()
+	store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	var ids []uuid.UUID
+	for _, name := range append([]string{owner}, members...) {
+		u := &model.User{ID: uuid.New(), Email: strings.ToLower(name) + "@example.com", DisplayName: name, Status: "offline", Type: model.UserTypeHuman}
+		if err := store.Users.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, u.ID)
+	}
+	name := "Team"
+	convo, err := NewConversationService(store.Conversations, store).Create(ctx, ids[0], "group", &name, ids[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, ids[0], ids[1:], convo.Conversation.ID
+}
+
 // ---------------------------------------------------------------------------
 // Tests: Create
 // ---------------------------------------------------------------------------
 
 func TestCreate_Direct(t *testing.T) {
 	convos := newMockConversationRepo()
-	svc := NewConversationService(convos)
+	svc := NewConversationService(convos, &repo.Store{Conversations: convos})
 
 	userID := uuid.New()
 	otherID := uuid.New()
@@ -172,7 +203,7 @@ func TestCreate_Direct(t *testing.T) {
 
 func TestCreate_DirectWithSelf(t *testing.T) {
 	convos := newMockConversationRepo()
-	svc := NewConversationService(convos)
+	svc := NewConversationService(convos, &repo.Store{Conversations: convos})
 
 	userID := uuid.New()
 
@@ -195,7 +226,7 @@ func TestCreate_DirectWithSelf(t *testing.T) {
 
 func TestCreate_Group(t *testing.T) {
 	convos := newMockConversationRepo()
-	svc := NewConversationService(convos)
+	svc := NewConversationService(convos, &repo.Store{Conversations: convos})
 
 	userID := uuid.New()
 	member1 := uuid.New()
@@ -226,7 +257,7 @@ func TestCreate_Group(t *testing.T) {
 
 func TestCreate_InvalidType(t *testing.T) {
 	convos := newMockConversationRepo()
-	svc := NewConversationService(convos)
+	svc := NewConversationService(convos, &repo.Store{Conversations: convos})
 
 	userID := uuid.New()
 
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// DigestBatchSize is the most users one SendDue call sends digests to.
+	DigestBatchSize = 50
+	// digestMessageLimit is the most messages one digest summarizes. The
+	// rest wait for the next digest.
+	digestMessageLimit = 200
+	// digestPreviews is how many messages of each conversation a digest quotes.
+	digestPreviews = 3
+	// digestRetryDelay is how long after a failed send a digest is tried again.
+	digestRetryDelay = 15 * time.Minute
+)
+
+// DigestDeliveryConfig configures the sending of email digests.
+type DigestDeliveryConfig struct {
+	Mailer Mailer
+	// Presence, if set, holds back digests from users who are connected.
+	Presence Presence
+	// OfflineAfter is how long a user must have been away before unread
+	// messages are emailed to them.
+	OfflineAfter time.Duration
+}
+
+// DigestService manages email digest settings and sends users who have been
+// offline for a while a summary of the messages they have not read.
+//
+// A user's last sighting is recorded when their first live connection opens
+// and when their last one closes. A digest covers the unread messages that
+// arrived since, grouped by conversation; each is included in one digest only.
+type DigestService struct {
+	digests repo.DigestRepository
+	now     func() time.Time
+
+	mu           sync.Mutex
+	mailer       Mailer
+	presence     Presence
+	offlineAfter time.Duration
+}
+
+// NewDigestService creates a new DigestService. It sends nothing until
+// EnableDelivery is called.
+func NewDigestService(digests repo.DigestRepository) *DigestService {
+	return &DigestService{digests: digests, now: time.Now}
+}
+
+// EnableDelivery starts sending digests through the configured mailer.
+func (s *DigestService) EnableDelivery(cfg DigestDeliveryConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mailer = cfg.Mailer
+	s.presence = cfg.Presence
+	s.offlineAfter = cfg.OfflineAfter
+}
+
+// GetSettings returns the user's digest settings, or the defaults if they
+// never changed them.
+func (s *DigestService) GetSettings(ctx context.Context, userID uuid.UUID) (*model.DigestSettings, error) {
+	ctx, span := tracer.Start(ctx, "DigestService.GetSettings")
+	defer span.End()
+
+	settings, err := s.digests.GetSettings(ctx, userID)
+	if errors.Is(err, model.ErrNotFound) {
+		return &model.DigestSettings{UserID: userID, Enabled: true, Frequency: model.DefaultDigestFrequency}, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateSettings turns digests on or off and changes their frequency. Nil
+// arguments keep the current value. A new frequency counts from the last
+// digest sent, so switching to a shorter one may make a digest due at once.
+func (s *DigestService) UpdateSettings(ctx context.Context, userID uuid.UUID, enabled *bool, frequency *string) (*model.DigestSettings, error) {
+	ctx, span := tracer.Start(ctx, "DigestService.UpdateSettings")
+	defer span.End()
+
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if frequency != nil {
+		f := strings.ToLower(strings.TrimSpace(*frequency))
+		if _, ok := model.DigestInterval(f); !ok {
+			return nil, &model.ValidationError{Field: "frequency", Message: "must be 'hourly', 'daily' or 'weekly'"}
+		}
+		settings.Frequency = f
+	}
+	if enabled != nil {
+		settings.Enabled = *enabled
+	}
+
+	settings.NextDigestAt = nil
+	if settings.LastSentAt != nil {
+		interval, _ := model.DigestInterval(settings.Frequency)
+		next := settings.LastSentAt.Add(interval)
+		settings.NextDigestAt = &next
+	}
+	settings.UpdatedAt = s.now().UTC()
+	if err := s.digests.UpsertSettings(ctx, settings); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Seen records that the user is, or just was, connected. Messages that
+// arrived before are left out of their digests.
+func (s *DigestService) Seen(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "DigestService.Seen")
+	defer span.End()
+
+	if err := s.digests.SetLastSeen(ctx, userID, s.now().UTC()); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// SendDue emails a digest to each user who is due one and returns how many
+// users it handled. The messages of a digest are claimed before it is sent,
+// so that concurrent senders never include one twice; if the send fails they
+// are released and the digest is retried later.
+func (s *DigestService) SendDue(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "DigestService.SendDue")
+	defer span.End()
+
+	s.mu.Lock()
+	mailer, presence, offlineAfter := s.mailer, s.presence, s.offlineAfter
+	s.mu.Unlock()
+	if mailer == nil {
+		return 0, nil
+	}
+
+	now := s.now().UTC()
+	due, err := s.digests.ListDue(ctx, now, now.Add(-offlineAfter), DigestBatchSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	var errs []error
+	for _, rc := range due {
+		if presence != nil && presence.Online(rc.UserID) {
+			// Connected all along: move their last sighting up to now.
+			if err := s.digests.SetLastSeen(ctx, rc.UserID, now); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := s.send(ctx, mailer, rc, now); err != nil {
+			errs = append(errs, fmt.Errorf("digest for %s: %w", rc.UserID, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return len(due), err
+	}
+	return len(due), nil
+}
+
+// send claims the user's undigested messages and emails them.
+func (s *DigestService) send(ctx context.Context, mailer Mailer, rc model.DigestRecipient, now time.Time) error {
+	msgs, err := s.digests.ListUndigested(ctx, rc.UserID, digestMessageLimit)
+	if err != nil {
+		return err
+	}
+	ids := make([]uuid.UUID, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.DeliveryID
+	}
+	claimed, err := s.digests.MarkDigested(ctx, ids, now)
+	if err != nil {
+		return err
+	}
+	msgs = slices.DeleteFunc(msgs, func(m model.DigestMessage) bool { return !slices.Contains(claimed, m.DeliveryID) })
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := mailer.Send(ctx, composeDigest(rc, msgs)); err != nil {
+		// Release the claim even when the send failed because ctx was
+		// cancelled for shutdown, or the messages would never be digested.
+		relCtx := context.WithoutCancel(ctx)
+		if relErr := s.digests.UnmarkDigested(relCtx, claimed); relErr != nil {
+			err = errors.Join(err, relErr)
+		}
+		if relErr := s.digests.Reschedule(relCtx, rc.UserID, now.Add(digestRetryDelay), nil); relErr != nil {
+			err = errors.Join(err, relErr)
+		}
+		return err
+	}
+	interval, ok := model.DigestInterval(rc.Frequency)
+	if !ok {
+		interval, _ = model.DigestInterval(model.DefaultDigestFrequency)
+	}
+	return s.digests.Reschedule(ctx, rc.UserID, now.Add(interval), &now)
+}
+
+// composeDigest writes the email summarizing msgs, which are oldest first,
+// per conversation in the order they were last active.
+func composeDigest(rc model.DigestRecipient, msgs []model.DigestMessage) Mail {
+	type group struct {
+		name    *string
+		senders []string
+		msgs    []model.DigestMessage
+	}
+	groups := make(map[uuid.UUID]*group)
+	var order []uuid.UUID
+	for _, m := range msgs {
+		g, ok := groups[m.ConversationID]
+		if !ok {
+			g = &group{name: m.ConversationName}
+			groups[m.ConversationID] = g
+		}
+		if !slices.Contains(g.senders, m.SenderName) {
+			g.senders = append(g.senders, m.SenderName)
+		}
+		g.msgs = append(g.msgs, m)
+		order = slices.DeleteFunc(order, func(id uuid.UUID) bool { return id == m.ConversationID })
+		order = append(order, m.ConversationID)
+	}
+	slices.Reverse(order)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", rc.DisplayName)
+	fmt.Fprintf(&b, "You have %s in %s since you were last online.\n",
+		plural(len(msgs), "unread message"), plural(len(order), "conversation"))
+	for _, id := range order {
+		g := groups[id]
+		title := strings.Join(g.senders, ", ")
+		if g.name != nil && *g.name != "" {
+			title = *g.name
+		}
+		fmt.Fprintf(&b, "\n%s (%s)\n", title, plural(len(g.msgs), "new message"))
+		shown := g.msgs[max(0, len(g.msgs)-digestPreviews):]
+		for _, m := range shown {
+			fmt.Fprintf(&b, "  %s: %s\n", m.SenderName, pushPreview(m.Body))
+		}
+		if more := len(g.msgs) - len(shown); more > 0 {
+			fmt.Fprintf(&b, "  …and %d earlier\n", more)
+		}
+	}
+	fmt.Fprintf(&b, "\nYou get this email %s while you are offline. You can change how often, or turn it off, in your notification settings.\n", rc.Frequency)
+
+	return Mail{
+		To:      rc.Email,
+		Subject: fmt.Sprintf("You have %s", plural(len(msgs), "unread message")),
+		Text:    b.String(),
+	}
+}
+
+// plural formats n followed by noun, pluralized with an "s" unless n is one.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// failingMailer fails every send.
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, Mail) error { return errors.New("relay down") }
+
+// cancellingMailer cancels the send's context, as a shutdown would, and fails.
+type cancellingMailer struct{ cancel context.CancelFunc }
+
+func (m cancellingMailer) Send(ctx context.Context, _ Mail) error {
+	m.cancel()
+	return ctx.Err()
+}
+
+// ctxDigestRepo fails releases on a cancelled context, as a SQL store does.
+type ctxDigestRepo struct{ repo.DigestRepository }
+
+func (r ctxDigestRepo) UnmarkDigested(ctx context.Context, ids []uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.DigestRepository.UnmarkDigested(ctx, ids)
+}
+
+func (r ctxDigestRepo) Reschedule(ctx context.Context, userID uuid.UUID, next time.Time, sentAt *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.DigestRepository.Reschedule(ctx, userID, next, sentAt)
+}
+
+type testDigest struct {
+	messages *MessageService
+	digests  *DigestService
+	mailer   *FileMailer
+	dir      string
+	online   *onlineUsers
+	clock    time.Time
+	convoID  uuid.UUID
+	sender   uuid.UUID
+	bob, una uuid.UUID
+}
+
+// newTestDigest wires the message and digest services on an in-memory store,
+// with a conversation named "Team" where Sam writes to Bob and Una. Both were
+// last seen two hours ago; the clock starts at the present.
+func newTestDigest(t *testing.T) *testDigest {
+	t.Helper()
+	store, sender, members, convoID := newTestGroup(t, "Sam", "Bob", "Una")
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir, "Chat <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &testDigest{
+		messages: NewMessageService(store.Messages, store.Conversations, store),
+		digests:  NewDigestService(store.Digests),
+		mailer:   mailer,
+		dir:      dir,
+		online:   &onlineUsers{users: make(map[uuid.UUID]bool)},
+		clock:    time.Now().Add(-2 * time.Hour),
+		convoID:  convoID,
+		sender:   sender,
+		bob:      members[0],
+		una:      members[1],
+	}
+	d.digests.now = func() time.Time { return d.clock }
+	for _, id := range members {
+		if err := d.digests.Seen(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.clock = time.Now()
+	d.digests.EnableDelivery(DigestDeliveryConfig{Mailer: mailer, Presence: d.online, OfflineAfter: time.Hour})
+	return d
+}
+
+func (d *testDigest) send(t *testing.T, body string) {
+	t.Helper()
+	if _, _, err := d.messages.Send(context.Background(), d.sender, d.convoID, body, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (d *testDigest) sendDue(t *testing.T) int {
+	t.Helper()
+	n, err := d.digests.SendDue(context.Background())
+	if err != nil {
+		t.Fatalf("send due: %v", err)
+	}
+	return n
+}
+
+func TestDigestService_SendsUnreadMessagesOnce(t *testing.T) {
+	d := newTestDigest(t)
+	d.online.set(d.una, true)
+	for _, body := range []string{"one", "two", "three", "four"} {
+		d.send(t, body)
+	}
+	d.clock = d.clock.Add(time.Minute)
+
+	if n := d.sendDue(t); n != 2 {
+		t.Errorf("expected bob and una to be handled, got %d", n)
+	}
+	sent := d.mailer.Sent()
+	if len(sent) != 1 || sent[0].To != "bob@example.com" {
+		t.Fatalf("expected one digest to bob, got %+v", sent)
+	}
+	if sent[0].Subject != "You have 4 unread messages" {
+		t.Errorf("unexpected subject %q", sent[0].Subject)
+	}
+	for _, want := range []string{"Hi Bob,", "Team (4 new messages)", "Sam: four", "…and 1 earlier"} {
+		if !strings.Contains(sent[0].Text, want) {
+			t.Errorf("expected digest to contain %q:\n%s", want, sent[0].Text)
+		}
+	}
+	if strings.Contains(sent[0].Text, "Sam: one") {
+		t.Errorf("expected only the latest previews:\n%s", sent[0].Text)
+	}
+	files, err := os.ReadDir(d.dir)
+	if err != nil || len(files) != 1 || !strings.HasSuffix(files[0].Name(), ".eml") {
+		t.Fatalf("expected one .eml file, got %v, %v", files, err)
+	}
+	raw, _ := os.ReadFile(d.dir + "/" + files[0].Name())
+	if !strings.Contains(string(raw), "To: bob@example.com\r\n") || !strings.Contains(string(raw), "Subject: You have 4 unread messages\r\n") {
+		t.Errorf("unexpected email file:\n%s", raw)
+	}
+
+	// Nothing is sent twice, and the next digest waits a day.
+	d.clock = d.clock.Add(2 * time.Hour)
+	d.sendDue(t)
+	d.send(t, "five")
+	d.clock = d.clock.Add(time.Hour)
+	d.sendDue(t)
+	if sent := d.mailer.Sent(); len(sent) != 1 {
+		t.Fatalf("expected no new digest before a day passed, got %d", len(sent))
+	}
+
+	d.clock = d.clock.Add(24 * time.Hour)
+	d.sendDue(t)
+	sent = d.mailer.Sent()
+	if len(sent) != 2 || sent[1].Subject != "You have 1 unread message" || !strings.Contains(sent[1].Text, "Sam: five") {
+		t.Fatalf("expected a digest of the new message only, got %+v", sent)
+	}
+}
+
+func TestDigestService_OptOutAndRetry(t *testing.T) {
+	d := newTestDigest(t)
+	ctx := context.Background()
+
+	off := false
+	if _, err := d.digests.UpdateSettings(ctx, d.una, &off, nil); err != nil {
+		t.Fatal(err)
+	}
+	d.send(t, "hello")
+	d.clock = d.clock.Add(time.Minute)
+
+	// A failed send releases the messages and retries later.
+	d.digests.EnableDelivery(DigestDeliveryConfig{Mailer: failingMailer{}, OfflineAfter: time.Hour})
+	if _, err := d.digests.SendDue(ctx); err == nil {
+		t.Fatal("expected the mailer's error")
+	}
+	d.digests.EnableDelivery(DigestDeliveryConfig{Mailer: d.mailer, OfflineAfter: time.Hour})
+	if n := d.sendDue(t); n != 0 {
+		t.Errorf("expected the retry to wait, got %d handled", n)
+	}
+	d.clock = d.clock.Add(digestRetryDelay)
+	d.sendDue(t)
+
+	sent := d.mailer.Sent()
+	if len(sent) != 1 || sent[0].To != "bob@example.com" || !strings.Contains(sent[0].Text, "Sam: hello") {
+		t.Fatalf("expected only bob's digest after the retry, got %+v", sent)
+	}
+}
+
+func TestDigestService_ReleasesClaimOnShutdown(t *testing.T) {
+	d := newTestDigest(t)
+	d.online.set(d.una, true)
+	d.digests.digests = ctxDigestRepo{d.digests.digests}
+	d.send(t, "hello")
+	d.clock = d.clock.Add(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.digests.EnableDelivery(DigestDeliveryConfig{Mailer: cancellingMailer{cancel}, Presence: d.online, OfflineAfter: time.Hour})
+	if _, err := d.digests.SendDue(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation, got %v", err)
+	}
+
+	d.digests.EnableDelivery(DigestDeliveryConfig{Mailer: d.mailer, Presence: d.online, OfflineAfter: time.Hour})
+	d.clock = d.clock.Add(digestRetryDelay)
+	d.sendDue(t)
+	if sent := d.mailer.Sent(); len(sent) != 1 || !strings.Contains(sent[0].Text, "Sam: hello") {
+		t.Fatalf("expected the released message in the retried digest, got %+v", sent)
+	}
+}
+
+func TestDigestService_Settings(t *testing.T) {
+	d := newTestDigest(t)
+	ctx := context.Background()
+
+	settings, err := d.digests.GetSettings(ctx, d.bob)
+	if err != nil || !settings.Enabled || settings.Frequency != model.DigestDaily {
+		t.Fatalf("expected the defaults, got %+v, %v", settings, err)
+	}
+
+	bad := "monthly"
+	var verr *model.ValidationError
+	if _, err := d.digests.UpdateSettings(ctx, d.bob, nil, &bad); !errors.As(err, &verr) || verr.Field != "frequency" {
+		t.Errorf("expected a frequency validation error, got %v", err)
+	}
+
+	weekly := " Weekly "
+	settings, err = d.digests.UpdateSettings(ctx, d.bob, nil, &weekly)
+	if err != nil || settings.Frequency != model.DigestWeekly || !settings.Enabled || settings.NextDigestAt != nil {
+		t.Fatalf("unexpected settings %+v, %v", settings, err)
+	}
+	if got, _ := d.digests.GetSettings(ctx, d.bob); got.Frequency != model.DigestWeekly {
+		t.Errorf("expected the frequency to be stored, got %+v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+const (
+	// IdempotencyKeyTTL is how long a response stays available for replay.
+	IdempotencyKeyTTL = 24 * time.Hour
+	// idempotencyLockTimeout is how long a request may hold its key before a
+	// retry presumes it died and runs in its place.
+	idempotencyLockTimeout = time.Minute
+	maxIdempotencyKeyLen   = 255
+)
+
+// IdempotencyKeys records the responses to requests made with an
+// Idempotency-Key, so that retries are answered with the original response
+// rather than repeating the request.
+type IdempotencyKeys struct {
+	records repo.IdempotencyRepository
+	now     func() time.Time
+}
+
+// NewIdempotencyKeys creates a new IdempotencyKeys.
+func NewIdempotencyKeys(records repo.IdempotencyRepository) *IdempotencyKeys {
+	return &IdempotencyKeys{records: records, now: time.Now}
+}
+
+// Begin reserves rec's key for the caller. It returns nil if the request
+// should go ahead, followed by Complete or Release, and otherwise the record
+// left by an earlier request with the same key: either its response, or an
+// in-progress marker if that request has not finished.
+func (k *IdempotencyKeys) Begin(ctx context.Context, rec *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeys.Begin")
+	defer span.End()
+
+	if rec.Key == "" || len(rec.Key) > maxIdempotencyKeyLen {
+		return nil, &model.ValidationError{Field: "Idempotency-Key", Message: "must be 1 to 255 characters"}
+	}
+	now := k.now().UTC()
+	rec.CreatedAt = now
+	return k.records.Reserve(ctx, rec, now.Add(-idempotencyLockTimeout))
+}
+
+// Complete stores the response to a request begun with Begin.
+func (k *IdempotencyKeys) Complete(ctx context.Context, rec *model.IdempotencyRecord) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeys.Complete")
+	defer span.End()
+	return k.records.Complete(ctx, rec)
+}
+
+// Release gives up a key reserved with Begin without storing a response, so
+// that a retry runs the request again. It is used when the request failed in
+// a way worth retrying.
+func (k *IdempotencyKeys) Release(ctx context.Context, rec *model.IdempotencyRecord) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeys.Release")
+	defer span.End()
+	return k.records.Release(ctx, rec)
+}
+
+// Prune forgets responses older than IdempotencyKeyTTL.
+func (k *IdempotencyKeys) Prune(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyKeys.Prune")
+	defer span.End()
+	return k.records.Prune(ctx, k.now().UTC().Add(-IdempotencyKeyTTL))
+}
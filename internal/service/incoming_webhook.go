@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// IncomingWebhookTokenPrefix marks incoming webhook tokens.
+const IncomingWebhookTokenPrefix = "hook_"
+
+const maxIncomingWebhooksPerConversation = 10
+
+// IncomingWebhookService manages incoming webhooks, which let an external
+// system post to a conversation with a token instead of a user session. Each
+// webhook posts as its own bot, named after it.
+type IncomingWebhookService struct {
+	integrations repo.IntegrationRepository
+	convos       repo.ConversationRepository
+	tx           repo.Transactor
+	messages     *MessageService
+}
+
+// NewIncomingWebhookService creates a new IncomingWebhookService that posts
+// through messages.
+func NewIncomingWebhookService(integrations repo.IntegrationRepository, convos repo.ConversationRepository, tx repo.Transactor, messages *MessageService) *IncomingWebhookService {
+	return &IncomingWebhookService{integrations: integrations, convos: convos, tx: tx, messages: messages}
+}
+
+// Create adds an incoming webhook to a conversation the caller owns. The raw
+// token is returned once; only its hash is stored.
+func (s *IncomingWebhookService) Create(ctx context.Context, userID, conversationID uuid.UUID, name string) (*model.IncomingWebhook, string, error) {
+	ctx, span := tracer.Start(ctx, "IncomingWebhookService.Create")
+	defer span.End()
+
+	name, err := validateBotName("name", name)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, "", err
+	}
+
+	existing, err := s.integrations.ListIncomingWebhooks(ctx, conversationID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(existing) >= maxIncomingWebhooksPerConversation {
+		return nil, "", &model.ValidationError{
+			Field:   "name",
+			Message: fmt.Sprintf("a conversation can have at most %d incoming webhooks", maxIncomingWebhooksPerConversation),
+		}
+	}
+
+	rawBytes := make([]byte, 32)
+	if _, err := rand.Read(rawBytes); err != nil {
+		return nil, "", fmt.Errorf("incoming webhook: generate token: %w", err)
+	}
+	raw := IncomingWebhookTokenPrefix + hex.EncodeToString(rawBytes)
+
+	bot := newBot(userID, name)
+	hook := &model.IncomingWebhook{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		BotID:          bot.ID,
+		CreatedBy:      userID,
+		Name:           name,
+		TokenHash:      hashToken(raw),
+		CreatedAt:      time.Now().UTC(),
+	}
+	// The bot and its webhook are created together or not at all.
+	err = s.tx.WithTx(ctx, func(tx *repo.Store) error {
+		if err := tx.Users.Create(ctx, bot); err != nil {
+			return err
+		}
+		return tx.Integrations.CreateIncomingWebhook(ctx, hook)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return hook, raw, nil
+}
+
+// List returns the incoming webhooks of a conversation the caller owns.
+func (s *IncomingWebhookService) List(ctx context.Context, userID, conversationID uuid.UUID) ([]model.IncomingWebhook, error) {
+	ctx, span := tracer.Start(ctx, "IncomingWebhookService.List")
+	defer span.End()
+
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.integrations.ListIncomingWebhooks(ctx, conversationID)
+}
+
+// Delete removes an incoming webhook from a conversation the caller owns. Its
+// token stops working at once; its bot stays, so that the messages it posted
+// keep their sender.
+func (s *IncomingWebhookService) Delete(ctx context.Context, userID, conversationID, hookID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "IncomingWebhookService.Delete")
+	defer span.End()
+
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return err
+	}
+	return s.integrations.DeleteIncomingWebhook(ctx, conversationID, hookID)
+}
+
+// Post posts body to the webhook's conversation as its bot. An unknown
+// webhook and a wrong token both yield ErrUnauthenticated.
+func (s *IncomingWebhookService) Post(ctx context.Context, hookID uuid.UUID, token, body string) (*model.Message, error) {
+	ctx, span := tracer.Start(ctx, "IncomingWebhookService.Post")
+	defer span.End()
+
+	if !strings.HasPrefix(token, IncomingWebhookTokenPrefix) {
+		return nil, model.ErrUnauthenticated
+	}
+	hook, err := s.integrations.GetIncomingWebhook(ctx, hookID)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, model.ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(hook.TokenHash)) != 1 {
+		return nil, model.ErrUnauthenticated
+	}
+	return s.messages.PostAsBot(ctx, hook.BotID, hook.ConversationID, body)
+}
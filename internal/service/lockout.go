@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// LockoutPolicy controls login brute-force protection. Failures are counted per
+// account and per client IP; once a counter reaches its threshold the key is
+// locked, and each further failure doubles the lockout up to MaxLockout.
+type LockoutPolicy struct {
+	MaxAccountFailures int           // failures per account before lockout
+	MaxIPFailures      int           // failures per client IP before lockout
+	BaseLockout        time.Duration // lockout after reaching a threshold
+	MaxLockout         time.Duration // upper bound for the doubling lockout
+	Window             time.Duration // failures older than this are forgotten
+}
+
+// withDefaults fills unset fields with conservative defaults.
+func (p LockoutPolicy) withDefaults() LockoutPolicy {
+	if p.MaxAccountFailures == 0 {
+		p.MaxAccountFailures = 5
+	}
+	if p.MaxIPFailures == 0 {
+		p.MaxIPFailures = 20
+	}
+	if p.BaseLockout == 0 {
+		p.BaseLockout = 30 * time.Second
+	}
+	if p.MaxLockout == 0 {
+		p.MaxLockout = 15 * time.Minute
+	}
+	if p.Window == 0 {
+		p.Window = 15 * time.Minute
+	}
+	return p
+}
+
+// lockoutFor returns how long to lock a key after its n-th consecutive failure,
+// or zero if the threshold has not been reached.
+func (p LockoutPolicy) lockoutFor(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := threshold; i < failures && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxLockout)
+}
+
+// Unlock clears failed-login counters for an account and/or a client IP.
+func (s *AuthService) Unlock(ctx context.Context, email, clientIP string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Unlock")
+	defer span.End()
+
+	email = strings.TrimSpace(strings.ToLower(email))
+	clientIP = strings.TrimSpace(clientIP)
+	if email == "" && clientIP == "" {
+		return &model.ValidationError{Field: "email", Message: "email or ip must be provided"}
+	}
+	return s.clearFailures(ctx, email, clientIP)
+}
+
+// checkLockout returns a TooManyAttemptsError if the account or IP is locked.
+func (s *AuthService) checkLockout(ctx context.Context, email, clientIP string) error {
+	now := time.Now().UTC()
+	var wait time.Duration
+	for _, key := range lockoutKeys(email, clientIP) {
+		a, err := s.attempts.Get(ctx, key)
+		if errors.Is(err, model.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if a.LockedUntil != nil && a.LockedUntil.After(now) {
+			wait = max(wait, a.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return &model.TooManyAttemptsError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordFailure bumps the account and IP counters and locks any that crossed their threshold.
+func (s *AuthService) recordFailure(ctx context.Context, email, clientIP string) error {
+	policy := s.config.Lockout
+	now := time.Now().UTC()
+	for _, key := range lockoutKeys(email, clientIP) {
+		a, err := s.attempts.RecordFailure(ctx, key, now, policy.Window)
+		if err != nil {
+			return err
+		}
+		threshold := policy.MaxAccountFailures
+		if strings.HasPrefix(key, "ip:") {
+			threshold = policy.MaxIPFailures
+		}
+		if d := policy.lockoutFor(a.Failures, threshold); d > 0 {
+			if err := s.attempts.Lock(ctx, key, now.Add(d)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// clearAccountFailures resets the account's counter after its password was
+// given correctly. The IP's counter is left to expire: otherwise anyone with
+// one working account could reset it between rounds of guessing at others.
+func (s *AuthService) clearAccountFailures(ctx context.Context, email string) error {
+	return s.attempts.Reset(ctx, "account:"+email)
+}
+
+// clearFailures resets the account and IP counters.
+func (s *AuthService) clearFailures(ctx context.Context, email, clientIP string) error {
+	for _, key := range lockoutKeys(email, clientIP) {
+		if err := s.attempts.Reset(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lockoutKeys(email, clientIP string) []string {
+	keys := make([]string, 0, 2)
+	if email != "" {
+		keys = append(keys, "account:"+email)
+	}
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	return keys
+}
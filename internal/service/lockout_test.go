@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+func newLockoutTestService(t *testing.T, policy LockoutPolicy) *AuthService {
+	t.Helper()
+	cfg := testAuthConfig()
+	cfg.Lockout = policy
+	users, sessions := newMockUserRepo(), newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, cfg)
+	if _, err := svc.Register(context.Background(), "dave@example.com", "correctpass", "Dave"); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	return svc
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Lockout
+// ---------------------------------------------------------------------------
+
+func TestLogin_LocksAccountAfterThreshold(t *testing.T) {
+	svc := newLockoutTestService(t, LockoutPolicy{MaxAccountFailures: 3, BaseLockout: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Login(ctx, "dave@example.com", "wrongpass", "10.0.0.1")
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Fatalf("attempt %d: expected ErrNotFound, got %v", i+1, err)
+		}
+	}
+
+	// Even the correct password is refused while locked.
+	_, err := svc.Login(ctx, "dave@example.com", "correctpass", "10.0.0.2")
+	var tme *model.TooManyAttemptsError
+	if !errors.As(err, &tme) {
+		t.Fatalf("expected TooManyAttemptsError, got %v", err)
+	}
+	if tme.RetryAfter <= 0 || tme.RetryAfter > time.Minute {
+		t.Errorf("expected RetryAfter in (0, 1m], got %v", tme.RetryAfter)
+	}
+	if !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Error("expected error to unwrap to ErrTooManyAttempts")
+	}
+}
+
+func TestLogin_LocksIPAcrossAccounts(t *testing.T) {
+	svc := newLockoutTestService(t, LockoutPolicy{MaxAccountFailures: 100, MaxIPFailures: 2})
+	ctx := context.Background()
+
+	svc.Login(ctx, "a@example.com", "guess1234", "10.0.0.9")
+	svc.Login(ctx, "b@example.com", "guess1234", "10.0.0.9")
+
+	_, err := svc.Login(ctx, "dave@example.com", "correctpass", "10.0.0.9")
+	if !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Fatalf("expected ErrTooManyAttempts from the locked IP, got %v", err)
+	}
+
+	// Another IP is unaffected.
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", "10.0.0.10"); err != nil {
+		t.Fatalf("expected login from another IP to succeed, got %v", err)
+	}
+}
+
+func TestLogin_SuccessResetsAccountCounter(t *testing.T) {
+	svc := newLockoutTestService(t, LockoutPolicy{MaxAccountFailures: 3})
+	ctx := context.Background()
+
+	svc.Login(ctx, "dave@example.com", "wrongpass", "")
+	svc.Login(ctx, "dave@example.com", "wrongpass", "")
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", ""); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	// Two more failures stay below the threshold because the counter was reset.
+	svc.Login(ctx, "dave@example.com", "wrongpass", "")
+	svc.Login(ctx, "dave@example.com", "wrongpass", "")
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", ""); err != nil {
+		t.Fatalf("expected success after reset, got %v", err)
+	}
+}
+
+func TestLogin_SuccessKeepsIPCounter(t *testing.T) {
+	svc := newLockoutTestService(t, LockoutPolicy{MaxAccountFailures: 100, MaxIPFailures: 3})
+	ctx := context.Background()
+
+	svc.Login(ctx, "a@example.com", "guess1234", "10.0.0.9")
+	svc.Login(ctx, "b@example.com", "guess1234", "10.0.0.9")
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", "10.0.0.9"); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	// Logging in to one account must not buy more guesses at others.
+	svc.Login(ctx, "c@example.com", "guess1234", "10.0.0.9")
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", "10.0.0.9"); !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Fatalf("expected the IP to be locked, got %v", err)
+	}
+}
+
+func TestUnlock_ClearsLockout(t *testing.T) {
+	svc := newLockoutTestService(t, LockoutPolicy{MaxAccountFailures: 1})
+	ctx := context.Background()
+
+	svc.Login(ctx, "dave@example.com", "wrongpass", "")
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", ""); !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Fatalf("expected lockout, got %v", err)
+	}
+
+	if err := svc.Unlock(ctx, "Dave@Example.com", ""); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	if _, err := svc.Login(ctx, "dave@example.com", "correctpass", ""); err != nil {
+		t.Fatalf("expected success after unlock, got %v", err)
+	}
+}
+
+func TestUnlock_RequiresTarget(t *testing.T) {
+	svc := newLockoutTestService(t, LockoutPolicy{})
+
+	err := svc.Unlock(context.Background(), "", "")
+	if !errors.Is(err, model.ErrValidation) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestLockoutPolicy_ExponentialBackoff(t *testing.T) {
+	p := LockoutPolicy{BaseLockout: time.Second, MaxLockout: 10 * time.Second}.withDefaults()
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 4, want: 0},
+		{failures: 5, want: time.Second},
+		{failures: 6, want: 2 * time.Second},
+		{failures: 8, want: 8 * time.Second},
+		{failures: 9, want: 10 * time.Second},
+		{failures: 50, want: 10 * time.Second},
+	}
+	for _, c := range cases {
+		if got := p.lockoutFor(c.failures, 5); got != c.want {
+			t.Errorf("lockoutFor(%d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// serveSMTP accepts one SMTP session on ln, without STARTTLS or auth, and
+// sends the envelope recipient and message data it received on the returned
+// channel.
+func serveSMTP(t *testing.T, ln net.Listener) <-chan [2]string {
+	t.Helper()
+	got := make(chan [2]string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 test ESMTP")
+		var rcpt string
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 test")
+			case strings.HasPrefix(cmd, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO"):
+				rcpt = strings.TrimSpace(line[len("RCPT TO:"):])
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				got <- [2]string{rcpt, data.String()}
+				return
+			default:
+				reply("502 unsupported")
+			}
+		}
+	}()
+	return got
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := serveSMTP(t, ln)
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	m, err := NewSMTPMailer(SMTPConfig{Host: host, Port: p, From: "Chat <noreply@example.com>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), Mail{To: "bob@example.com", Subject: "Grüße", Text: "Hi Bob,\n\nSam: hello\n"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	session := <-got
+	if session[0] != "<bob@example.com>" {
+		t.Errorf("unexpected recipient %q", session[0])
+	}
+	for _, want := range []string{"From: Chat <noreply@example.com>\r\n", "To: bob@example.com\r\n", "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n", "Sam: hello"} {
+		if !strings.Contains(session[1], want) {
+			t.Errorf("expected the message to contain %q:\n%s", want, session[1])
+		}
+	}
+
+	if _, err := NewSMTPMailer(SMTPConfig{Host: host, Port: p, From: "not an address"}); err == nil {
+		t.Error("expected an invalid from address to be rejected")
+	}
+}
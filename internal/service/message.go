@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,19 +12,117 @@ import (
 	"github.com/kareempaes/planning/internal/repo"
 )
 
+// MessageListener is told about messages that reach a conversation without
+// passing through a request handler, such as bot replies and reminders.
+// recipientIDs are the conversation's participants.
+type MessageListener interface {
+	MessagePosted(ctx context.Context, msg *model.Message, recipientIDs []uuid.UUID)
+}
+
 // MessageService handles message business logic.
 type MessageService struct {
 	messages repo.MessageRepository
 	convos   repo.ConversationRepository
+	tx       repo.Transactor
+	metrics  Metrics
+	commands *CommandService // runs slash commands; nil leaves them as plain messages
+	push     *PushService    // notifies offline recipients; nil sends no notifications
+
+	mu       sync.Mutex
+	listener MessageListener
 }
 
 // NewMessageService creates a new MessageService.
-func NewMessageService(messages repo.MessageRepository, convos repo.ConversationRepository) *MessageService {
-	return &MessageService{messages: messages, convos: convos}
+func NewMessageService(messages repo.MessageRepository, convos repo.ConversationRepository, tx repo.Transactor) *MessageService {
+	return &MessageService{messages: messages, convos: convos, tx: tx, metrics: noopMetrics{}}
 }
 
 // Send creates a new message in a conversation. The caller must be a participant.
-func (s *MessageService) Send(ctx context.Context, senderID uuid.UUID, conversationID uuid.UUID, body string) (*model.Message, error) {
+//
+// clientMessageID, if not empty, is the sender's own ID for the message. A
+// send repeating an ID the sender already used in the conversation creates
+// nothing and returns the original message, with created false, so clients
+// can safely retry sends whose outcome they did not learn.
+func (s *MessageService) Send(ctx context.Context, senderID uuid.UUID, conversationID uuid.UUID, body string, clientMessageID string) (msg *model.Message, created bool, err error) {
+	ctx, span := tracer.Start(ctx, "MessageService.Send")
+	defer span.End()
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, false, &model.ValidationError{Field: "body", Message: "must not be empty"}
+	}
+	if len(body) > 10000 {
+		return nil, false, &model.ValidationError{Field: "body", Message: "must be 10000 characters or fewer"}
+	}
+	if len(clientMessageID) > 255 {
+		return nil, false, &model.ValidationError{Field: "client_message_id", Message: "must be 255 characters or fewer"}
+	}
+
+	ok, err := s.convos.IsParticipant(ctx, conversationID, senderID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, model.ErrNotFound
+	}
+
+	if clientMessageID != "" {
+		existing, err := s.messages.GetByClientID(ctx, conversationID, senderID, clientMessageID)
+		if err == nil {
+			return existing, false, nil
+		}
+		if !errors.Is(err, model.ErrNotFound) {
+			return nil, false, err
+		}
+	}
+
+	msg = newMessage(senderID, conversationID, body)
+	if clientMessageID != "" {
+		msg.ClientMessageID = &clientMessageID
+	}
+
+	recipientIDs, err := s.create(ctx, msg)
+	if errors.Is(err, model.ErrConflict) && clientMessageID != "" {
+		// A concurrent retry created the message first.
+		existing, getErr := s.messages.GetByClientID(ctx, conversationID, senderID, clientMessageID)
+		if getErr != nil {
+			return nil, false, getErr
+		}
+		return existing, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s.metrics.MessageSent()
+	if s.push != nil {
+		s.push.Notify(ctx, msg, recipientIDs)
+	}
+
+	if s.commands != nil && strings.HasPrefix(body, CommandPrefix) {
+		// Commands may call out over HTTP, so they answer after the send
+		// returns, through the listener.
+		if !s.commands.enqueue(ctx, msg) {
+			span.AddEvent("slash command dropped: queue full or shut down")
+		}
+	}
+
+	return msg, true, nil
+}
+
+// SetListener sets the listener told about messages posted with PostAsBot.
+func (s *MessageService) SetListener(l MessageListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener = l
+}
+
+// PostAsBot posts a message from a bot to a conversation and tells the
+// listener about it. Unlike Send it does not require the bot to be a
+// participant: integrations speak in conversations without joining them.
+func (s *MessageService) PostAsBot(ctx context.Context, botID, conversationID uuid.UUID, body string) (*model.Message, error) {
+	ctx, span := tracer.Start(ctx, "MessageService.PostAsBot")
+	defer span.End()
+
 	body = strings.TrimSpace(body)
 	if body == "" {
 		return nil, &model.ValidationError{Field: "body", Message: "must not be empty"}
@@ -31,16 +131,28 @@ func (s *MessageService) Send(ctx context.Context, senderID uuid.UUID, conversat
 		return nil, &model.ValidationError{Field: "body", Message: "must be 10000 characters or fewer"}
 	}
 
-	ok, err := s.convos.IsParticipant(ctx, conversationID, senderID)
+	msg := newMessage(botID, conversationID, body)
+	recipientIDs, err := s.create(ctx, msg)
 	if err != nil {
 		return nil, err
 	}
-	if !ok {
-		return nil, model.ErrNotFound
+	s.metrics.MessageSent()
+	if s.push != nil {
+		s.push.Notify(ctx, msg, recipientIDs)
 	}
 
+	s.mu.Lock()
+	listener := s.listener
+	s.mu.Unlock()
+	if listener != nil {
+		listener.MessagePosted(ctx, msg, recipientIDs)
+	}
+	return msg, nil
+}
+
+func newMessage(senderID, conversationID uuid.UUID, body string) *model.Message {
 	now := time.Now().UTC()
-	msg := &model.Message{
+	return &model.Message{
 		ID:             uuid.New(),
 		ConversationID: conversationID,
 		SenderID:       senderID,
@@ -49,35 +161,45 @@ func (s *MessageService) Send(ctx context.Context, senderID uuid.UUID, conversat
 		CreatedAt:      now,
 		UpdatedAt:      now,
 	}
+}
 
-	if err := s.messages.Create(ctx, msg); err != nil {
-		return nil, err
-	}
+// create stores a message along with a delivery row for every participant
+// other than the sender, atomically, and returns those participants.
+func (s *MessageService) create(ctx context.Context, msg *model.Message) ([]uuid.UUID, error) {
+	var recipientIDs []uuid.UUID
+	err := s.tx.WithTx(ctx, func(tx *repo.Store) error {
+		if err := tx.Messages.Create(ctx, msg); err != nil {
+			return err
+		}
 
-	// Create delivery records for all participants except the sender.
-	participants, err := s.convos.GetParticipants(ctx, conversationID)
-	if err != nil {
-		return nil, err
-	}
+		participants, err := tx.Conversations.GetParticipants(ctx, msg.ConversationID)
+		if err != nil {
+			return err
+		}
 
-	recipientIDs := make([]uuid.UUID, 0, len(participants))
-	for _, p := range participants {
-		if p.UserID != senderID {
-			recipientIDs = append(recipientIDs, p.UserID)
+		recipientIDs = make([]uuid.UUID, 0, len(participants))
+		for _, p := range participants {
+			if p.UserID != msg.SenderID {
+				recipientIDs = append(recipientIDs, p.UserID)
+			}
 		}
-	}
 
-	if len(recipientIDs) > 0 {
-		if err := s.messages.CreateDeliveries(ctx, msg.ID, recipientIDs); err != nil {
-			return nil, err
+		if len(recipientIDs) > 0 {
+			return tx.Messages.CreateDeliveries(ctx, msg.ID, recipientIDs)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return msg, nil
+	return recipientIDs, nil
 }
 
 // GetHistory returns paginated messages for a conversation. The caller must be a participant.
 func (s *MessageService) GetHistory(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID, cursor string, limit int) (*model.Page[model.Message], error) {
+	ctx, span := tracer.Start(ctx, "MessageService.GetHistory")
+	defer span.End()
+
 	ok, err := s.convos.IsParticipant(ctx, conversationID, userID)
 	if err != nil {
 		return nil, err
@@ -98,6 +220,9 @@ func (s *MessageService) GetHistory(ctx context.Context, userID uuid.UUID, conve
 
 // GetByID returns a single message. The caller must be a participant in the conversation.
 func (s *MessageService) GetByID(ctx context.Context, userID uuid.UUID, conversationID uuid.UUID, messageID uuid.UUID) (*model.Message, error) {
+	ctx, span := tracer.Start(ctx, "MessageService.GetByID")
+	defer span.End()
+
 	ok, err := s.convos.IsParticipant(ctx, conversationID, userID)
 	if err != nil {
 		return nil, err
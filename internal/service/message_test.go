@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
 )
 
 // ---------------------------------------------------------------------------
@@ -42,6 +43,18 @@ func (m *mockMessageRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Messa
 	return msg, nil
 }
 
+func (m *mockMessageRepo) GetByClientID(_ context.Context, conversationID uuid.UUID, senderID uuid.UUID, clientMessageID string) (*model.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.messages {
+		if msg.ConversationID == conversationID && msg.SenderID == senderID &&
+			msg.ClientMessageID != nil && *msg.ClientMessageID == clientMessageID {
+			return msg, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
 func (m *mockMessageRepo) ListByConversation(_ context.Context, _ uuid.UUID, _ string, _ int) (*model.Page[model.Message], error) {
 	return &model.Page[model.Message]{Items: []model.Message{}}, nil
 }
@@ -83,7 +96,7 @@ func setupConvoWithParticipants(convoRepo *mockConversationRepo, convoID uuid.UU
 func TestSend_Success(t *testing.T) {
 	msgRepo := newMockMessageRepo()
 	convoRepo := newMockConversationRepo()
-	svc := NewMessageService(msgRepo, convoRepo)
+	svc := NewMessageService(msgRepo, convoRepo, &repo.Store{Messages: msgRepo, Conversations: convoRepo})
 
 	senderID := uuid.New()
 	otherID := uuid.New()
@@ -91,10 +104,13 @@ func TestSend_Success(t *testing.T) {
 
 	setupConvoWithParticipants(convoRepo, convoID, "direct", senderID, otherID)
 
-	msg, err := svc.Send(context.Background(), senderID, convoID, "Hello!")
+	msg, created, err := svc.Send(context.Background(), senderID, convoID, "Hello!", "")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	if !created {
+		t.Error("expected the message to be reported as created")
+	}
 	if msg == nil {
 		t.Fatal("expected non-nil message")
 	}
@@ -120,7 +136,7 @@ func TestSend_Success(t *testing.T) {
 func TestSend_NotParticipant(t *testing.T) {
 	msgRepo := newMockMessageRepo()
 	convoRepo := newMockConversationRepo()
-	svc := NewMessageService(msgRepo, convoRepo)
+	svc := NewMessageService(msgRepo, convoRepo, &repo.Store{Messages: msgRepo, Conversations: convoRepo})
 
 	outsiderID := uuid.New()
 	convoID := uuid.New()
@@ -128,7 +144,7 @@ func TestSend_NotParticipant(t *testing.T) {
 	// Create a conversation that does not include outsiderID.
 	setupConvoWithParticipants(convoRepo, convoID, "direct", uuid.New(), uuid.New())
 
-	_, err := svc.Send(context.Background(), outsiderID, convoID, "Should fail")
+	_, _, err := svc.Send(context.Background(), outsiderID, convoID, "Should fail", "")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -140,14 +156,14 @@ func TestSend_NotParticipant(t *testing.T) {
 func TestSend_EmptyBody(t *testing.T) {
 	msgRepo := newMockMessageRepo()
 	convoRepo := newMockConversationRepo()
-	svc := NewMessageService(msgRepo, convoRepo)
+	svc := NewMessageService(msgRepo, convoRepo, &repo.Store{Messages: msgRepo, Conversations: convoRepo})
 
 	senderID := uuid.New()
 	convoID := uuid.New()
 
 	setupConvoWithParticipants(convoRepo, convoID, "direct", senderID, uuid.New())
 
-	_, err := svc.Send(context.Background(), senderID, convoID, "")
+	_, _, err := svc.Send(context.Background(), senderID, convoID, "", "")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -163,3 +179,39 @@ func TestSend_EmptyBody(t *testing.T) {
 		t.Error("expected error to unwrap to ErrValidation")
 	}
 }
+
+func TestSend_ClientMessageIDDeduplicates(t *testing.T) {
+	msgRepo := newMockMessageRepo()
+	convoRepo := newMockConversationRepo()
+	svc := NewMessageService(msgRepo, convoRepo, &repo.Store{Messages: msgRepo, Conversations: convoRepo})
+
+	senderID := uuid.New()
+	otherID := uuid.New()
+	convoID := uuid.New()
+	setupConvoWithParticipants(convoRepo, convoID, "direct", senderID, otherID)
+	ctx := context.Background()
+
+	first, created, err := svc.Send(ctx, senderID, convoID, "Hello!", "c-1")
+	if err != nil || !created {
+		t.Fatalf("expected the first send to create a message, got %v, %v", created, err)
+	}
+	if first.ClientMessageID == nil || *first.ClientMessageID != "c-1" {
+		t.Errorf("expected the client message ID to be stored, got %v", first.ClientMessageID)
+	}
+
+	retry, created, err := svc.Send(ctx, senderID, convoID, "Hello!", "c-1")
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if created || retry.ID != first.ID {
+		t.Errorf("expected the retry to return the original message, got created=%v id=%s", created, retry.ID)
+	}
+
+	// The ID is scoped to the sender: another participant may reuse it.
+	if _, created, err := svc.Send(ctx, otherID, convoID, "Hi!", "c-1"); err != nil || !created {
+		t.Errorf("expected another sender's message to be created, got %v, %v", created, err)
+	}
+	if len(msgRepo.messages) != 2 {
+		t.Errorf("expected 2 stored messages, got %d", len(msgRepo.messages))
+	}
+}
@@ -22,6 +22,9 @@ func NewModerationService(mod repo.ModerationRepository) *ModerationService {
 
 // Block blocks a user. The caller cannot block themselves.
 func (s *ModerationService) Block(ctx context.Context, blockerID uuid.UUID, blockedID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "ModerationService.Block")
+	defer span.End()
+
 	if blockerID == blockedID {
 		return &model.ValidationError{Field: "user_id", Message: "cannot block yourself"}
 	}
@@ -30,16 +33,25 @@ func (s *ModerationService) Block(ctx context.Context, blockerID uuid.UUID, bloc
 
 // Unblock removes a block on a user.
 func (s *ModerationService) Unblock(ctx context.Context, blockerID uuid.UUID, blockedID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "ModerationService.Unblock")
+	defer span.End()
+
 	return s.mod.Unblock(ctx, blockerID, blockedID)
 }
 
 // ListBlocked returns all users blocked by the given user.
 func (s *ModerationService) ListBlocked(ctx context.Context, blockerID uuid.UUID) ([]model.BlockedUser, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.ListBlocked")
+	defer span.End()
+
 	return s.mod.ListBlocked(ctx, blockerID)
 }
 
 // Report creates a new report against a user, message, or conversation.
 func (s *ModerationService) Report(ctx context.Context, reporterID uuid.UUID, targetType string, targetID uuid.UUID, reason string) (*model.Report, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.Report")
+	defer span.End()
+
 	targetType = strings.TrimSpace(strings.ToLower(targetType))
 	reason = strings.TrimSpace(reason)
 
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProviderConfig describes an OpenID Connect provider users can sign in with.
+type OIDCProviderConfig struct {
+	Name         string // URL-safe identifier, e.g. "corp" or "google"
+	IssuerURL    string // used for discovery at {IssuerURL}/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // must point at GET /api/v1/auth/oidc/{name}/callback
+	Scopes       []string // defaults to openid, email, profile
+
+	// AssumeEmailVerified treats the email claim as verified even when the
+	// provider omits email_verified. Only enable for providers that own the
+	// email domain (e.g. company SSO).
+	AssumeEmailVerified bool
+}
+
+// OIDCAuthRequest is the state needed to start an authorization-code + PKCE flow.
+// State, Nonce, and Verifier must be kept by the caller and handed back on completion.
+type OIDCAuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// OIDCService handles sign-in through external OpenID Connect providers.
+type OIDCService struct {
+	auth       *AuthService
+	users      repo.UserRepository
+	identities repo.IdentityRepository
+	configs    map[string]OIDCProviderConfig
+
+	mu        sync.Mutex
+	providers map[string]*oidcProvider
+}
+
+type oidcProvider struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	config   OIDCProviderConfig
+}
+
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// NewOIDCService creates a new OIDCService. Providers are discovered lazily on first use.
+func NewOIDCService(auth *AuthService, users repo.UserRepository, identities repo.IdentityRepository, providers []OIDCProviderConfig) *OIDCService {
+	configs := make(map[string]OIDCProviderConfig, len(providers))
+	for _, p := range providers {
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+		}
+		configs[p.Name] = p
+	}
+	return &OIDCService{
+		auth:       auth,
+		users:      users,
+		identities: identities,
+		configs:    configs,
+		providers:  make(map[string]*oidcProvider),
+	}
+}
+
+// BeginLogin builds the provider authorization URL along with fresh state, nonce, and PKCE verifier.
+func (s *OIDCService) BeginLogin(ctx context.Context, providerName string) (*OIDCAuthRequest, error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.BeginLogin")
+	defer span.End()
+
+	p, err := s.provider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	url := p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return &OIDCAuthRequest{URL: url, State: state, Nonce: nonce, Verifier: verifier}, nil
+}
+
+// CompleteLogin exchanges an authorization code, validates the ID token, and signs the user in.
+// Unknown identities are linked to an existing account by verified email, or a new account is provisioned.
+func (s *OIDCService) CompleteLogin(ctx context.Context, providerName, code, verifier, nonce string) (*AuthResult, error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.CompleteLogin")
+	defer span.End()
+
+	if code == "" {
+		return nil, &model.ValidationError{Field: "code", Message: "must not be empty"}
+	}
+
+	p, err := s.provider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: code exchange failed", model.ErrUnauthenticated)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", model.ErrUnauthenticated)
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid id_token", model.ErrUnauthenticated)
+	}
+	if nonce == "" || idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", model.ErrUnauthenticated)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed id_token claims", model.ErrUnauthenticated)
+	}
+
+	user, err := s.resolveUser(ctx, p.config, idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.issueTokens(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.auth.metrics.LoginSucceeded("oidc")
+
+	return &AuthResult{User: user, Tokens: *tokens}, nil
+}
+
+// resolveUser finds the user behind an external identity, linking or provisioning as needed.
+func (s *OIDCService) resolveUser(ctx context.Context, cfg OIDCProviderConfig, subject string, claims oidcClaims) (*model.User, error) {
+	identity, err := s.identities.GetByProviderSubject(ctx, cfg.Name, subject)
+	if err == nil {
+		return s.users.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	verified := cfg.AssumeEmailVerified
+	if claims.EmailVerified != nil {
+		verified = *claims.EmailVerified
+	}
+	if email == "" || !verified {
+		return nil, &model.ValidationError{Field: "email", Message: "identity provider did not supply a verified email"}
+	}
+	// Bot addresses can neither be linked to nor provisioned from outside.
+	if isBotEmail(email) {
+		return nil, &model.ValidationError{Field: "email", Message: "uses a domain reserved for bots"}
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, model.ErrNotFound) {
+		user, err = s.provisionUser(ctx, email, claims.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Registering with a password never proves the address, so a password
+	// account may be a stranger's squatting on it. Linking would sign the
+	// address's owner in to an account the stranger can still open.
+	if user.PasswordHash != "" {
+		return nil, &model.ValidationError{Field: "email", Message: "an account with this email signs in with a password; use that instead"}
+	}
+
+	link := &model.UserIdentity{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Provider:  cfg.Name,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.identities.Create(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionUser creates an account for a first-time SSO user. The account has no
+// password, so it can only sign in through a linked provider until one is set.
+func (s *OIDCService) provisionUser(ctx context.Context, email, name string) (*model.User, error) {
+	displayName := strings.TrimSpace(name)
+	if displayName == "" {
+		displayName, _, _ = strings.Cut(email, "@")
+	}
+	if len(displayName) > 100 {
+		displayName = displayName[:100]
+	}
+
+	now := time.Now().UTC()
+	user := &model.User{
+		ID:          uuid.New(),
+		Email:       email,
+		DisplayName: displayName,
+		Status:      "offline",
+		Type:        model.UserTypeHuman,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	s.auth.metrics.UserRegistered("oidc")
+	return user, nil
+}
+
+// provider returns the discovered provider, running discovery on first use.
+func (s *OIDCService) provider(ctx context.Context, name string) (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.providers[name]; ok {
+		return p, nil
+	}
+	cfg, ok := s.configs[name]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+
+	discovered, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: discover provider %q: %w", name, err)
+	}
+
+	p := &oidcProvider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		config:   cfg,
+	}
+	s.providers[name] = p
+	return p, nil
+}
+
+// randomHex returns n random bytes, hex-encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("oidc: generate random value: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// ---------------------------------------------------------------------------
+// Mock: IdentityRepository
+// ---------------------------------------------------------------------------
+
+type mockIdentityRepo struct {
+	mu         sync.Mutex
+	identities []model.UserIdentity
+}
+
+func newMockIdentityRepo() *mockIdentityRepo {
+	return &mockIdentityRepo{}
+}
+
+func (m *mockIdentityRepo) Create(_ context.Context, identity *model.UserIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.identities {
+		if i.Provider == identity.Provider && i.Subject == identity.Subject {
+			return model.ErrConflict
+		}
+	}
+	m.identities = append(m.identities, *identity)
+	return nil
+}
+
+func (m *mockIdentityRepo) GetByProviderSubject(_ context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockIdentityRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]model.UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []model.UserIdentity
+	for _, i := range m.identities {
+		if i.UserID == userID {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// ---------------------------------------------------------------------------
+// Mock OIDC provider
+// ---------------------------------------------------------------------------
+
+// mockOIDCProvider is an in-process OpenID Connect provider. Tests "authorize"
+// a user by calling grant with the auth URL the service produced, which yields
+// an authorization code bound to that URL's PKCE challenge and nonce.
+type mockOIDCProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &mockOIDCProvider{key: key, clientID: "test-client", codes: make(map[string]mockGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		grant, ok := p.codes[r.Form.Get("code")]
+		delete(p.codes, r.Form.Get("code"))
+		p.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.sign(t, grant.nonce, grant.claims),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockOIDCProvider) sign(t *testing.T, nonce string, extra jwt.MapClaims) string {
+	t.Helper()
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   p.clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "test-key"
+	signed, err := tok.SignedString(p.key)
+	if err != nil {
+		t.Fatalf("sign id token: %v", err)
+	}
+	return signed
+}
+
+// grant simulates the user approving the login at the provider.
+func (p *mockOIDCProvider) grant(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 PKCE challenge, got %q", q.Get("code_challenge_method"))
+	}
+	code := uuid.NewString()
+	p.mu.Lock()
+	p.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	p.mu.Unlock()
+	return code
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+func newTestOIDCService(t *testing.T) (*OIDCService, *mockOIDCProvider, *mockUserRepo, *mockIdentityRepo) {
+	t.Helper()
+	provider := newMockOIDCProvider(t)
+	users := newMockUserRepo()
+	identities := newMockIdentityRepo()
+	sessions := newMockSessionRepo()
+	auth := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+	svc := NewOIDCService(auth, users, identities, []OIDCProviderConfig{{
+		Name:         "corp",
+		IssuerURL:    provider.server.URL,
+		ClientID:     provider.clientID,
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost/api/v1/auth/oidc/corp/callback",
+	}})
+	return svc, provider, users, identities
+}
+
+func oidcLogin(t *testing.T, svc *OIDCService, provider *mockOIDCProvider, claims jwt.MapClaims) (*AuthResult, error) {
+	t.Helper()
+	req, err := svc.BeginLogin(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	code := provider.grant(t, req.URL, claims)
+	return svc.CompleteLogin(context.Background(), "corp", code, req.Verifier, req.Nonce)
+}
+
+// ---------------------------------------------------------------------------
+// Tests: OIDC login
+// ---------------------------------------------------------------------------
+
+func TestOIDCLogin_ProvisionsNewUser(t *testing.T) {
+	svc, provider, users, identities := newTestOIDCService(t)
+
+	result, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-123", "email": "Dana@Example.com", "email_verified": true, "name": "Dana",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.User.Email != "dana@example.com" {
+		t.Errorf("expected email dana@example.com, got %s", result.User.Email)
+	}
+	if result.User.DisplayName != "Dana" {
+		t.Errorf("expected display name Dana, got %s", result.User.DisplayName)
+	}
+	if result.Tokens.AccessToken == "" || result.Tokens.RefreshToken == "" {
+		t.Error("expected tokens to be issued")
+	}
+	if len(users.byID) != 1 {
+		t.Errorf("expected 1 provisioned user, got %d", len(users.byID))
+	}
+	if len(identities.identities) != 1 || identities.identities[0].Subject != "corp-123" {
+		t.Errorf("expected identity for subject corp-123, got %+v", identities.identities)
+	}
+}
+
+func TestOIDCLogin_LinksExistingUserByVerifiedEmail(t *testing.T) {
+	svc, provider, users, identities := newTestOIDCService(t)
+
+	// An account without a password, as another provider provisions them.
+	existing := &model.User{ID: uuid.New(), Email: "erin@example.com", DisplayName: "Erin", Status: "offline", Type: model.UserTypeHuman}
+	if err := users.Create(context.Background(), existing); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-456", "email": "erin@example.com", "email_verified": true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.User.ID != existing.ID {
+		t.Errorf("expected linked user %s, got %s", existing.ID, result.User.ID)
+	}
+	if len(users.byID) != 1 {
+		t.Errorf("expected no new user, got %d users", len(users.byID))
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != existing.ID {
+		t.Errorf("expected identity linked to existing user, got %+v", identities.identities)
+	}
+}
+
+func TestOIDCLogin_RefusesToLinkPasswordAccount(t *testing.T) {
+	svc, provider, users, identities := newTestOIDCService(t)
+
+	// Anyone can register an address they do not own.
+	if _, err := svc.auth.Register(context.Background(), "erin@example.com", "strongpass", "Mallory"); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	_, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-456", "email": "erin@example.com", "email_verified": true,
+	})
+	var ve *model.ValidationError
+	if !errors.As(err, &ve) || ve.Field != "email" {
+		t.Fatalf("expected email ValidationError, got %v", err)
+	}
+	if len(identities.identities) != 0 {
+		t.Errorf("expected no identity linked, got %+v", identities.identities)
+	}
+	if len(users.byID) != 1 {
+		t.Errorf("expected no new user, got %d users", len(users.byID))
+	}
+}
+
+func TestOIDCLogin_ReturningIdentity(t *testing.T) {
+	svc, provider, users, _ := newTestOIDCService(t)
+
+	first, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-789", "email": "finn@example.com", "email_verified": true,
+	})
+	if err != nil {
+		t.Fatalf("first login failed: %v", err)
+	}
+
+	// The email changed at the provider; the subject is what identifies the account.
+	second, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-789", "email": "finn.new@example.com", "email_verified": true,
+	})
+	if err != nil {
+		t.Fatalf("second login failed: %v", err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Errorf("expected the same user on second login")
+	}
+	if len(users.byID) != 1 {
+		t.Errorf("expected 1 user, got %d", len(users.byID))
+	}
+}
+
+func TestOIDCLogin_UnverifiedEmail(t *testing.T) {
+	svc, provider, _, _ := newTestOIDCService(t)
+
+	_, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-999", "email": "gail@example.com", "email_verified": false,
+	})
+	var ve *model.ValidationError
+	if !errors.As(err, &ve) || ve.Field != "email" {
+		t.Fatalf("expected email ValidationError, got %v", err)
+	}
+}
+
+func TestOIDCLogin_ReservedBotDomain(t *testing.T) {
+	svc, provider, users, _ := newTestOIDCService(t)
+
+	_, err := oidcLogin(t, svc, provider, jwt.MapClaims{
+		"sub": "corp-998", "email": BuiltinBotEmail, "email_verified": true,
+	})
+	var ve *model.ValidationError
+	if !errors.As(err, &ve) || ve.Field != "email" {
+		t.Fatalf("expected email ValidationError, got %v", err)
+	}
+	if len(users.byID) != 0 {
+		t.Errorf("expected no user provisioned, got %d", len(users.byID))
+	}
+}
+
+func TestOIDCLogin_WrongVerifier(t *testing.T) {
+	svc, provider, _, _ := newTestOIDCService(t)
+
+	req, err := svc.BeginLogin(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	code := provider.grant(t, req.URL, jwt.MapClaims{"sub": "corp-1", "email": "h@example.com", "email_verified": true})
+
+	_, err = svc.CompleteLogin(context.Background(), "corp", code, "not-the-verifier", req.Nonce)
+	if !errors.Is(err, model.ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated, got %v", err)
+	}
+}
+
+func TestOIDCLogin_NonceMismatch(t *testing.T) {
+	svc, provider, _, _ := newTestOIDCService(t)
+
+	req, err := svc.BeginLogin(context.Background(), "corp")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	code := provider.grant(t, req.URL, jwt.MapClaims{"sub": "corp-1", "email": "h@example.com", "email_verified": true})
+
+	_, err = svc.CompleteLogin(context.Background(), "corp", code, req.Verifier, "some-other-nonce")
+	if !errors.Is(err, model.ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated, got %v", err)
+	}
+}
+
+func TestOIDCLogin_UnknownProvider(t *testing.T) {
+	svc, _, _, _ := newTestOIDCService(t)
+
+	_, err := svc.BeginLogin(context.Background(), "nope")
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// bcryptMaxBytes is the input length beyond which bcrypt refuses to hash.
+const bcryptMaxBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords is the bundled breached/common password list, lowercased.
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+// PasswordPolicy controls which passwords are accepted on registration and change.
+type PasswordPolicy struct {
+	MinLength      int  // minimum length in characters (default 8)
+	MaxLength      int  // maximum length in bytes; capped at bcrypt's 72-byte limit (default 72)
+	MinCharClasses int  // distinct classes required among lower, upper, digit and symbol (default 1)
+	AllowCommon    bool // skip the bundled breached/common password check
+}
+
+// withDefaults fills unset fields and clamps MaxLength to what bcrypt can hash.
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength == 0 {
+		p.MinLength = 8
+	}
+	if p.MaxLength == 0 || p.MaxLength > bcryptMaxBytes {
+		p.MaxLength = bcryptMaxBytes
+	}
+	if p.MinCharClasses == 0 {
+		p.MinCharClasses = 1
+	}
+	return p
+}
+
+// Validate checks a password against the policy. field names the request field
+// the password came from, for the returned ValidationError.
+func (p PasswordPolicy) Validate(field, password string) error {
+	if password == "" {
+		return &model.ValidationError{Field: field, Message: "must not be empty"}
+	}
+	if len([]rune(password)) < p.MinLength {
+		return &model.ValidationError{Field: field, Message: fmt.Sprintf("must be at least %d characters", p.MinLength)}
+	}
+	if len(password) > p.MaxLength {
+		return &model.ValidationError{Field: field, Message: fmt.Sprintf("must be at most %d bytes", p.MaxLength)}
+	}
+	if charClasses(password) < p.MinCharClasses {
+		return &model.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must mix at least %d of: lowercase, uppercase, digits, symbols", p.MinCharClasses),
+		}
+	}
+	if !p.AllowCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			return &model.ValidationError{Field: field, Message: "is too common; choose a less guessable password"}
+		}
+	}
+	return nil
+}
+
+// charClasses counts how many of lowercase, uppercase, digit and symbol appear in s.
+func charClasses(s string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+func parseCommonPasswords(file string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, line := range strings.Split(file, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	return set
+}
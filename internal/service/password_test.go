@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ---------------------------------------------------------------------------
+// Tests: PasswordPolicy
+// ---------------------------------------------------------------------------
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, MinCharClasses: 3}.withDefaults()
+
+	cases := []struct {
+		name     string
+		password string
+		ok       bool
+	}{
+		{name: "empty", password: "", ok: false},
+		{name: "too short", password: "Ab1!", ok: false},
+		{name: "too few classes", password: "alllowercase", ok: false},
+		{name: "over bcrypt limit", password: "Aa1" + strings.Repeat("x", 70), ok: false},
+		{name: "common", password: "Password123", ok: false},
+		{name: "acceptable", password: "Tr0ub4dor&3x", ok: true},
+		{name: "multibyte counts characters", password: "Pässwörd-Ünïcode", ok: true},
+	}
+	for _, c := range cases {
+		err := policy.Validate("password", c.password)
+		if c.ok && err != nil {
+			t.Errorf("%s: expected valid, got %v", c.name, err)
+		}
+		if !c.ok && !errors.Is(err, model.ErrValidation) {
+			t.Errorf("%s: expected validation error, got %v", c.name, err)
+		}
+	}
+}
+
+func TestPasswordPolicy_AllowCommon(t *testing.T) {
+	policy := PasswordPolicy{AllowCommon: true}.withDefaults()
+	if err := policy.Validate("password", "password123"); err != nil {
+		t.Errorf("expected common password to be allowed, got %v", err)
+	}
+}
+
+func TestPasswordPolicy_MaxLengthCappedAtBcryptLimit(t *testing.T) {
+	policy := PasswordPolicy{MaxLength: 500}.withDefaults()
+	if policy.MaxLength != bcryptMaxBytes {
+		t.Errorf("expected MaxLength capped at %d, got %d", bcryptMaxBytes, policy.MaxLength)
+	}
+}
+
+func TestRegister_RejectsCommonPassword(t *testing.T) {
+	users, sessions := newMockUserRepo(), newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+
+	_, err := svc.Register(context.Background(), "alice@example.com", "qwertyuiop", "Alice")
+	var ve *model.ValidationError
+	if !errors.As(err, &ve) || ve.Field != "password" {
+		t.Fatalf("expected password validation error, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests: ChangePassword
+// ---------------------------------------------------------------------------
+
+func TestChangePassword_Success(t *testing.T) {
+	users := newMockUserRepo()
+	sessions := newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+	ctx := context.Background()
+
+	reg, err := svc.Register(ctx, "alice@example.com", "strongpass", "Alice")
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	tokens, err := svc.ChangePassword(ctx, reg.User.ID, "strongpass", "n3w-Passphrase", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Error("expected a fresh token pair")
+	}
+
+	// The session from registration is revoked.
+	if _, err := svc.RefreshToken(ctx, reg.Tokens.RefreshToken); err == nil {
+		t.Error("expected old refresh token to be revoked")
+	}
+
+	if _, err := svc.Login(ctx, "alice@example.com", "strongpass", ""); err == nil {
+		t.Error("expected old password to be rejected")
+	}
+	if _, err := svc.Login(ctx, "alice@example.com", "n3w-Passphrase", ""); err != nil {
+		t.Errorf("expected login with new password, got %v", err)
+	}
+}
+
+func TestChangePassword_Errors(t *testing.T) {
+	users, sessions := newMockUserRepo(), newMockSessionRepo()
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, testAuthConfig())
+	ctx := context.Background()
+
+	reg, _ := svc.Register(ctx, "alice@example.com", "strongpass", "Alice")
+
+	cases := []struct {
+		name, current, next, field string
+	}{
+		{name: "wrong current", current: "notmypass", next: "n3w-Passphrase", field: "current_password"},
+		{name: "missing current", current: "", next: "n3w-Passphrase", field: "current_password"},
+		{name: "weak new", current: "strongpass", next: "short", field: "new_password"},
+		{name: "unchanged", current: "strongpass", next: "strongpass", field: "new_password"},
+	}
+	for _, c := range cases {
+		_, err := svc.ChangePassword(ctx, reg.User.ID, c.current, c.next, "")
+		var ve *model.ValidationError
+		if !errors.As(err, &ve) || ve.Field != c.field {
+			t.Errorf("%s: expected validation error on %q, got %v", c.name, c.field, err)
+		}
+	}
+}
+
+func TestChangePassword_CountsWrongGuessesTowardsLockout(t *testing.T) {
+	users, sessions := newMockUserRepo(), newMockSessionRepo()
+	cfg := testAuthConfig()
+	cfg.Lockout = LockoutPolicy{MaxAccountFailures: 2}
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, cfg)
+	ctx := context.Background()
+
+	reg, _ := svc.Register(ctx, "alice@example.com", "strongpass", "Alice")
+	for range 2 {
+		svc.ChangePassword(ctx, reg.User.ID, "notmypass", "n3w-Passphrase", "10.0.0.1")
+	}
+
+	// The right password is refused once locked, here and at login.
+	if _, err := svc.ChangePassword(ctx, reg.User.ID, "strongpass", "n3w-Passphrase", "10.0.0.1"); !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Errorf("expected ErrTooManyAttempts changing the password, got %v", err)
+	}
+	if _, err := svc.Login(ctx, "alice@example.com", "strongpass", "10.0.0.2"); !errors.Is(err, model.ErrTooManyAttempts) {
+		t.Errorf("expected ErrTooManyAttempts logging in, got %v", err)
+	}
+}
+
+// failingSessionsTx runs transactions on store with sessions that cannot be revoked.
+type failingSessionsTx struct{ store *repo.Store }
+
+func (f failingSessionsTx) WithTx(ctx context.Context, fn func(tx *repo.Store) error) error {
+	return f.store.WithTx(ctx, func(tx *repo.Store) error {
+		failing := *tx
+		failing.Sessions = failingSessions{tx.Sessions}
+		return fn(&failing)
+	})
+}
+
+type failingSessions struct{ repo.SessionRepository }
+
+func (failingSessions) RevokeAllForUser(context.Context, uuid.UUID) error {
+	return errors.New("revoke failed")
+}
+
+func TestChangePassword_KeepsPasswordWhenRevokeFails(t *testing.T) {
+	store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAuthService(store.Users, store.Sessions, store.LoginAttempts, failingSessionsTx{store}, testAuthConfig())
+	ctx := context.Background()
+
+	reg, err := svc.Register(ctx, "alice@example.com", "strongpass", "Alice")
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if _, err := svc.ChangePassword(ctx, reg.User.ID, "strongpass", "n3w-Passphrase", ""); err == nil {
+		t.Fatal("expected the change to fail")
+	}
+
+	// The new hash rolled back with the failed revocation.
+	if _, err := svc.Login(ctx, "alice@example.com", "strongpass", ""); err != nil {
+		t.Errorf("expected the old password to still work, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Rehash
+// ---------------------------------------------------------------------------
+
+func TestLogin_RehashesLowerCostHash(t *testing.T) {
+	users := newMockUserRepo()
+	ctx := context.Background()
+
+	cfg := testAuthConfig()
+	cfg.BcryptCost = bcrypt.MinCost
+	sessions := newMockSessionRepo()
+	weak := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, cfg)
+	reg, err := weak.Register(ctx, "alice@example.com", "strongpass", "Alice")
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	cfg.BcryptCost = bcrypt.MinCost + 1
+	svc := NewAuthService(users, sessions, repo.NewMemoryLoginAttemptRepo(), &repo.Store{Users: users, Sessions: sessions}, cfg)
+	if _, err := svc.Login(ctx, "alice@example.com", "strongpass", ""); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	u, _ := users.GetByID(ctx, reg.User.ID)
+	cost, err := bcrypt.Cost([]byte(u.PasswordHash))
+	if err != nil {
+		t.Fatalf("stored hash unreadable: %v", err)
+	}
+	if cost != bcrypt.MinCost+1 {
+		t.Errorf("expected hash upgraded to cost %d, got %d", bcrypt.MinCost+1, cost)
+	}
+}
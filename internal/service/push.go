@@ -0,0 +1,493 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ErrDeviceUnregistered is returned by a PushProvider when the platform
+// reports that a device token is no longer valid. The token is forgotten.
+var ErrDeviceUnregistered = errors.New("push: device unregistered")
+
+// deviceTokenPatterns is the shape of a device token on each platform. APNs
+// tokens are 32 bytes in hex; FCM registration tokens are opaque but stick to
+// a URL-safe alphabet.
+var deviceTokenPatterns = map[string]*regexp.Regexp{
+	model.PlatformAPNs: regexp.MustCompile(`^[0-9a-f]{64}$`),
+	model.PlatformFCM:  regexp.MustCompile(`^[A-Za-z0-9_:-]+$`),
+}
+
+const (
+	maxDeviceTokenLen = 4096
+	// pushPreviewLen is how many characters of a message a notification shows.
+	pushPreviewLen = 100
+)
+
+// Notification is what a push notification tells a device.
+type Notification struct {
+	ConversationID uuid.UUID
+	MessageID      uuid.UUID // the latest message it stands for
+	Title          string
+	Body           string
+	Count          int // messages it stands for; more than one for a burst summary
+	// CollapseKey groups notifications that replace one another on the
+	// device: a burst summary replaces the notification it follows.
+	CollapseKey string
+}
+
+// PushProvider sends notifications to the devices of one platform.
+type PushProvider interface {
+	// Platform returns the model.Platform* value of the devices it serves.
+	Platform() string
+	// Send delivers n to device, returning ErrDeviceUnregistered if the
+	// platform no longer knows the token.
+	Send(ctx context.Context, device model.DeviceToken, n Notification) error
+}
+
+// Presence reports whether a user is connected and so is told about messages
+// without a notification. It is satisfied by *infra.Hub.
+type Presence interface {
+	Online(userID uuid.UUID) bool
+}
+
+// PushDispatchConfig configures notification dispatch.
+type PushDispatchConfig struct {
+	Presence  Presence
+	Providers []PushProvider
+	// CollapseWindow is how long after a notification further messages of
+	// the same conversation are held back and summarised in one notification.
+	// Zero sends a notification for every message.
+	CollapseWindow time.Duration
+}
+
+// PushService manages the devices and conversation mutes of users, and sends
+// push notifications about new messages to recipients who are not connected.
+//
+// Bursts are collapsed per user and conversation: the first message notifies
+// at once, and the messages that follow within the collapse window are
+// summarised when it closes. Open windows are kept in memory, so a summary
+// due when the process stops is never sent.
+type PushService struct {
+	push   repo.PushRepository
+	convos repo.ConversationRepository
+	users  repo.UserRepository
+	mod    repo.ModerationRepository
+	now    func() time.Time
+
+	mu        sync.Mutex
+	enabled   bool
+	presence  Presence
+	providers map[string]PushProvider
+	window    time.Duration
+	pending   map[pushKey]*pushBurst
+}
+
+// pushKey identifies a burst: one user's notifications about one conversation.
+type pushKey struct {
+	userID, conversationID uuid.UUID
+}
+
+// pushBurst is an open collapse window.
+type pushBurst struct {
+	count    int          // messages held back since the last notification
+	senderID uuid.UUID    // sender of the latest of them
+	latest   Notification // notification for the latest of them
+}
+
+// NewPushService creates a new PushService. It sends nothing until
+// EnableDispatch is called.
+func NewPushService(push repo.PushRepository, convos repo.ConversationRepository, users repo.UserRepository, mod repo.ModerationRepository) *PushService {
+	return &PushService{
+		push:    push,
+		convos:  convos,
+		users:   users,
+		mod:     mod,
+		now:     time.Now,
+		pending: make(map[pushKey]*pushBurst),
+	}
+}
+
+// EnableDispatch starts sending notifications through the given providers.
+// Devices of platforms without a provider are skipped.
+func (s *PushService) EnableDispatch(cfg PushDispatchConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = true
+	s.presence = cfg.Presence
+	s.window = cfg.CollapseWindow
+	s.providers = make(map[string]PushProvider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		s.providers[p.Platform()] = p
+	}
+}
+
+// RegisterDevice registers a device to receive the user's notifications. A
+// token already registered is moved to the user, since it identifies an app
+// install and the user signed in to it last.
+func (s *PushService) RegisterDevice(ctx context.Context, userID uuid.UUID, platform, token string) (*model.DeviceToken, error) {
+	ctx, span := tracer.Start(ctx, "PushService.RegisterDevice")
+	defer span.End()
+
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	token = strings.TrimSpace(token)
+	if platform != model.PlatformAPNs && platform != model.PlatformFCM {
+		return nil, &model.ValidationError{Field: "platform", Message: "must be 'apns' or 'fcm'"}
+	}
+	if token == "" {
+		return nil, &model.ValidationError{Field: "token", Message: "must not be empty"}
+	}
+	if len(token) > maxDeviceTokenLen {
+		return nil, &model.ValidationError{Field: "token", Message: fmt.Sprintf("must be %d characters or fewer", maxDeviceTokenLen)}
+	}
+	if platform == model.PlatformAPNs {
+		token = strings.ToLower(token)
+	}
+	if !deviceTokenPatterns[platform].MatchString(token) {
+		return nil, &model.ValidationError{Field: "token", Message: "is not a valid " + platform + " device token"}
+	}
+
+	now := s.now().UTC()
+	device := &model.DeviceToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Platform:  platform,
+		Token:     token,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.push.UpsertDevice(ctx, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// ListDevices returns the devices registered for the user.
+func (s *PushService) ListDevices(ctx context.Context, userID uuid.UUID) ([]model.DeviceToken, error) {
+	ctx, span := tracer.Start(ctx, "PushService.ListDevices")
+	defer span.End()
+
+	return s.push.ListDevices(ctx, userID)
+}
+
+// DeleteDevice unregisters one of the user's devices.
+func (s *PushService) DeleteDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "PushService.DeleteDevice")
+	defer span.End()
+
+	return s.push.DeleteDevice(ctx, userID, deviceID)
+}
+
+// Mute stops notifications from a conversation for the caller, until the
+// given time or, when until is nil, until they unmute it. The caller must be
+// a participant.
+func (s *PushService) Mute(ctx context.Context, userID, conversationID uuid.UUID, until *time.Time) (*model.ConversationMute, error) {
+	ctx, span := tracer.Start(ctx, "PushService.Mute")
+	defer span.End()
+
+	now := s.now().UTC()
+	if until != nil && !until.After(now) {
+		return nil, &model.ValidationError{Field: "until", Message: "must be in the future"}
+	}
+	if err := s.requireParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	mute := &model.ConversationMute{ConversationID: conversationID, UserID: userID, CreatedAt: now}
+	if until != nil {
+		u := until.UTC()
+		mute.MutedUntil = &u
+	}
+	if err := s.push.SetMute(ctx, mute); err != nil {
+		return nil, err
+	}
+	return mute, nil
+}
+
+// GetMute returns the caller's mute of a conversation, or nil when its
+// notifications are not muted. The caller must be a participant.
+func (s *PushService) GetMute(ctx context.Context, userID, conversationID uuid.UUID) (*model.ConversationMute, error) {
+	ctx, span := tracer.Start(ctx, "PushService.GetMute")
+	defer span.End()
+
+	if err := s.requireParticipant(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.activeMute(ctx, userID, conversationID)
+}
+
+// Unmute resumes notifications from a conversation. Unmuting a conversation
+// that is not muted succeeds. The caller must be a participant.
+func (s *PushService) Unmute(ctx context.Context, userID, conversationID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "PushService.Unmute")
+	defer span.End()
+
+	if err := s.requireParticipant(ctx, userID, conversationID); err != nil {
+		return err
+	}
+	return s.push.DeleteMute(ctx, conversationID, userID)
+}
+
+func (s *PushService) requireParticipant(ctx context.Context, userID, conversationID uuid.UUID) error {
+	ok, err := s.convos.IsParticipant(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// activeMute returns the user's mute of a conversation if it still applies.
+func (s *PushService) activeMute(ctx context.Context, userID, conversationID uuid.UUID) (*model.ConversationMute, error) {
+	mute, err := s.push.GetMute(ctx, conversationID, userID)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !mute.ActiveAt(s.now()) {
+		return nil, nil
+	}
+	return mute, nil
+}
+
+// Notify sends notifications about a new message to those of its recipients
+// who are offline, have not muted the conversation and have not blocked the
+// sender. It returns at once; notifications are sent in the background.
+func (s *PushService) Notify(ctx context.Context, msg *model.Message, recipientIDs []uuid.UUID) {
+	s.mu.Lock()
+	enabled := s.enabled
+	s.mu.Unlock()
+	if !enabled || len(recipientIDs) == 0 {
+		return
+	}
+	go s.dispatch(context.WithoutCancel(ctx), msg, recipientIDs)
+}
+
+func (s *PushService) dispatch(ctx context.Context, msg *model.Message, recipientIDs []uuid.UUID) {
+	ctx, span := tracer.Start(ctx, "PushService.dispatch")
+	defer span.End()
+
+	n, err := s.notification(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "build notification")
+		return
+	}
+	for _, userID := range recipientIDs {
+		ok, err := s.wants(ctx, userID, msg.ConversationID, msg.SenderID)
+		if err != nil {
+			span.RecordError(err)
+			continue
+		}
+		if ok {
+			s.collapse(ctx, userID, msg.SenderID, n)
+		}
+	}
+}
+
+// notification describes msg: who sent it and, for named conversations,
+// where, followed by a preview of the text.
+func (s *PushService) notification(ctx context.Context, msg *model.Message) (Notification, error) {
+	sender, err := s.users.GetByID(ctx, msg.SenderID)
+	if err != nil {
+		return Notification{}, err
+	}
+	convo, err := s.convos.GetByID(ctx, msg.ConversationID)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	n := Notification{
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+		Title:          sender.DisplayName,
+		Body:           pushPreview(msg.Body),
+		Count:          1,
+		CollapseKey:    msg.ConversationID.String(),
+	}
+	if convo.Name != nil && *convo.Name != "" {
+		n.Title = *convo.Name
+		n.Body = sender.DisplayName + ": " + n.Body
+	}
+	return n, nil
+}
+
+func pushPreview(body string) string {
+	if utf8.RuneCountInString(body) <= pushPreviewLen {
+		return body
+	}
+	runes := []rune(body)
+	return strings.TrimSpace(string(runes[:pushPreviewLen-1])) + "…"
+}
+
+// wants reports whether a user should be notified now about a message from
+// senderID in a conversation.
+func (s *PushService) wants(ctx context.Context, userID, conversationID, senderID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	presence := s.presence
+	s.mu.Unlock()
+	if presence != nil && presence.Online(userID) {
+		return false, nil
+	}
+	mute, err := s.activeMute(ctx, userID, conversationID)
+	if err != nil || mute != nil {
+		return false, err
+	}
+	blocked, err := s.mod.IsBlocked(ctx, userID, senderID)
+	if err != nil || blocked {
+		return false, err
+	}
+	return true, nil
+}
+
+// collapse sends n to the user unless a collapse window is open for the
+// conversation, in which case it is held for the window's summary.
+func (s *PushService) collapse(ctx context.Context, userID, senderID uuid.UUID, n Notification) {
+	key := pushKey{userID: userID, conversationID: n.ConversationID}
+
+	s.mu.Lock()
+	window := s.window
+	if burst, ok := s.pending[key]; ok {
+		burst.count++
+		burst.senderID = senderID
+		burst.latest = n
+		s.mu.Unlock()
+		return
+	}
+	if window > 0 {
+		s.pending[key] = &pushBurst{}
+		time.AfterFunc(window, func() { s.flush(ctx, key) })
+	}
+	s.mu.Unlock()
+
+	s.deliver(ctx, userID, n)
+}
+
+// flush closes a collapse window. Messages held back during it are summarised
+// in one notification, which opens the next window.
+func (s *PushService) flush(parent context.Context, key pushKey) {
+	s.mu.Lock()
+	burst := s.pending[key]
+	if burst == nil || burst.count == 0 {
+		delete(s.pending, key)
+		s.mu.Unlock()
+		return
+	}
+	n := burst.latest
+	n.Count = burst.count
+	if n.Count > 1 {
+		n.Body = fmt.Sprintf("%d new messages", n.Count)
+	}
+	senderID := burst.senderID
+	*burst = pushBurst{}
+	time.AfterFunc(s.window, func() { s.flush(parent, key) })
+	s.mu.Unlock()
+
+	ctx, span := tracer.Start(parent, "PushService.flush")
+	defer span.End()
+
+	// The user may have come online, or muted the conversation, since.
+	ok, err := s.wants(ctx, key.userID, key.conversationID, senderID)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if ok {
+		s.deliver(ctx, key.userID, n)
+	}
+}
+
+// deliver sends n to each of the user's devices that has a provider,
+// forgetting devices the platform no longer knows.
+func (s *PushService) deliver(ctx context.Context, userID uuid.UUID, n Notification) {
+	ctx, span := tracer.Start(ctx, "PushService.deliver")
+	defer span.End()
+
+	devices, err := s.push.ListDevices(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	s.mu.Lock()
+	providers := s.providers
+	s.mu.Unlock()
+
+	for _, device := range devices {
+		provider, ok := providers[device.Platform]
+		if !ok {
+			continue
+		}
+		err := provider.Send(ctx, device, n)
+		if errors.Is(err, ErrDeviceUnregistered) {
+			err = s.push.DeleteDeviceToken(ctx, device.Platform, device.Token)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "push failed")
+		}
+	}
+}
+
+// FakePush is a notification recorded by a FakePushProvider.
+type FakePush struct {
+	Device       model.DeviceToken
+	Notification Notification
+}
+
+// FakePushProvider records notifications instead of sending them. It serves
+// tests, and development without platform credentials.
+type FakePushProvider struct {
+	platform string
+
+	mu           sync.Mutex
+	sent         []FakePush
+	unregistered map[string]bool
+}
+
+// NewFakePushProvider creates a FakePushProvider for the given platform.
+func NewFakePushProvider(platform string) *FakePushProvider {
+	return &FakePushProvider{platform: platform, unregistered: make(map[string]bool)}
+}
+
+// Platform implements PushProvider.
+func (p *FakePushProvider) Platform() string { return p.platform }
+
+// Send implements PushProvider.
+func (p *FakePushProvider) Send(_ context.Context, device model.DeviceToken, n Notification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unregistered[device.Token] {
+		return ErrDeviceUnregistered
+	}
+	p.sent = append(p.sent, FakePush{Device: device, Notification: n})
+	return nil
+}
+
+// Unregister makes later sends to token fail with ErrDeviceUnregistered, as
+// when the app is uninstalled.
+func (p *FakePushProvider) Unregister(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unregistered[token] = true
+}
+
+// Sent returns the notifications recorded so far.
+func (p *FakePushProvider) Sent() []FakePush {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]FakePush(nil), p.sent...)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// APNs hosts, for production and development builds of the app.
+const (
+	APNsProductionURL = "https://api.push.apple.com"
+	APNsSandboxURL    = "https://api.sandbox.push.apple.com"
+)
+
+const (
+	// apnsTokenTTL is how long a provider token is reused. Apple rejects
+	// tokens older than an hour and throttles ones refreshed more often than
+	// every twenty minutes.
+	apnsTokenTTL = 50 * time.Minute
+	pushTimeout  = 10 * time.Second
+)
+
+// APNsConfig configures an APNsProvider with token-based authentication.
+type APNsConfig struct {
+	Key    []byte // the .p8 signing key, PEM encoded
+	KeyID  string
+	TeamID string
+	Topic  string // the app's bundle ID
+	// URL is the APNs host. It defaults to APNsProductionURL.
+	URL string
+}
+
+// APNsProvider sends notifications to iOS devices through the Apple Push
+// Notification service HTTP/2 API.
+type APNsProvider struct {
+	cfg    APNsConfig
+	key    *ecdsa.PrivateKey
+	client *http.Client
+	now    func() time.Time
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsProvider creates an APNsProvider. Notifications are sent with
+// client, or if it is nil with http.DefaultClient.
+func NewAPNsProvider(cfg APNsConfig, client *http.Client) (*APNsProvider, error) {
+	block, _ := pem.Decode(cfg.Key)
+	if block == nil {
+		return nil, errors.New("apns: key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("apns: parse key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns: key is not an ECDSA key")
+	}
+	if cfg.URL == "" {
+		cfg.URL = APNsProductionURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &APNsProvider{cfg: cfg, key: key, client: client, now: time.Now}, nil
+}
+
+// Platform implements PushProvider.
+func (p *APNsProvider) Platform() string { return model.PlatformAPNs }
+
+type apnsPayload struct {
+	APS            apnsAPS `json:"aps"`
+	ConversationID string  `json:"conversation_id"`
+	MessageID      string  `json:"message_id"`
+}
+
+type apnsAPS struct {
+	Alert    apnsAlert `json:"alert"`
+	Sound    string    `json:"sound"`
+	ThreadID string    `json:"thread-id"`
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Send implements PushProvider.
+func (p *APNsProvider) Send(ctx context.Context, device model.DeviceToken, n Notification) error {
+	ctx, span := tracer.Start(ctx, "APNsProvider.Send")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(apnsPayload{
+		APS: apnsAPS{
+			Alert:    apnsAlert{Title: n.Title, Body: n.Body},
+			Sound:    "default",
+			ThreadID: n.ConversationID.String(),
+		},
+		ConversationID: n.ConversationID.String(),
+		MessageID:      n.MessageID.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("apns: encode payload: %w", err)
+	}
+	token, err := p.providerToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL+"/3/device/"+url.PathEscape(device.Token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", p.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	if n.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var reply struct {
+		Reason string `json:"reason"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	_ = json.Unmarshal(data, &reply)
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		resp.StatusCode == http.StatusBadRequest && (reply.Reason == "BadDeviceToken" || reply.Reason == "Unregistered"):
+		return ErrDeviceUnregistered
+	case reply.Reason == "ExpiredProviderToken":
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+	}
+	return fmt.Errorf("apns: responded %d %s", resp.StatusCode, reply.Reason)
+}
+
+// providerToken returns the signed JWT that authenticates requests, reusing
+// it until it is close to expiring.
+func (p *APNsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if p.token != "" && now.Sub(p.issuedAt) < apnsTokenTTL {
+		return p.token, nil
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.cfg.TeamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = p.cfg.KeyID
+	signed, err := t.SignedString(p.key)
+	if err != nil {
+		return "", fmt.Errorf("apns: sign provider token: %w", err)
+	}
+	p.token, p.issuedAt = signed, now
+	return signed, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/kareempaes/planning/internal/model"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+// FCMURL is the Firebase Cloud Messaging API host.
+const FCMURL = "https://fcm.googleapis.com"
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMConfig configures an FCMProvider.
+type FCMConfig struct {
+	// Credentials is the JSON key of a service account allowed to send
+	// messages for the Firebase project.
+	Credentials []byte
+	// ProjectID defaults to the project of the service account.
+	ProjectID string
+	// URL is the FCM host. It defaults to FCMURL.
+	URL string
+}
+
+// FCMProvider sends notifications to Android devices, and to iOS devices
+// registered through Firebase, with the FCM HTTP v1 API.
+type FCMProvider struct {
+	endpoint string
+	client   *http.Client
+	tokens   oauth2.TokenSource
+}
+
+// NewFCMProvider creates an FCMProvider. Notifications, and the requests that
+// obtain access tokens, are sent with client, or if it is nil with
+// http.DefaultClient.
+func NewFCMProvider(cfg FCMConfig, client *http.Client) (*FCMProvider, error) {
+	var creds struct {
+		Type         string `json:"type"`
+		ProjectID    string `json:"project_id"`
+		PrivateKeyID string `json:"private_key_id"`
+		PrivateKey   string `json:"private_key"`
+		ClientEmail  string `json:"client_email"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(cfg.Credentials, &creds); err != nil {
+		return nil, fmt.Errorf("fcm: parse credentials: %w", err)
+	}
+	if creds.Type != "service_account" || creds.ClientEmail == "" || creds.PrivateKey == "" || creds.TokenURI == "" {
+		return nil, errors.New("fcm: credentials are not a service account key")
+	}
+	projectID := cfg.ProjectID
+	if projectID == "" {
+		projectID = creds.ProjectID
+	}
+	if projectID == "" {
+		return nil, errors.New("fcm: project ID is required")
+	}
+	if cfg.URL == "" {
+		cfg.URL = FCMURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	conf := &jwt.Config{
+		Email:        creds.ClientEmail,
+		PrivateKey:   []byte(creds.PrivateKey),
+		PrivateKeyID: creds.PrivateKeyID,
+		Scopes:       []string{fcmScope},
+		TokenURL:     creds.TokenURI,
+	}
+	return &FCMProvider{
+		endpoint: cfg.URL + "/v1/projects/" + url.PathEscape(projectID) + "/messages:send",
+		client:   client,
+		tokens:   conf.TokenSource(context.WithValue(context.Background(), oauth2.HTTPClient, client)),
+	}, nil
+}
+
+// Platform implements PushProvider.
+func (p *FCMProvider) Platform() string { return model.PlatformFCM }
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data"`
+	Android      fcmAndroid        `json:"android"`
+	APNs         fcmAPNs           `json:"apns"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	CollapseKey string `json:"collapse_key,omitempty"`
+	Priority    string `json:"priority"`
+}
+
+type fcmAPNs struct {
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Send implements PushProvider.
+func (p *FCMProvider) Send(ctx context.Context, device model.DeviceToken, n Notification) error {
+	ctx, span := tracer.Start(ctx, "FCMProvider.Send")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+
+	msg := fcmMessage{
+		Token:        device.Token,
+		Notification: fcmNotification{Title: n.Title, Body: n.Body},
+		Data: map[string]string{
+			"conversation_id": n.ConversationID.String(),
+			"message_id":      n.MessageID.String(),
+			"count":           strconv.Itoa(n.Count),
+		},
+		Android: fcmAndroid{CollapseKey: n.CollapseKey, Priority: "high"},
+	}
+	if n.CollapseKey != "" {
+		msg.APNs.Headers = map[string]string{"apns-collapse-id": n.CollapseKey}
+	}
+	body, err := json.Marshal(fcmRequest{Message: msg})
+	if err != nil {
+		return fmt.Errorf("fcm: encode message: %w", err)
+	}
+	token, err := p.tokens.Token()
+	if err != nil {
+		return fmt.Errorf("fcm: access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	token.SetAuthHeader(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var reply struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	_ = json.Unmarshal(data, &reply)
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrDeviceUnregistered
+	}
+	for _, d := range reply.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return ErrDeviceUnregistered
+		}
+	}
+	return fmt.Errorf("fcm: responded %d %s: %s", resp.StatusCode, reply.Error.Status, reply.Error.Message)
+}
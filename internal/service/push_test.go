@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// onlineUsers is a Presence the test controls.
+type onlineUsers struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]bool
+}
+
+func (o *onlineUsers) Online(userID uuid.UUID) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.users[userID]
+}
+
+func (o *onlineUsers) set(userID uuid.UUID, online bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.users[userID] = online
+}
+
+type testPush struct {
+	store    *repo.Store
+	messages *MessageService
+	push     *PushService
+	fake     *FakePushProvider
+	online   *onlineUsers
+	convoID  uuid.UUID
+	sender   uuid.UUID
+	members  []uuid.UUID // each with one FCM device, its token the member's ID
+}
+
+// newTestPush wires the message and push services on an in-memory store, with
+// a conversation named "Team" between a sender and three members.
+func newTestPush(t *testing.T, window time.Duration) *testPush {
+	t.Helper()
+	store, sender, members, convoID := newTestGroup(t, "Sam", "Ann", "Ben", "Cal")
+	ctx := context.Background()
+
+	b := &testPush{
+		store:    store,
+		messages: NewMessageService(store.Messages, store.Conversations, store),
+		push:     NewPushService(store.Push, store.Conversations, store.Users, store.Moderation),
+		fake:     NewFakePushProvider(model.PlatformFCM),
+		online:   &onlineUsers{users: make(map[uuid.UUID]bool)},
+		convoID:  convoID,
+		sender:   sender,
+		members:  members,
+	}
+	b.messages.push = b.push
+	for _, id := range members {
+		if _, err := b.push.RegisterDevice(ctx, id, model.PlatformFCM, id.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.push.EnableDispatch(PushDispatchConfig{Presence: b.online, Providers: []PushProvider{b.fake}, CollapseWindow: window})
+	return b
+}
+
+func (b *testPush) send(t *testing.T, body string) {
+	t.Helper()
+	if _, _, err := b.messages.Send(context.Background(), b.sender, b.convoID, body, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForPushes waits until the fake provider has recorded n notifications.
+func (b *testPush) waitForPushes(t *testing.T, n int) []FakePush {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sent := b.fake.Sent()
+		if len(sent) >= n {
+			return sent
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d notifications, got %d", n, len(sent))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPushService_NotifiesOfflineRecipients(t *testing.T) {
+	b := newTestPush(t, 0)
+	ctx := context.Background()
+	online, muted, blocker := b.members[0], b.members[1], b.members[2]
+
+	b.online.set(online, true)
+	if _, err := b.push.Mute(ctx, muted, b.convoID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.store.Moderation.Block(ctx, blocker, b.sender); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a collapse window dispatch delivers before it returns.
+	msg := &model.Message{ID: uuid.New(), ConversationID: b.convoID, SenderID: b.sender, Body: "hello"}
+	b.push.dispatch(ctx, msg, b.members)
+	if sent := b.fake.Sent(); len(sent) != 0 {
+		t.Fatalf("expected no notifications, got %+v", sent)
+	}
+
+	if err := b.store.Moderation.Unblock(ctx, blocker, b.sender); err != nil {
+		t.Fatal(err)
+	}
+	b.push.dispatch(ctx, msg, b.members)
+	sent := b.fake.Sent()
+	if len(sent) != 1 || sent[0].Device.UserID != blocker {
+		t.Fatalf("expected one notification, to %s, got %+v", blocker, sent)
+	}
+	if n := sent[0].Notification; n.Title != "Team" || n.Body != "Sam: hello" || n.Count != 1 || n.CollapseKey != b.convoID.String() {
+		t.Errorf("unexpected notification %+v", n)
+	}
+}
+
+func TestPushService_CollapsesBursts(t *testing.T) {
+	const window = 100 * time.Millisecond
+	b := newTestPush(t, window)
+	b.online.set(b.members[1], true)
+	b.online.set(b.members[2], true)
+
+	for _, body := range []string{"one", "two", "three"} {
+		b.send(t, body)
+	}
+	// Messages are dispatched concurrently, so any of them may come first.
+	sent := b.waitForPushes(t, 2)
+	first, summary := sent[0].Notification, sent[1].Notification
+	if !strings.HasPrefix(first.Body, "Sam: ") || first.Count != 1 {
+		t.Errorf("expected the first message to notify at once, got %+v", first)
+	}
+	if summary.Body != "2 new messages" || summary.Count != 2 || summary.CollapseKey != first.CollapseKey {
+		t.Errorf("expected a summary of the burst, got %+v", summary)
+	}
+
+	// The summary opened another window, which closes with nothing to send.
+	time.Sleep(3 * window)
+	if sent := b.fake.Sent(); len(sent) != 2 {
+		t.Errorf("expected no more notifications, got %+v", sent[2:])
+	}
+
+	// Once the windows have closed, the next message notifies at once.
+	b.send(t, "four")
+	if n := b.waitForPushes(t, 3)[2].Notification; n.Body != "Sam: four" {
+		t.Errorf("expected a notification for the next message, got %+v", n)
+	}
+}
+
+func TestPushService_ForgetsUnregisteredDevices(t *testing.T) {
+	b := newTestPush(t, 0)
+	ctx := context.Background()
+	gone := b.members[0]
+	b.fake.Unregister(gone.String())
+
+	b.send(t, "hello")
+	b.waitForPushes(t, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		devices, err := b.push.ListDevices(ctx, gone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the unregistered device to be forgotten, got %+v", devices)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPushService_DevicesAndMutes(t *testing.T) {
+	b := newTestPush(t, 0)
+	ctx := context.Background()
+	member := b.members[0]
+
+	var verr *model.ValidationError
+	if _, err := b.push.RegisterDevice(ctx, member, "webpush", "t"); !errors.As(err, &verr) || verr.Field != "platform" {
+		t.Errorf("expected a platform validation error, got %v", err)
+	}
+	for _, bad := range []struct{ platform, token string }{
+		{model.PlatformAPNs, " "},
+		{model.PlatformAPNs, "abc"},
+		{model.PlatformAPNs, strings.Repeat("g", 64)},
+		{model.PlatformAPNs, strings.Repeat("a", 63) + "/"},
+		{model.PlatformFCM, "../../admin"},
+		{model.PlatformFCM, "tok en"},
+	} {
+		if _, err := b.push.RegisterDevice(ctx, member, bad.platform, bad.token); !errors.As(err, &verr) || verr.Field != "token" {
+			t.Errorf("%s token %q: expected a token validation error, got %v", bad.platform, bad.token, err)
+		}
+	}
+	apns, err := b.push.RegisterDevice(ctx, member, model.PlatformAPNs, strings.Repeat("AB", 32))
+	if err != nil {
+		t.Fatalf("register apns device: %v", err)
+	}
+	if apns.Token != strings.Repeat("ab", 32) {
+		t.Errorf("expected the APNs token lowercased, got %q", apns.Token)
+	}
+	if err := b.push.DeleteDevice(ctx, member, apns.ID); err != nil {
+		t.Errorf("delete device: %v", err)
+	}
+
+	// A device signed in to by another user moves to them.
+	moved, err := b.push.RegisterDevice(ctx, b.members[1], " FCM ", member.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devices, _ := b.push.ListDevices(ctx, member); len(devices) != 0 {
+		t.Errorf("expected the device to leave %s, got %+v", member, devices)
+	}
+	if err := b.push.DeleteDevice(ctx, member, moved.ID); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting another user's device, got %v", err)
+	}
+	if err := b.push.DeleteDevice(ctx, b.members[1], moved.ID); err != nil {
+		t.Errorf("delete device: %v", err)
+	}
+
+	if _, err := b.push.Mute(ctx, uuid.New(), b.convoID, nil); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound muting as a non-participant, got %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if _, err := b.push.Mute(ctx, member, b.convoID, &past); !errors.As(err, &verr) || verr.Field != "until" {
+		t.Errorf("expected an until validation error, got %v", err)
+	}
+
+	now := time.Now().UTC()
+	b.push.now = func() time.Time { return now }
+	until := now.Add(time.Hour)
+	if _, err := b.push.Mute(ctx, member, b.convoID, &until); err != nil {
+		t.Fatal(err)
+	}
+	if mute, err := b.push.GetMute(ctx, member, b.convoID); err != nil || mute == nil || !mute.MutedUntil.Equal(until) {
+		t.Errorf("expected a mute until %v, got %+v, %v", until, mute, err)
+	}
+	b.push.now = func() time.Time { return until }
+	if mute, err := b.push.GetMute(ctx, member, b.convoID); err != nil || mute != nil {
+		t.Errorf("expected the mute to have lapsed, got %+v, %v", mute, err)
+	}
+	if err := b.push.Unmute(ctx, member, b.convoID); err != nil {
+		t.Errorf("unmute: %v", err)
+	}
+}
+
+func TestAPNsProvider_Send(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type request struct {
+		path, topic, collapseID, auth string
+		payload                       apnsPayload
+	}
+	requests := make(chan request, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{
+			path:       r.URL.Path,
+			topic:      r.Header.Get("apns-topic"),
+			collapseID: r.Header.Get("apns-collapse-id"),
+			auth:       r.Header.Get("Authorization"),
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req.payload)
+		requests <- req
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewAPNsProvider(APNsConfig{
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		KeyID:  "KEY123",
+		TeamID: "TEAM123",
+		Topic:  "com.example.chat",
+		URL:    srv.URL,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := Notification{ConversationID: uuid.New(), MessageID: uuid.New(), Title: "Sam", Body: "hi", Count: 1, CollapseKey: "c1"}
+	if err := p.Send(context.Background(), model.DeviceToken{Token: "abc"}, n); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	req := <-requests
+	if req.path != "/3/device/abc" || req.topic != "com.example.chat" || req.collapseID != "c1" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.payload.APS.Alert.Title != "Sam" || req.payload.APS.Alert.Body != "hi" || req.payload.MessageID != n.MessageID.String() {
+		t.Errorf("unexpected payload %+v", req.payload)
+	}
+	token, err := jwt.Parse(strings.TrimPrefix(req.auth, "bearer "), func(*jwt.Token) (any, error) { return &key.PublicKey, nil },
+		jwt.WithValidMethods([]string{"ES256"}), jwt.WithIssuer("TEAM123"))
+	if err != nil || token.Header["kid"] != "KEY123" {
+		t.Errorf("expected a provider token signed with the key, got %v", err)
+	}
+
+	if err := p.Send(context.Background(), model.DeviceToken{Token: "gone"}, n); !errors.Is(err, ErrDeviceUnregistered) {
+		t.Errorf("expected ErrDeviceUnregistered, got %v", err)
+	}
+	if again := <-requests; again.auth != req.auth {
+		t.Error("expected the provider token to be reused")
+	}
+}
+
+func TestFCMProvider_Send(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var tokenRequests int
+	var got fcmRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokenRequests++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access-1","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("POST /v1/projects/chat-app/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req fcmRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Message.Token == "gone" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+		mu.Lock()
+		got = req
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"name":"projects/chat-app/messages/1"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	creds, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "chat-app",
+		"client_email": "push@chat-app.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL + "/token",
+	})
+	p, err := NewFCMProvider(FCMConfig{Credentials: creds, URL: srv.URL}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := Notification{ConversationID: uuid.New(), MessageID: uuid.New(), Title: "Sam", Body: "hi", Count: 3, CollapseKey: "c1"}
+	if err := p.Send(context.Background(), model.DeviceToken{Token: "abc"}, n); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err := p.Send(context.Background(), model.DeviceToken{Token: "gone"}, n); !errors.Is(err, ErrDeviceUnregistered) {
+		t.Errorf("expected ErrDeviceUnregistered, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got.Message.Token != "abc" || got.Message.Notification.Body != "hi" || got.Message.Data["count"] != "3" ||
+		got.Message.Android.CollapseKey != "c1" || got.Message.APNs.Headers["apns-collapse-id"] != "c1" {
+		t.Errorf("unexpected message %+v", got.Message)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected the access token to be reused, got %d token requests", tokenRequests)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// RateLimit is a token bucket: it holds Burst tokens, refilled at Requests per
+// Per, and each request spends one. The zero value imposes no limit.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int // defaults to Requests
+}
+
+// ParseRateLimit parses a limit written as "N/period", such as "60/m",
+// "1000/h" or "5/30s". The period is s, m, h or a Go duration. An empty string,
+// "0" or "off" disables the limit.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" || s == "off" {
+		return RateLimit{}, nil
+	}
+	n, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q: expected N/period", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: invalid request count", s)
+	}
+	switch period {
+	case "s", "m", "h":
+		period = "1" + period
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+	return RateLimit{Requests: requests, Per: per}, nil
+}
+
+// String formats the limit the way ParseRateLimit reads it, such as "60/m",
+// or "off" for the zero value. A custom Burst is not shown.
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	period := l.Per.String()
+	switch l.Per {
+	case time.Second:
+		period = "s"
+	case time.Minute:
+		period = "m"
+	case time.Hour:
+		period = "h"
+	}
+	return strconv.Itoa(l.Requests) + "/" + period
+}
+
+// MarshalText implements encoding.TextMarshaler, so that limits appear in
+// configuration files as written.
+func (l RateLimit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseRateLimit.
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	parsed, err := ParseRateLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// RateLimitDecision is the outcome of one RateLimiter.Allow call, carrying what
+// the RateLimit-* and Retry-After response headers report.
+type RateLimitDecision struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // tokens left after this request
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request would be admitted; zero if Allowed
+}
+
+// RateLimiter applies RateLimits to caller-chosen keys. Buckets are held by a
+// RateLimitRepository, so replicas sharing a database share their limits.
+type RateLimiter struct {
+	buckets repo.RateLimitRepository
+	now     func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter.
+func NewRateLimiter(buckets repo.RateLimitRepository) *RateLimiter {
+	return &RateLimiter{buckets: buckets, now: time.Now}
+}
+
+// Allow spends a token from key's bucket under limit. A disabled limit always
+// allows. Requests that are rejected spend nothing.
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (RateLimitDecision, error) {
+	if !limit.Enabled() {
+		return RateLimitDecision{Allowed: true}, nil
+	}
+	ctx, span := tracer.Start(ctx, "RateLimiter.Allow")
+	defer span.End()
+
+	now := l.now().UTC()
+	interval := limit.Per / time.Duration(limit.Requests)
+	burst := limit.burst()
+	tat, ok, err := l.buckets.Take(ctx, key, now, interval, burst)
+	if err != nil {
+		return RateLimitDecision{}, err
+	}
+
+	d := RateLimitDecision{Allowed: ok, Limit: burst, Reset: max(tat.Sub(now), 0)}
+	if ok {
+		// Every whole interval of headroom left before capacity is a token.
+		d.Remaining = int((time.Duration(burst)*interval - tat.Sub(now)) / interval)
+	} else {
+		d.RetryAfter = max(tat.Add(interval).Sub(now.Add(time.Duration(burst)*interval)), 0)
+	}
+	return d, nil
+}
+
+// Prune forgets buckets that have refilled completely, which is lossless.
+func (l *RateLimiter) Prune(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "RateLimiter.Prune")
+	defer span.End()
+	return l.buckets.Prune(ctx, l.now().UTC())
+}
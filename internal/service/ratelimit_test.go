@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// ---------------------------------------------------------------------------
+// Tests: ParseRateLimit
+// ---------------------------------------------------------------------------
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RateLimit
+	}{
+		{"60/m", RateLimit{Requests: 60, Per: time.Minute}},
+		{"1000/h", RateLimit{Requests: 1000, Per: time.Hour}},
+		{"5/30s", RateLimit{Requests: 5, Per: 30 * time.Second}},
+		{" 10/s ", RateLimit{Requests: 10, Per: time.Second}},
+		{"", RateLimit{}},
+		{"off", RateLimit{}},
+		{"0", RateLimit{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRateLimit(tt.in)
+		if err != nil {
+			t.Errorf("ParseRateLimit(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if again, err := ParseRateLimit(got.String()); err != nil || again != got {
+			t.Errorf("ParseRateLimit(%q) does not round-trip through %q", tt.in, got.String())
+		}
+	}
+
+	for _, in := range []string{"60", "x/m", "-1/m", "10/fortnight", "10/0s"} {
+		if _, err := ParseRateLimit(in); err == nil {
+			t.Errorf("ParseRateLimit(%q): expected an error", in)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests: RateLimiter
+// ---------------------------------------------------------------------------
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := NewRateLimiter(repo.NewMemoryRateLimitRepo())
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := RateLimit{Requests: 3, Per: 3 * time.Second}
+
+	for i := 1; i <= 3; i++ {
+		d, err := limiter.Allow(ctx, "k", limit)
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		if !d.Allowed || d.Limit != 3 || d.Remaining != 3-i {
+			t.Fatalf("request %d: unexpected decision %+v", i, d)
+		}
+		if d.Reset != time.Duration(i)*time.Second {
+			t.Errorf("request %d: expected reset %ds, got %v", i, i, d.Reset)
+		}
+	}
+
+	d, err := limiter.Allow(ctx, "k", limit)
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if d.Allowed || d.Remaining != 0 || d.RetryAfter != time.Second {
+		t.Fatalf("expected rejection with a 1s retry, got %+v", d)
+	}
+
+	now = now.Add(time.Second)
+	if d, _ := limiter.Allow(ctx, "k", limit); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("expected one refilled token, got %+v", d)
+	}
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	limiter := NewRateLimiter(repo.NewMemoryRateLimitRepo())
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	limit := RateLimit{Requests: 60, Per: time.Minute, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if d, _ := limiter.Allow(context.Background(), "k", limit); !d.Allowed {
+			t.Fatalf("request %d: expected to be admitted", i+1)
+		}
+	}
+	d, _ := limiter.Allow(context.Background(), "k", limit)
+	if d.Allowed || d.Limit != 2 {
+		t.Errorf("expected the burst of 2 to be exhausted, got %+v", d)
+	}
+}
+
+func TestRateLimiter_DisabledLimitAllows(t *testing.T) {
+	limiter := NewRateLimiter(repo.NewMemoryRateLimitRepo())
+	for i := 0; i < 100; i++ {
+		if d, err := limiter.Allow(context.Background(), "k", RateLimit{}); err != nil || !d.Allowed {
+			t.Fatalf("expected a disabled limit to allow, got %+v, %v", d, err)
+		}
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 
 	"github.com/kareempaes/planning/internal/repo"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/kareempaes/planning/internal/service")
+
 // RegistryType identifies a service configuration variant.
 type RegistryType int
 
@@ -20,18 +23,72 @@ type Registry struct {
 	Conversations *ConversationService
 	Messages      *MessageService
 	Moderation    *ModerationService
+	OIDC          *OIDCService
+	Tokens        *TokenService
+	Accounts      *AccountService
+	RateLimits    *RateLimiter
+	Idempotency   *IdempotencyKeys
+	Webhooks      *WebhookService
+	Commands      *CommandService
+	Push          *PushService
+	Digests       *DigestService
+
+	IncomingWebhooks *IncomingWebhookService
+}
+
+// Metrics receives the business events worth counting. It is satisfied by
+// *infra.Metrics.
+type Metrics interface {
+	MessageSent()
+	UserRegistered(method string)
+	LoginSucceeded(method string)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) MessageSent()          {}
+func (noopMetrics) UserRegistered(string) {}
+func (noopMetrics) LoginSucceeded(string) {}
+
+// RegistryConfig holds the settings of the services a Registry creates.
+type RegistryConfig struct {
+	Auth     AuthConfig
+	Accounts AccountConfig
+	Outbound OutboundConfig
 }
 
 // NewRegistry creates a Registry based on the given configuration type.
-func NewRegistry(regType RegistryType, store *repo.Store, authCfg AuthConfig) (*Registry, error) {
+// metrics may be nil to count nothing.
+func NewRegistry(regType RegistryType, store *repo.Store, cfg RegistryConfig, metrics Metrics) (*Registry, error) {
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+
 	switch regType {
 	case DefaultRegistry:
+		auth := NewAuthService(store.Users, store.Sessions, store.LoginAttempts, store, cfg.Auth)
+		auth.metrics = metrics
+		messages := NewMessageService(store.Messages, store.Conversations, store)
+		messages.metrics = metrics
+		messages.push = NewPushService(store.Push, store.Conversations, store.Users, store.Moderation)
+		messages.commands = NewCommandService(store.Integrations, store.Conversations, store.Users, store.Reminders, store.Polls, store, messages, NewOutboundClient(cfg.Outbound))
 		return &Registry{
 			Users:         NewUserService(store.Users),
-			Auth:          NewAuthService(store.Users, store.Sessions, authCfg),
-			Conversations: NewConversationService(store.Conversations),
-			Messages:      NewMessageService(store.Messages, store.Conversations),
+			Auth:          auth,
+			Conversations: NewConversationService(store.Conversations, store),
+			Messages:      messages,
 			Moderation:    NewModerationService(store.Moderation),
+			OIDC:          NewOIDCService(auth, store.Users, store.Identities, cfg.Auth.OIDCProviders),
+			Tokens:        NewTokenService(store.Users, store.Tokens),
+			Accounts:      NewAccountService(store.Users, store.Accounts, store, cfg.Accounts),
+			RateLimits:    NewRateLimiter(store.RateLimits),
+			Idempotency:   NewIdempotencyKeys(store.Idempotency),
+			Webhooks:      NewWebhookService(store.Webhooks, store.Conversations, NewOutboundClient(cfg.Outbound)),
+			Commands:      messages.commands,
+			Push:          messages.push,
+			Digests:       NewDigestService(store.Digests),
+
+			IncomingWebhooks: NewIncomingWebhookService(store.Integrations, store.Conversations, store, messages),
 		}, nil
 	default:
 		return nil, fmt.Errorf("unknown registry type: %d", regType)
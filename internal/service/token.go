@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// TokenPrefix marks personal access tokens so they can be told apart from JWTs.
+const TokenPrefix = "pat_"
+
+// lastUsedGranularity limits how often a token's last-used timestamp is written.
+const lastUsedGranularity = time.Minute
+
+// CreateTokenParams holds the inputs for issuing a personal access token.
+type CreateTokenParams struct {
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration // zero means the token never expires
+	BotID     *uuid.UUID    // issue the token for one of the caller's bots instead of the caller
+}
+
+// TokenService manages bot accounts and personal access tokens.
+type TokenService struct {
+	users  repo.UserRepository
+	tokens repo.TokenRepository
+}
+
+// NewTokenService creates a new TokenService.
+func NewTokenService(users repo.UserRepository, tokens repo.TokenRepository) *TokenService {
+	return &TokenService{users: users, tokens: tokens}
+}
+
+// CreateBot creates a bot account owned by the given user. Bots have no
+// password and can only authenticate with personal access tokens.
+func (s *TokenService) CreateBot(ctx context.Context, ownerID uuid.UUID, displayName string) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.CreateBot")
+	defer span.End()
+
+	displayName, err := validateBotName("display_name", displayName)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.users.GetByID(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.Type == model.UserTypeBot {
+		return nil, model.ErrForbidden
+	}
+
+	bot := newBot(owner.ID, displayName)
+	if err := s.users.Create(ctx, bot); err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+// validateBotName trims a bot's display name and checks its length, reporting
+// problems against field.
+func validateBotName(field, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", &model.ValidationError{Field: field, Message: "must not be empty"}
+	}
+	if len(name) > 100 {
+		return "", &model.ValidationError{Field: field, Message: "must be 100 characters or fewer"}
+	}
+	return name, nil
+}
+
+// botEmailDomain is the domain of every bot account's address. People cannot
+// sign up with it, so an address there always belongs to a bot.
+const botEmailDomain = "bots.invalid"
+
+// isBotEmail reports whether email is in the domain reserved for bots.
+func isBotEmail(email string) bool {
+	_, domain, _ := strings.Cut(email, "@")
+	return domain == botEmailDomain || strings.HasSuffix(domain, "."+botEmailDomain)
+}
+
+// newBot returns a bot account owned by ownerID, ready to be stored.
+func newBot(ownerID uuid.UUID, displayName string) *model.User {
+	now := time.Now().UTC()
+	id := uuid.New()
+	return &model.User{
+		ID:          id,
+		Email:       "bot-" + id.String() + "@" + botEmailDomain,
+		DisplayName: displayName,
+		Status:      "offline",
+		Type:        model.UserTypeBot,
+		OwnerID:     &ownerID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// ListBots returns the bots owned by the given user.
+func (s *TokenService) ListBots(ctx context.Context, ownerID uuid.UUID) ([]model.User, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.ListBots")
+	defer span.End()
+
+	return s.users.ListByOwner(ctx, ownerID)
+}
+
+// CreateToken issues a personal access token for the caller or one of their bots.
+// The raw token is returned once; only its hash is stored.
+func (s *TokenService) CreateToken(ctx context.Context, callerID uuid.UUID, params CreateTokenParams) (*model.PersonalAccessToken, string, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.CreateToken")
+	defer span.End()
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, "", &model.ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	if len(name) > 100 {
+		return nil, "", &model.ValidationError{Field: "name", Message: "must be 100 characters or fewer"}
+	}
+	scopes, err := normalizeScopes(params.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if params.ExpiresIn < 0 {
+		return nil, "", &model.ValidationError{Field: "expires_in_days", Message: "must not be negative"}
+	}
+
+	subject := callerID
+	if params.BotID != nil {
+		bot, err := s.users.GetByID(ctx, *params.BotID)
+		if err != nil {
+			return nil, "", err
+		}
+		// Hide other users' bots behind not-found, like non-participant conversations.
+		if bot.Type != model.UserTypeBot || bot.OwnerID == nil || *bot.OwnerID != callerID {
+			return nil, "", model.ErrNotFound
+		}
+		subject = bot.ID
+	}
+
+	rawBytes := make([]byte, 32)
+	if _, err := rand.Read(rawBytes); err != nil {
+		return nil, "", fmt.Errorf("token: generate token: %w", err)
+	}
+	raw := TokenPrefix + hex.EncodeToString(rawBytes)
+
+	now := time.Now().UTC()
+	token := &model.PersonalAccessToken{
+		ID:        uuid.New(),
+		UserID:    subject,
+		CreatedBy: callerID,
+		Name:      name,
+		TokenHash: hashToken(raw),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if params.ExpiresIn > 0 {
+		exp := now.Add(params.ExpiresIn)
+		token.ExpiresAt = &exp
+	}
+
+	if err := s.tokens.Create(ctx, token); err != nil {
+		return nil, "", err
+	}
+	return token, raw, nil
+}
+
+// ListTokens returns the active tokens the caller created, including those for their bots.
+func (s *TokenService) ListTokens(ctx context.Context, callerID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.ListTokens")
+	defer span.End()
+
+	return s.tokens.ListByCreator(ctx, callerID)
+}
+
+// RevokeToken revokes a token the caller created.
+func (s *TokenService) RevokeToken(ctx context.Context, callerID, tokenID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "TokenService.RevokeToken")
+	defer span.End()
+
+	return s.tokens.Revoke(ctx, tokenID, callerID)
+}
+
+// Authenticate resolves a raw personal access token. Unknown, revoked and
+// expired tokens all yield ErrUnauthenticated.
+func (s *TokenService) Authenticate(ctx context.Context, raw string) (*model.PersonalAccessToken, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(raw, TokenPrefix) {
+		return nil, model.ErrUnauthenticated
+	}
+
+	token, err := s.tokens.GetByHash(ctx, hashToken(raw))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, model.ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return nil, model.ErrUnauthenticated
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedGranularity {
+		// Best effort: a failed bookkeeping write should not fail the request.
+		_ = s.tokens.TouchLastUsed(ctx, token.ID, now)
+	}
+	return token, nil
+}
+
+// normalizeScopes validates requested scopes and removes duplicates.
+func normalizeScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, &model.ValidationError{Field: "scopes", Message: "at least one scope is required"}
+	}
+	scopes := make([]string, 0, len(requested))
+	for _, sc := range requested {
+		sc = strings.TrimSpace(sc)
+		if !slices.Contains(model.AllScopes, sc) {
+			return nil, &model.ValidationError{Field: "scopes", Message: fmt.Sprintf("unknown scope %q", sc)}
+		}
+		if !slices.Contains(scopes, sc) {
+			scopes = append(scopes, sc)
+		}
+	}
+	return scopes, nil
+}
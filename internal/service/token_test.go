@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+)
+
+// ---------------------------------------------------------------------------
+// Mock: TokenRepository
+// ---------------------------------------------------------------------------
+
+type mockTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*model.PersonalAccessToken
+}
+
+func newMockTokenRepo() *mockTokenRepo {
+	return &mockTokenRepo{tokens: make(map[uuid.UUID]*model.PersonalAccessToken)}
+}
+
+func (m *mockTokenRepo) Create(_ context.Context, token *model.PersonalAccessToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[token.ID] = token
+	return nil
+}
+
+func (m *mockTokenRepo) GetByHash(_ context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash && t.RevokedAt == nil {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (m *mockTokenRepo) ListByCreator(_ context.Context, createdBy uuid.UUID) ([]model.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var tokens []model.PersonalAccessToken
+	for _, t := range m.tokens {
+		if t.CreatedBy == createdBy && t.RevokedAt == nil {
+			tokens = append(tokens, *t)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *mockTokenRepo) Revoke(_ context.Context, id uuid.UUID, createdBy uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[id]
+	if !ok || t.CreatedBy != createdBy || t.RevokedAt != nil {
+		return model.ErrNotFound
+	}
+	now := time.Now().UTC()
+	t.RevokedAt = &now
+	return nil
+}
+
+func (m *mockTokenRepo) TouchLastUsed(_ context.Context, id uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tokens[id]; ok {
+		t.LastUsedAt = &at
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+func newTokenTestService(t *testing.T) (*TokenService, *mockTokenRepo, uuid.UUID) {
+	t.Helper()
+	users := newMockUserRepo()
+	owner := &model.User{ID: uuid.New(), Email: "owner@example.com", DisplayName: "Owner", Type: model.UserTypeHuman}
+	if err := users.Create(context.Background(), owner); err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	tokens := newMockTokenRepo()
+	return NewTokenService(users, tokens), tokens, owner.ID
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Bots
+// ---------------------------------------------------------------------------
+
+func TestCreateBot_Success(t *testing.T) {
+	svc, _, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	bot, err := svc.CreateBot(ctx, ownerID, "  Deploy Bot  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bot.Type != model.UserTypeBot {
+		t.Errorf("expected type bot, got %q", bot.Type)
+	}
+	if bot.OwnerID == nil || *bot.OwnerID != ownerID {
+		t.Errorf("expected owner %s, got %v", ownerID, bot.OwnerID)
+	}
+	if bot.DisplayName != "Deploy Bot" {
+		t.Errorf("expected trimmed display name, got %q", bot.DisplayName)
+	}
+	if bot.PasswordHash != "" {
+		t.Error("expected bot to have no password")
+	}
+
+	bots, err := svc.ListBots(ctx, ownerID)
+	if err != nil {
+		t.Fatalf("list bots: %v", err)
+	}
+	if len(bots) != 1 || bots[0].ID != bot.ID {
+		t.Errorf("expected the new bot to be listed, got %+v", bots)
+	}
+}
+
+func TestCreateBot_BotsCannotOwnBots(t *testing.T) {
+	svc, _, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	bot, _ := svc.CreateBot(ctx, ownerID, "Bot")
+	_, err := svc.CreateBot(ctx, bot.ID, "Sub Bot")
+	if !errors.Is(err, model.ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Tokens
+// ---------------------------------------------------------------------------
+
+func TestCreateToken_StoresHashAndAuthenticates(t *testing.T) {
+	svc, repo, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	token, raw, err := svc.CreateToken(ctx, ownerID, CreateTokenParams{
+		Name:   "ci",
+		Scopes: []string{model.ScopeMessagesWrite, model.ScopeMessagesWrite, model.ScopeConversationsRead},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(raw, TokenPrefix) {
+		t.Errorf("expected raw token to start with %q, got %q", TokenPrefix, raw)
+	}
+	if repo.tokens[token.ID].TokenHash == raw || repo.tokens[token.ID].TokenHash != hashToken(raw) {
+		t.Error("expected only the token hash to be stored")
+	}
+	if len(token.Scopes) != 2 {
+		t.Errorf("expected duplicate scopes to be collapsed, got %v", token.Scopes)
+	}
+	if token.ExpiresAt != nil {
+		t.Error("expected a non-expiring token")
+	}
+
+	got, err := svc.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if got.UserID != ownerID {
+		t.Errorf("expected user %s, got %s", ownerID, got.UserID)
+	}
+	if repo.tokens[token.ID].LastUsedAt == nil {
+		t.Error("expected last_used_at to be recorded")
+	}
+}
+
+func TestCreateToken_Validation(t *testing.T) {
+	svc, _, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	cases := []CreateTokenParams{
+		{Name: "", Scopes: []string{model.ScopeMessagesRead}},
+		{Name: "x", Scopes: nil},
+		{Name: "x", Scopes: []string{"admin:everything"}},
+		{Name: "x", Scopes: []string{model.ScopeMessagesRead}, ExpiresIn: -time.Hour},
+	}
+	for _, params := range cases {
+		if _, _, err := svc.CreateToken(ctx, ownerID, params); !errors.Is(err, model.ErrValidation) {
+			t.Errorf("params %+v: expected validation error, got %v", params, err)
+		}
+	}
+}
+
+func TestCreateToken_ForOwnBot(t *testing.T) {
+	svc, _, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	bot, _ := svc.CreateBot(ctx, ownerID, "Bot")
+	token, raw, err := svc.CreateToken(ctx, ownerID, CreateTokenParams{
+		Name: "bot token", Scopes: []string{model.ScopeMessagesWrite}, BotID: &bot.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.UserID != bot.ID || token.CreatedBy != ownerID {
+		t.Errorf("expected token for bot created by owner, got user=%s created_by=%s", token.UserID, token.CreatedBy)
+	}
+
+	got, err := svc.Authenticate(ctx, raw)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if got.UserID != bot.ID {
+		t.Errorf("expected token to act as the bot, got %s", got.UserID)
+	}
+}
+
+func TestCreateToken_ForSomeoneElsesBot(t *testing.T) {
+	svc, _, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	bot, _ := svc.CreateBot(ctx, ownerID, "Bot")
+	_, _, err := svc.CreateToken(ctx, uuid.New(), CreateTokenParams{
+		Name: "stolen", Scopes: []string{model.ScopeMessagesWrite}, BotID: &bot.ID,
+	})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAuthenticate_RejectsRevokedExpiredAndUnknown(t *testing.T) {
+	svc, repo, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+	params := CreateTokenParams{Name: "t", Scopes: []string{model.ScopeMessagesRead}}
+
+	revoked, rawRevoked, _ := svc.CreateToken(ctx, ownerID, params)
+	if err := svc.RevokeToken(ctx, ownerID, revoked.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	expired, rawExpired, _ := svc.CreateToken(ctx, ownerID, params)
+	past := time.Now().Add(-time.Minute)
+	repo.tokens[expired.ID].ExpiresAt = &past
+
+	for name, raw := range map[string]string{
+		"revoked": rawRevoked,
+		"expired": rawExpired,
+		"unknown": TokenPrefix + "deadbeef",
+		"jwt":     "eyJhbGciOiJIUzI1NiJ9.e30.x",
+	} {
+		if _, err := svc.Authenticate(ctx, raw); !errors.Is(err, model.ErrUnauthenticated) {
+			t.Errorf("%s: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+}
+
+func TestRevokeToken_OnlyByCreator(t *testing.T) {
+	svc, _, ownerID := newTokenTestService(t)
+	ctx := context.Background()
+
+	token, _, _ := svc.CreateToken(ctx, ownerID, CreateTokenParams{Name: "t", Scopes: []string{model.ScopeMessagesRead}})
+	if err := svc.RevokeToken(ctx, uuid.New(), token.ID); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another user, got %v", err)
+	}
+
+	tokens, _ := svc.ListTokens(ctx, ownerID)
+	if len(tokens) != 1 {
+		t.Errorf("expected token to remain active, got %d tokens", len(tokens))
+	}
+}
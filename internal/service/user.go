@@ -21,11 +21,17 @@ func NewUserService(r repo.UserRepository) *UserService {
 
 // GetProfile returns the full profile of the authenticated user.
 func (s *UserService) GetProfile(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetProfile")
+	defer span.End()
+
 	return s.repo.GetByID(ctx, userID)
 }
 
 // UpdateProfile validates and applies profile updates for the authenticated user.
 func (s *UserService) UpdateProfile(ctx context.Context, userID uuid.UUID, params model.UpdateProfileParams) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateProfile")
+	defer span.End()
+
 	if params.DisplayName != nil {
 		name := strings.TrimSpace(*params.DisplayName)
 		if name == "" {
@@ -54,6 +60,9 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID uuid.UUID, param
 
 // GetPublicProfile returns the public-facing profile of any user.
 func (s *UserService) GetPublicProfile(ctx context.Context, userID uuid.UUID) (*model.PublicProfile, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetPublicProfile")
+	defer span.End()
+
 	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -63,11 +72,15 @@ func (s *UserService) GetPublicProfile(ctx context.Context, userID uuid.UUID) (*
 		DisplayName: user.DisplayName,
 		AvatarURL:   user.AvatarURL,
 		Status:      user.Status,
+		Type:        user.Type,
 	}, nil
 }
 
 // SearchUsers searches for users by display name with cursor-based pagination.
 func (s *UserService) SearchUsers(ctx context.Context, query string, cursor string, limit int) (*model.Page[model.UserSearchResult], error) {
+	ctx, span := tracer.Start(ctx, "UserService.SearchUsers")
+	defer span.End()
+
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return nil, &model.ValidationError{Field: "q", Message: "search query must not be empty"}
@@ -0,0 +1,508 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kareempaes/planning/internal/model"
+	"github.com/kareempaes/planning/internal/repo"
+)
+
+// Headers sent with every webhook delivery.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256>
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// WebhookSecretPrefix marks webhook signing secrets.
+const WebhookSecretPrefix = "whsec_"
+
+// WebhookBatchSize is the most deliveries one DeliverDue call attempts.
+const WebhookBatchSize = 20
+
+const (
+	maxWebhooksPerConversation = 10
+	maxWebhookURLLen           = 2048
+
+	// webhookTimeout bounds one delivery attempt, including reading the response.
+	webhookTimeout = 10 * time.Second
+	// webhookLease is how long a claimed delivery is hidden from other
+	// dispatchers; it must outlast an attempt.
+	webhookLease = time.Minute
+
+	// With these defaults a delivery is retried for about two hours before
+	// it is dead-lettered.
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookRetryBase   = time.Minute
+	webhookMaxRetryDelay      = time.Hour
+)
+
+// WebhookEvent is the JSON body of every webhook delivery.
+type WebhookEvent struct {
+	ID             uuid.UUID       `json:"id"`
+	Type           string          `json:"type"`
+	ConversationID uuid.UUID       `json:"conversation_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// CreateWebhookParams holds the inputs for registering a webhook.
+type CreateWebhookParams struct {
+	URL    string
+	Events []string // empty subscribes to every event
+}
+
+// WebhookService manages conversation webhooks and delivers their events.
+type WebhookService struct {
+	webhooks repo.WebhookRepository
+	convos   repo.ConversationRepository
+	client   *http.Client
+	now      func() time.Time
+
+	maxAttempts int
+	retryBase   time.Duration
+}
+
+// ErrPrivateAddress is returned, wrapped, when a request to a URL a user
+// supplied would connect to an address that is not publicly routable.
+var ErrPrivateAddress = errors.New("destination address is not public")
+
+// OutboundConfig governs the requests the server makes to URLs its users
+// supply, such as webhook and slash-command endpoints.
+type OutboundConfig struct {
+	// AllowPrivateNetworks permits loopback, link-local and private
+	// addresses, for development or endpoints on an internal network.
+	AllowPrivateNetworks bool
+}
+
+// NewOutboundClient returns a client for requests to URLs users supply. It
+// does not follow redirects and, unless cfg allows private networks, refuses
+// to connect to loopback, link-local, private and unspecified addresses. The
+// check runs on the resolved address of every connection, so a host name
+// that later resolves to an internal address does not get around it.
+func NewOutboundClient(cfg OutboundConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = refusePrivateAddress
+	}
+	return &http.Client{
+		// No proxy: it would make the connection on our behalf, past the check.
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// nonPublicPrefixes are the ranges netip's predicates miss that must not be
+// reached either.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, home to some cloud metadata services
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which maps back onto IPv4, private addresses included
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// refusePrivateAddress is a net.Dialer Control function that fails
+// connections to addresses which are not publicly routable.
+func refusePrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+		}
+	}
+	return nil
+}
+
+// NewWebhookService creates a new WebhookService. Deliveries are sent with
+// client, or if it is nil with NewOutboundClient's default client.
+func NewWebhookService(webhooks repo.WebhookRepository, convos repo.ConversationRepository, client *http.Client) *WebhookService {
+	if client == nil {
+		client = NewOutboundClient(OutboundConfig{})
+	}
+	return &WebhookService{
+		webhooks:    webhooks,
+		convos:      convos,
+		client:      client,
+		now:         time.Now,
+		maxAttempts: defaultWebhookMaxAttempts,
+		retryBase:   defaultWebhookRetryBase,
+	}
+}
+
+// Create registers a webhook for a conversation the caller owns. The
+// returned webhook carries its signing secret, which is not shown again.
+func (s *WebhookService) Create(ctx context.Context, userID, conversationID uuid.UUID, params CreateWebhookParams) (*model.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Create")
+	defer span.End()
+
+	if err := validateWebhookURL(params.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(params.Events)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webhooks.ListByConversation(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerConversation {
+		return nil, &model.ValidationError{
+			Field:   "url",
+			Message: fmt.Sprintf("a conversation can have at most %d webhooks", maxWebhooksPerConversation),
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("webhook: generate secret: %w", err)
+	}
+	hook := &model.Webhook{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		CreatedBy:      userID,
+		URL:            params.URL,
+		Secret:         WebhookSecretPrefix + hex.EncodeToString(secret),
+		Events:         events,
+		CreatedAt:      s.now().UTC(),
+	}
+	if err := s.webhooks.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// List returns the webhooks of a conversation the caller owns.
+func (s *WebhookService) List(ctx context.Context, userID, conversationID uuid.UUID) ([]model.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.List")
+	defer span.End()
+
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.ListByConversation(ctx, conversationID)
+}
+
+// Delete removes a webhook and its delivery log.
+func (s *WebhookService) Delete(ctx context.Context, userID, conversationID, webhookID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.Delete")
+	defer span.End()
+
+	if _, err := s.get(ctx, userID, conversationID, webhookID); err != nil {
+		return err
+	}
+	return s.webhooks.Delete(ctx, webhookID)
+}
+
+// ListDeliveries returns a page of a webhook's deliveries, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, conversationID, webhookID uuid.UUID, cursor string, limit int) (*model.Page[model.WebhookDelivery], error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	if _, err := s.get(ctx, userID, conversationID, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return s.webhooks.ListDeliveries(ctx, webhookID, cursor, limit)
+}
+
+// Redeliver queues a delivery to be sent again straight away, with a fresh
+// set of retries. Dead-lettered deliveries are revived this way.
+func (s *WebhookService) Redeliver(ctx context.Context, userID, conversationID, webhookID, deliveryID uuid.UUID) (*model.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	if _, err := s.get(ctx, userID, conversationID, webhookID); err != nil {
+		return nil, err
+	}
+	d, err := s.webhooks.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d.WebhookID != webhookID {
+		return nil, model.ErrNotFound
+	}
+
+	d.Status = model.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = s.now().UTC()
+	if err := s.webhooks.UpdateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Publish queues an event for every webhook of the conversation that
+// subscribes to its type. data becomes the "data" field of the body.
+func (s *WebhookService) Publish(ctx context.Context, conversationID uuid.UUID, eventType string, data any) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.Publish")
+	defer span.End()
+
+	hooks, err := s.webhooks.ListByConversation(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	hooks = slices.DeleteFunc(hooks, func(h model.Webhook) bool { return !h.Subscribes(eventType) })
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("webhook: encode %s event: %w", eventType, err)
+	}
+	now := s.now().UTC()
+	payload, err := json.Marshal(WebhookEvent{
+		ID:             uuid.New(),
+		Type:           eventType,
+		ConversationID: conversationID,
+		CreatedAt:      now,
+		Data:           raw,
+	})
+	if err != nil {
+		return fmt.Errorf("webhook: encode %s event: %w", eventType, err)
+	}
+
+	for _, hook := range hooks {
+		err := s.webhooks.CreateDelivery(ctx, &model.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     hook.ID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverDue sends a batch of the deliveries that are due and records the
+// outcome of each, returning how many were attempted. A failed attempt is
+// retried with exponential backoff until the attempts run out, after which
+// the delivery is dead-lettered.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeliverDue")
+	defer span.End()
+
+	now := s.now().UTC()
+	due, err := s.webhooks.ClaimDue(ctx, now, now.Add(webhookLease), WebhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	hooks := make(map[uuid.UUID]*model.Webhook)
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex // guards errs and n, which the deliveries update
+		errs       []error
+		n          int
+		lookupErrs []error // only touched by this loop
+	)
+	for _, d := range due {
+		hook, ok := hooks[d.WebhookID]
+		if !ok {
+			hook, err = s.webhooks.GetByID(ctx, d.WebhookID)
+			if errors.Is(err, model.ErrNotFound) {
+				continue // deleted since the delivery was claimed
+			}
+			if err != nil {
+				lookupErrs = append(lookupErrs, err)
+				continue
+			}
+			hooks[d.WebhookID] = hook
+		}
+
+		wg.Go(func() {
+			s.attempt(ctx, hook, &d)
+			err := s.webhooks.UpdateDelivery(ctx, &d)
+			mu.Lock()
+			defer mu.Unlock()
+			n++
+			if err != nil {
+				errs = append(errs, err)
+			}
+		})
+	}
+	wg.Wait()
+	return n, errors.Join(append(lookupErrs, errs...)...)
+}
+
+// attempt POSTs a delivery to its webhook and updates d with the outcome.
+func (s *WebhookService) attempt(ctx context.Context, hook *model.Webhook, d *model.WebhookDelivery) {
+	ctx, span := tracer.Start(ctx, "WebhookService.attempt")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	now := s.now().UTC()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatus = nil
+
+	err := s.post(ctx, hook, d, now)
+	if err == nil {
+		d.Status = model.WebhookDeliverySucceeded
+		d.LastError = nil
+		return
+	}
+	msg := err.Error()
+	d.LastError = &msg
+	if d.Attempts >= s.maxAttempts {
+		d.Status = model.WebhookDeliveryDead
+		return
+	}
+	d.NextAttemptAt = now.Add(s.retryDelay(d.Attempts))
+}
+
+func (s *WebhookService) post(ctx context.Context, hook *model.Webhook, d *model.WebhookDelivery, now time.Time) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chat-webhooks/1")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, d.ID.String())
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(hook.Secret, now, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	// The body is drained, up to a point, so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	status := resp.StatusCode
+	d.ResponseStatus = &status
+	if status < 200 || status > 299 {
+		return fmt.Errorf("endpoint responded %d %s", status, http.StatusText(status))
+	}
+	return nil
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.retryBase
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= webhookMaxRetryDelay {
+			return webhookMaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// WebhookSignature returns the signature header for a delivery body sent at
+// the given time: the HMAC-SHA256, keyed with the webhook's secret, of the
+// Unix timestamp, a dot and the body. Including the timestamp lets receivers
+// reject replayed deliveries.
+func WebhookSignature(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// get returns a webhook of a conversation the caller owns.
+func (s *WebhookService) get(ctx context.Context, userID, conversationID, webhookID uuid.UUID) (*model.Webhook, error) {
+	if err := requireConversationOwner(ctx, s.convos, userID, conversationID); err != nil {
+		return nil, err
+	}
+	hook, err := s.webhooks.GetByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if hook.ConversationID != conversationID {
+		return nil, model.ErrNotFound
+	}
+	return hook, nil
+}
+
+func validateWebhookURL(raw string) error {
+	if raw == "" {
+		return &model.ValidationError{Field: "url", Message: "must not be empty"}
+	}
+	if len(raw) > maxWebhookURLLen {
+		return &model.ValidationError{Field: "url", Message: fmt.Sprintf("must be %d characters or fewer", maxWebhookURLLen)}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &model.ValidationError{Field: "url", Message: "must be an absolute http or https URL"}
+	}
+	if u.User != nil {
+		return &model.ValidationError{Field: "url", Message: "must not contain credentials"}
+	}
+	return nil
+}
+
+// normalizeWebhookEvents validates requested event types and removes
+// duplicates, defaulting to every event.
+func normalizeWebhookEvents(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return slices.Clone(model.AllWebhookEvents), nil
+	}
+	events := make([]string, 0, len(requested))
+	for _, e := range requested {
+		if !slices.Contains(model.AllWebhookEvents, e) {
+			return nil, &model.ValidationError{Field: "events", Message: "unknown event " + strconv.Quote(e)}
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Register creates an account and signs the client in as it.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
+	var resp AuthResponse
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/auth/register", body: req}, &resp); err != nil {
+		return nil, err
+	}
+	c.setTokens(resp.Tokens)
+	return &resp, nil
+}
+
+// Login signs the client in with an email and password.
+func (c *Client) Login(ctx context.Context, email, password string) (*AuthResponse, error) {
+	var resp AuthResponse
+	body := LoginRequest{Email: email, Password: password}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/auth/login", body: body}, &resp); err != nil {
+		return nil, err
+	}
+	c.setTokens(resp.Tokens)
+	return &resp, nil
+}
+
+// Refresh rotates the session's tokens now rather than when they expire.
+func (c *Client) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refreshToken == "" {
+		return ErrNotAuthenticated
+	}
+	return c.refreshLocked(ctx)
+}
+
+// Logout ends the session and forgets its tokens.
+func (c *Client) Logout(ctx context.Context) error {
+	// Refresh first if due, so that the refresh token sent is the current one.
+	if _, err := c.token(ctx); err != nil {
+		return err
+	}
+	tokens := c.Tokens()
+	if tokens.RefreshToken == "" {
+		return ErrNotAuthenticated
+	}
+	body := LogoutRequest{RefreshToken: tokens.RefreshToken}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/auth/logout", body: body, auth: true}, nil); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.accessToken, c.refreshToken, c.expiresAt = "", "", time.Time{}
+	c.mu.Unlock()
+	return nil
+}
+
+// ChangePassword replaces the password. The server ends every other session
+// and the client switches to the new token pair it issues.
+func (c *Client) ChangePassword(ctx context.Context, current, next string) error {
+	var tokens Tokens
+	body := ChangePasswordRequest{CurrentPassword: current, NewPassword: next}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/users/me/password", body: body, auth: true}, &tokens); err != nil {
+		return err
+	}
+	c.setTokens(tokens)
+	return nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CreateIncomingWebhook adds an incoming webhook to a conversation the caller
+// owns. Its token is in the response only; the server cannot show it again.
+func (c *Client) CreateIncomingWebhook(ctx context.Context, conversationID uuid.UUID, name string) (*CreatedIncomingWebhook, error) {
+	var hook CreatedIncomingWebhook
+	req := request{method: http.MethodPost, path: incomingWebhooksPath(conversationID), body: CreateIncomingWebhookRequest{Name: name}, auth: true}
+	if _, err := c.do(ctx, req, &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// IncomingWebhooks lists a conversation's incoming webhooks, without their tokens.
+func (c *Client) IncomingWebhooks(ctx context.Context, conversationID uuid.UUID) ([]IncomingWebhook, error) {
+	var list IncomingWebhookList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: incomingWebhooksPath(conversationID), auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return list.IncomingWebhooks, nil
+}
+
+// DeleteIncomingWebhook deletes an incoming webhook; its token stops working.
+func (c *Client) DeleteIncomingWebhook(ctx context.Context, conversationID, hookID uuid.UUID) error {
+	path := incomingWebhooksPath(conversationID) + "/" + hookID.String()
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: path, auth: true}, nil)
+	return err
+}
+
+// PostToHook posts a message through an incoming webhook. It authenticates
+// with the webhook's token alone, so the client needs no session.
+func (c *Client) PostToHook(ctx context.Context, hookID uuid.UUID, token, body string) (*Message, error) {
+	var msg Message
+	req := request{
+		method: http.MethodPost,
+		path:   "/hooks/" + hookID.String(),
+		header: http.Header{"Authorization": {"Bearer " + token}},
+		body:   PostHookMessageRequest{Body: body},
+	}
+	if _, err := c.do(ctx, req, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// CreateCommand registers an HTTP slash command on a conversation the caller
+// owns. The secret its calls are signed with is in the response only.
+func (c *Client) CreateCommand(ctx context.Context, conversationID uuid.UUID, req CreateCommandRequest) (*CreatedCommand, error) {
+	var cmd CreatedCommand
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: commandsPath(conversationID), body: req, auth: true}, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// Commands lists a conversation's HTTP slash commands, without their secrets.
+func (c *Client) Commands(ctx context.Context, conversationID uuid.UUID) ([]Command, error) {
+	var list CommandList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: commandsPath(conversationID), auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return list.Commands, nil
+}
+
+// DeleteCommand deletes an HTTP slash command.
+func (c *Client) DeleteCommand(ctx context.Context, conversationID, commandID uuid.UUID) error {
+	path := commandsPath(conversationID) + "/" + commandID.String()
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: path, auth: true}, nil)
+	return err
+}
+
+func incomingWebhooksPath(conversationID uuid.UUID) string {
+	return "/conversations/" + conversationID.String() + "/incoming-webhooks"
+}
+
+func commandsPath(conversationID uuid.UUID) string {
+	return "/conversations/" + conversationID.String() + "/commands"
+}
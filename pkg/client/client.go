@@ -0,0 +1,313 @@
+// Package client is a Go client for the chat server's REST API and WebSocket
+// event stream.
+//
+// A Client authenticates either with a personal access token or with a
+// session: after Login or Register it keeps the token pair, refreshes the
+// access token shortly before it expires or when the server rejects it, and
+// stores each rotated refresh token. Request and response bodies are the
+// server's own DTO types, re-exported here under shorter names.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before expiry an access token is refreshed.
+const refreshMargin = 30 * time.Second
+
+// Config configures a Client. Zero fields take defaults.
+type Config struct {
+	HTTPClient *http.Client // used for REST calls; nil uses http.DefaultClient
+
+	// Token authenticates requests: a personal access token (pat_…) or a JWT
+	// access token. Leave it empty to authenticate with Login or Register.
+	Token string
+	// RefreshToken, with Token, resumes a saved session.
+	RefreshToken string
+
+	// OnTokens, when set, is called with every token pair the client obtains,
+	// so callers can persist the rotated refresh token. It must not call the
+	// Client.
+	OnTokens func(Tokens)
+}
+
+// Client calls the API of one server. It is safe for concurrent use.
+type Client struct {
+	baseURL  *url.URL
+	http     *http.Client
+	onTokens func(Tokens)
+	now      func() time.Time
+
+	mu           sync.Mutex // guards the fields below and serializes refreshes
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time // zero if unknown
+}
+
+// New creates a Client for the server at baseURL, such as
+// "https://chat.example.com". The /api/v1 prefix is added by the client.
+func New(baseURL string, cfg Config) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: parse base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL must be http or https, got %q", baseURL)
+	}
+	c := &Client{
+		baseURL:      u,
+		http:         cfg.HTTPClient,
+		onTokens:     cfg.OnTokens,
+		now:          time.Now,
+		accessToken:  cfg.Token,
+		refreshToken: cfg.RefreshToken,
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	return c, nil
+}
+
+// Tokens returns the client's current token pair. ExpiresIn is the number
+// of seconds the access token has left, or 0 if unknown.
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := Tokens{AccessToken: c.accessToken, RefreshToken: c.refreshToken}
+	if !c.expiresAt.IsZero() {
+		t.ExpiresIn = max(0, int(c.expiresAt.Sub(c.now()).Seconds()))
+	}
+	return t
+}
+
+// APIError is an error response from the server.
+type APIError struct {
+	StatusCode int
+	Code       string        // machine-readable code, such as "not_found"
+	Message    string        // human-readable message
+	RetryAfter time.Duration // from the Retry-After header of 429 and 409 responses
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsStatus reports whether err is an *APIError with the given status code.
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// request describes one REST call.
+type request struct {
+	method string
+	path   string // below /api/v1
+	query  url.Values
+	header http.Header
+	body   any // encoded as JSON when non-nil
+	auth   bool
+}
+
+// do sends req and decodes a JSON response into out, which may be nil. An
+// authenticated request that fails with 401 is retried once after
+// refreshing the session.
+func (c *Client) do(ctx context.Context, req request, out any) (int, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return 0, fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+
+	resp, err := c.send(ctx, req, body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && req.auth {
+		sent := strings.TrimPrefix(resp.Request.Header.Get("Authorization"), "Bearer ")
+		retry, err := c.refreshAfterReject(ctx, sent)
+		if err != nil {
+			resp.Body.Close()
+			return 0, err
+		}
+		if retry {
+			resp.Body.Close()
+			if resp, err = c.send(ctx, req, body); err != nil {
+				return 0, err
+			}
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, decodeError(resp)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("client: decode %s %s response: %w", req.method, req.path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte) (*http.Response, error) {
+	u := c.endpoint(req.path)
+	u.RawQuery = req.query.Encode()
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), r)
+	if err != nil {
+		return nil, fmt.Errorf("client: build request: %w", err)
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if req.auth {
+		token, err := c.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", req.method, req.path, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) endpoint(path string) *url.URL {
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1" + path
+	return &u
+}
+
+// pageQuery encodes p as the cursor and limit query parameters.
+func pageQuery(p Page) url.Values {
+	q := url.Values{}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err == nil && body.Error.Code != "" {
+		apiErr.Code = body.Error.Code
+		apiErr.Message = body.Error.Message
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return apiErr
+}
+
+// ErrNotAuthenticated is returned by calls that need a token when the client
+// has none.
+var ErrNotAuthenticated = errors.New("client: not authenticated")
+
+// token returns an access token to send, refreshing it first if it is about
+// to expire.
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken == "" {
+		return "", ErrNotAuthenticated
+	}
+	if c.refreshToken != "" && !c.expiresAt.IsZero() && c.now().Add(refreshMargin).After(c.expiresAt) {
+		if err := c.refreshLocked(ctx); err != nil {
+			return "", err
+		}
+	}
+	return c.accessToken, nil
+}
+
+// refreshAfterReject refreshes the session after the server rejected the
+// access token sent, and reports whether the request is worth retrying. It
+// is if another call has already replaced that token, and not if there is
+// no refresh token to use.
+func (c *Client) refreshAfterReject(ctx context.Context, sent string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken != sent {
+		return c.accessToken != "", nil
+	}
+	if c.refreshToken == "" {
+		return false, nil
+	}
+	return true, c.refreshLocked(ctx)
+}
+
+// refreshLocked exchanges the refresh token for a new pair. The server
+// revokes a refresh token when it is used, so refreshes must not overlap;
+// c.mu is held throughout.
+func (c *Client) refreshLocked(ctx context.Context) error {
+	body, _ := json.Marshal(RefreshRequest{RefreshToken: c.refreshToken})
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: "/auth/refresh"}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := decodeError(resp)
+		if resp.StatusCode == http.StatusUnauthorized {
+			// The session is gone; stop offering a token that cannot work.
+			c.accessToken, c.refreshToken, c.expiresAt = "", "", time.Time{}
+		}
+		return fmt.Errorf("client: refresh session: %w", err)
+	}
+	var tokens Tokens
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return fmt.Errorf("client: decode refresh response: %w", err)
+	}
+	c.setTokensLocked(tokens)
+	return nil
+}
+
+func (c *Client) setTokens(t Tokens) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setTokensLocked(t)
+}
+
+func (c *Client) setTokensLocked(t Tokens) {
+	c.accessToken = t.AccessToken
+	c.refreshToken = t.RefreshToken
+	c.expiresAt = time.Time{}
+	if t.ExpiresIn > 0 {
+		c.expiresAt = c.now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	if c.onTokens != nil {
+		c.onTokens(t)
+	}
+}
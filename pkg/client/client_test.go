@@ -0,0 +1,424 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kareempaes/planning/internal/handler"
+	"github.com/kareempaes/planning/internal/infra"
+	"github.com/kareempaes/planning/internal/repo"
+	"github.com/kareempaes/planning/internal/service"
+)
+
+// testServer runs the real router over an in-memory store.
+type testServer struct {
+	*httptest.Server
+	registry *service.Registry
+	logger   *slog.Logger
+	hub      atomic.Pointer[infra.Hub]
+	router   atomic.Pointer[http.Handler]
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	store, err := repo.NewStore(repo.MemoryStore, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := service.NewRegistry(service.DefaultRegistry, store, service.RegistryConfig{
+		Auth: service.AuthConfig{
+			JWTSecret:          "test-secret",
+			AccessTokenExpiry:  15 * time.Minute,
+			RefreshTokenExpiry: time.Hour,
+			BcryptCost:         4,
+		},
+		// The webhook receivers of these tests listen on loopback.
+		Outbound: service.OutboundConfig{AllowPrivateNetworks: true},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{registry: registry, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.restart()
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*s.router.Load()).ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		s.hub.Load().Shutdown(context.Background())
+		s.Close()
+	})
+	return s
+}
+
+// restart replaces the WebSocket hub, as a server restart would: clients of
+// the old hub are told to reconnect.
+func (s *testServer) restart() {
+	hub := infra.NewHub(s.logger, infra.HubConfig{})
+	go hub.Run()
+	events := handler.NewConversationEvents(s.registry.Conversations, s.registry.Webhooks, hub, s.logger)
+	s.registry.Messages.SetListener(events)
+	var router http.Handler = handler.NewRouter(s.registry, hub, handler.RouterConfig{JWTSecret: "test-secret", Logger: s.logger, Events: events})
+	s.router.Store(&router)
+	if old := s.hub.Swap(hub); old != nil {
+		old.Shutdown(context.Background())
+	}
+}
+
+func newTestClient(t *testing.T, s *testServer, cfg Config) *Client {
+	t.Helper()
+	c, err := New(s.URL, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func registerTestClient(t *testing.T, s *testServer, email, name string) (*Client, *User) {
+	t.Helper()
+	c := newTestClient(t, s, Config{})
+	resp, err := c.Register(context.Background(), RegisterRequest{Email: email, Password: "correct-horse-7", DisplayName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, &resp.User
+}
+
+func TestClient_ConversationFlow(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+	bob, bobUser := registerTestClient(t, s, "bob@example.com", "Bob")
+
+	found, err := alice.SearchUsers(ctx, "Bo", Page{Limit: 5})
+	if err != nil || len(found.Users) != 1 || found.Users[0].ID != bobUser.ID {
+		t.Fatalf("expected to find bob, got %+v, %v", found, err)
+	}
+
+	convo, created, err := alice.CreateConversation(ctx, CreateConversationRequest{Type: "direct", ParticipantIDs: []string{bobUser.ID.String()}})
+	if err != nil || !created {
+		t.Fatalf("expected a new conversation, got %v, %v", created, err)
+	}
+	if _, created, err := alice.CreateConversation(ctx, CreateConversationRequest{Type: "direct", ParticipantIDs: []string{bobUser.ID.String()}}); err != nil || created {
+		t.Errorf("expected the existing direct conversation, got created=%v, %v", created, err)
+	}
+
+	msg, created, err := alice.SendMessage(ctx, convo.ID, SendMessageRequest{Body: "hi bob", ClientMessageID: "m1"})
+	if err != nil || !created {
+		t.Fatalf("expected a new message, got %v, %v", created, err)
+	}
+	retry, created, err := alice.SendMessage(ctx, convo.ID, SendMessageRequest{Body: "hi bob", ClientMessageID: "m1"})
+	if err != nil || created || retry.ID != msg.ID {
+		t.Errorf("expected the retry to return the original message, got %v, %v", created, err)
+	}
+
+	history, err := bob.Messages(ctx, convo.ID, Page{})
+	if err != nil || len(history.Messages) != 1 || history.Messages[0].Body != "hi bob" {
+		t.Fatalf("expected bob to see the message, got %+v, %v", history, err)
+	}
+	if _, err := bob.Report(ctx, ReportRequest{TargetType: "message", TargetID: msg.ID.String(), Reason: "spam"}); err != nil {
+		t.Errorf("report: %v", err)
+	}
+
+	_, err = alice.Conversation(ctx, [16]byte{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Code == "" {
+		t.Errorf("expected a not found APIError, got %v", err)
+	}
+}
+
+func TestClient_RefreshesExpiredAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	var saved []Tokens
+	c := newTestClient(t, s, Config{OnTokens: func(t Tokens) { saved = append(saved, t) }})
+	if _, err := c.Register(ctx, RegisterRequest{Email: "alice@example.com", Password: "correct-horse-7", DisplayName: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	first := c.Tokens()
+
+	// Shortly before expiry the client refreshes before sending.
+	c.now = func() time.Time { return time.Now().Add(15 * time.Minute) }
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.now = time.Now
+	second := c.Tokens()
+	if second.RefreshToken == first.RefreshToken || len(saved) != 2 {
+		t.Fatalf("expected a rotated refresh token to be reported, got %d token pairs", len(saved))
+	}
+
+	// A rejected access token is refreshed and the call retried.
+	c.mu.Lock()
+	c.accessToken = "not-a-jwt"
+	c.mu.Unlock()
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatalf("expected the call to succeed after a refresh, got %v", err)
+	}
+	if c.Tokens().RefreshToken == second.RefreshToken {
+		t.Error("expected the refresh token to rotate again")
+	}
+
+	// Once the session is gone, calls fail rather than loop.
+	if err := c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Me(ctx); !errors.Is(err, ErrNotAuthenticated) {
+		t.Errorf("expected ErrNotAuthenticated after logout, got %v", err)
+	}
+}
+
+func TestClient_PersonalAccessToken(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+
+	pat, err := alice.CreateToken(ctx, CreateTokenRequest{Name: "ci", Scopes: []string{"users:read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, s, Config{Token: pat.Token})
+	if me, err := c.Me(ctx); err != nil || me.Email != "alice@example.com" {
+		t.Fatalf("expected the token to authenticate as alice, got %v", err)
+	}
+	if _, err := c.Conversations(ctx, Page{}); !IsStatus(err, http.StatusForbidden) {
+		t.Errorf("expected a missing scope to be refused, got %v", err)
+	}
+
+	if err := alice.RevokeToken(ctx, pat.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Me(ctx); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("expected a revoked token to be rejected, got %v", err)
+	}
+}
+
+func TestClient_Events(t *testing.T) {
+	s := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+	bob, bobUser := registerTestClient(t, s, "bob@example.com", "Bob")
+
+	convo, _, err := alice.CreateConversation(ctx, CreateConversationRequest{Type: "direct", ParticipantIDs: []string{bobUser.ID.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := bob.Events(ctx)
+	next := func(want string) Event {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed waiting for %s", want)
+			}
+			if e.Type != want {
+				t.Fatalf("expected a %s event, got %s (%v)", want, e.Type, e.Err)
+			}
+			return e
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s", want)
+		}
+		return Event{}
+	}
+
+	next(EventConnected)
+	if _, _, err := alice.SendMessage(ctx, convo.ID, SendMessageRequest{Body: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(EventMessage); e.Message == nil || e.Message.Body != "one" {
+		t.Errorf("expected the message, got %+v", e.Message)
+	}
+
+	// A server restart closes the stream; the client reconnects by itself.
+	s.restart()
+	next(EventDisconnected)
+	next(EventConnected)
+	if _, _, err := alice.SendMessage(ctx, convo.ID, SendMessageRequest{Body: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(EventMessage); e.Message == nil || e.Message.Body != "two" {
+		t.Errorf("expected the message after reconnecting, got %+v", e.Message)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestClient_Webhooks(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+	_, bobUser := registerTestClient(t, s, "bob@example.com", "Bob")
+
+	type received struct {
+		signature string
+		body      []byte
+	}
+	deliveries := make(chan received, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- received{signature: r.Header.Get("X-Webhook-Signature"), body: body}
+	}))
+	t.Cleanup(receiver.Close)
+
+	name := "team"
+	convo, _, err := alice.CreateConversation(ctx, CreateConversationRequest{Type: "group", Name: &name, ParticipantIDs: []string{bobUser.ID.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := alice.CreateWebhook(ctx, convo.ID, CreateWebhookRequest{URL: receiver.URL, Events: []string{WebhookConversationRenamed}})
+	if err != nil || hook.Secret == "" {
+		t.Fatalf("expected a webhook with its secret, got %+v, %v", hook, err)
+	}
+	if hooks, err := alice.Webhooks(ctx, convo.ID); err != nil || len(hooks) != 1 || hooks[0].ID != hook.ID {
+		t.Fatalf("expected the webhook to be listed, got %+v, %v", hooks, err)
+	}
+
+	if _, err := alice.RenameConversation(ctx, convo.ID, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.registry.Webhooks.DeliverDue(ctx); err != nil || n != 1 {
+		t.Fatalf("expected one delivery, got %d, %v", n, err)
+	}
+	got := <-deliveries
+	if err := VerifyWebhookSignature(hook.Secret, got.signature, got.body, time.Minute); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+	if err := VerifyWebhookSignature(hook.Secret, got.signature, append(got.body, ' '), time.Minute); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("expected a tampered body to be rejected, got %v", err)
+	}
+
+	log, err := alice.WebhookDeliveries(ctx, convo.ID, hook.ID, Page{})
+	if err != nil || len(log.Deliveries) != 1 || log.Deliveries[0].Status != "succeeded" {
+		t.Fatalf("expected a succeeded delivery, got %+v, %v", log, err)
+	}
+	d, err := alice.RedeliverWebhook(ctx, convo.ID, hook.ID, log.Deliveries[0].ID)
+	if err != nil || d.Status != "pending" {
+		t.Errorf("expected the delivery to be queued again, got %+v, %v", d, err)
+	}
+
+	if err := alice.DeleteWebhook(ctx, convo.ID, hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if hooks, err := alice.Webhooks(ctx, convo.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected no webhooks after delete, got %+v, %v", hooks, err)
+	}
+}
+
+func TestClient_IncomingWebhooks(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+	bob, bobUser := registerTestClient(t, s, "bob@example.com", "Bob")
+
+	name := "team"
+	convo, _, err := alice.CreateConversation(ctx, CreateConversationRequest{Type: "group", Name: &name, ParticipantIDs: []string{bobUser.ID.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := alice.CreateIncomingWebhook(ctx, convo.ID, "CI")
+	if err != nil || hook.Token == "" {
+		t.Fatalf("expected an incoming webhook with its token, got %+v, %v", hook, err)
+	}
+	if _, err := bob.CreateIncomingWebhook(ctx, convo.ID, "CI"); !IsStatus(err, http.StatusForbidden) {
+		t.Errorf("expected a member to be forbidden, got %v", err)
+	}
+
+	// The webhook needs no session, only its token.
+	anonymous := newTestClient(t, s, Config{})
+	msg, err := anonymous.PostToHook(ctx, hook.ID, hook.Token, "build passed")
+	if err != nil || msg.SenderID != hook.BotID {
+		t.Fatalf("expected the webhook's bot to post, got %+v, %v", msg, err)
+	}
+	if _, err := anonymous.PostToHook(ctx, hook.ID, "hook_wrong", "hi"); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("expected a wrong token to be rejected, got %v", err)
+	}
+	history, err := bob.Messages(ctx, convo.ID, Page{})
+	if err != nil || len(history.Messages) != 1 || history.Messages[0].Body != "build passed" {
+		t.Fatalf("expected bob to see the message, got %+v, %v", history, err)
+	}
+
+	cmd, err := alice.CreateCommand(ctx, convo.ID, CreateCommandRequest{Name: "/deploy", URL: "https://example.com/deploy"})
+	if err != nil || cmd.Name != "deploy" || cmd.Secret == "" {
+		t.Fatalf("expected a command with its secret, got %+v, %v", cmd, err)
+	}
+	if cmds, err := alice.Commands(ctx, convo.ID); err != nil || len(cmds) != 1 {
+		t.Errorf("expected the command to be listed, got %+v, %v", cmds, err)
+	}
+	if err := alice.DeleteCommand(ctx, convo.ID, cmd.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := alice.DeleteIncomingWebhook(ctx, convo.ID, hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if hooks, err := alice.IncomingWebhooks(ctx, convo.ID); err != nil || len(hooks) != 0 {
+		t.Errorf("expected no incoming webhooks after delete, got %+v, %v", hooks, err)
+	}
+}
+
+func TestClient_DevicesAndMutes(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+	_, bobUser := registerTestClient(t, s, "bob@example.com", "Bob")
+
+	device, err := alice.RegisterDevice(ctx, "fcm", "device-token")
+	if err != nil || device.Platform != "fcm" {
+		t.Fatalf("expected an FCM device, got %+v, %v", device, err)
+	}
+	if _, err := alice.RegisterDevice(ctx, "sms", "device-token"); !IsStatus(err, http.StatusUnprocessableEntity) {
+		t.Errorf("expected an unknown platform to be rejected, got %v", err)
+	}
+	if devices, err := alice.Devices(ctx); err != nil || len(devices) != 1 || devices[0].ID != device.ID {
+		t.Errorf("expected the device to be listed, got %+v, %v", devices, err)
+	}
+	if err := alice.DeleteDevice(ctx, device.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	convo, _, err := alice.CreateConversation(ctx, CreateConversationRequest{Type: "direct", ParticipantIDs: []string{bobUser.ID.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if status, err := alice.Mute(ctx, convo.ID, &until); err != nil || !status.Muted || !status.MutedUntil.Equal(until) {
+		t.Fatalf("expected a mute until %v, got %+v, %v", until, status, err)
+	}
+	if err := alice.Unmute(ctx, convo.ID); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := alice.MuteStatus(ctx, convo.ID); err != nil || status.Muted {
+		t.Errorf("expected the conversation to be unmuted, got %+v, %v", status, err)
+	}
+}
+
+func TestClient_DigestSettings(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := registerTestClient(t, s, "alice@example.com", "Alice")
+
+	if settings, err := alice.DigestSettings(ctx); err != nil || !settings.Enabled || settings.Frequency != "daily" {
+		t.Fatalf("expected daily digests by default, got %+v, %v", settings, err)
+	}
+	off, hourly := false, "hourly"
+	settings, err := alice.UpdateDigestSettings(ctx, DigestSettingsRequest{Enabled: &off, Frequency: &hourly})
+	if err != nil || settings.Enabled || settings.Frequency != "hourly" {
+		t.Fatalf("expected hourly digests turned off, got %+v, %v", settings, err)
+	}
+	monthly := "monthly"
+	if _, err := alice.UpdateDigestSettings(ctx, DigestSettingsRequest{Frequency: &monthly}); !IsStatus(err, http.StatusUnprocessableEntity) {
+		t.Errorf("expected an unknown frequency to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CreateConversation starts a conversation. Creating a direct conversation
+// that already exists returns the existing one, with created false.
+func (c *Client) CreateConversation(ctx context.Context, req CreateConversationRequest) (convo *Conversation, created bool, err error) {
+	convo = new(Conversation)
+	status, err := c.do(ctx, request{method: http.MethodPost, path: "/conversations", body: req, auth: true}, convo)
+	if err != nil {
+		return nil, false, err
+	}
+	return convo, status == http.StatusCreated, nil
+}
+
+// Conversations lists the caller's conversations, most recently active first.
+func (c *Client) Conversations(ctx context.Context, page Page) (*ConversationList, error) {
+	var list ConversationList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/conversations", query: pageQuery(page), auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Conversation returns a conversation the caller takes part in.
+func (c *Client) Conversation(ctx context.Context, id uuid.UUID) (*Conversation, error) {
+	var convo Conversation
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/conversations/" + id.String(), auth: true}, &convo); err != nil {
+		return nil, err
+	}
+	return &convo, nil
+}
+
+// RenameConversation renames a group conversation.
+func (c *Client) RenameConversation(ctx context.Context, id uuid.UUID, name string) (*Conversation, error) {
+	var convo Conversation
+	body := UpdateConversationRequest{Name: name}
+	if _, err := c.do(ctx, request{method: http.MethodPatch, path: "/conversations/" + id.String(), body: body, auth: true}, &convo); err != nil {
+		return nil, err
+	}
+	return &convo, nil
+}
+
+// AddParticipants adds users to a group conversation and returns the
+// participants added.
+func (c *Client) AddParticipants(ctx context.Context, id uuid.UUID, userIDs ...uuid.UUID) ([]Participant, error) {
+	body := AddParticipantsRequest{UserIDs: uuidStrings(userIDs)}
+	var added []Participant
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/conversations/" + id.String() + "/participants", body: body, auth: true}, &added); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveParticipant removes a user from a group conversation. Removing
+// oneself leaves it.
+func (c *Client) RemoveParticipant(ctx context.Context, id, userID uuid.UUID) error {
+	path := "/conversations/" + id.String() + "/participants/" + userID.String()
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: path, auth: true}, nil)
+	return err
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	return s
+}
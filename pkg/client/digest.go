@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// DigestSettings returns the caller's email digest settings.
+func (c *Client) DigestSettings(ctx context.Context) (*DigestSettings, error) {
+	var settings DigestSettings
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/users/me/digest", auth: true}, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateDigestSettings turns email digests on or off and changes how often
+// they are sent. Nil fields of update keep their current value.
+func (c *Client) UpdateDigestSettings(ctx context.Context, update DigestSettingsRequest) (*DigestSettings, error) {
+	var settings DigestSettings
+	req := request{method: http.MethodPut, path: "/users/me/digest", body: update, auth: true}
+	if _, err := c.do(ctx, req, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event types. Connected and Disconnected are generated by the client; the
+// rest are frames from the server.
+const (
+	EventConnected    = "connected"    // the stream (re)connected; events missed while down are not replayed
+	EventDisconnected = "disconnected" // the stream dropped; Err says why, and the client reconnects
+	EventMessage      = "message"      // a new message in one of the caller's conversations
+	EventError        = "error"        // the server dropped a frame; Err is a *StreamError
+)
+
+// Event is one item of an event stream.
+type Event struct {
+	Type    string
+	Data    json.RawMessage // the frame's payload, for any type
+	Message *Message        // set for EventMessage
+	Err     error           // set for EventDisconnected and EventError
+}
+
+// StreamError is an error frame from the server, such as "rate_limited".
+type StreamError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("client: stream error %s (retry after %s)", e.Code, e.RetryAfter)
+}
+
+// Reconnect backoff bounds.
+const (
+	minReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Events opens the WebSocket event stream and returns a channel of its
+// events. The client reconnects with backoff whenever the connection drops,
+// reporting each drop and reconnect as an event. The channel is closed when
+// ctx is done, or if the server rejects the client's credentials.
+//
+// The caller must keep receiving: while the channel is full the client stops
+// reading, and the server drops frames it cannot deliver.
+func (c *Client) Events(ctx context.Context) <-chan Event {
+	events := make(chan Event, 64)
+	go c.runEvents(ctx, events)
+	return events
+}
+
+func (c *Client) runEvents(ctx context.Context, events chan<- Event) {
+	defer close(events)
+	emit := func(e Event) bool {
+		select {
+		case events <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	delay := minReconnectDelay
+	for {
+		conn, err := c.dial(ctx)
+		if err == nil {
+			delay = minReconnectDelay
+			if !emit(Event{Type: EventConnected}) {
+				conn.Close()
+				return
+			}
+			err = readEvents(ctx, conn, emit)
+			conn.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if IsStatus(err, http.StatusUnauthorized) || errors.Is(err, ErrNotAuthenticated) {
+			emit(Event{Type: EventDisconnected, Err: err})
+			return
+		}
+		if !emit(Event{Type: EventDisconnected, Err: err}) {
+			return
+		}
+
+		wait := delay
+		if websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+			wait = minReconnectDelay // the server asked for an immediate reconnect
+		}
+		delay = min(delay*2, maxReconnectDelay)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dial opens a WebSocket, refreshing the session once if the server
+// rejects its access token.
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	u := c.endpoint("/ws")
+	u.Scheme = map[string]string{"http": "ws", "https": "wss"}[u.Scheme]
+
+	for attempt := 0; ; attempt++ {
+		token, err := c.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		header := http.Header{"Authorization": {"Bearer " + token}}
+		conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+		if err == nil {
+			return conn, nil
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("client: dial event stream: %w", err)
+		}
+		apiErr := decodeError(resp)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return nil, apiErr
+		}
+		if retry, err := c.refreshAfterReject(ctx, token); err != nil {
+			return nil, err
+		} else if !retry {
+			return nil, apiErr
+		}
+	}
+}
+
+// readEvents forwards frames from conn until it fails or ctx is done.
+func readEvents(ctx context.Context, conn *websocket.Conn, emit func(Event) bool) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		var frame struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				continue
+			}
+			return err
+		}
+
+		e := Event{Type: frame.Type, Data: frame.Data}
+		switch frame.Type {
+		case EventMessage:
+			e.Message = new(Message)
+			if err := json.Unmarshal(frame.Data, e.Message); err != nil {
+				continue
+			}
+		case EventError:
+			var body struct {
+				Code       string `json:"code"`
+				RetryAfter int    `json:"retry_after"`
+			}
+			json.Unmarshal(frame.Data, &body)
+			e.Err = &StreamError{Code: body.Code, RetryAfter: time.Duration(body.RetryAfter) * time.Second}
+		}
+		if !emit(e) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// SendMessage posts a message to a conversation. Setting
+// req.ClientMessageID makes the call safe to retry: a repeat returns the
+// original message, with created false.
+func (c *Client) SendMessage(ctx context.Context, conversationID uuid.UUID, req SendMessageRequest) (msg *Message, created bool, err error) {
+	msg = new(Message)
+	path := "/conversations/" + conversationID.String() + "/messages"
+	status, err := c.do(ctx, request{method: http.MethodPost, path: path, body: req, auth: true}, msg)
+	if err != nil {
+		return nil, false, err
+	}
+	return msg, status == http.StatusCreated, nil
+}
+
+// Messages returns a conversation's messages, newest first.
+func (c *Client) Messages(ctx context.Context, conversationID uuid.UUID, page Page) (*MessageList, error) {
+	var list MessageList
+	path := "/conversations/" + conversationID.String() + "/messages"
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: path, query: pageQuery(page), auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Message returns one message of a conversation.
+func (c *Client) Message(ctx context.Context, conversationID, messageID uuid.UUID) (*Message, error) {
+	var msg Message
+	path := "/conversations/" + conversationID.String() + "/messages/" + messageID.String()
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: path, auth: true}, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
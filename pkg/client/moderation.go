@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Block stops a user from messaging the caller.
+func (c *Client) Block(ctx context.Context, userID uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/users/" + userID.String() + "/block", auth: true}, nil)
+	return err
+}
+
+// Unblock lifts a block.
+func (c *Client) Unblock(ctx context.Context, userID uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/users/" + userID.String() + "/block", auth: true}, nil)
+	return err
+}
+
+// Blocked lists the users the caller has blocked.
+func (c *Client) Blocked(ctx context.Context) ([]BlockedUser, error) {
+	var resp BlockedList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/users/me/blocked", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Blocked, nil
+}
+
+// Report flags a user, message or conversation for moderators.
+func (c *Client) Report(ctx context.Context, req ReportRequest) (*Report, error) {
+	var report Report
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/reports", body: req, auth: true}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
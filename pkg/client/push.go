@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegisterDevice registers a device token to receive push notifications for
+// the caller. platform is "apns" or "fcm". Registering a token again, even
+// from another account, updates the existing device.
+func (c *Client) RegisterDevice(ctx context.Context, platform, token string) (*Device, error) {
+	var device Device
+	req := request{method: http.MethodPost, path: "/users/me/devices", body: RegisterDeviceRequest{Platform: platform, Token: token}, auth: true}
+	if _, err := c.do(ctx, req, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// Devices lists the caller's registered devices, without their tokens.
+func (c *Client) Devices(ctx context.Context) ([]Device, error) {
+	var list DeviceList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/users/me/devices", auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return list.Devices, nil
+}
+
+// DeleteDevice unregisters one of the caller's devices.
+func (c *Client) DeleteDevice(ctx context.Context, deviceID uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/users/me/devices/" + deviceID.String(), auth: true}, nil)
+	return err
+}
+
+// Mute stops push notifications from a conversation until the given time, or
+// until Unmute when until is nil.
+func (c *Client) Mute(ctx context.Context, conversationID uuid.UUID, until *time.Time) (*MuteStatus, error) {
+	var status MuteStatus
+	req := request{method: http.MethodPut, path: mutePath(conversationID), body: MuteRequest{Until: until}, auth: true}
+	if _, err := c.do(ctx, req, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// MuteStatus reports whether push notifications from a conversation are muted.
+func (c *Client) MuteStatus(ctx context.Context, conversationID uuid.UUID) (*MuteStatus, error) {
+	var status MuteStatus
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: mutePath(conversationID), auth: true}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Unmute resumes push notifications from a conversation.
+func (c *Client) Unmute(ctx context.Context, conversationID uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: mutePath(conversationID), auth: true}, nil)
+	return err
+}
+
+func mutePath(conversationID uuid.UUID) string {
+	return "/conversations/" + conversationID.String() + "/mute"
+}
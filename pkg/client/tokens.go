@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CreateBot creates a bot account owned by the caller. Like the other bot and
+// token calls it needs a session; personal access tokens are refused.
+func (c *Client) CreateBot(ctx context.Context, displayName string) (*Bot, error) {
+	var bot Bot
+	body := CreateBotRequest{DisplayName: displayName}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/bots", body: body, auth: true}, &bot); err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+// Bots lists the caller's bots.
+func (c *Client) Bots(ctx context.Context) ([]Bot, error) {
+	var list BotList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/bots", auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return list.Bots, nil
+}
+
+// CreateToken issues a personal access token. Its secret is in the response
+// only; the server cannot show it again.
+func (c *Client) CreateToken(ctx context.Context, req CreateTokenRequest) (*CreatedToken, error) {
+	var token CreatedToken
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/tokens", body: req, auth: true}, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// PersonalAccessTokens lists the caller's tokens, without their secrets.
+func (c *Client) PersonalAccessTokens(ctx context.Context) ([]PersonalAccessToken, error) {
+	var list TokenList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/tokens", auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return list.Tokens, nil
+}
+
+// RevokeToken revokes a personal access token.
+func (c *Client) RevokeToken(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/tokens/" + id.String(), auth: true}, nil)
+	return err
+}
@@ -0,0 +1,82 @@
+package client
+
+import "github.com/kareempaes/planning/internal/dto"
+
+// Request and response bodies are the server's DTOs. They are aliased here so
+// that code outside this module can name them.
+type (
+	RegisterRequest       = dto.RegisterRequest
+	LoginRequest          = dto.LoginRequest
+	RefreshRequest        = dto.RefreshRequest
+	LogoutRequest         = dto.LogoutRequest
+	ChangePasswordRequest = dto.ChangePasswordRequest
+	AuthResponse          = dto.AuthResponse
+	Tokens                = dto.TokenResponse
+
+	User                 = dto.UserResponse
+	UpdateProfileRequest = dto.UpdateProfileRequest
+	PublicProfile        = dto.PublicProfileResponse
+	SearchResult         = dto.SearchResponse
+	Pagination           = dto.PaginationResponse
+	AccountDeletion      = dto.AccountDeletionResponse
+
+	CreateConversationRequest = dto.CreateConversationRequest
+	UpdateConversationRequest = dto.UpdateConversationRequest
+	AddParticipantsRequest    = dto.AddParticipantsRequest
+	Conversation              = dto.ConversationResponse
+	ConversationList          = dto.ConversationListResponse
+	ConversationSummary       = dto.ConversationSummaryResponse
+	Participant               = dto.ParticipantResponse
+
+	SendMessageRequest = dto.SendMessageRequest
+	Message            = dto.MessageResponse
+	MessageList        = dto.MessageListResponse
+
+	ReportRequest = dto.ReportRequest
+	Report        = dto.ReportResponse
+	BlockedUser   = dto.BlockedUserDTO
+	BlockedList   = dto.BlockedListResponse
+
+	Bot                 = dto.BotResponse
+	BotList             = dto.BotListResponse
+	CreateBotRequest    = dto.CreateBotRequest
+	CreateTokenRequest  = dto.CreateTokenRequest
+	CreatedToken        = dto.CreateTokenResponse
+	PersonalAccessToken = dto.PersonalAccessTokenResponse
+	TokenList           = dto.TokenListResponse
+
+	CreateWebhookRequest     = dto.CreateWebhookRequest
+	CreatedWebhook           = dto.CreateWebhookResponse
+	Webhook                  = dto.WebhookResponse
+	WebhookList              = dto.WebhookListResponse
+	WebhookDelivery          = dto.WebhookDeliveryResponse
+	WebhookDeliveryList      = dto.WebhookDeliveryListResponse
+	ParticipantsAddedEvent   = dto.ParticipantsAddedEvent
+	ConversationRenamedEvent = dto.ConversationRenamedEvent
+
+	CreateIncomingWebhookRequest = dto.CreateIncomingWebhookRequest
+	CreatedIncomingWebhook       = dto.CreateIncomingWebhookResponse
+	IncomingWebhook              = dto.IncomingWebhookResponse
+	IncomingWebhookList          = dto.IncomingWebhookListResponse
+	PostHookMessageRequest       = dto.PostHookMessageRequest
+	CreateCommandRequest         = dto.CreateCommandRequest
+	CreatedCommand               = dto.CreateCommandResponse
+	Command                      = dto.CommandResponse
+	CommandList                  = dto.CommandListResponse
+
+	RegisterDeviceRequest = dto.RegisterDeviceRequest
+	Device                = dto.DeviceResponse
+	DeviceList            = dto.DeviceListResponse
+	MuteRequest           = dto.MuteRequest
+	MuteStatus            = dto.MuteResponse
+
+	DigestSettingsRequest = dto.DigestSettingsRequest
+	DigestSettings        = dto.DigestSettingsResponse
+)
+
+// Page selects a page of a cursor-paginated list. The zero value asks for the
+// first page at the server's default size.
+type Page struct {
+	Cursor string // NextCursor from the previous page
+	Limit  int
+}
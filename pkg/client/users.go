@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Me returns the authenticated user.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/users/me", auth: true}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateMe changes the authenticated user's profile. Nil fields are left as they are.
+func (c *Client) UpdateMe(ctx context.Context, req UpdateProfileRequest) (*User, error) {
+	var user User
+	if _, err := c.do(ctx, request{method: http.MethodPatch, path: "/users/me", body: req, auth: true}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// User returns another user's public profile.
+func (c *Client) User(ctx context.Context, id uuid.UUID) (*PublicProfile, error) {
+	var profile PublicProfile
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/users/" + id.String(), auth: true}, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SearchUsers finds users by display name.
+func (c *Client) SearchUsers(ctx context.Context, query string, page Page) (*SearchResult, error) {
+	q := pageQuery(page)
+	q.Set("q", query)
+	var result SearchResult
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/users", query: q, auth: true}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteAccount schedules the account for deletion. It can be cancelled
+// with CancelAccountDeletion until the returned time.
+func (c *Client) DeleteAccount(ctx context.Context) (*AccountDeletion, error) {
+	var resp AccountDeletion
+	if _, err := c.do(ctx, request{method: http.MethodDelete, path: "/users/me", auth: true}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CancelAccountDeletion keeps an account scheduled for deletion.
+func (c *Client) CancelAccountDeletion(ctx context.Context) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/users/me/deletion/cancel", auth: true}, nil)
+	return err
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types, sent in the X-Webhook-Event header and the envelope's
+// type field.
+const (
+	WebhookMessageCreated      = "message.created"
+	WebhookParticipantAdded    = "participant.added"
+	WebhookConversationRenamed = "conversation.renamed"
+)
+
+// CreateWebhook registers a webhook on a conversation the caller owns. Its
+// signing secret is in the response only; the server cannot show it again.
+func (c *Client) CreateWebhook(ctx context.Context, conversationID uuid.UUID, req CreateWebhookRequest) (*CreatedWebhook, error) {
+	var hook CreatedWebhook
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: webhooksPath(conversationID), body: req, auth: true}, &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// Webhooks lists a conversation's webhooks, without their secrets.
+func (c *Client) Webhooks(ctx context.Context, conversationID uuid.UUID) ([]Webhook, error) {
+	var list WebhookList
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: webhooksPath(conversationID), auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return list.Webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log.
+func (c *Client) DeleteWebhook(ctx context.Context, conversationID, webhookID uuid.UUID) error {
+	path := webhooksPath(conversationID) + "/" + webhookID.String()
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: path, auth: true}, nil)
+	return err
+}
+
+// WebhookDeliveries lists a webhook's deliveries, newest first.
+func (c *Client) WebhookDeliveries(ctx context.Context, conversationID, webhookID uuid.UUID, page Page) (*WebhookDeliveryList, error) {
+	var list WebhookDeliveryList
+	path := webhooksPath(conversationID) + "/" + webhookID.String() + "/deliveries"
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: path, query: pageQuery(page), auth: true}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// RedeliverWebhook queues a delivery to be sent again, with a fresh set of
+// attempts. It is sent by the server's dispatcher, not during the call.
+func (c *Client) RedeliverWebhook(ctx context.Context, conversationID, webhookID, deliveryID uuid.UUID) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	path := webhooksPath(conversationID) + "/" + webhookID.String() + "/deliveries/" + deliveryID.String() + "/redeliver"
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: path, auth: true}, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func webhooksPath(conversationID uuid.UUID) string {
+	return "/conversations/" + conversationID.String() + "/webhooks"
+}
+
+// ErrInvalidWebhookSignature is returned by VerifyWebhookSignature for a
+// delivery that was not signed with the secret, or was signed too long ago.
+var ErrInvalidWebhookSignature = errors.New("client: invalid webhook signature")
+
+// VerifyWebhookSignature checks a delivery's X-Webhook-Signature header
+// against its raw body. Deliveries signed more than tolerance from now are
+// rejected so that captured requests cannot be replayed; zero skips the check.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for part := range strings.SplitSeq(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidWebhookSignature
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return ErrInvalidWebhookSignature
+		}
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
@@ -16,7 +16,9 @@ export interface User {
 	display_name: string;
 	avatar_url: string | null;
 	status: string;
+	type: 'human' | 'bot';
 	created_at: string;
+	deletion_scheduled_at?: string;
 }
 
 // Users
@@ -25,6 +27,7 @@ export interface PublicProfile {
 	display_name: string;
 	avatar_url: string | null;
 	status: string;
+	type?: 'human' | 'bot';
 }
 
 // Conversations
@@ -70,6 +73,7 @@ export interface Message {
 	sender_id: string;
 	body: string;
 	status: string;
+	client_message_id?: string;
 	created_at: string;
 }
 